@@ -24,26 +24,28 @@ import (
 )
 
 type initParameter struct {
-	CInitPath     string        `json:"cinitPath"`
-	Parallelism   int           `json:"parallelism"`
-	TmpFsParam    string        `json:"tmpfsParam"`
-	Dir           string        `json:"dir"`
-	NetShare      bool          `json:"netShare"`
-	MountConf     string        `json:"mountConf"`
-	SrcPrefix     string        `json:"srcPrefix"`
-	CgroupPrefix  string        `json:"cgroupPrefix"`
-	CPUSet        []string      `json:"cpuset"`
-	CredStart     int           `json:"credStart"`
-	EnableCPURate bool          `json:"enableCpuRate"`
-	CPUCfsPeriod  time.Duration `json:"cpuCfsPeriod"`
-	NoFallback    bool          `json:"noFallback"`
+	CInitPath       string        `json:"cinitPath"`
+	Parallelism     int           `json:"parallelism"`
+	TmpFsParam      string        `json:"tmpfsParam"`
+	Dir             string        `json:"dir"`
+	NetShare        bool          `json:"netShare"`
+	MountConf       string        `json:"mountConf"`
+	SrcPrefix       string        `json:"srcPrefix"`
+	DecompressLimit int64         `json:"decompressLimit"`
+	CgroupPrefix    string        `json:"cgroupPrefix"`
+	CPUSet          []string      `json:"cpuset"`
+	CredStart       int           `json:"credStart"`
+	EnableCPURate   bool          `json:"enableCpuRate"`
+	CPUCfsPeriod    time.Duration `json:"cpuCfsPeriod"`
+	NoFallback      bool          `json:"noFallback"`
 }
 
 var (
 	fs   filestore.FileStore
 	work worker.Worker
 
-	srcPrefix []string
+	srcPrefix       []string
+	decompressLimit int64
 )
 
 func newFileStore(dir string) (filestore.FileStore, error) {
@@ -89,13 +91,18 @@ func Init(i *C.char) C.int {
 
 	srcPrefix = strings.Split(ip.SrcPrefix, ",")
 
+	if ip.DecompressLimit == 0 {
+		ip.DecompressLimit = 256 << 20
+	}
+	decompressLimit = ip.DecompressLimit
+
 	var err error
 	fs, err = newFileStore(ip.Dir)
 	if err != nil {
 		log.Fatalln("file store create failed", err)
 	}
 
-	b, _, err := env.NewBuilder(env.Config{
+	b, param, err := env.NewBuilder(env.Config{
 		ContainerInitPath:  ip.CInitPath,
 		MountConf:          ip.MountConf,
 		TmpFsParam:         ip.TmpFsParam,
@@ -110,6 +117,7 @@ func Init(i *C.char) C.int {
 		log.Fatalln("create environment builder failed", err)
 	}
 	envPool := pool.NewPool(b)
+	separateCred, _ := param["separateCred"].(bool)
 	work = worker.New(worker.Config{
 		FileStore:             fs,
 		EnvironmentPool:       envPool,
@@ -117,6 +125,7 @@ func Init(i *C.char) C.int {
 		WorkDir:               ip.Dir,
 		TimeLimitTickInterval: 100 * time.Millisecond,
 		CPUSets:               ip.CPUSet,
+		SeparateCred:          separateCred,
 	})
 	work.Start()
 
@@ -134,7 +143,7 @@ func Exec(e *C.char) *C.char {
 	if err := json.NewDecoder(bytes.NewBufferString(es)).Decode(&req); err != nil {
 		return nil
 	}
-	r, err := model.ConvertRequest(&req, srcPrefix)
+	r, err := model.ConvertRequest(&req, srcPrefix, decompressLimit)
 	if err != nil {
 		return nil
 	}
@@ -145,6 +154,10 @@ func Exec(e *C.char) *C.char {
 		return nil
 	}
 	defer ret.Close()
+	if err := ret.CompressFiles(&req); err != nil {
+		return nil
+	}
+	ret.EncodeFiles(&req)
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(ret); err != nil {
 		return nil
@@ -203,6 +216,21 @@ func FileAdd(content *C.char, contentLen C.int, name *C.char) *C.char {
 	return C.CString(id)
 }
 
+// DirAdd adds directory tree from tar, tar.gz or zip archive to the file store
+//
+// Remember to free the return char pointer value
+//
+//export DirAdd
+func DirAdd(content *C.char, contentLen C.int, name *C.char) *C.char {
+	sContent := C.GoBytes(unsafe.Pointer(content), contentLen)
+
+	id, err := filestore.AddDir(fs, C.GoString(name), bytes.NewReader(sContent), int64(len(sContent)))
+	if err != nil {
+		return nil
+	}
+	return C.CString(id)
+}
+
 // FileGet gets file from file store by id.
 // If the return value is a positive number or zero, the value represents the length of the file.
 // Otherwise, if the return value is negative, the following error occurred:
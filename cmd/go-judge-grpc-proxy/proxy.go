@@ -79,16 +79,42 @@ func (p *execProxy) FileGet(c *gin.Context) {
 }
 
 func (p *execProxy) FilePost(c *gin.Context) {
+	req, ok := formFileContent(c)
+	if !ok {
+		return
+	}
+	rep, err := p.client.FileAdd(c, req)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+func (p *execProxy) DirPost(c *gin.Context) {
+	req, ok := formFileContent(c)
+	if !ok {
+		return
+	}
+	rep, err := p.client.DirAdd(c, req)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+func formFileContent(c *gin.Context) (*pb.FileContent, bool) {
 	fh, err := c.FormFile("file")
 	if err != nil {
 		c.AbortWithError(http.StatusBadRequest, err)
-		return
+		return nil, false
 	}
 
 	fi, err := fh.Open()
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
-		return
+		return nil, false
 	}
 	defer fi.Close()
 	var buf bytes.Buffer
@@ -97,20 +123,14 @@ func (p *execProxy) FilePost(c *gin.Context) {
 	}
 	if _, err := buf.ReadFrom(fi); err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
-		return
+		return nil, false
 	}
 	b := buf.Bytes()
 
-	req := pb.FileContent_builder{
+	return pb.FileContent_builder{
 		Name:    fh.Filename,
 		Content: b,
-	}.Build()
-	rep, err := p.client.FileAdd(c, req)
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
-	}
-	c.JSON(http.StatusOK, rep)
+	}.Build(), true
 }
 
 func (p *execProxy) FileDelete(c *gin.Context) {
@@ -154,6 +174,7 @@ func main() {
 	r.GET("/file", p.FileList)
 	r.GET("/file/:fid", p.FileGet)
 	r.POST("/file", p.FilePost)
+	r.POST("/dir", p.DirPost)
 	r.DELETE("/file/:fid", p.FileDelete)
 
 	log.Println(r.Run(*addr))
@@ -0,0 +1,179 @@
+// Package compress provides gzip and zstd compression for request / response
+// bodies and file contents with limits on the decompressed size to avoid
+// decompression bombs
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithm defines the compression algorithm, named as the HTTP content coding
+type Algorithm string
+
+// Algorithm enums
+const (
+	Identity Algorithm = ""
+	Gzip     Algorithm = "gzip"
+	Zstd     Algorithm = "zstd"
+)
+
+// ErrLimitExceeded is returned when the decompressed size exceeds the limit
+var ErrLimitExceeded = errors.New("decompressed size exceeds limit")
+
+// Writer is the compress writer which is able to flush partial content
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Parse parses the algorithm name, empty and identity are both Identity
+func Parse(s string) (Algorithm, error) {
+	switch a := Algorithm(strings.ToLower(strings.TrimSpace(s))); a {
+	case Identity, "identity":
+		return Identity, nil
+	case Gzip, "x-gzip":
+		return Gzip, nil
+	case Zstd:
+		return Zstd, nil
+	default:
+		return Identity, fmt.Errorf("unknown compression: %q", s)
+	}
+}
+
+// Negotiate returns the preferred algorithm accepted by the Accept-Encoding
+// header, zstd is preferred over gzip
+func Negotiate(acceptEncoding string) Algorithm {
+	var gz bool
+	for v := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(v, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(q, 64); err != nil || f <= 0 {
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case string(Zstd):
+			return Zstd
+		case string(Gzip), "x-gzip":
+			gz = true
+		}
+	}
+	if gz {
+		return Gzip
+	}
+	return Identity
+}
+
+// NewReader creates a decompress reader over r, the decompressed size is not
+// limited
+func NewReader(r io.Reader, a Algorithm) (io.ReadCloser, error) {
+	switch a {
+	case Identity:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %q", a)
+	}
+}
+
+// NewWriter creates a compress writer over w, Close need to be called to
+// flush the remaining content
+func NewWriter(w io.Writer, a Algorithm) (Writer, error) {
+	switch a {
+	case Identity:
+		return nopWriter{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, fmt.Errorf("unknown compression: %q", a)
+	}
+}
+
+// LimitReader returns a reader that returns ErrLimitExceeded when more than
+// n bytes is read from r (n <= 0 for unlimited)
+func LimitReader(r io.Reader, n int64) io.Reader {
+	if n <= 0 {
+		return r
+	}
+	return &limitReader{r: r, n: n}
+}
+
+// Compress compresses the content
+func Compress(b []byte, a Algorithm) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, a)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses the content with the decompressed size limited by
+// limit (<= 0 for unlimited)
+func Decompress(b []byte, a Algorithm, limit int64) ([]byte, error) {
+	if a == Identity {
+		return b, nil
+	}
+	r, err := NewReader(bytes.NewReader(b), a)
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", a, err)
+	}
+	defer r.Close()
+
+	c, err := io.ReadAll(LimitReader(r, limit))
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", a, err)
+	}
+	return c, nil
+}
+
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrLimitExceeded
+	}
+	// read one more byte to detect exceeding
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), ErrLimitExceeded
+	}
+	return n, err
+}
+
+type nopWriter struct {
+	io.Writer
+}
+
+func (nopWriter) Close() error { return nil }
+func (nopWriter) Flush() error { return nil }
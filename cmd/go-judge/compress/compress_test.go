@@ -0,0 +1,70 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("1 2 3\n"), 1024)
+	for _, a := range []Algorithm{Identity, Gzip, Zstd} {
+		c, err := Compress(content, a)
+		if err != nil {
+			t.Fatalf("compress %q: %v", a, err)
+		}
+		if a != Identity && len(c) >= len(content) {
+			t.Errorf("compress %q: expected smaller output, got %d bytes", a, len(c))
+		}
+		d, err := Decompress(c, a, int64(len(content)))
+		if err != nil {
+			t.Fatalf("decompress %q: %v", a, err)
+		}
+		if !bytes.Equal(d, content) {
+			t.Errorf("decompress %q: content mismatch", a)
+		}
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	content := make([]byte, 1<<20)
+	for _, a := range []Algorithm{Gzip, Zstd} {
+		c, err := Compress(content, a)
+		if err != nil {
+			t.Fatalf("compress %q: %v", a, err)
+		}
+		if _, err := Decompress(c, a, 1<<10); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("decompress %q: expected limit exceeded, got %v", a, err)
+		}
+		if _, err := Decompress(c, a, 0); err != nil {
+			t.Errorf("decompress %q unlimited: %v", a, err)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Algorithm
+	}{
+		{"", Identity},
+		{"gzip, deflate, br", Gzip},
+		{"gzip;q=0.5, zstd", Zstd},
+		{"zstd;q=0, gzip", Gzip},
+		{"identity", Identity},
+	}
+	for _, tc := range tests {
+		if got := Negotiate(tc.header); got != tc.want {
+			t.Errorf("Negotiate(%q) = %q, expected %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if a, err := Parse("GZIP"); err != nil || a != Gzip {
+		t.Errorf("Parse(GZIP) = %q %v", a, err)
+	}
+	if _, err := Parse("br"); err == nil {
+		t.Errorf("Parse(br) expected error")
+	}
+}
@@ -24,29 +24,53 @@ type Config struct {
 	NoFallback         bool   `flagUsage:"exit if fallback to rlimit / rusage mode"`
 
 	// file store
-	SrcPrefix []string `flagUsage:"specifies directory prefix for source type copyin (example: -src-prefix=/home,/usr)"`
-	Dir       string   `flagUsage:"specifies directory to store file upload / download (in memory by default)"`
+	SrcPrefix   []string `flagUsage:"specifies directory prefix for source type copyin (example: -src-prefix=/home,/usr)"`
+	Dir         string   `flagUsage:"specifies directory to store file upload / download (in memory by default)"`
+	Datasets    []string `flagUsage:"specifies read-only datasets for copyin in form of name:dir, addressed by src as dataset:name/path (example: -datasets=tests:/data/tests)"`
+	ZeroCopyDir string   `flagUsage:"specifies host directory mounted read-only into containers to stage zeroCopy copyin files, files on the same file system are linked without copy (default: dir with -bind suffix, linux only)"`
 
 	// runner limit
 	TimeLimitCheckerInterval time.Duration `flagUsage:"specifies time limit checker interval" default:"100ms"`
 	ExtraMemoryLimit         *envexec.Size `flagUsage:"specifies extra memory buffer for check memory limit" default:"16k"`
 	OutputLimit              *envexec.Size `flagUsage:"specifies POSIX rlimit for output for each command" default:"256m"`
 	CopyOutLimit             *envexec.Size `flagUsage:"specifies default file copy out max" default:"256m"`
+	CopyOutFileLimit         int           `flagUsage:"specifies default max number of files copied out by glob patterns" default:"1024"`
 	OpenFileLimit            int           `flagUsage:"specifies max open file count" default:"256"`
+	ExtractLimit             *envexec.Size `flagUsage:"specifies max total size of files extracted from archive in copyIn (0 for unlimited)" default:"256m"`
+	ExtractFileLimit         int           `flagUsage:"specifies max number of entries extracted from archive in copyIn (0 for unlimited)" default:"4096"`
+	ExtractDepthLimit        int           `flagUsage:"specifies max path depth of entries extracted from archive in copyIn (0 for unlimited)" default:"32"`
+	LiveOutputInterval       time.Duration `flagUsage:"specifies min interval between live output events of a collector" default:"100ms"`
+	LiveOutputLimit          *envexec.Size `flagUsage:"specifies max bytes of live output events for each collector (0 to disable)" default:"1m"`
 	Cpuset                   []string      `flagUsage:"control the usage of cpuset for all container process"`
 	EnableCPURate            bool          `flagUsage:"enable cpu cgroup rate control"`
 	CPUCfsPeriod             time.Duration `flagUsage:"set cpu.cfs_period" default:"100ms"`
 	FileTimeout              time.Duration `flagUsage:"specified timeout for filestore files"`
+	EnableNamespace          bool          `flagUsage:"isolate filestore files by namespace derived from auth token or X-Namespace header"`
+	NamespaceQuota           *envexec.Size `flagUsage:"specifies total file size quota for each namespace (0 for unlimited)" default:"0"`
+	FileCacheLimit           *envexec.Size `flagUsage:"specifies total size of filestore files cached in memory (0 to disable)" default:"0"`
+	FileCacheMaxFileSize     *envexec.Size `flagUsage:"specifies max size of a filestore file to be cached in memory" default:"1m"`
+	FileCachePromoteHits     int           `flagUsage:"specifies number of accesses before a filestore file is cached in memory" default:"2"`
+	FileCacheMemfd           bool          `flagUsage:"keep filestore files cached in sealed memfd instead of heap (linux only)"`
 
 	// server config
-	HTTPAddr      string        `flagUsage:"specifies the http binding address"`
-	EnableGRPC    bool          `flagUsage:"enable gRPC endpoint"`
-	GRPCAddr      string        `flagUsage:"specifies the grpc binding address"`
-	MonitorAddr   string        `flagUsage:"specifies the metrics binding address"`
-	AuthToken     string        `flagUsage:"bearer token auth for REST / gRPC"`
-	GRPCMsgSize   *envexec.Size `flagUsage:"message size limit for gRPC message" default:"64m"`
-	EnableDebug   bool          `flagUsage:"enable debug endpoint"`
-	EnableMetrics bool          `flagUsage:"enable prometheus metrics endpoint"`
+	HTTPAddr        string        `flagUsage:"specifies the http binding address"`
+	EnableGRPC      bool          `flagUsage:"enable gRPC endpoint"`
+	GRPCAddr        string        `flagUsage:"specifies the grpc binding address"`
+	MonitorAddr     string        `flagUsage:"specifies the metrics binding address"`
+	AuthToken       string        `flagUsage:"bearer token auth for REST / gRPC"`
+	AuthTokens      []string      `flagUsage:"namespaced bearer tokens for REST / gRPC in form of namespace:token (example: -auth-tokens=team1:token1,team2:token2)"`
+	GRPCMsgSize     *envexec.Size `flagUsage:"message size limit for gRPC message" default:"64m"`
+	GRPCReflection  bool          `flagUsage:"enable gRPC server reflection"`
+	DecompressLimit *envexec.Size `flagUsage:"specifies max decompressed size of compressed REST request body and file content (0 for unlimited)" default:"256m"`
+	EnableDebug     bool          `flagUsage:"enable debug endpoint"`
+	EnableMetrics   bool          `flagUsage:"enable prometheus metrics endpoint"`
+	EnableAdmin     bool          `flagUsage:"enable admin endpoint (e.g. filestore snapshot export / import), requires auth token"`
+	DrainTimeout    time.Duration `flagUsage:"specifies max time to wait for requests in flight to finish on shutdown" default:"30s"`
+
+	// stream session config
+	StreamResumeGrace  time.Duration `flagUsage:"specifies how long a resumable /stream session keeps running after disconnect (0 to disable)" default:"30s"`
+	StreamResumeBuffer *envexec.Size `flagUsage:"specifies max size of output kept for replay of each resumable /stream session" default:"1m"`
+	StreamResumeMax    int           `flagUsage:"specifies max number of resumable /stream sessions kept (0 for unlimited)" default:"1024"`
 
 	// logger config
 	Release bool `flagUsage:"release level of logs"`
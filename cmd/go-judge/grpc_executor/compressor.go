@@ -0,0 +1,48 @@
+package grpcexecutor
+
+import (
+	"io"
+
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // register gzip compressor
+)
+
+// registers zstd compressor, the decompressed message size is limited by
+// max receive message size of the server
+func init() {
+	encoding.RegisterCompressor(&compressor{algorithm: compress.Zstd})
+}
+
+type compressor struct {
+	algorithm compress.Algorithm
+}
+
+func (c *compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return compress.NewWriter(w, c.algorithm)
+}
+
+func (c *compressor) Decompress(r io.Reader) (io.Reader, error) {
+	rc, err := compress.NewReader(r, c.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &closeOnEOFReader{rc}, nil
+}
+
+func (c *compressor) Name() string {
+	return string(c.algorithm)
+}
+
+// closeOnEOFReader releases the decoder when the message is fully read
+type closeOnEOFReader struct {
+	io.ReadCloser
+}
+
+func (r *closeOnEOFReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.Close()
+	}
+	return n, err
+}
@@ -1,13 +1,17 @@
 package grpcexecutor
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"time"
 
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
 	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/cmd/go-judge/stream"
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/filestore"
 	"github.com/criyle/go-judge/pb"
@@ -18,29 +22,37 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-// New creates grpc executor server
-func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, logger *zap.Logger) pb.ExecutorServer {
+// New creates grpc executor server, admin RPCs are rejected unless enableAdmin.
+// Stream flow control statistic is recorded to flow if it is not nil.
+func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, decompressLimit int64, flow *stream.Flow, enableAdmin bool, logger *zap.Logger) pb.ExecutorServer {
 	return &execServer{
-		worker:    worker,
-		fs:        fs,
-		srcPrefix: srcPrefix,
-		logger:    logger,
+		worker:          worker,
+		fs:              fs,
+		srcPrefix:       srcPrefix,
+		decompressLimit: decompressLimit,
+		flow:            flow,
+		enableAdmin:     enableAdmin,
+		logger:          logger,
 	}
 }
 
 type execServer struct {
 	pb.UnimplementedExecutorServer
-	worker    worker.Worker
-	fs        filestore.FileStore
-	srcPrefix []string
-	logger    *zap.Logger
+	worker          worker.Worker
+	fs              filestore.FileStore
+	srcPrefix       []string
+	decompressLimit int64
+	flow            *stream.Flow
+	enableAdmin     bool
+	logger          *zap.Logger
 }
 
 func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, error) {
-	r, err := convertPBRequest(req, e.srcPrefix)
+	r, err := convertPBRequest(req, e.srcPrefix, e.decompressLimit)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	r.Namespace = filestore.NamespaceFromContext(ctx)
 	if ce := e.logger.Check(zap.DebugLevel, "request"); ce != nil {
 		ce.Write(zap.String("body", fmt.Sprintf("%+v", r)))
 	}
@@ -49,6 +61,14 @@ func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, e
 	if ce := e.logger.Check(zap.DebugLevel, "response"); ce != nil {
 		ce.Write(zap.String("body", fmt.Sprintf("%+v", rt)))
 	}
+	return convertPBExecResponse(req, rt)
+}
+
+// convertPBExecResponse converts the worker response of the request
+func convertPBExecResponse(req *pb.Request, rt worker.Response) (*pb.Response, error) {
+	if errors.Is(rt.Error, worker.ErrDraining) {
+		return nil, status.Error(codes.Unavailable, rt.Error.Error())
+	}
 	if rt.Error != nil {
 		return nil, status.Error(codes.Internal, rt.Error.Error())
 	}
@@ -56,6 +76,14 @@ func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, e
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	for i, c := range req.GetCmd() {
+		if i >= len(ret.Results) {
+			break
+		}
+		if err := ret.Results[i].CompressFiles(convertPBCompressions(c)); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
 	resp, err := convertPBResponse(ret)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
@@ -63,14 +91,19 @@ func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, e
 	return resp, nil
 }
 
+// store returns the file store scoped to the namespace of the request
+func (e *execServer) store(c context.Context) filestore.FileStore {
+	return filestore.ForNamespace(e.fs, filestore.NamespaceFromContext(c))
+}
+
 func (e *execServer) FileList(c context.Context, n *emptypb.Empty) (*pb.FileListType, error) {
 	return pb.FileListType_builder{
-		FileIDs: e.fs.List(),
+		FileIDs: e.store(c).List(),
 	}.Build(), nil
 }
 
 func (e *execServer) FileGet(c context.Context, f *pb.FileID) (*pb.FileContent, error) {
-	name, file := e.fs.Get(f.GetFileID())
+	name, file := e.store(c).Get(f.GetFileID())
 	if file == nil {
 		return nil, status.Errorf(codes.NotFound, "file not found: %q", f.GetFileID())
 	}
@@ -91,7 +124,8 @@ func (e *execServer) FileGet(c context.Context, f *pb.FileID) (*pb.FileContent,
 }
 
 func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID, error) {
-	f, err := e.fs.New()
+	fs := e.store(c)
+	f, err := fs.New()
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -105,7 +139,10 @@ func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID,
 	if _, err := f.Write(fc.GetContent()); err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	fid, err := e.fs.Add(fc.GetName(), f.Name())
+	fid, err := fs.Add(fc.GetName(), f.Name())
+	if errors.Is(err, filestore.ErrQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -114,14 +151,50 @@ func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID,
 	}.Build(), nil
 }
 
+func (e *execServer) DirAdd(c context.Context, fc *pb.FileContent) (*pb.FileID, error) {
+	content := fc.GetContent()
+	fid, err := filestore.AddDir(e.store(c), fc.GetName(), bytes.NewReader(content), int64(len(content)))
+	switch {
+	case errors.Is(err, filestore.ErrInvalidArchive):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, filestore.ErrQuotaExceeded):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return pb.FileID_builder{
+		FileID: fid,
+	}.Build(), nil
+}
+
 func (e *execServer) FileDelete(c context.Context, f *pb.FileID) (*emptypb.Empty, error) {
-	ok := e.fs.Remove(f.GetFileID())
+	ok := e.store(c).Remove(f.GetFileID())
 	if !ok {
 		return nil, status.Errorf(codes.NotFound, "file id does not exists: %q", f.GetFileID())
 	}
 	return &emptypb.Empty{}, nil
 }
 
+// convertPBCompressions returns compressions of the collectors by name
+func convertPBCompressions(c *pb.Request_CmdType) map[string]compress.Algorithm {
+	var rt map[string]compress.Algorithm
+	for _, f := range c.GetFiles() {
+		p := f.GetPipe()
+		if p == nil || p.GetCompression() == "" {
+			continue
+		}
+		a, err := compress.Parse(p.GetCompression())
+		if err != nil {
+			continue
+		}
+		if rt == nil {
+			rt = make(map[string]compress.Algorithm)
+		}
+		rt[p.GetName()] = a
+	}
+	return rt
+}
+
 func convertPBResponse(r model.Response) (*pb.Response, error) {
 	res := pb.Response_builder{
 		RequestID: r.RequestID,
@@ -140,19 +213,34 @@ func convertPBResponse(r model.Response) (*pb.Response, error) {
 
 func convertPBResult(r model.Result) (*pb.Response_Result, error) {
 	return pb.Response_Result_builder{
-		Status:     pb.Response_Result_StatusType(r.Status),
-		ExitStatus: int32(r.ExitStatus),
-		Error:      r.Error,
-		Time:       r.Time,
-		RunTime:    r.RunTime,
-		Memory:     r.Memory,
-		ProcPeak:   r.ProcPeak,
-		Files:      r.Buffs,
-		FileIDs:    r.FileIDs,
-		FileError:  convertPBFileError(r.FileError),
+		Status:      pb.Response_Result_StatusType(r.Status),
+		ExitStatus:  int32(r.ExitStatus),
+		Error:       r.Error,
+		Time:        r.Time,
+		RunTime:     r.RunTime,
+		Memory:      r.Memory,
+		ProcPeak:    r.ProcPeak,
+		Files:       r.Buffs,
+		FileIDs:     r.FileIDs,
+		FileDigests: convertPBFileDigests(r.FileDigests),
+		FileError:   convertPBFileError(r.FileError),
 	}.Build(), nil
 }
 
+func convertPBFileDigests(d map[string]model.FileDigest) map[string]*pb.Response_FileDigest {
+	if d == nil {
+		return nil
+	}
+	rt := make(map[string]*pb.Response_FileDigest, len(d))
+	for k, v := range d {
+		rt[k] = pb.Response_FileDigest_builder{
+			Size:   v.Size,
+			Sha256: v.SHA256,
+		}.Build()
+	}
+	return rt
+}
+
 func convertPBFileError(fe []envexec.FileError) []*pb.Response_FileError {
 	rt := make([]*pb.Response_FileError, 0, len(fe))
 	for _, e := range fe {
@@ -160,6 +248,7 @@ func convertPBFileError(fe []envexec.FileError) []*pb.Response_FileError {
 			Name:    e.Name,
 			Type:    convertPBFileErrorType(e.Type),
 			Message: e.Message,
+			Size:    e.Size,
 		}.Build())
 	}
 	return rt
@@ -187,19 +276,24 @@ func convertPBFileErrorType(t envexec.FileErrorType) pb.Response_FileError_Error
 		return pb.Response_FileError_CollectSizeExceeded
 	case envexec.ErrSymlink:
 		return pb.Response_FileError_Symlink
+	case envexec.ErrCopyInExtract:
+		return pb.Response_FileError_CopyInExtract
+	case envexec.ErrCopyOutCountExceeded:
+		return pb.Response_FileError_CopyOutCountExceeded
 	default:
 		return pb.Response_FileError_CopyInOpenFile
 	}
 }
 
-func convertPBRequest(r *pb.Request, srcPrefix []string) (req *worker.Request, err error) {
+func convertPBRequest(r *pb.Request, srcPrefix []string, decompressLimit int64) (req *worker.Request, err error) {
 	req = &worker.Request{
 		RequestID:   r.GetRequestID(),
 		Cmd:         make([]worker.Cmd, 0, len(r.GetCmd())),
 		PipeMapping: make([]worker.PipeMap, 0, len(r.GetPipeMapping())),
+		Tags:        r.GetTags(),
 	}
 	for _, c := range r.GetCmd() {
-		cm, err := convertPBCmd(c, srcPrefix)
+		cm, err := convertPBCmd(c, srcPrefix, decompressLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -226,28 +320,38 @@ func convertPBPipeIndex(p *pb.Request_PipeMap_PipeIndex) worker.PipeIndex {
 	return worker.PipeIndex{Index: int(p.GetIndex()), Fd: int(p.GetFd())}
 }
 
-func convertPBCmd(c *pb.Request_CmdType, srcPrefix []string) (cm worker.Cmd, err error) {
+func convertPBCmd(c *pb.Request_CmdType, srcPrefix []string, decompressLimit int64) (cm worker.Cmd, err error) {
 	cm = worker.Cmd{
-		Args:              c.GetArgs(),
-		Env:               c.GetEnv(),
-		TTY:               c.GetTty(),
-		CPULimit:          time.Duration(c.GetCpuTimeLimit()),
-		ClockLimit:        time.Duration(c.GetClockTimeLimit()),
-		MemoryLimit:       envexec.Size(c.GetMemoryLimit()),
-		StackLimit:        envexec.Size(c.GetStackLimit()),
-		ProcLimit:         c.GetProcLimit(),
-		CPURateLimit:      c.GetCpuRateLimit(),
-		CPUSetLimit:       c.GetCpuSetLimit(),
-		DataSegmentLimit:  c.GetDataSegmentLimit(),
-		AddressSpaceLimit: c.GetAddressSpaceLimit(),
-		CopyOut:           convertCopyOut(c.GetCopyOut()),
-		CopyOutCached:     convertCopyOut(c.GetCopyOutCached()),
-		CopyOutMax:        c.GetCopyOutMax(),
-		CopyOutTruncate:   c.GetCopyOutTruncate(),
-		Symlinks:          c.GetSymlinks(),
+		Args:                c.GetArgs(),
+		Env:                 c.GetEnv(),
+		TTY:                 c.GetTty(),
+		CPULimit:            time.Duration(c.GetCpuTimeLimit()),
+		ClockLimit:          time.Duration(c.GetClockTimeLimit()),
+		MemoryLimit:         envexec.Size(c.GetMemoryLimit()),
+		StackLimit:          envexec.Size(c.GetStackLimit()),
+		ProcLimit:           c.GetProcLimit(),
+		CPURateLimit:        c.GetCpuRateLimit(),
+		CPUSetLimit:         c.GetCpuSetLimit(),
+		DataSegmentLimit:    c.GetDataSegmentLimit(),
+		AddressSpaceLimit:   c.GetAddressSpaceLimit(),
+		CopyOut:             convertCopyOut(c.GetCopyOut()),
+		CopyOutCached:       convertCopyOut(c.GetCopyOutCached()),
+		CopyOutMax:          c.GetCopyOutMax(),
+		CopyOutMaxFiles:     c.GetCopyOutMaxFiles(),
+		CopyOutTruncate:     c.GetCopyOutTruncate(),
+		CopyOutTruncateTail: c.GetCopyOutTruncateTail(),
+		CopyOutDigest:       c.GetCopyOutDigest(),
+		CopyOutDigestOnly:   c.GetCopyOutDigestOnly(),
+		Symlinks:            c.GetSymlinks(),
+	}
+	switch f := envexec.ArchiveFormat(c.GetCopyOutDirFormat()); f {
+	case "", envexec.ArchiveTar, envexec.ArchiveZip:
+		cm.CopyOutDirFormat = f
+	default:
+		return cm, fmt.Errorf("unknown copyOut directory format: %q", c.GetCopyOutDirFormat())
 	}
 	for _, f := range c.GetFiles() {
-		cf, err := convertPBFile(f, srcPrefix)
+		cf, err := convertPBFile(f, srcPrefix, decompressLimit)
 		if err != nil {
 			return cm, err
 		}
@@ -256,21 +360,38 @@ func convertPBCmd(c *pb.Request_CmdType, srcPrefix []string) (cm worker.Cmd, err
 	if copyIn := c.GetCopyIn(); copyIn != nil {
 		cm.CopyIn = make(map[string]worker.CmdFile)
 		for k, f := range copyIn {
-			cf, err := convertPBFile(f, srcPrefix)
+			cf, err := convertPBFile(f, srcPrefix, decompressLimit)
 			if err != nil {
 				return cm, err
 			}
+			if f.GetExtract() && cf != nil {
+				cf = &worker.ArchiveFile{File: cf}
+			}
+			if (f.GetMode() != 0 || f.GetOwner() != pb.Request_File_Root || f.GetReadOnly()) && cf != nil {
+				cf = &worker.AttrFile{
+					File:     cf,
+					Mode:     os.FileMode(f.GetMode()).Perm(),
+					Owner:    convertPBFileOwner(f.GetOwner()),
+					ReadOnly: f.GetReadOnly(),
+				}
+			}
+			if f.GetZeroCopy() && cf != nil {
+				cf = &worker.BindFile{File: cf}
+			}
 			cm.CopyIn[k] = cf
 		}
 	}
 	return cm, nil
 }
 
-func convertPBFile(c *pb.Request_File, srcPrefix []string) (worker.CmdFile, error) {
+func convertPBFile(c *pb.Request_File, srcPrefix []string, decompressLimit int64) (worker.CmdFile, error) {
 	switch c.WhichFile() {
 	case 0:
 		return nil, nil
 	case pb.Request_File_Local_case:
+		if df, ok := worker.ParseDatasetFile(c.GetLocal().GetSrc()); ok {
+			return df, nil
+		}
 		if len(srcPrefix) > 0 {
 			ok, err := model.CheckPathPrefixes(c.GetLocal().GetSrc(), srcPrefix)
 			if err != nil {
@@ -282,21 +403,43 @@ func convertPBFile(c *pb.Request_File, srcPrefix []string) (worker.CmdFile, erro
 		}
 		return &worker.LocalFile{Src: c.GetLocal().GetSrc()}, nil
 	case pb.Request_File_Memory_case:
-		return &worker.MemoryFile{Content: c.GetMemory().GetContent()}, nil
+		a, err := compress.Parse(c.GetMemory().GetCompression())
+		if err != nil {
+			return nil, err
+		}
+		b, err := compress.Decompress(c.GetMemory().GetContent(), a, decompressLimit)
+		if err != nil {
+			return nil, err
+		}
+		return &worker.MemoryFile{Content: b}, nil
 	case pb.Request_File_Cached_case:
 		return &worker.CachedFile{FileID: c.GetCached().GetFileID()}, nil
+	case pb.Request_File_CachedDir_case:
+		return &worker.CachedDir{DirID: c.GetCachedDir().GetDirID()}, nil
 	case pb.Request_File_Pipe_case:
-		return &worker.Collector{Name: c.GetPipe().GetName(), Max: envexec.Size(c.GetPipe().GetMax()), Pipe: c.GetPipe().GetPipe()}, nil
+		if _, err := compress.Parse(c.GetPipe().GetCompression()); err != nil {
+			return nil, err
+		}
+		return &worker.Collector{Name: c.GetPipe().GetName(), Max: envexec.Size(c.GetPipe().GetMax()), Pipe: c.GetPipe().GetPipe(), Tail: envexec.Size(c.GetPipe().GetTail()), Live: c.GetPipe().GetLive()}, nil
 	}
 	return nil, fmt.Errorf("request file type not supported: %T", c)
 }
 
+func convertPBFileOwner(o pb.Request_File_Owner) envexec.FileOwner {
+	if o == pb.Request_File_User {
+		return envexec.OwnerUser
+	}
+	return envexec.OwnerRoot
+}
+
 func convertCopyOut(copyOut []*pb.Request_CmdCopyOutFile) []worker.CmdCopyOutFile {
 	rt := make([]worker.CmdCopyOutFile, 0, len(copyOut))
 	for _, n := range copyOut {
 		rt = append(rt, worker.CmdCopyOutFile{
 			Name:     n.GetName(),
 			Optional: n.GetOptional(),
+			Dir:      n.GetDir(),
+			Glob:     n.GetGlob() && !n.GetDir(),
 		})
 	}
 	return rt
@@ -0,0 +1,52 @@
+package grpcexecutor
+
+import (
+	"context"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/pb"
+	"github.com/criyle/go-judge/worker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Cancel cancels the selected requests and returns their partial results after
+// they are finished
+func (e *execServer) Cancel(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	if !e.enableAdmin {
+		return nil, status.Error(codes.Unimplemented, "admin is not enabled")
+	}
+	if filestore.NamespaceFromContext(ctx) != "" {
+		return nil, status.Error(codes.PermissionDenied, "admin is only accessible from the default namespace")
+	}
+	cr := &model.CancelRequest{
+		RequestID: req.GetRequestID(),
+		Namespace: req.GetNamespace(),
+		Tag:       req.GetTag(),
+		Kill:      req.GetKill(),
+		Timeout:   req.GetTimeout(),
+	}
+	if err := cr.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	waitCtx, cancel := cr.WaitContext(ctx)
+	defer cancel()
+
+	var cancelled []*pb.CancelResponse_Cancelled
+	for _, c := range worker.CancelRequests(e.worker, cr.Match, cr.Kill) {
+		resp, err := convertPBResponse(model.WaitCancelled(waitCtx, c))
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		cancelled = append(cancelled, pb.CancelResponse_Cancelled_builder{
+			RequestID: c.RequestID,
+			Namespace: c.Namespace,
+			Tags:      c.Tags,
+			Phase:     pbEventTypes[c.Phase],
+			Response:  resp,
+		}.Build())
+	}
+	return pb.CancelResponse_builder{Cancelled: cancelled}.Build(), nil
+}
@@ -0,0 +1,89 @@
+package grpcexecutor
+
+import (
+	"fmt"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/pb"
+	"github.com/criyle/go-judge/worker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var pbEventTypes = map[worker.EventType]pb.Event_Type{
+	worker.EventQueued:          pb.Event_Queued,
+	worker.EventStarted:         pb.Event_Started,
+	worker.EventCopyInDone:      pb.Event_CopyInDone,
+	worker.EventProcessStarted:  pb.Event_ProcessStarted,
+	worker.EventProcessFinished: pb.Event_ProcessFinished,
+	worker.EventCopyOutDone:     pb.Event_CopyOutDone,
+	worker.EventOutput:          pb.Event_Output,
+}
+
+func (e *execServer) ExecEvents(req *pb.Request, es grpc.ServerStreamingServer[pb.Event]) error {
+	ctx := es.Context()
+	r, err := convertPBRequest(req, e.srcPrefix, e.decompressLimit)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	r.Namespace = filestore.NamespaceFromContext(ctx)
+	if ce := e.logger.Check(zap.DebugLevel, "request"); ce != nil {
+		ce.Write(zap.String("body", fmt.Sprintf("%+v", r)))
+	}
+
+	evCh := r.BufferEvents()
+	rtCh, _ := e.worker.Submit(ctx, r)
+
+	var rt worker.Response
+	var sendErr error
+	send := func(ev worker.Event) {
+		if sendErr == nil {
+			sendErr = es.Send(convertPBEvent(ev))
+		}
+	}
+loop:
+	for {
+		select {
+		case ev := <-evCh:
+			send(ev)
+		case rt = <-rtCh:
+			break loop
+		}
+	}
+	// events are sent before the result
+	for len(evCh) > 0 {
+		send(<-evCh)
+	}
+	if ce := e.logger.Check(zap.DebugLevel, "response"); ce != nil {
+		ce.Write(zap.String("body", fmt.Sprintf("%+v", rt)))
+	}
+	resp, err := convertPBExecResponse(req, rt)
+	if err != nil {
+		return err
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	return es.Send(pb.Event_builder{
+		Type:     pb.Event_Finished,
+		Response: resp,
+	}.Build())
+}
+
+func convertPBEvent(e worker.Event) *pb.Event {
+	ev := pb.Event_builder{
+		Type:     pbEventTypes[e.Type],
+		Index:    int32(e.Index),
+		Position: int32(e.Position),
+		Name:     e.Name,
+		Content:  e.Content,
+	}
+	if e.Result != nil {
+		me := model.ConvertEvent(e)
+		ev.Result, _ = convertPBResult(*me.Result)
+	}
+	return ev.Build()
+}
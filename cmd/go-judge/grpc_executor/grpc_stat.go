@@ -0,0 +1,47 @@
+package grpcexecutor
+
+import (
+	"context"
+
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/pb"
+	"github.com/criyle/go-judge/worker"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Stat returns the statistic visible to the namespace of the request
+func (e *execServer) Stat(c context.Context, _ *emptypb.Empty) (*pb.StatResponse, error) {
+	ns := filestore.NamespaceFromContext(c)
+	s := e.worker.Stat()
+	fs := e.fs
+	if ns != "" {
+		fs = filestore.ForNamespace(fs, ns)
+	}
+	u := filestore.StatUsage(fs)
+
+	var reqs []*pb.StatResponse_Request
+	for _, r := range worker.StatRequests(e.worker) {
+		if ns != "" && r.Namespace != ns {
+			continue
+		}
+		reqs = append(reqs, pb.StatResponse_Request_builder{
+			RequestID: r.RequestID,
+			Namespace: r.Namespace,
+			Phase:     pbEventTypes[r.Phase],
+			StartTime: r.StartTime.UnixNano(),
+			Elapsed:   uint64(r.Elapsed),
+			Tags:      r.Tags,
+		}.Build())
+	}
+	return pb.StatResponse_builder{
+		Queue:       uint64(s.Queue),
+		Running:     uint64(s.Running),
+		Parallelism: uint64(s.Parallelism),
+		EnvIdle:     uint64(s.Pool.Idle),
+		EnvTotal:    uint64(s.Pool.Total),
+		FileCount:   uint64(u.Files),
+		FileSize:    uint64(u.Bytes),
+		Draining:    s.Draining,
+		Requests:    reqs,
+	}.Build(), nil
+}
@@ -2,10 +2,13 @@ package grpcexecutor
 
 import (
 	"errors"
+	"slices"
+	"sync"
 
 	"github.com/criyle/go-judge/cmd/go-judge/model"
 	"github.com/criyle/go-judge/cmd/go-judge/stream"
 	"github.com/criyle/go-judge/pb"
+	"github.com/criyle/go-judge/worker"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -15,10 +18,12 @@ var _ stream.Stream = &streamWrapper{}
 
 type streamWrapper struct {
 	es pb.Executor_ExecStreamServer
+	mu sync.Mutex // input acks are sent concurrently with outputs
 }
 
 func (sw *streamWrapper) Send(r stream.Response) error {
 	res := &pb.StreamResponse{}
+	res.SetExecId(r.ExecID)
 	switch {
 	case r.Response != nil:
 		resp, err := convertPBResponse(*r.Response)
@@ -32,7 +37,19 @@ func (sw *streamWrapper) Send(r stream.Response) error {
 			Fd:      uint32(r.Output.Fd),
 			Content: r.Output.Content,
 		}.Build())
+	case r.Reject != nil:
+		res.SetExecReject(pb.StreamResponse_Reject_builder{
+			Error: r.Reject.Error,
+		}.Build())
+	case r.InputAck != nil:
+		res.SetExecInputAck(pb.StreamResponse_InputAck_builder{
+			Index: uint32(r.InputAck.Index),
+			Fd:    uint32(r.InputAck.Fd),
+			Bytes: uint32(r.InputAck.Bytes),
+		}.Build())
 	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
 	return sw.es.Send(res)
 }
 
@@ -41,17 +58,18 @@ func (sw *streamWrapper) Recv() (*stream.Request, error) {
 	if err != nil {
 		return nil, err
 	}
+	id := req.GetExecId()
 	switch req.WhichRequest() {
 	case pb.StreamRequest_ExecRequest_case:
-		return &stream.Request{Request: convertPBStreamRequest(req.GetExecRequest())}, nil
+		return &stream.Request{ExecID: id, Request: convertPBStreamRequest(req.GetExecRequest())}, nil
 	case pb.StreamRequest_ExecInput_case:
-		return &stream.Request{Input: &stream.InputRequest{
+		return &stream.Request{ExecID: id, Input: &stream.InputRequest{
 			Index:   int(req.GetExecInput().GetIndex()),
 			Fd:      int(req.GetExecInput().GetFd()),
 			Content: req.GetExecInput().GetContent(),
 		}}, nil
 	case pb.StreamRequest_ExecResize_case:
-		return &stream.Request{Resize: &stream.ResizeRequest{
+		return &stream.Request{ExecID: id, Resize: &stream.ResizeRequest{
 			Index: int(req.GetExecResize().GetIndex()),
 			Fd:    int(req.GetExecResize().GetFd()),
 			Rows:  int(req.GetExecResize().GetRows()),
@@ -60,7 +78,9 @@ func (sw *streamWrapper) Recv() (*stream.Request, error) {
 			Y:     int(req.GetExecResize().GetY()),
 		}}, nil
 	case pb.StreamRequest_ExecCancel_case:
-		return &stream.Request{Cancel: &struct{}{}}, nil
+		return &stream.Request{ExecID: id, Cancel: &struct{}{}}, nil
+	case pb.StreamRequest_ExecCredit_case:
+		return &stream.Request{ExecID: id, Credit: &stream.CreditRequest{Bytes: req.GetExecCredit().GetBytes()}}, nil
 	}
 	return nil, errors.ErrUnsupported
 }
@@ -71,24 +91,30 @@ func convertPBStreamRequest(req *pb.Request) *model.Request {
 	}
 	for _, cmd := range req.GetCmd() {
 		ret.Cmd = append(ret.Cmd, model.Cmd{
-			Args:              cmd.GetArgs(),
-			Env:               cmd.GetEnv(),
-			TTY:               cmd.GetTty(),
-			Files:             convertPBStreamFiles(cmd.GetFiles()),
-			CPULimit:          cmd.GetCpuTimeLimit(),
-			ClockLimit:        cmd.GetClockTimeLimit(),
-			MemoryLimit:       cmd.GetMemoryLimit(),
-			StackLimit:        cmd.GetStackLimit(),
-			ProcLimit:         cmd.GetProcLimit(),
-			CPURateLimit:      cmd.GetCpuRateLimit(),
-			CPUSetLimit:       cmd.GetCpuSetLimit(),
-			DataSegmentLimit:  cmd.GetDataSegmentLimit(),
-			AddressSpaceLimit: cmd.GetAddressSpaceLimit(),
-			CopyIn:            convertPBStreamCopyIn(cmd),
-			CopyOut:           convertStreamCopyOut(cmd.GetCopyOut()),
-			CopyOutCached:     convertStreamCopyOut(cmd.GetCopyOutCached()),
-			CopyOutMax:        cmd.GetCopyOutMax(),
-			CopyOutDir:        cmd.GetCopyOutDir(),
+			Args:                cmd.GetArgs(),
+			Env:                 cmd.GetEnv(),
+			TTY:                 cmd.GetTty(),
+			Files:               convertPBStreamFiles(cmd.GetFiles()),
+			CPULimit:            cmd.GetCpuTimeLimit(),
+			ClockLimit:          cmd.GetClockTimeLimit(),
+			MemoryLimit:         cmd.GetMemoryLimit(),
+			StackLimit:          cmd.GetStackLimit(),
+			ProcLimit:           cmd.GetProcLimit(),
+			CPURateLimit:        cmd.GetCpuRateLimit(),
+			CPUSetLimit:         cmd.GetCpuSetLimit(),
+			DataSegmentLimit:    cmd.GetDataSegmentLimit(),
+			AddressSpaceLimit:   cmd.GetAddressSpaceLimit(),
+			CopyIn:              convertPBStreamCopyIn(cmd),
+			CopyOut:             convertStreamCopyOut(cmd.GetCopyOut()),
+			CopyOutCached:       convertStreamCopyOut(cmd.GetCopyOutCached()),
+			CopyOutMax:          cmd.GetCopyOutMax(),
+			CopyOutGlob:         hasCopyOutGlob(cmd.GetCopyOut()) || hasCopyOutGlob(cmd.GetCopyOutCached()),
+			CopyOutMaxFiles:     cmd.GetCopyOutMaxFiles(),
+			CopyOutDirFormat:    cmd.GetCopyOutDirFormat(),
+			CopyOutDigest:       cmd.GetCopyOutDigest(),
+			CopyOutDigestOnly:   cmd.GetCopyOutDigestOnly(),
+			CopyOutTruncateTail: cmd.GetCopyOutTruncateTail(),
+			CopyOutDir:          cmd.GetCopyOutDir(),
 		})
 	}
 	for _, p := range req.GetPipeMapping() {
@@ -127,7 +153,17 @@ func convertPBStreamCopyIn(cmd *pb.Request_CmdType) map[string]model.CmdFile {
 		if !i.HasFile() {
 			continue
 		}
-		rt[k] = convertPBStreamFile(i)
+		f := convertPBStreamFile(i)
+		f.Extract = i.GetExtract()
+		if i.GetMode() != 0 {
+			f.Mode = proto.Uint32(i.GetMode())
+		}
+		if i.GetOwner() == pb.Request_File_User {
+			f.Owner = "user"
+		}
+		f.ReadOnly = i.GetReadOnly()
+		f.ZeroCopy = i.GetZeroCopy()
+		rt[k] = f
 	}
 	for k, v := range cmd.GetSymlinks() {
 		rt[k] = model.CmdFile{Symlink: &v}
@@ -141,11 +177,13 @@ func convertPBStreamFile(i *pb.Request_File) model.CmdFile {
 		return model.CmdFile{Src: proto.String(i.GetLocal().GetSrc())}
 	case pb.Request_File_Memory_case:
 		s := byteArrayToString(i.GetMemory().GetContent())
-		return model.CmdFile{Content: &s}
+		return model.CmdFile{Content: &s, Compression: i.GetMemory().GetCompression()}
 	case pb.Request_File_Cached_case:
 		return model.CmdFile{FileID: proto.String(i.GetCached().GetFileID())}
+	case pb.Request_File_CachedDir_case:
+		return model.CmdFile{DirID: proto.String(i.GetCachedDir().GetDirID())}
 	case pb.Request_File_Pipe_case:
-		return model.CmdFile{Name: proto.String(i.GetPipe().GetName()), Max: proto.Int64(i.GetPipe().GetMax()), Pipe: i.GetPipe().GetPipe()}
+		return model.CmdFile{Name: proto.String(i.GetPipe().GetName()), Max: proto.Int64(i.GetPipe().GetMax()), Pipe: i.GetPipe().GetPipe(), Tail: i.GetPipe().GetTail(), Compression: i.GetPipe().GetCompression(), Live: i.GetPipe().GetLive()}
 	case pb.Request_File_StreamIn_case:
 		return model.CmdFile{StreamIn: true}
 	case pb.Request_File_StreamOut_case:
@@ -158,6 +196,9 @@ func convertStreamCopyOut(copyOut []*pb.Request_CmdCopyOutFile) []string {
 	rt := make([]string, 0, len(copyOut))
 	for _, n := range copyOut {
 		name := n.GetName()
+		if n.GetDir() {
+			name += "/"
+		}
 		if n.GetOptional() {
 			name += "?"
 		}
@@ -166,11 +207,20 @@ func convertStreamCopyOut(copyOut []*pb.Request_CmdCopyOutFile) []string {
 	return rt
 }
 
+// hasCopyOutGlob reports whether any copyOut file is a glob pattern, the
+// stream request opts in glob for the cmd as a whole
+func hasCopyOutGlob(copyOut []*pb.Request_CmdCopyOutFile) bool {
+	return slices.ContainsFunc(copyOut, (*pb.Request_CmdCopyOutFile).GetGlob)
+}
+
 func (e *execServer) ExecStream(es pb.Executor_ExecStreamServer) error {
 	w := &streamWrapper{
 		es: es,
 	}
-	if err := stream.Start(es.Context(), w, e.worker, e.srcPrefix, e.logger); err != nil {
+	if err := stream.Start(es.Context(), w, e.worker, e.srcPrefix, e.decompressLimit, e.flow, e.logger); err != nil {
+		if errors.Is(err, worker.ErrDraining) {
+			return status.Error(codes.Unavailable, err.Error())
+		}
 		return status.Error(codes.Internal, err.Error())
 	}
 	return nil
@@ -24,6 +24,8 @@ func TestConvertPBFileErrorType(t *testing.T) {
 		{name: "CopyOutCopyContent", in: envexec.ErrCopyOutCopyContent, want: pb.Response_FileError_CopyOutCopyContent},
 		{name: "CollectSizeExceeded", in: envexec.ErrCollectSizeExceeded, want: pb.Response_FileError_CollectSizeExceeded},
 		{name: "Symlink", in: envexec.ErrSymlink, want: pb.Response_FileError_Symlink},
+		{name: "CopyInExtract", in: envexec.ErrCopyInExtract, want: pb.Response_FileError_CopyInExtract},
+		{name: "CopyOutCountExceeded", in: envexec.ErrCopyOutCountExceeded, want: pb.Response_FileError_CopyOutCountExceeded},
 	}
 
 	for _, tc := range tests {
@@ -34,3 +36,14 @@ func TestConvertPBFileErrorType(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertPBCmdDirFormat(t *testing.T) {
+	cmd := pb.Request_CmdType_builder{CopyOutDirFormat: "zip"}.Build()
+	if cm, err := convertPBCmd(cmd, nil, 0); err != nil || cm.CopyOutDirFormat != envexec.ArchiveZip {
+		t.Fatalf("expected zip format, got %q %v", cm.CopyOutDirFormat, err)
+	}
+	cmd = pb.Request_CmdType_builder{CopyOutDirFormat: "rar"}.Build()
+	if _, err := convertPBCmd(cmd, nil, 0); err == nil {
+		t.Fatal("expected unknown format rejected")
+	}
+}
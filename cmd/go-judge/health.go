@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/pb"
+	"github.com/criyle/go-judge/worker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthProbeInterval is the interval to retry the environment pool after it
+// failed to build environment
+const healthProbeInterval = 5 * time.Second
+
+// drainCheckInterval is the interval to check whether requests in flight are
+// finished during drain
+const drainCheckInterval = 100 * time.Millisecond
+
+// healthState tracks whether the server is able to serve new requests. It is
+// not serving when draining or when the environment pool cannot build
+// containers.
+type healthState struct {
+	mu       sync.Mutex
+	draining bool
+	poolErr  error
+	watchers []func(serving bool)
+}
+
+func newHealthState() *healthState {
+	return &healthState{}
+}
+
+// watch calls f with the current status and on every change of status
+func (h *healthState) watch(f func(serving bool)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchers = append(h.watchers, f)
+	f(h.servingLocked())
+}
+
+func (h *healthState) servingLocked() bool {
+	return !h.draining && h.poolErr == nil
+}
+
+func (h *healthState) update(f func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	before := h.servingLocked()
+	f()
+	if after := h.servingLocked(); after != before {
+		for _, w := range h.watchers {
+			w(after)
+		}
+	}
+}
+
+// serving returns whether the server is ready for new requests, or the reason
+// if it is not
+func (h *healthState) serving() (bool, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case h.draining:
+		return false, "draining"
+	case h.poolErr != nil:
+		return false, "environment pool: " + h.poolErr.Error()
+	}
+	return true, ""
+}
+
+// setDraining marks the server as not serving when draining, or serving again
+// when resumed
+func (h *healthState) setDraining(draining bool) {
+	h.update(func() { h.draining = draining })
+}
+
+func (h *healthState) poolFailed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.poolErr != nil
+}
+
+// setPoolErr records the result of the environment pool, it returns true if the
+// pool turns failed
+func (h *healthState) setPoolErr(err error) bool {
+	var failed bool
+	h.update(func() {
+		failed = h.poolErr == nil && err != nil
+		h.poolErr = err
+	})
+	return failed
+}
+
+// newDrainFunc returns the func to drain the worker so that new requests are
+// rejected and the health state reports not serving, or to resume both
+func newDrainFunc(work worker.Worker, h *healthState) func(draining bool) {
+	return func(draining bool) {
+		if !worker.DrainRequests(work, draining) {
+			logger.Warn("Drain is not supported by the worker")
+		}
+		h.setDraining(draining)
+		logger.Info("Drain", zap.Bool("draining", draining))
+	}
+}
+
+// cancelInFlight cancels all the requests in flight and waits for them to
+// finish so that their responses are sent before the servers are stopped
+func cancelInFlight(work worker.Worker) {
+	ctx, cancel := context.WithTimeout(context.TODO(), model.DefaultCancelTimeout)
+	defer cancel()
+
+	for _, c := range worker.CancelRequests(work, func(worker.RequestStat) bool { return true }, false) {
+		if _, err := c.Wait(ctx); err != nil {
+			logger.Warn("Cancelled request not finished", zap.String("requestId", c.RequestID), zap.Error(err))
+		}
+	}
+}
+
+// waitDrained waits until the requests in flight are finished or ctx is done,
+// it returns the number of requests left
+func waitDrained(ctx context.Context, work worker.Worker) int {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for {
+		n := len(worker.StatRequests(work))
+		if n == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return n
+		case <-ticker.C:
+		}
+	}
+}
+
+var _ worker.EnvironmentPool = &healthEnvPool{}
+
+// healthEnvPool reports the failures of the environment pool to the health
+// state. Once failed, it probes the pool in background until an environment is
+// built again.
+type healthEnvPool struct {
+	worker.EnvironmentPool
+	health *healthState
+}
+
+func newHealthEnvPool(p worker.EnvironmentPool, h *healthState) worker.EnvironmentPool {
+	return &healthEnvPool{EnvironmentPool: p, health: h}
+}
+
+func (p *healthEnvPool) Get() (envexec.Environment, error) {
+	e, err := p.EnvironmentPool.Get()
+	if p.health.setPoolErr(err) {
+		logger.Warn("environment pool failed, reporting not serving", zap.Error(err))
+		go p.probe()
+	}
+	return e, err
+}
+
+func (p *healthEnvPool) Stat() worker.PoolStat {
+	return worker.StatPool(p.EnvironmentPool)
+}
+
+func (p *healthEnvPool) probe() {
+	ticker := time.NewTicker(healthProbeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !p.health.poolFailed() {
+			return
+		}
+		e, err := p.EnvironmentPool.Get()
+		if err != nil {
+			p.health.setPoolErr(err)
+			continue
+		}
+		p.EnvironmentPool.Put(e)
+		p.health.setPoolErr(nil)
+		logger.Info("environment pool recovered")
+		return
+	}
+}
+
+// grpcHealthServer is the standard gRPC health service reporting the health
+// state for the server and the executor service. It does not require auth so
+// that load balancers could probe it.
+type grpcHealthServer struct {
+	*health.Server
+}
+
+func newGRPCHealthServer(h *healthState) *grpcHealthServer {
+	s := health.NewServer()
+	h.watch(func(serving bool) {
+		st := healthpb.HealthCheckResponse_SERVING
+		if !serving {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		s.SetServingStatus("", st)
+		s.SetServingStatus(pb.Executor_ServiceDesc.ServiceName, st)
+	})
+	return &grpcHealthServer{s}
+}
+
+// AuthFuncOverride implements grpc_auth.ServiceAuthFuncOverride
+func (s *grpcHealthServer) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	return ctx, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/pb"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type failEnvPool struct {
+	err error
+}
+
+func (p *failEnvPool) Get() (envexec.Environment, error) { return nil, p.err }
+func (p *failEnvPool) Put(envexec.Environment)           {}
+func (p *failEnvPool) Destroy()                          {}
+
+func TestGRPCHealth(t *testing.T) {
+	logger = zap.NewNop()
+	h := newHealthState()
+	s := newGRPCHealthServer(h)
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := s.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("check %q: %v", service, err)
+		}
+		return resp.GetStatus()
+	}
+	if st := check(pb.Executor_ServiceDesc.ServiceName); st != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected serving, got %v", st)
+	}
+
+	fp := &failEnvPool{err: errors.New("clone failed")}
+	p := newHealthEnvPool(fp, h)
+	if _, err := p.Get(); err == nil {
+		t.Fatal("expected pool error")
+	}
+	if st := check(""); st != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected not serving after pool failure, got %v", st)
+	}
+	fp.err = nil
+	p.Get()
+	if st := check(""); st != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected serving after pool recovered, got %v", st)
+	}
+
+	h.setDraining(true)
+	if st := check(pb.Executor_ServiceDesc.ServiceName); st != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected not serving when draining, got %v", st)
+	}
+	h.setDraining(false)
+	if st := check(pb.Executor_ServiceDesc.ServiceName); st != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected serving after drain resumed, got %v", st)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := newHealthState()
+	r := gin.New()
+	r.GET("/healthz", handleHealthz)
+	r.GET("/readyz", generateHandleReadyz(h))
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Fatalf("expected ready, got %d", code)
+	}
+	h.setDraining(true)
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready when draining, got %d", code)
+	}
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected alive when draining, got %d", code)
+	}
+}
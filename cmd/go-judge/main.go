@@ -22,6 +22,7 @@ import (
 	"github.com/criyle/go-judge/cmd/go-judge/config"
 	grpcexecutor "github.com/criyle/go-judge/cmd/go-judge/grpc_executor"
 	restexecutor "github.com/criyle/go-judge/cmd/go-judge/rest_executor"
+	"github.com/criyle/go-judge/cmd/go-judge/stream"
 	"github.com/criyle/go-judge/cmd/go-judge/version"
 	wsexecutor "github.com/criyle/go-judge/cmd/go-judge/ws_executor"
 	"github.com/criyle/go-judge/env"
@@ -46,12 +47,18 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
 var logger *zap.Logger
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		os.Exit(runSnapshot(os.Args[2:]))
+	}
 	conf := loadConf()
 	if conf.Version {
 		fmt.Println(version.Version)
@@ -66,23 +73,30 @@ func main() {
 
 	// Init environment pool
 	fs, fsCleanUp := newFileStore(conf)
+	datasets := newDatasets(conf)
 	b, builderParam := newEnvBuilder(conf)
-	envPool := newEnvPool(b, conf.EnableMetrics)
+	health := newHealthState()
+	envPool := newHealthEnvPool(newEnvPool(b, conf.EnableMetrics), health)
 	prefork(envPool, conf.PreFork)
-	work := newWorker(conf, envPool, fs)
+	work := newWorker(conf, envPool, fs, datasets, builderParam)
 	work.Start()
+	drain := newDrainFunc(work, health)
 	logger.Info("Worker stated ",
 		zap.Int("parallelism", conf.Parallelism),
 		zap.String("dir", conf.Dir),
 		zap.Duration("timeLimitCheckInterval", conf.TimeLimitCheckerInterval))
 	initCgroupMetrics(conf, builderParam)
+	var flow *stream.Flow
+	if conf.EnableMetrics {
+		flow = newMetricsStream()
+	}
 
 	servers := []initFunc{
 		cleanUpWorker(work),
 		cleanUpFs(fsCleanUp),
-		initHTTPServer(conf, work, fs, builderParam),
+		initHTTPServer(conf, work, fs, datasets, builderParam, flow, health, drain),
 		initMonitorHTTPServer(conf),
-		initGRPCServer(conf, work, fs),
+		initGRPCServer(conf, work, fs, flow, health),
 	}
 
 	// Gracefully shutdown, with signal / HTTP server / gRPC server / Monitor HTTP server
@@ -108,9 +122,14 @@ func main() {
 
 	// Graceful shutdown...
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	if datasets != nil {
+		signal.Notify(sig, syscall.SIGHUP)
+	}
 loop:
 	for s := range sig {
 		switch s {
+		case syscall.SIGHUP:
+			reindexDatasets(datasets)
 		case syscall.SIGINT:
 			break loop
 		case syscall.SIGTERM:
@@ -121,9 +140,18 @@ loop:
 			}
 		}
 	}
-	signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+	signal.Reset(syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	logger.Info("Shutting Down...")
+	drain(true)
+	if conf.DrainTimeout > 0 {
+		dctx, dcancel := context.WithTimeout(context.TODO(), conf.DrainTimeout)
+		if n := waitDrained(dctx, work); n > 0 {
+			logger.Warn("Drain timeout, cancelling requests in flight", zap.Int("requests", n))
+			cancelInFlight(work)
+		}
+		dcancel()
+	}
 
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*3)
 	defer cancel()
@@ -159,6 +187,14 @@ func loadConf() *config.Config {
 		}
 		log.Fatalln("load config failed ", err)
 	}
+	// namespaced tokens always isolates files by namespace
+	if len(conf.AuthTokens) > 0 {
+		conf.EnableNamespace = true
+	}
+	// admin endpoints operate on all files and requests thus never go unauthenticated
+	if conf.EnableAdmin && conf.AuthToken == "" {
+		log.Fatalln("enable admin requires auth token")
+	}
 	return &conf
 }
 
@@ -190,10 +226,10 @@ func cleanUpFs(fsCleanUp func() error) initFunc {
 	}
 }
 
-func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, builderParam map[string]any) initFunc {
+func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, datasets *filestore.Datasets, builderParam map[string]any, flow *stream.Flow, health *healthState, drain func(bool)) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
 		// Init http handle
-		r := initHTTPMux(conf, work, fs, builderParam)
+		r := initHTTPMux(conf, work, fs, datasets, builderParam, flow, health, drain)
 		srv := http.Server{
 			Addr:    conf.HTTPAddr,
 			Handler: r,
@@ -244,14 +280,14 @@ func initMonitorHTTPServer(conf *config.Config) initFunc {
 	}
 }
 
-func initGRPCServer(conf *config.Config, work worker.Worker, fs filestore.FileStore) initFunc {
+func initGRPCServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, flow *stream.Flow, health *healthState) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
 		if !conf.EnableGRPC {
 			return nil, nil
 		}
 		// Init gRPC server
-		esServer := grpcexecutor.New(work, fs, conf.SrcPrefix, logger)
-		grpcServer := newGRPCServer(conf, esServer)
+		esServer := grpcexecutor.New(work, fs, conf.SrcPrefix, int64(*conf.DecompressLimit), flow, conf.EnableAdmin, logger)
+		grpcServer := newGRPCServer(conf, esServer, health)
 
 		return func() {
 				lis, err := newListener(conf.GRPCAddr)
@@ -309,7 +345,7 @@ func prefork(envPool worker.EnvironmentPool, prefork int) {
 	}
 }
 
-func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore, builderParam map[string]any) http.Handler {
+func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore, datasets *filestore.Datasets, builderParam map[string]any, flow *stream.Flow, health *healthState, drain func(bool)) http.Handler {
 	var r *gin.Engine
 	if conf.Release {
 		gin.SetMode(gin.ReleaseMode)
@@ -329,20 +365,41 @@ func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore
 	// Config handle
 	r.GET("/config", generateHandleConfig(conf, builderParam))
 
-	// Add auth token
-	if conf.AuthToken != "" {
-		r.Use(tokenAuth(conf.AuthToken))
-		logger.Info("Attach token auth", zap.String("token", conf.AuthToken))
+	// Liveness and readiness handle
+	r.GET("/healthz", handleHealthz)
+	r.GET("/readyz", generateHandleReadyz(health))
+
+	// Add auth token and namespace
+	nsTokens := parseNamespaceTokens(conf.AuthTokens)
+	if conf.AuthToken != "" || len(nsTokens) > 0 || conf.EnableNamespace {
+		r.Use(tokenAuth(conf.AuthToken, nsTokens, conf.EnableNamespace))
+		logger.Info("Attach token auth", zap.String("token", conf.AuthToken),
+			zap.Int("namespaceTokens", len(nsTokens)), zap.Bool("namespace", conf.EnableNamespace))
 	}
 
-	// Rest Handle
-	cmdHandle := restexecutor.NewCmdHandle(work, conf.SrcPrefix, logger)
-	cmdHandle.Register(r)
+	// Rest Handle, with compressed request and response bodies
+	rest := r.Group("", restexecutor.Compression(int64(*conf.DecompressLimit)))
+	cmdHandle := restexecutor.NewCmdHandle(work, conf.SrcPrefix, int64(*conf.DecompressLimit), logger)
+	cmdHandle.Register(rest)
 	fileHandle := restexecutor.NewFileHandle(fs)
-	fileHandle.Register(r)
+	fileHandle.Register(rest)
+	statHandle := restexecutor.NewStatHandle(work, fs)
+	statHandle.Register(rest)
+	if datasets != nil {
+		datasetHandle := restexecutor.NewDatasetHandle(datasets)
+		datasetHandle.Register(rest)
+	}
+	if conf.EnableAdmin {
+		adminHandle := restexecutor.NewAdminHandle(fs, work, drain)
+		adminHandle.Register(rest)
+	}
 
 	// WebSocket Handle
-	wsHandle := wsexecutor.New(work, conf.SrcPrefix, logger)
+	var sessions *stream.Sessions
+	if conf.StreamResumeGrace > 0 {
+		sessions = stream.NewSessions(conf.StreamResumeGrace, int(*conf.StreamResumeBuffer), conf.StreamResumeMax)
+	}
+	wsHandle := wsexecutor.New(work, conf.SrcPrefix, int64(*conf.DecompressLimit), sessions, flow, logger)
 	wsHandle.Register(r)
 
 	return r
@@ -407,7 +464,7 @@ func InterceptorLogger(l *zap.Logger) grpc_logging.Logger {
 	})
 }
 
-func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer) *grpc.Server {
+func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer, health *healthState) *grpc.Server {
 	prom := grpc_prometheus.NewServerMetrics(grpc_prometheus.WithServerHandlingTimeHistogram())
 	grpclog.SetLoggerV2(zapgrpc.NewLogger(logger))
 	streamMiddleware := []grpc.StreamServerInterceptor{
@@ -420,8 +477,8 @@ func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer) *grpc.Server
 		grpc_logging.UnaryServerInterceptor(InterceptorLogger(logger)),
 		grpc_recovery.UnaryServerInterceptor(),
 	}
-	if conf.AuthToken != "" {
-		authFunc := grpcTokenAuth(conf.AuthToken)
+	if nsTokens := parseNamespaceTokens(conf.AuthTokens); conf.AuthToken != "" || len(nsTokens) > 0 || conf.EnableNamespace {
+		authFunc := grpcTokenAuth(conf.AuthToken, nsTokens, conf.EnableNamespace)
 		streamMiddleware = append(streamMiddleware, grpc_auth.StreamServerInterceptor(authFunc))
 		unaryMiddleware = append(unaryMiddleware, grpc_auth.UnaryServerInterceptor(authFunc))
 	}
@@ -431,6 +488,10 @@ func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer) *grpc.Server
 		grpc.MaxRecvMsgSize(int(conf.GRPCMsgSize.Byte())),
 	)
 	pb.RegisterExecutorServer(grpcServer, esServer)
+	healthpb.RegisterHealthServer(grpcServer, newGRPCHealthServer(health))
+	if conf.GRPCReflection {
+		reflection.Register(grpcServer)
+	}
 	prometheus.MustRegister(prom)
 	return grpcServer
 }
@@ -446,27 +507,116 @@ func initGinMetrics(r *gin.Engine) {
 	r.Use(p.HandlerFunc())
 }
 
-func tokenAuth(token string) gin.HandlerFunc {
+// newDatasets indexes the datasets registered as name:dir, or returns nil if
+// there is none
+func newDatasets(conf *config.Config) *filestore.Datasets {
+	if len(conf.Datasets) == 0 {
+		return nil
+	}
+	roots := make(map[string]string, len(conf.Datasets))
+	for _, d := range conf.Datasets {
+		name, dir, ok := strings.Cut(d, ":")
+		if !ok || name == "" || dir == "" {
+			logger.Fatal("invalid dataset, expect name:dir", zap.String("dataset", d))
+		}
+		roots[name] = dir
+	}
+	datasets, err := filestore.NewDatasets(roots)
+	if err != nil {
+		logger.Fatal("Failed to index datasets", zap.Error(err))
+	}
+	for _, d := range datasets.List() {
+		logger.Info("Dataset indexed", zap.String("name", d.Name), zap.Int("count", d.Count), zap.Int64("size", d.Size))
+	}
+	return datasets
+}
+
+// reindexDatasets rebuilds the index of the datasets and keeps the previous
+// index on failure
+func reindexDatasets(datasets *filestore.Datasets) {
+	if err := datasets.Reindex(); err != nil {
+		logger.Error("Failed to reindex datasets, keep the previous index", zap.Error(err))
+		return
+	}
+	for _, d := range datasets.List() {
+		logger.Info("Dataset reindexed", zap.String("name", d.Name), zap.Int("count", d.Count), zap.Int64("size", d.Size))
+	}
+}
+
+// namespaceHeader specifies the namespace of the request when authenticated
+// by the global token or no token is required
+const namespaceHeader = "X-Namespace"
+
+// parseNamespaceTokens parses namespace:token pairs into token to namespace mapping
+func parseNamespaceTokens(tokens []string) map[string]string {
+	rt := make(map[string]string, len(tokens))
+	for _, t := range tokens {
+		ns, token, ok := strings.Cut(t, ":")
+		if !ok || ns == "" || token == "" {
+			logger.Fatal("invalid namespaced auth token, expect namespace:token", zap.String("token", t))
+		}
+		rt[token] = ns
+	}
+	return rt
+}
+
+func tokenAuth(token string, nsTokens map[string]string, enableNamespace bool) gin.HandlerFunc {
 	const bearer = "Bearer "
+	setNamespace := func(c *gin.Context, ns string) {
+		c.Request = c.Request.WithContext(filestore.WithNamespace(c.Request.Context(), ns))
+	}
 	return func(c *gin.Context) {
-		reqToken := c.GetHeader("Authorization")
-		if strings.HasPrefix(reqToken, bearer) && reqToken[len(bearer):] == token {
+		if token == "" && len(nsTokens) == 0 {
+			setNamespace(c, c.GetHeader(namespaceHeader))
+			c.Next()
+			return
+		}
+		reqToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), bearer)
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if ns, ok := nsTokens[reqToken]; ok {
+			setNamespace(c, ns)
 			c.Next()
 			return
 		}
-		c.AbortWithStatus(http.StatusUnauthorized)
+		if token == "" || reqToken != token {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if enableNamespace {
+			setNamespace(c, c.GetHeader(namespaceHeader))
+		}
+		c.Next()
 	}
 }
 
-func grpcTokenAuth(token string) func(context.Context) (context.Context, error) {
+func grpcTokenAuth(token string, nsTokens map[string]string, enableNamespace bool) func(context.Context) (context.Context, error) {
+	mdNamespace := func(ctx context.Context) context.Context {
+		var ns string
+		if v := metadata.ValueFromIncomingContext(ctx, strings.ToLower(namespaceHeader)); len(v) > 0 {
+			ns = v[0]
+		}
+		return filestore.WithNamespace(ctx, ns)
+	}
 	return func(ctx context.Context) (context.Context, error) {
+		if token == "" && len(nsTokens) == 0 {
+			return mdNamespace(ctx), nil
+		}
 		reqToken, err := grpc_auth.AuthFromMD(ctx, "bearer")
 		if err != nil {
 			return nil, err
 		}
-		if reqToken != token {
+		if ns, ok := nsTokens[reqToken]; ok {
+			return filestore.WithNamespace(ctx, ns), nil
+		}
+		if token == "" || reqToken != token {
 			return nil, status.Errorf(codes.Unauthenticated, "invalid auth token: %v", err)
 		}
+		if enableNamespace {
+			return mdNamespace(ctx), nil
+		}
 		return ctx, nil
 	}
 }
@@ -497,9 +647,24 @@ func newFileStore(conf *config.Config) (filestore.FileStore, func() error) {
 	if conf.EnableMetrics {
 		fs = newMetricsFileStore(fs)
 	}
+	if *conf.FileCacheLimit > 0 {
+		cache := filestore.NewCache(fs, filestore.CacheConfig{
+			Limit:       *conf.FileCacheLimit,
+			MaxFileSize: *conf.FileCacheMaxFileSize,
+			PromoteHits: conf.FileCachePromoteHits,
+			Memfd:       conf.FileCacheMemfd,
+		})
+		if conf.EnableMetrics {
+			registerMetricsFileCache(cache)
+		}
+		fs = cache
+	}
 	if conf.FileTimeout > 0 {
 		fs = filestore.NewTimeout(fs, conf.FileTimeout, timeoutCheckInterval)
 	}
+	if conf.EnableNamespace {
+		fs = filestore.NewNamespaced(fs, *conf.NamespaceQuota)
+	}
 
 	if removeDir == nil {
 		return fs, fs.Close
@@ -528,6 +693,7 @@ func newEnvBuilder(conf *config.Config) (pool.EnvBuilder, map[string]any) {
 		CPUCfsPeriod:       conf.CPUCfsPeriod,
 		SeccompConf:        conf.SeccompConf,
 		NoFallback:         conf.NoFallback,
+		BindDir:            zeroCopyDir(conf),
 	}, logger)
 	if err != nil {
 		logger.Fatal("create environment builder failed ", zap.Error(err))
@@ -538,6 +704,16 @@ func newEnvBuilder(conf *config.Config) (pool.EnvBuilder, map[string]any) {
 	return b, param
 }
 
+// zeroCopyDir returns the staging directory of zero-copy copyIn files, it
+// defaults to be next to the file store so that files are linked rather than
+// copied
+func zeroCopyDir(conf *config.Config) string {
+	if conf.ZeroCopyDir != "" {
+		return conf.ZeroCopyDir
+	}
+	return filepath.Clean(conf.Dir) + "-bind"
+}
+
 func newEnvPool(b pool.EnvBuilder, enableMetrics bool) worker.EnvironmentPool {
 	p := pool.NewPool(b)
 	if enableMetrics {
@@ -546,9 +722,20 @@ func newEnvPool(b pool.EnvBuilder, enableMetrics bool) worker.EnvironmentPool {
 	return p
 }
 
-func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore.FileStore) worker.Worker {
+func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore.FileStore, datasets *filestore.Datasets, builderParam map[string]any) worker.Worker {
+	separateCred, _ := builderParam["separateCred"].(bool)
+	if !separateCred {
+		logger.Warn("Programs run as the container root, copyIn with readOnly or owner user is rejected",
+			zap.Int("containerCredStart", conf.ContainerCredStart))
+	}
+	extractLimit := envexec.ArchiveLimit{
+		Size:  *conf.ExtractLimit,
+		Count: conf.ExtractFileLimit,
+		Depth: conf.ExtractDepthLimit,
+	}
 	w := worker.New(worker.Config{
 		FileStore:             fs,
+		Datasets:              datasets,
 		EnvironmentPool:       envPool,
 		Parallelism:           conf.Parallelism,
 		WorkDir:               conf.Dir,
@@ -556,9 +743,14 @@ func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore
 		ExtraMemoryLimit:      *conf.ExtraMemoryLimit,
 		OutputLimit:           *conf.OutputLimit,
 		CopyOutLimit:          *conf.CopyOutLimit,
+		CopyOutFileLimit:      uint64(conf.CopyOutFileLimit),
+		ExtractLimit:          extractLimit,
 		OpenFileLimit:         uint64(conf.OpenFileLimit),
 		ExecObserver:          execObserve,
 		CPUSets:               conf.Cpuset,
+		LiveOutputInterval:    conf.LiveOutputInterval,
+		LiveOutputLimit:       *conf.LiveOutputLimit,
+		SeparateCred:          separateCred,
 	})
 	if conf.EnableMetrics {
 		w = newMetricsWorker(w)
@@ -600,6 +792,32 @@ func generateHandleVersion(_ *config.Config, _ map[string]any) func(*gin.Context
 			"copyOutTruncate":   true,
 			"pipeProxyZeroCopy": true,
 			"fixSymlinkEscape":  true,
+			"fileNamespace":     true,
+			"cachedDir":         true,
+			"archiveExtract":    true,
+			"copyOutGlob":       true,
+			"copyOutDirFormat":  true,
+			"copyInAttr":        true,
+			"fileCache":         true,
+			"dataset":           true,
+			"zeroCopy":          true,
+			"snapshot":          true,
+			"copyOutDigest":     true,
+			"copyOutTail":       true,
+			"encoding":          true,
+			"wireFormat":        true,
+			"compression":       true,
+			"multipartResponse": true,
+			"progressEvents":    true,
+			"liveOutput":        true,
+			"streamMultiplex":   true,
+			"streamResume":      true,
+			"streamFlowControl": true,
+			"grpcHealth":        true,
+			"grpcReflection":    true,
+			"stat":              true,
+			"cancel":            true,
+			"drain":             true,
 		})
 	}
 }
@@ -616,12 +834,53 @@ func generateHandleConfig(conf *config.Config, builderParam map[string]any) func
 			"copyOutTruncate":   true,
 			"pipeProxyZeroCopy": true,
 			"fixSymlinkEscape":  true,
+			"fileNamespace":     conf.EnableNamespace,
+			"fileCache":         *conf.FileCacheLimit > 0,
+			"dataset":           len(conf.Datasets) > 0,
+			"zeroCopy":          builderParam["bindDir"] != nil,
+			"snapshot":          conf.EnableAdmin,
+			"copyOutDigest":     true,
+			"copyOutTail":       true,
+			"encoding":          true,
+			"wireFormat":        true,
+			"compression":       true,
+			"multipartResponse": true,
+			"progressEvents":    true,
+			"liveOutput":        true,
+			"streamMultiplex":   true,
+			"streamResume":      conf.StreamResumeGrace > 0,
+			"streamFlowControl": true,
+			"grpcHealth":        conf.EnableGRPC,
+			"grpcReflection":    conf.EnableGRPC && conf.GRPCReflection,
+			"stat":              true,
+			"cancel":            conf.EnableAdmin,
+			"drain":             conf.EnableAdmin,
+			"cachedDir":         true,
+			"archiveExtract":    true,
+			"copyOutGlob":       true,
 			"fileStorePath":     conf.Dir,
 			"runnerConfig":      builderParam,
 		})
 	}
 }
 
+// handleHealthz reports the process is alive regardless of readiness
+func handleHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// generateHandleReadyz reports whether the server is ready for new requests, it
+// turns not ready immediately when draining
+func generateHandleReadyz(health *healthState) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if ok, reason := health.serving(); !ok {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "reason": reason})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
+
 func isManagedByPM2() bool {
 	// List of environment variables that pm2 typically sets.
 	pm2EnvVars := []string{
@@ -4,6 +4,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/criyle/go-judge/cmd/go-judge/stream"
 	"github.com/criyle/go-judge/env/pool"
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/filestore"
@@ -17,6 +18,7 @@ const (
 	filestoreSubsystem   = "file"
 	environmentSubsystem = "environment"
 	workerSubsystem      = "worker"
+	streamSubsystem      = "stream"
 )
 
 var (
@@ -90,6 +92,36 @@ var (
 		Help:      "Total number of environment currently in use",
 	})
 
+	fsCacheHits = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, filestoreSubsystem, "cache_hits_total"),
+		"Total number of file store reads served by the memory tier", nil, nil,
+	)
+
+	fsCacheMisses = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, filestoreSubsystem, "cache_misses_total"),
+		"Total number of file store reads served by the underlying store", nil, nil,
+	)
+
+	fsCachePromotions = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, filestoreSubsystem, "cache_promotions_total"),
+		"Total number of files promoted into the memory tier", nil, nil,
+	)
+
+	fsCacheDemotions = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, filestoreSubsystem, "cache_demotions_total"),
+		"Total number of files demoted from the memory tier", nil, nil,
+	)
+
+	fsCacheCount = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, filestoreSubsystem, "cache_current_count"),
+		"Number of files currently in the memory tier", nil, nil,
+	)
+
+	fsCacheSize = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, filestoreSubsystem, "cache_current_bytes"),
+		"Total size of files currently in the memory tier", nil, nil,
+	)
+
 	workerQueue = prometheus.NewDesc(
 		prometheus.BuildFQName(metricsNamespace, workerSubsystem, "queue_count"),
 		"Number of requests waiting in worker queue", nil, nil,
@@ -99,6 +131,26 @@ var (
 		prometheus.BuildFQName(metricsNamespace, workerSubsystem, "running_count"),
 		"Number of request running by workers", nil, nil,
 	)
+
+	streamStalled = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, streamSubsystem, "stalled_count"),
+		"Number of streams currently stalled by flow control", []string{"direction"}, nil,
+	)
+
+	streamStalls = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, streamSubsystem, "stalls_total"),
+		"Number of times streams stalled by flow control", []string{"direction"}, nil,
+	)
+
+	streamStallSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, streamSubsystem, "stall_seconds_total"),
+		"Total time streams stalled by flow control", []string{"direction"}, nil,
+	)
+
+	streamInputOverruns = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, streamSubsystem, "input_overruns_total"),
+		"Number of inputs rejected by exceeding the flow control window", nil, nil,
+	)
 )
 
 func init() {
@@ -123,7 +175,10 @@ func execObserve(res worker.Response) {
 	}
 }
 
-var _ filestore.FileStore = &metricsFileStore{}
+var (
+	_ filestore.FileStore = &metricsFileStore{}
+	_ filestore.Unwrapper = &metricsFileStore{}
+)
 
 type metricsFileStore struct {
 	mu sync.Mutex
@@ -204,10 +259,40 @@ func (m *metricsFileStore) Remove(id string) bool {
 	return success
 }
 
+func (m *metricsFileStore) Unwrap() filestore.FileStore {
+	return m.FileStore
+}
+
 func (m *metricsFileStore) Close() error {
 	return m.FileStore.Close()
 }
 
+var _ prometheus.Collector = &metricsFileCache{}
+
+type metricsFileCache struct {
+	*filestore.Cache
+}
+
+// Collect implements prometheus.Collector.
+func (m *metricsFileCache) Collect(ch chan<- prometheus.Metric) {
+	s := m.Stat()
+	ch <- prometheus.MustNewConstMetric(fsCacheHits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(fsCacheMisses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(fsCachePromotions, prometheus.CounterValue, float64(s.Promotions))
+	ch <- prometheus.MustNewConstMetric(fsCacheDemotions, prometheus.CounterValue, float64(s.Demotions))
+	ch <- prometheus.MustNewConstMetric(fsCacheCount, prometheus.GaugeValue, float64(s.Files))
+	ch <- prometheus.MustNewConstMetric(fsCacheSize, prometheus.GaugeValue, float64(s.Bytes))
+}
+
+// Describe implements prometheus.Collector.
+func (m *metricsFileCache) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(m, ch)
+}
+
+func registerMetricsFileCache(c *filestore.Cache) {
+	prometheus.MustRegister(&metricsFileCache{c})
+}
+
 var _ pool.EnvBuilder = &metricsEnvBuilder{}
 
 type metricsEnvBuilder struct {
@@ -224,6 +309,7 @@ func (b *metricsEnvBuilder) Build() (pool.Environment, error) {
 }
 
 var _ worker.EnvironmentPool = &metricsEnvPool{}
+var _ worker.EnvironmentDiscarder = &metricsEnvPool{}
 
 type metricsEnvPool struct {
 	worker.EnvironmentPool
@@ -243,7 +329,19 @@ func (p *metricsEnvPool) Put(env envexec.Environment) {
 	envInUse.Dec()
 }
 
+func (p *metricsEnvPool) Discard(env envexec.Environment) {
+	worker.DiscardEnvironment(p.EnvironmentPool, env)
+	envInUse.Dec()
+}
+
+func (p *metricsEnvPool) Stat() worker.PoolStat {
+	return worker.StatPool(p.EnvironmentPool)
+}
+
 var _ worker.Worker = &metricsWorker{}
+var _ worker.RequestStater = &metricsWorker{}
+var _ worker.Canceller = &metricsWorker{}
+var _ worker.Drainer = &metricsWorker{}
 var _ prometheus.Collector = &metricsWorker{}
 
 type metricsWorker struct {
@@ -266,8 +364,49 @@ func (m *metricsWorker) Describe(ch chan<- *prometheus.Desc) {
 	prometheus.DescribeByCollect(m, ch)
 }
 
+func (m *metricsWorker) Requests() []worker.RequestStat {
+	return worker.StatRequests(m.Worker)
+}
+
+func (m *metricsWorker) Drain(draining bool) {
+	worker.DrainRequests(m.Worker, draining)
+}
+
+func (m *metricsWorker) Cancel(match func(worker.RequestStat) bool, kill bool) []worker.Cancelled {
+	return worker.CancelRequests(m.Worker, match, kill)
+}
+
 func newMetricsWorker(w worker.Worker) worker.Worker {
 	rt := &metricsWorker{w}
 	prometheus.MustRegister(rt)
 	return rt
 }
+
+var _ prometheus.Collector = &metricsStream{}
+
+type metricsStream struct {
+	flow *stream.Flow
+}
+
+// Collect implements prometheus.Collector.
+func (m *metricsStream) Collect(ch chan<- prometheus.Metric) {
+	s := m.flow.Stat()
+	ch <- prometheus.MustNewConstMetric(streamStalled, prometheus.GaugeValue, float64(s.OutputStalled), "output")
+	ch <- prometheus.MustNewConstMetric(streamStalled, prometheus.GaugeValue, float64(s.InputStalled), "input")
+	ch <- prometheus.MustNewConstMetric(streamStalls, prometheus.CounterValue, float64(s.OutputStalls), "output")
+	ch <- prometheus.MustNewConstMetric(streamStalls, prometheus.CounterValue, float64(s.InputStalls), "input")
+	ch <- prometheus.MustNewConstMetric(streamStallSeconds, prometheus.CounterValue, s.OutputStallTime.Seconds(), "output")
+	ch <- prometheus.MustNewConstMetric(streamStallSeconds, prometheus.CounterValue, s.InputStallTime.Seconds(), "input")
+	ch <- prometheus.MustNewConstMetric(streamInputOverruns, prometheus.CounterValue, float64(s.InputOverruns))
+}
+
+// Describe implements prometheus.Collector.
+func (m *metricsStream) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(m, ch)
+}
+
+func newMetricsStream() *stream.Flow {
+	flow := new(stream.Flow)
+	prometheus.MustRegister(&metricsStream{flow})
+	return flow
+}
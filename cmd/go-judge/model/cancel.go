@@ -0,0 +1,101 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/criyle/go-judge/worker"
+)
+
+// ErrCancelSelectorRequired is returned when none of the fields to select
+// the requests to cancel is specified
+var ErrCancelSelectorRequired = errors.New("one of requestId, namespace and tag is required")
+
+// DefaultCancelTimeout bounds the wait for the cancelled requests to finish
+// if Timeout is not specified
+const DefaultCancelTimeout = 10 * time.Second
+
+// CancelRequest selects the requests queued or running by all the fields
+// specified, and Kill destroys the containers of the running requests.
+// Timeout (ns) bounds the wait for the cancelled requests to finish
+type CancelRequest struct {
+	RequestID string `json:"requestId"`
+	Namespace string `json:"namespace"`
+	Tag       string `json:"tag"`
+	Kill      bool   `json:"kill"`
+	Timeout   uint64 `json:"timeout"`
+}
+
+// CancelResult defines the cancelled request with its partial response
+type CancelResult struct {
+	RequestStat
+	Response Response `json:"response"`
+}
+
+// Validate checks at least one field is specified to select the requests
+func (r *CancelRequest) Validate() error {
+	if r.RequestID == "" && r.Namespace == "" && r.Tag == "" {
+		return ErrCancelSelectorRequired
+	}
+	return nil
+}
+
+// Match returns whether the request is selected
+func (r *CancelRequest) Match(s worker.RequestStat) bool {
+	return (r.RequestID == "" || s.RequestID == r.RequestID) &&
+		(r.Namespace == "" || s.Namespace == r.Namespace) &&
+		(r.Tag == "" || slices.Contains(s.Tags, r.Tag))
+}
+
+// WaitContext derives the context from the request context that bounds the
+// wait for the cancelled requests by the timeout
+func (r *CancelRequest) WaitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := DefaultCancelTimeout
+	if r.Timeout > 0 {
+		timeout = time.Duration(r.Timeout)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// WaitCancelled waits for the partial response of the cancelled request, the
+// error is recorded in the response if it is not finished before ctx is done
+func WaitCancelled(ctx context.Context, c worker.Cancelled) Response {
+	resp, err := c.Wait(ctx)
+	if err != nil {
+		return Response{RequestID: c.RequestID, ErrorMsg: err.Error()}
+	}
+	res, err := ConvertResponse(resp, false)
+	if err != nil {
+		return Response{RequestID: resp.RequestID, ErrorMsg: err.Error()}
+	}
+	return res
+}
+
+// CancelRequests cancels the selected requests and waits for their partial
+// responses until the timeout
+func CancelRequests(ctx context.Context, w worker.Worker, r *CancelRequest) ([]CancelResult, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := r.WaitContext(ctx)
+	defer cancel()
+
+	rt := make([]CancelResult, 0)
+	for _, c := range worker.CancelRequests(w, r.Match, r.Kill) {
+		res := WaitCancelled(ctx, c)
+		rt = append(rt, CancelResult{
+			RequestStat: RequestStat{
+				RequestID: c.RequestID,
+				Namespace: c.Namespace,
+				Tags:      c.Tags,
+				Phase:     eventTypes[c.Phase],
+				StartTime: c.StartTime,
+				Elapsed:   uint64(c.Elapsed),
+			},
+			Response: res,
+		})
+	}
+	return rt, nil
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Format defines the wire format of Request and Response
+type Format int
+
+// Format enums
+const (
+	FormatJSON Format = iota
+	FormatMsgPack
+	FormatCBOR
+)
+
+// MIME types of the formats
+const (
+	MIMEJSON    = "application/json"
+	MIMEMsgPack = "application/msgpack"
+	MIMECBOR    = "application/cbor"
+)
+
+var (
+	msgpackHandle = &codec.MsgpackHandle{WriteExt: true} // []byte as bin
+	cborHandle    = &codec.CborHandle{}
+)
+
+// ParseFormat returns the format of the media type in Content-Type or the
+// first supported media type in Accept
+func ParseFormat(header string) (Format, bool) {
+	for v := range strings.SplitSeq(header, ",") {
+		t, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		switch t {
+		case MIMEJSON:
+			return FormatJSON, true
+		case MIMEMsgPack, "application/x-msgpack", "application/vnd.msgpack":
+			return FormatMsgPack, true
+		case MIMECBOR:
+			return FormatCBOR, true
+		}
+	}
+	return FormatJSON, false
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatMsgPack:
+		return MIMEMsgPack
+	case FormatCBOR:
+		return MIMECBOR
+	default:
+		return MIMEJSON + "; charset=utf-8"
+	}
+}
+
+// Binary returns whether the format is a binary format that frames messages
+// as binary (e.g. WebSocket binary message)
+func (f Format) Binary() bool {
+	return f != FormatJSON
+}
+
+func (f Format) handle() codec.Handle {
+	if f == FormatCBOR {
+		return cborHandle
+	}
+	return msgpackHandle
+}
+
+// Decode decodes the request (e.g. Request) from the reader. File content in
+// binary formats could be either string or raw bytes.
+func (f Format) Decode(r io.Reader, v any) error {
+	if f == FormatJSON {
+		return json.NewDecoder(r).Decode(v)
+	}
+	return codec.NewDecoder(r, f.handle()).Decode(v)
+}
+
+// EncodeResults encodes results to the writer. File content in binary formats
+// is encoded as raw bytes.
+func (f Format) EncodeResults(w io.Writer, r []Result) error {
+	if f == FormatJSON {
+		return json.NewEncoder(w).Encode(r)
+	}
+	return codec.NewEncoder(w, f.handle()).Encode(binaryResults(r))
+}
+
+// EncodeResponse encodes the response to the writer. File content in binary
+// formats is encoded as raw bytes.
+func (f Format) EncodeResponse(w io.Writer, r Response) error {
+	if f == FormatJSON {
+		return json.NewEncoder(w).Encode(r)
+	}
+	return codec.NewEncoder(w, f.handle()).Encode(binaryResponse{Response: &r, Results: binaryResults(r.Results)})
+}
+
+// binaryResponse shadows fields of Response for binary formats
+type binaryResponse struct {
+	*Response
+	Results []binaryResult `json:"results"`
+}
+
+// binaryResult shadows fields of Result for binary formats, with status and
+// file error type as string, and files as raw bytes
+type binaryResult struct {
+	*Result
+	Status    string            `json:"status"`
+	Files     map[string][]byte `json:"files,omitempty"`
+	FileError []binaryFileError `json:"fileError,omitempty"`
+}
+
+type binaryFileError struct {
+	FileError
+	Type string `json:"type"`
+}
+
+func binaryResults(r []Result) []binaryResult {
+	rt := make([]binaryResult, 0, len(r))
+	for i := range r {
+		res := binaryResult{
+			Result: &r[i],
+			Status: r[i].Status.String(),
+			Files:  r[i].Buffs,
+		}
+		for _, fe := range r[i].FileError {
+			res.FileError = append(res.FileError, binaryFileError{FileError: fe, Type: fe.Type.String()})
+		}
+		rt = append(rt, res)
+	}
+	return rt
+}
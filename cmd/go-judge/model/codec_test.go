@@ -0,0 +1,80 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/ugorji/go/codec"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		header string
+		format Format
+		ok     bool
+	}{
+		{"", FormatJSON, false},
+		{"application/json; charset=utf-8", FormatJSON, true},
+		{"application/x-msgpack", FormatMsgPack, true},
+		{"text/html, application/cbor;q=0.9, */*", FormatCBOR, true},
+		{"*/*", FormatJSON, false},
+	}
+	for _, tc := range tests {
+		f, ok := ParseFormat(tc.header)
+		if f != tc.format || ok != tc.ok {
+			t.Errorf("ParseFormat(%q) = %v %v, expected %v %v", tc.header, f, ok, tc.format, tc.ok)
+		}
+	}
+}
+
+func TestFormatBinary(t *testing.T) {
+	for _, f := range []Format{FormatMsgPack, FormatCBOR} {
+		// request with content as raw bytes
+		var in []byte
+		err := codec.NewEncoderBytes(&in, f.handle()).Encode(map[string]any{
+			"requestId": "1",
+			"cmd": []any{map[string]any{
+				"args":  []string{"cat"},
+				"files": []any{map[string]any{"content": []byte{0, 0xff}}},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+		var req Request
+		if err := f.Decode(bytes.NewReader(in), &req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.RequestID != "1" || len(req.Cmd) != 1 || *req.Cmd[0].Files[0].Content != "\x00\xff" {
+			t.Fatalf("unexpected request %+v", req)
+		}
+
+		// results with files as raw bytes
+		var out bytes.Buffer
+		err = f.EncodeResults(&out, []Result{{
+			Status:    Status(envexec.StatusAccepted),
+			Files:     map[string]string{"stdout": "\xff"},
+			Buffs:     map[string][]byte{"stdout": {0xff}},
+			FileError: []FileError{{Name: "out", Type: envexec.ErrCopyOutOpen}},
+		}})
+		if err != nil {
+			t.Fatalf("encode results: %v", err)
+		}
+		var rt []struct {
+			Status    string            `json:"status"`
+			Files     map[string][]byte `json:"files"`
+			FileError []struct {
+				Name string `json:"name"`
+				Type string `json:"type"`
+			} `json:"fileError"`
+		}
+		if err := codec.NewDecoder(&out, f.handle()).Decode(&rt); err != nil {
+			t.Fatalf("decode results: %v", err)
+		}
+		if len(rt) != 1 || rt[0].Status != "Accepted" || !bytes.Equal(rt[0].Files["stdout"], []byte{0xff}) ||
+			len(rt[0].FileError) != 1 || rt[0].FileError[0].Type != "CopyOutOpen" {
+			t.Fatalf("unexpected results %+v", rt)
+		}
+	}
+}
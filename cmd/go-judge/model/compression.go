@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
+)
+
+// CompressFiles compresses the output files of results collected by the
+// collectors with compression. It should be called before EncodeFiles and
+// compressed files are always encoded as base64 in JSON.
+func (r *Response) CompressFiles(req *Request) error {
+	for i := range r.Results {
+		if i >= len(req.Cmd) {
+			break
+		}
+		if err := r.Results[i].CompressFiles(req.Cmd[i].Compressions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compressions returns the compression of collectors by name, invalid
+// compressions are rejected by ConvertRequest thus ignored
+func (c *Cmd) Compressions() map[string]compress.Algorithm {
+	var rt map[string]compress.Algorithm
+	for _, f := range c.Files {
+		if f == nil || f.Name == nil || f.Max == nil || f.Compression == "" {
+			continue
+		}
+		a, err := compress.Parse(f.Compression)
+		if err != nil || a == compress.Identity {
+			continue
+		}
+		if rt == nil {
+			rt = make(map[string]compress.Algorithm)
+		}
+		rt[*f.Name] = a
+	}
+	return rt
+}
+
+// CompressFiles compresses the output files by name with the algorithm
+func (r *Result) CompressFiles(c map[string]compress.Algorithm) error {
+	for name, a := range c {
+		b, ok := r.Buffs[name]
+		if !ok || a == compress.Identity {
+			continue
+		}
+		cb, err := compress.Compress(b, a)
+		if err != nil {
+			return fmt.Errorf("compress %s: %w", name, err)
+		}
+		if r.compressed == nil {
+			r.compressed = make(map[string][]byte)
+		}
+		if _, ok := r.compressed[name]; !ok {
+			r.compressed[name] = b
+		}
+		r.Buffs[name] = cb
+		r.Files[name] = byteArrayToString(cb)
+	}
+	return nil
+}
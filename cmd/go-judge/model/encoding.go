@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Encoding defines how file content is represented in the JSON string
+type Encoding string
+
+// Encoding enums
+const (
+	EncodingUTF8   Encoding = "utf8"   // as is, invalid UTF-8 is replaced by U+FFFD by JSON
+	EncodingBase64 Encoding = "base64" // standard base64 with padding
+	EncodingAuto   Encoding = "auto"   // utf8 if the content is valid UTF-8, otherwise base64 (output only)
+)
+
+func checkEncoding(e string) error {
+	switch Encoding(e) {
+	case "", EncodingUTF8, EncodingBase64, EncodingAuto:
+		return nil
+	default:
+		return fmt.Errorf("unknown encoding: %q", e)
+	}
+}
+
+// decodeContent decodes the content of the input file by its encoding
+func decodeContent(content string, e string) ([]byte, error) {
+	switch Encoding(e) {
+	case "", EncodingUTF8:
+		return strToBytes(content), nil
+	case EncodingBase64:
+		b, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("decode base64 content: %w", err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("encoding %q is not valid for content", e)
+	}
+}
+
+// EncodeFiles encodes the output files of results by the encoding of the
+// request and the file encodings of each cmd. The encoding used for each file
+// is recorded in FileEncodings if base64 or auto is specified.
+func (r *Response) EncodeFiles(req *Request) {
+	for i := range r.Results {
+		var fe map[string]string
+		if i < len(req.Cmd) {
+			fe = req.Cmd[i].FileEncodings
+		}
+		r.Results[i].encodeFiles(Encoding(req.Encoding), fe)
+	}
+}
+
+func (r *Result) encodeFiles(def Encoding, fileEncodings map[string]string) {
+	for name, b := range r.Buffs {
+		e := def
+		if fe, ok := fileEncodings[name]; ok {
+			e = Encoding(fe)
+		}
+		// compressed content is binary
+		if _, ok := r.compressed[name]; ok {
+			e = EncodingBase64
+		}
+		switch e {
+		case EncodingAuto:
+			e = EncodingBase64
+			if utf8.Valid(b) {
+				e = EncodingUTF8
+			}
+		case EncodingBase64:
+		default:
+			continue
+		}
+		if e == EncodingBase64 {
+			r.Files[name] = base64.StdEncoding.EncodeToString(b)
+		}
+		if r.FileEncodings == nil {
+			r.FileEncodings = make(map[string]Encoding)
+		}
+		r.FileEncodings[name] = e
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"github.com/criyle/go-judge/worker"
+)
+
+// EventType defines the stage of the request in the progress event
+type EventType string
+
+// EventType enums
+const (
+	EventQueued          EventType = "queued"
+	EventStarted         EventType = "started"
+	EventCopyInDone      EventType = "copyInDone"
+	EventProcessStarted  EventType = "processStarted"
+	EventProcessFinished EventType = "processFinished"
+	EventCopyOutDone     EventType = "copyOutDone"
+	EventOutput          EventType = "output"
+	EventResponse        EventType = "response" // final response (e.g. SSE event name)
+)
+
+// Event defines the progress event of a request
+type Event struct {
+	Type EventType `json:"type"`
+	// Index is the index of the cmd for cmd events
+	Index *int `json:"index,omitempty"`
+	// Position is the position in the queue for queued event
+	Position int `json:"position,omitempty"`
+	// Result is the result without files for processFinished event
+	Result *Result `json:"result,omitempty"`
+	// Name and Content are the collector name and output chunk for output
+	// event, the content is base64 encoded in JSON
+	Name    string `json:"name,omitempty"`
+	Content []byte `json:"content,omitempty"`
+}
+
+var eventTypes = map[worker.EventType]EventType{
+	worker.EventQueued:          EventQueued,
+	worker.EventStarted:         EventStarted,
+	worker.EventCopyInDone:      EventCopyInDone,
+	worker.EventProcessStarted:  EventProcessStarted,
+	worker.EventProcessFinished: EventProcessFinished,
+	worker.EventCopyOutDone:     EventCopyOutDone,
+	worker.EventOutput:          EventOutput,
+}
+
+// ConvertEvent converts worker progress event
+func ConvertEvent(e worker.Event) Event {
+	rt := Event{
+		Type:     eventTypes[e.Type],
+		Position: e.Position,
+		Name:     e.Name,
+		Content:  e.Content,
+	}
+	switch e.Type {
+	case worker.EventQueued, worker.EventStarted:
+	default:
+		rt.Index = &e.Index
+	}
+	if e.Result != nil {
+		// result without files does not fail
+		r, _ := convertResult(*e.Result, false)
+		rt.Result = &r
+	}
+	return rt
+}
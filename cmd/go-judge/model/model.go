@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/worker"
 )
@@ -18,17 +19,32 @@ type FileError = envexec.FileError
 // FileErrorType defines the location that file operation fails
 type FileErrorType = envexec.FileErrorType
 
+// FileDigest defines the size and SHA-256 of a copy out file
+type FileDigest = envexec.FileDigest
+
 // CmdFile defines file from multiple source including local / memory / cached or pipe collector
 type CmdFile struct {
 	Src       *string `json:"src"`
 	Content   *string `json:"content"`
 	FileID    *string `json:"fileId"`
+	DirID     *string `json:"dirId"`
 	Name      *string `json:"name"`
 	Max       *int64  `json:"max"`
+	Tail      int64   `json:"tail"`
 	Symlink   *string `json:"symlink"`
 	StreamIn  bool    `json:"streamIn"`
 	StreamOut bool    `json:"streamOut"`
 	Pipe      bool    `json:"pipe"`
+	Extract   bool    `json:"extract"`
+	Mode      *uint32 `json:"mode"`
+	Owner     string  `json:"owner"`
+	ReadOnly  bool    `json:"readOnly"`
+	ZeroCopy  bool    `json:"zeroCopy"`
+	Encoding  string  `json:"encoding"` // encoding of content (utf8 / base64)
+	// Compression of content or collected output (gzip / zstd)
+	Compression string `json:"compression"`
+	// Live reports collected output as output events when events are requested
+	Live bool `json:"live"`
 }
 
 // Cmd defines command and limits to start a program using in envexec
@@ -51,9 +67,25 @@ type Cmd struct {
 	CopyOut       []string `json:"copyOut"`
 	CopyOutCached []string `json:"copyOutCached"`
 	CopyOutMax    uint64   `json:"copyOutMax"`
+	// CopyOutGlob treats names of copyOut / copyOutCached with glob
+	// characters as patterns, CopyOutMaxFiles limits the number of files
+	// matched by them.
+	CopyOutGlob     bool   `json:"copyOutGlob"`
+	CopyOutMaxFiles uint64 `json:"copyOutMaxFiles"`
 	// CopyOutDir is deprecated and ignored.
 	CopyOutDir      string `json:"copyOutDir"`
 	CopyOutTruncate bool   `json:"copyOutTruncate"`
+	// CopyOutTruncateTail keeps the last bytes out of copyOutMax when truncated.
+	CopyOutTruncateTail uint64 `json:"copyOutTruncateTail"`
+	// CopyOutDigest returns size and SHA-256 of copyOut / copyOutCached files
+	// in fileDigests, and CopyOutDigestOnly also omits the content of copyOut.
+	CopyOutDigest     bool `json:"copyOutDigest"`
+	CopyOutDigestOnly bool `json:"copyOutDigestOnly"`
+	// CopyOutDirFormat is the archive format (tar / zip) of directory copyOut.
+	CopyOutDirFormat string `json:"copyOutDirFormat"`
+	// FileEncodings overrides the encoding of the request for the output files
+	// by name.
+	FileEncodings map[string]string `json:"fileEncodings"`
 
 	TTY               bool `json:"tty,omitempty"`
 	StrictMemoryLimit bool `json:"strictMemoryLimit"`
@@ -82,6 +114,10 @@ type Request struct {
 	RequestID   string    `json:"requestId"`
 	Cmd         []Cmd     `json:"cmd"`
 	PipeMapping []PipeMap `json:"pipeMapping"`
+	// Tags labels the request to be selected by the admin cancel
+	Tags []string `json:"tags"`
+	// Encoding is the encoding of output files (utf8 / base64 / auto)
+	Encoding string `json:"encoding"`
 }
 
 // Status offers JSON marshal for envexec.Status
@@ -123,36 +159,46 @@ type Result struct {
 	ProcPeak   uint64            `json:"procPeak,omitempty"`
 	Files      map[string]string `json:"files,omitempty"`
 	FileIDs    map[string]string `json:"fileIds,omitempty"`
-	FileError  []FileError       `json:"fileError,omitempty"`
+	// FileDigests contains size and SHA-256 of files if copyOutDigest is specified
+	FileDigests map[string]FileDigest `json:"fileDigests,omitempty"`
+	// FileEncodings contains the encoding of files if base64 or auto is specified
+	FileEncodings map[string]Encoding `json:"fileEncodings,omitempty"`
+	// FileRefs contains the Content-ID of files sent as parts of multipart response
+	FileRefs  map[string]string `json:"fileRefs,omitempty"`
+	FileError []FileError       `json:"fileError,omitempty"`
 
 	files []string
 	Buffs map[string][]byte `json:"-"`
+	// compressed contains the original buffer of compressed files
+	compressed map[string][]byte
 }
 
 func (r Result) String() string {
 	type Result struct {
-		Status     Status
-		ExitStatus int
-		Error      string
-		Time       time.Duration
-		RunTime    time.Duration
-		ProcPeak   uint64
-		Memory     envexec.Size
-		Files      map[string]string
-		FileIDs    map[string]string
-		FileError  []FileError
+		Status      Status
+		ExitStatus  int
+		Error       string
+		Time        time.Duration
+		RunTime     time.Duration
+		ProcPeak    uint64
+		Memory      envexec.Size
+		Files       map[string]string
+		FileIDs     map[string]string
+		FileDigests map[string]FileDigest
+		FileError   []FileError
 	}
 	d := Result{
-		Status:     r.Status,
-		ExitStatus: r.ExitStatus,
-		Error:      r.Error,
-		Time:       time.Duration(r.Time),
-		RunTime:    time.Duration(r.RunTime),
-		Memory:     envexec.Size(r.Memory),
-		ProcPeak:   r.ProcPeak,
-		Files:      make(map[string]string),
-		FileIDs:    r.FileIDs,
-		FileError:  r.FileError,
+		Status:      r.Status,
+		ExitStatus:  r.ExitStatus,
+		Error:       r.Error,
+		Time:        time.Duration(r.Time),
+		RunTime:     time.Duration(r.RunTime),
+		Memory:      envexec.Size(r.Memory),
+		ProcPeak:    r.ProcPeak,
+		Files:       make(map[string]string),
+		FileIDs:     r.FileIDs,
+		FileDigests: r.FileDigests,
+		FileError:   r.FileError,
 	}
 	for k, v := range r.Files {
 		d.Files[k] = "len:" + strconv.Itoa(len(v))
@@ -165,6 +211,8 @@ type Response struct {
 	RequestID string   `json:"requestId"`
 	Results   []Result `json:"results"`
 	ErrorMsg  string   `json:"error,omitempty"`
+	// Event is the progress event of the request (e.g. WebSocket with progress)
+	Event *Event `json:"event,omitempty"`
 
 	mmap bool
 }
@@ -186,7 +234,10 @@ func (r *Result) Close() {
 		os.Remove(f)
 	}
 	// remove potential mmap
-	for _, b := range r.Buffs {
+	for name, b := range r.Buffs {
+		if o, ok := r.compressed[name]; ok {
+			b = o
+		}
 		releaseByte(b)
 	}
 }
@@ -232,15 +283,20 @@ func ConvertResponse(r worker.Response, mmap bool) (ret Response, err error) {
 	return ret, nil
 }
 
-// ConvertRequest converts json request into worker request
-func ConvertRequest(r *Request, srcPrefix []string) (*worker.Request, error) {
+// ConvertRequest converts json request into worker request, compressed file
+// contents are decompressed up to decompressLimit (0 for unlimited)
+func ConvertRequest(r *Request, srcPrefix []string, decompressLimit int64) (*worker.Request, error) {
 	req := &worker.Request{
 		RequestID:   r.RequestID,
 		Cmd:         make([]worker.Cmd, 0, len(r.Cmd)),
 		PipeMapping: make([]worker.PipeMap, 0, len(r.PipeMapping)),
+		Tags:        r.Tags,
+	}
+	if err := checkEncoding(r.Encoding); err != nil {
+		return nil, err
 	}
 	for _, c := range r.Cmd {
-		wc, err := convertCmd(c, srcPrefix)
+		wc, err := convertCmd(c, srcPrefix, decompressLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -254,15 +310,16 @@ func ConvertRequest(r *Request, srcPrefix []string) (*worker.Request, error) {
 
 func convertResult(r worker.Result, mmap bool) (Result, error) {
 	res := Result{
-		Status:     Status(r.Status),
-		ExitStatus: r.ExitStatus,
-		Error:      r.Error,
-		Time:       uint64(r.Time),
-		RunTime:    uint64(r.RunTime),
-		Memory:     uint64(r.Memory),
-		ProcPeak:   r.ProcPeak,
-		FileIDs:    r.FileIDs,
-		FileError:  r.FileError,
+		Status:      Status(r.Status),
+		ExitStatus:  r.ExitStatus,
+		Error:       r.Error,
+		Time:        uint64(r.Time),
+		RunTime:     uint64(r.RunTime),
+		Memory:      uint64(r.Memory),
+		ProcPeak:    r.ProcPeak,
+		FileIDs:     r.FileIDs,
+		FileDigests: r.FileDigests,
+		FileError:   r.FileError,
 	}
 	if r.Files != nil {
 		res.Files = make(map[string]string)
@@ -298,32 +355,56 @@ func convertPipe(p PipeMap) worker.PipeMap {
 	}
 }
 
-func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
+func convertCmd(c Cmd, srcPrefix []string, decompressLimit int64) (worker.Cmd, error) {
 	clockLimit := c.ClockLimit
 	if c.RealCPULimit > 0 {
 		clockLimit = c.RealCPULimit
 	}
 	w := worker.Cmd{
-		Args:              c.Args,
-		Env:               c.Env,
-		Files:             make([]worker.CmdFile, 0, len(c.Files)),
-		TTY:               c.TTY,
-		CPULimit:          time.Duration(c.CPULimit),
-		ClockLimit:        time.Duration(clockLimit),
-		MemoryLimit:       envexec.Size(c.MemoryLimit),
-		StackLimit:        envexec.Size(c.StackLimit),
-		ProcLimit:         c.ProcLimit,
-		CPURateLimit:      c.CPURateLimit,
-		CPUSetLimit:       c.CPUSetLimit,
-		DataSegmentLimit:  c.DataSegmentLimit || c.StrictMemoryLimit,
-		AddressSpaceLimit: c.AddressSpaceLimit,
-		CopyOut:           convertCopyOut(c.CopyOut),
-		CopyOutCached:     convertCopyOut(c.CopyOutCached),
-		CopyOutMax:        c.CopyOutMax,
-		CopyOutTruncate:   c.CopyOutTruncate,
+		Args:                c.Args,
+		Env:                 c.Env,
+		Files:               make([]worker.CmdFile, 0, len(c.Files)),
+		TTY:                 c.TTY,
+		CPULimit:            time.Duration(c.CPULimit),
+		ClockLimit:          time.Duration(clockLimit),
+		MemoryLimit:         envexec.Size(c.MemoryLimit),
+		StackLimit:          envexec.Size(c.StackLimit),
+		ProcLimit:           c.ProcLimit,
+		CPURateLimit:        c.CPURateLimit,
+		CPUSetLimit:         c.CPUSetLimit,
+		DataSegmentLimit:    c.DataSegmentLimit || c.StrictMemoryLimit,
+		AddressSpaceLimit:   c.AddressSpaceLimit,
+		CopyOut:             convertCopyOut(c.CopyOut, c.CopyOutGlob),
+		CopyOutCached:       convertCopyOut(c.CopyOutCached, c.CopyOutGlob),
+		CopyOutMax:          c.CopyOutMax,
+		CopyOutMaxFiles:     c.CopyOutMaxFiles,
+		CopyOutTruncate:     c.CopyOutTruncate,
+		CopyOutTruncateTail: c.CopyOutTruncateTail,
+		CopyOutDigest:       c.CopyOutDigest,
+		CopyOutDigestOnly:   c.CopyOutDigestOnly,
+	}
+	switch f := envexec.ArchiveFormat(c.CopyOutDirFormat); f {
+	case "", envexec.ArchiveTar, envexec.ArchiveZip:
+		w.CopyOutDirFormat = f
+	default:
+		return w, fmt.Errorf("unknown copyOut directory format: %q", c.CopyOutDirFormat)
+	}
+	for _, e := range c.FileEncodings {
+		if err := checkEncoding(e); err != nil {
+			return w, err
+		}
 	}
 	for _, f := range c.Files {
-		cf, err := convertCmdFile(f, srcPrefix)
+		if f != nil && f.Extract {
+			return w, fmt.Errorf("extract is only valid in copyIn: %v", f)
+		}
+		if f != nil && f.hasAttr() {
+			return w, fmt.Errorf("mode, owner and readOnly are only valid in copyIn: %v", f)
+		}
+		if f != nil && f.ZeroCopy {
+			return w, fmt.Errorf("zeroCopy is only valid in copyIn: %v", f)
+		}
+		cf, err := convertCmdFile(f, srcPrefix, decompressLimit)
 		if err != nil {
 			return w, err
 		}
@@ -337,20 +418,38 @@ func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
 				w.Symlinks[k] = *f.Symlink
 				continue
 			}
-			cf, err := convertCmdFile(&f, srcPrefix)
+			cf, err := convertCmdFile(&f, srcPrefix, decompressLimit)
 			if err != nil {
 				return w, err
 			}
+			if f.Extract {
+				if cf, err = convertArchiveFile(cf); err != nil {
+					return w, err
+				}
+			}
+			if f.hasAttr() {
+				if cf, err = convertAttrFile(&f, cf); err != nil {
+					return w, err
+				}
+			}
+			if f.ZeroCopy {
+				if cf, err = convertBindFile(&f, cf); err != nil {
+					return w, err
+				}
+			}
 			w.CopyIn[k] = cf
 		}
 	}
 	return w, nil
 }
 
-func convertCmdFile(f *CmdFile, srcPrefix []string) (worker.CmdFile, error) {
+func convertCmdFile(f *CmdFile, srcPrefix []string, decompressLimit int64) (worker.CmdFile, error) {
 	switch {
 	case f == nil:
 		return nil, nil
+	case f.Src != nil && strings.HasPrefix(*f.Src, worker.DatasetPrefix):
+		df, _ := worker.ParseDatasetFile(*f.Src)
+		return df, nil
 	case f.Src != nil:
 		if len(srcPrefix) != 0 {
 			ok, err := CheckPathPrefixes(*f.Src, srcPrefix)
@@ -363,16 +462,81 @@ func convertCmdFile(f *CmdFile, srcPrefix []string) (worker.CmdFile, error) {
 		}
 		return &worker.LocalFile{Src: *f.Src}, nil
 	case f.Content != nil:
-		return &worker.MemoryFile{Content: strToBytes(*f.Content)}, nil
+		b, err := decodeContent(*f.Content, f.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		a, err := compress.Parse(f.Compression)
+		if err != nil {
+			return nil, err
+		}
+		b, err = compress.Decompress(b, a, decompressLimit)
+		if err != nil {
+			return nil, err
+		}
+		return &worker.MemoryFile{Content: b}, nil
 	case f.FileID != nil:
 		return &worker.CachedFile{FileID: *f.FileID}, nil
+	case f.DirID != nil:
+		return &worker.CachedDir{DirID: *f.DirID}, nil
 	case f.Max != nil && f.Name != nil:
-		return &worker.Collector{Name: *f.Name, Max: envexec.Size(*f.Max), Pipe: f.Pipe}, nil
+		if _, err := compress.Parse(f.Compression); err != nil {
+			return nil, err
+		}
+		return &worker.Collector{Name: *f.Name, Max: envexec.Size(*f.Max), Pipe: f.Pipe, Tail: envexec.Size(f.Tail), Live: f.Live}, nil
 	default:
 		return nil, fmt.Errorf("file type is not valid for cmd: %v", f)
 	}
 }
 
+func convertArchiveFile(f worker.CmdFile) (worker.CmdFile, error) {
+	switch f.(type) {
+	case *worker.LocalFile, *worker.MemoryFile, *worker.CachedFile, *worker.DatasetFile:
+		return &worker.ArchiveFile{File: f}, nil
+	default:
+		return nil, fmt.Errorf("file type cannot be extracted: %v", f)
+	}
+}
+
+func (f *CmdFile) hasAttr() bool {
+	return f.Mode != nil || f.Owner != "" || f.ReadOnly
+}
+
+func convertAttrFile(f *CmdFile, cf worker.CmdFile) (worker.CmdFile, error) {
+	if _, ok := cf.(*worker.Collector); ok || f.Extract {
+		return nil, fmt.Errorf("file attributes cannot be applied: %v", cf)
+	}
+	var mode os.FileMode
+	if f.Mode != nil {
+		if *f.Mode&^0777 != 0 {
+			return nil, fmt.Errorf("file mode (%o) is not valid: %v", *f.Mode, cf)
+		}
+		mode = os.FileMode(*f.Mode)
+	}
+	var owner envexec.FileOwner
+	switch f.Owner {
+	case "", "root":
+		owner = envexec.OwnerRoot
+	case "user":
+		owner = envexec.OwnerUser
+	default:
+		return nil, fmt.Errorf("file owner (%s) is not valid: %v", f.Owner, cf)
+	}
+	return &worker.AttrFile{File: cf, Mode: mode, Owner: owner, ReadOnly: f.ReadOnly}, nil
+}
+
+func convertBindFile(f *CmdFile, cf worker.CmdFile) (worker.CmdFile, error) {
+	if f.Extract || f.hasAttr() {
+		return nil, fmt.Errorf("zeroCopy cannot be used with extract, mode, owner or readOnly: %v", cf)
+	}
+	switch cf.(type) {
+	case *worker.LocalFile, *worker.CachedFile, *worker.DatasetFile:
+		return &worker.BindFile{File: cf}, nil
+	default:
+		return nil, fmt.Errorf("file type cannot be zero-copied: %v", cf)
+	}
+}
+
 // CheckPathPrefixes ensure path is allowed by prefixes
 func CheckPathPrefixes(path string, prefixes []string) (bool, error) {
 	for _, p := range prefixes {
@@ -419,21 +583,30 @@ func checkPathPrefix(path, prefix string) (bool, error) {
 	return true, nil
 }
 
-const optionalSuffix = "?"
+const (
+	optionalSuffix = "?"
+	dirSuffix      = "/"
+)
 
-func convertCopyOut(copyOut []string) []worker.CmdCopyOutFile {
+func convertCopyOut(copyOut []string, glob bool) []worker.CmdCopyOutFile {
 	rt := make([]worker.CmdCopyOutFile, 0, len(copyOut))
 	for _, n := range copyOut {
-		if strings.HasSuffix(n, optionalSuffix) {
-			rt = append(rt, worker.CmdCopyOutFile{
-				Name:     strings.TrimSuffix(n, optionalSuffix),
-				Optional: true,
-			})
-			continue
+		f := worker.CmdCopyOutFile{Name: n}
+		if strings.HasSuffix(f.Name, optionalSuffix) {
+			f.Name = strings.TrimSuffix(f.Name, optionalSuffix)
+			f.Optional = true
+		}
+		// trailing slash copies out the directory tree as a whole
+		if len(f.Name) > len(dirSuffix) && strings.HasSuffix(f.Name, dirSuffix) {
+			f.Name = strings.TrimSuffix(f.Name, dirSuffix)
+			f.Dir = true
+		}
+		// glob pattern copies out all matched regular files when opted in so
+		// that names with glob characters are literal by default
+		if glob && !f.Dir && envexec.IsGlob(f.Name) {
+			f.Glob = true
 		}
-		rt = append(rt, worker.CmdCopyOutFile{
-			Name: n,
-		})
+		rt = append(rt, f)
 	}
 	return rt
 }
@@ -1,12 +1,16 @@
 package model
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
+	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/worker"
 )
 
@@ -38,7 +42,7 @@ func TestStatus_UnmarshalJSON_Invalid(t *testing.T) {
 
 func TestConvertCopyOut(t *testing.T) {
 	in := []string{"foo.txt", "bar.txt?"}
-	out := convertCopyOut(in)
+	out := convertCopyOut(in, false)
 	if len(out) != 2 {
 		t.Fatalf("expected 2, got %d", len(out))
 	}
@@ -50,6 +54,48 @@ func TestConvertCopyOut(t *testing.T) {
 	}
 }
 
+func TestConvertCopyOutDir(t *testing.T) {
+	out := convertCopyOut([]string{"out/", "opt/?", "/"}, false)
+	if len(out) != 3 {
+		t.Fatalf("expected 3, got %d", len(out))
+	}
+	if out[0].Name != "out" || !out[0].Dir || out[0].Optional {
+		t.Errorf("unexpected: %+v", out[0])
+	}
+	if out[1].Name != "opt" || !out[1].Dir || !out[1].Optional {
+		t.Errorf("unexpected: %+v", out[1])
+	}
+	if out[2].Name != "/" || out[2].Dir {
+		t.Errorf("unexpected: %+v", out[2])
+	}
+}
+
+func TestConvertCopyOutGlob(t *testing.T) {
+	out := convertCopyOut([]string{"*.class", "out/**?", "build/*/"}, true)
+	if len(out) != 3 {
+		t.Fatalf("expected 3, got %d", len(out))
+	}
+	if out[0].Name != "*.class" || !out[0].Glob || out[0].Optional {
+		t.Errorf("unexpected: %+v", out[0])
+	}
+	if out[1].Name != "out/**" || !out[1].Glob || !out[1].Optional {
+		t.Errorf("unexpected: %+v", out[1])
+	}
+	if out[2].Name != "build/*" || out[2].Glob || !out[2].Dir {
+		t.Errorf("unexpected: %+v", out[2])
+	}
+}
+
+func TestConvertCopyOutLiteral(t *testing.T) {
+	out := convertCopyOut([]string{"a[1].txt", "*.class?"}, false)
+	if out[0].Name != "a[1].txt" || out[0].Glob {
+		t.Errorf("unexpected: %+v", out[0])
+	}
+	if out[1].Name != "*.class" || out[1].Glob || !out[1].Optional {
+		t.Errorf("unexpected: %+v", out[1])
+	}
+}
+
 func TestCheckPathPrefixes(t *testing.T) {
 	tmp := t.TempDir()
 	abs := filepath.Join(tmp, "file.txt")
@@ -121,7 +167,7 @@ func TestCheckPathPrefixes_SymlinkEscape(t *testing.T) {
 func TestConvertCmdFile_Local(t *testing.T) {
 	src := "/tmp/foo"
 	f := &CmdFile{Src: &src}
-	_, err := convertCmdFile(f, nil)
+	_, err := convertCmdFile(f, nil, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -141,7 +187,7 @@ func TestConvertCmdFile_RejectsPrefixBoundaryEscape(t *testing.T) {
 		t.Fatalf("WriteFile error: %v", err)
 	}
 	src := target
-	_, err := convertCmdFile(&CmdFile{Src: &src}, []string{allowed})
+	_, err := convertCmdFile(&CmdFile{Src: &src}, []string{allowed}, 0)
 	if err == nil {
 		t.Fatal("expected prefix boundary escape to be rejected")
 	}
@@ -165,7 +211,7 @@ func TestConvertCmdFile_RejectsSymlinkEscape(t *testing.T) {
 		t.Fatalf("WriteFile error: %v", err)
 	}
 	src := filepath.Join(link, "file.txt")
-	_, err := convertCmdFile(&CmdFile{Src: &src}, []string{allowed})
+	_, err := convertCmdFile(&CmdFile{Src: &src}, []string{allowed}, 0)
 	if err == nil {
 		t.Fatal("expected symlink escape to be rejected")
 	}
@@ -174,7 +220,7 @@ func TestConvertCmdFile_RejectsSymlinkEscape(t *testing.T) {
 func TestConvertCmdFile_Content(t *testing.T) {
 	content := "abc"
 	f := &CmdFile{Content: &content}
-	cf, err := convertCmdFile(f, nil)
+	cf, err := convertCmdFile(f, nil, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -186,7 +232,7 @@ func TestConvertCmdFile_Content(t *testing.T) {
 func TestConvertCmdFile_FileID(t *testing.T) {
 	id := "id"
 	f := &CmdFile{FileID: &id}
-	cf, err := convertCmdFile(f, nil)
+	cf, err := convertCmdFile(f, nil, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -195,11 +241,35 @@ func TestConvertCmdFile_FileID(t *testing.T) {
 	}
 }
 
+func TestConvertCmdFile_DirID(t *testing.T) {
+	id := "id"
+	f := &CmdFile{DirID: &id}
+	cf, err := convertCmdFile(f, nil, 0)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if d, ok := cf.(*worker.CachedDir); !ok || d.DirID != id {
+		t.Errorf("expected cached dir, got %v", cf)
+	}
+}
+
+func TestConvertCmdFile_Dataset(t *testing.T) {
+	src := "dataset:tests/1/in.txt"
+	// dataset files are not restricted by src prefix
+	cf, err := convertCmdFile(&CmdFile{Src: &src}, []string{"/not/a/prefix"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d, ok := cf.(*worker.DatasetFile); !ok || d.Name != "tests" || d.Path != "1/in.txt" {
+		t.Errorf("expected dataset file, got %v", cf)
+	}
+}
+
 func TestConvertCmdFile_Collector(t *testing.T) {
 	name := "out"
 	max := int64(123)
 	f := &CmdFile{Name: &name, Max: &max}
-	cf, err := convertCmdFile(f, nil)
+	cf, err := convertCmdFile(f, nil, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -208,9 +278,22 @@ func TestConvertCmdFile_Collector(t *testing.T) {
 	}
 }
 
+func TestConvertCmdFile_CollectorTail(t *testing.T) {
+	name := "out"
+	max := int64(123)
+	f := &CmdFile{Name: &name, Max: &max, Tail: 23}
+	cf, err := convertCmdFile(f, nil, 0)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c, ok := cf.(*worker.Collector); !ok || c.Max != 123 || c.Tail != 23 {
+		t.Errorf("expected collector with tail, got %v", cf)
+	}
+}
+
 func TestConvertCmdFile_Invalid(t *testing.T) {
 	f := &CmdFile{}
-	_, err := convertCmdFile(f, nil)
+	_, err := convertCmdFile(f, nil, 0)
 	if err == nil {
 		t.Error("expected error for invalid CmdFile")
 	}
@@ -237,7 +320,7 @@ func TestConvertRequest_RejectsEscapingCopyIn(t *testing.T) {
 			},
 		}},
 	}
-	_, err := ConvertRequest(req, []string{allowed})
+	_, err := ConvertRequest(req, []string{allowed}, 0)
 	if err == nil {
 		t.Fatal("expected escaping copy-in to be rejected")
 	}
@@ -293,7 +376,7 @@ func TestConvertRequest_Basic(t *testing.T) {
 			MemoryLimit: 1024,
 		}},
 	}
-	workerReq, err := ConvertRequest(req, []string{tmp})
+	workerReq, err := ConvertRequest(req, []string{tmp}, 0)
 	if err != nil {
 		t.Fatalf("ConvertRequest error: %v", err)
 	}
@@ -319,7 +402,7 @@ func TestConvertRequest_InvalidFile(t *testing.T) {
 			},
 		},
 	}
-	_, err := ConvertRequest(req, nil)
+	_, err := ConvertRequest(req, nil, 0)
 	if err == nil {
 		t.Error("expected error for invalid CmdFile")
 	}
@@ -350,3 +433,219 @@ func TestConvertResponse_Basic(t *testing.T) {
 		t.Errorf("unexpected FileError: %+v", resp.Results[0].FileError)
 	}
 }
+
+func TestConvertCmd_Extract(t *testing.T) {
+	content := "archive"
+	c := Cmd{
+		CopyIn: map[string]CmdFile{
+			"dir": {Content: &content, Extract: true},
+		},
+	}
+	w, err := convertCmd(c, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, ok := w.CopyIn["dir"].(*worker.ArchiveFile)
+	if !ok {
+		t.Fatalf("expected archive file, got %v", w.CopyIn["dir"])
+	}
+	if _, ok := a.File.(*worker.MemoryFile); !ok {
+		t.Errorf("expected memory file inside archive, got %v", a.File)
+	}
+
+	c = Cmd{Files: []*CmdFile{{Content: &content, Extract: true}}}
+	if _, err := convertCmd(c, nil, 0); err == nil {
+		t.Error("expected extract to be rejected in files")
+	}
+}
+
+func TestConvertCmd_CopyOutDirFormat(t *testing.T) {
+	w, err := convertCmd(Cmd{CopyOutDirFormat: "zip"}, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.CopyOutDirFormat != envexec.ArchiveZip {
+		t.Errorf("expected zip format, got %q", w.CopyOutDirFormat)
+	}
+	if _, err := convertCmd(Cmd{CopyOutDirFormat: "rar"}, nil, 0); err == nil {
+		t.Error("expected unknown format to be rejected")
+	}
+}
+
+func TestConvertResult_FileDigests(t *testing.T) {
+	d := map[string]FileDigest{"stdout": {Size: 3, SHA256: "abc"}}
+	res, err := convertResult(worker.Result{FileDigests: d}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(b), `"fileDigests":{"stdout":{"size":3,"sha256":"abc"}}`) {
+		t.Errorf("unexpected json %s", b)
+	}
+}
+
+func TestConvertCmd_FileAttr(t *testing.T) {
+	content := "ans"
+	mode := uint32(0640)
+	c := Cmd{
+		CopyIn: map[string]CmdFile{
+			"ans.txt": {Content: &content, Mode: &mode, Owner: "root", ReadOnly: true},
+		},
+	}
+	w, err := convertCmd(c, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, ok := w.CopyIn["ans.txt"].(*worker.AttrFile)
+	if !ok {
+		t.Fatalf("expected attr file, got %v", w.CopyIn["ans.txt"])
+	}
+	if a.Mode != 0640 || a.Owner != envexec.OwnerRoot || !a.ReadOnly {
+		t.Errorf("unexpected attributes: %+v", a)
+	}
+
+	for _, f := range []CmdFile{
+		{Content: &content, Owner: "nobody"},
+		{Content: &content, Mode: ptr(uint32(01777))},
+		{Content: &content, Extract: true, ReadOnly: true},
+	} {
+		if _, err := convertCmd(Cmd{CopyIn: map[string]CmdFile{"f": f}}, nil, 0); err == nil {
+			t.Errorf("expected %+v to be rejected", f)
+		}
+	}
+	if _, err := convertCmd(Cmd{Files: []*CmdFile{{Content: &content, ReadOnly: true}}}, nil, 0); err == nil {
+		t.Error("expected readOnly to be rejected in files")
+	}
+}
+
+func TestConvertCmd_ZeroCopy(t *testing.T) {
+	id := "id"
+	w, err := convertCmd(Cmd{CopyIn: map[string]CmdFile{"in.txt": {FileID: &id, ZeroCopy: true}}}, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, ok := w.CopyIn["in.txt"].(*worker.BindFile)
+	if !ok {
+		t.Fatalf("expected bind file, got %v", w.CopyIn["in.txt"])
+	}
+	if _, ok := b.File.(*worker.CachedFile); !ok {
+		t.Errorf("expected cached file, got %v", b.File)
+	}
+
+	content := "content"
+	for _, f := range []CmdFile{
+		{Content: &content, ZeroCopy: true},
+		{FileID: &id, ZeroCopy: true, Extract: true},
+		{FileID: &id, ZeroCopy: true, ReadOnly: true},
+	} {
+		if _, err := convertCmd(Cmd{CopyIn: map[string]CmdFile{"f": f}}, nil, 0); err == nil {
+			t.Errorf("expected %+v to be rejected", f)
+		}
+	}
+	if _, err := convertCmd(Cmd{Files: []*CmdFile{{FileID: &id, ZeroCopy: true}}}, nil, 0); err == nil {
+		t.Error("expected zeroCopy to be rejected in files")
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestConvertCmdFile_ContentEncoding(t *testing.T) {
+	content := "AAEC/w=="
+	cf, err := convertCmdFile(&CmdFile{Content: &content, Encoding: "base64"}, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m, ok := cf.(*worker.MemoryFile); !ok || string(m.Content) != "\x00\x01\x02\xff" {
+		t.Errorf("expected decoded content, got %v", cf)
+	}
+	if _, err := convertCmdFile(&CmdFile{Content: &content, Encoding: "auto"}, nil, 0); err == nil {
+		t.Error("expected auto encoding to be rejected for content")
+	}
+	bad := "!"
+	if _, err := convertCmdFile(&CmdFile{Content: &bad, Encoding: "base64"}, nil, 0); err == nil {
+		t.Error("expected invalid base64 to be rejected")
+	}
+	if _, err := ConvertRequest(&Request{Encoding: "hex"}, nil, 0); err == nil {
+		t.Error("expected unknown encoding to be rejected")
+	}
+}
+
+func TestResponse_EncodeFiles(t *testing.T) {
+	res := Response{Results: []Result{{
+		Files: map[string]string{"stdout": "ok", "stderr": "\xff", "out.png": "\x89PNG"},
+		Buffs: map[string][]byte{"stdout": []byte("ok"), "stderr": []byte("\xff"), "out.png": []byte("\x89PNG")},
+	}}}
+	res.EncodeFiles(&Request{
+		Encoding: "auto",
+		Cmd:      []Cmd{{FileEncodings: map[string]string{"stdout": "base64"}}},
+	})
+	r := res.Results[0]
+	if r.Files["stdout"] != "b2s=" || r.FileEncodings["stdout"] != EncodingBase64 {
+		t.Errorf("expected stdout base64 encoded, got %q %q", r.Files["stdout"], r.FileEncodings["stdout"])
+	}
+	if r.Files["stderr"] != "/w==" || r.FileEncodings["stderr"] != EncodingBase64 {
+		t.Errorf("expected invalid utf8 base64 encoded, got %q %q", r.Files["stderr"], r.FileEncodings["stderr"])
+	}
+	if r.Files["out.png"] != "iVBORw==" {
+		t.Errorf("expected png base64 encoded, got %q", r.Files["out.png"])
+	}
+
+	res = Response{Results: []Result{{
+		Files: map[string]string{"stdout": "ok"},
+		Buffs: map[string][]byte{"stdout": []byte("ok")},
+	}}}
+	res.EncodeFiles(&Request{Encoding: "auto"})
+	if r := res.Results[0]; r.Files["stdout"] != "ok" || r.FileEncodings["stdout"] != EncodingUTF8 {
+		t.Errorf("expected stdout kept as utf8, got %q %q", r.Files["stdout"], r.FileEncodings["stdout"])
+	}
+}
+
+func TestConvertCmdFile_ContentCompression(t *testing.T) {
+	c, err := compress.Compress([]byte("1 2\n"), compress.Gzip)
+	if err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	content := base64.StdEncoding.EncodeToString(c)
+	cf, err := convertCmdFile(&CmdFile{Content: &content, Encoding: "base64", Compression: "gzip"}, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m, ok := cf.(*worker.MemoryFile); !ok || string(m.Content) != "1 2\n" {
+		t.Errorf("expected decompressed content, got %v", cf)
+	}
+	if _, err := convertCmdFile(&CmdFile{Name: ptr("stdout"), Max: ptr(int64(10)), Compression: "br"}, nil, 0); err == nil {
+		t.Error("expected unknown compression to be rejected")
+	}
+}
+
+func TestResponse_CompressFiles(t *testing.T) {
+	res := Response{Results: []Result{{
+		Files: map[string]string{"stdout": "ok", "stderr": "err"},
+		Buffs: map[string][]byte{"stdout": []byte("ok"), "stderr": []byte("err")},
+	}}}
+	req := &Request{Cmd: []Cmd{{Files: []*CmdFile{
+		nil,
+		{Name: ptr("stdout"), Max: ptr(int64(10)), Compression: "zstd"},
+		{Name: ptr("stderr"), Max: ptr(int64(10))},
+	}}}}
+	if err := res.CompressFiles(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.EncodeFiles(req)
+	r := res.Results[0]
+	b, err := compress.Decompress(r.Buffs["stdout"], compress.Zstd, 0)
+	if err != nil || string(b) != "ok" {
+		t.Errorf("expected stdout compressed, got %q %v", b, err)
+	}
+	if r.FileEncodings["stdout"] != EncodingBase64 || r.Files["stdout"] != base64.StdEncoding.EncodeToString(r.Buffs["stdout"]) {
+		t.Errorf("expected compressed stdout base64 encoded, got %q", r.Files["stdout"])
+	}
+	if r.Files["stderr"] != "err" {
+		t.Errorf("expected stderr unchanged, got %q", r.Files["stderr"])
+	}
+}
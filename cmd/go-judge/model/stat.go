@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// Stat defines the statistic of the worker, environment pool, file store and
+// the requests in flight
+type Stat struct {
+	Queue       int           `json:"queue"`
+	Running     int           `json:"running"`
+	Parallelism int           `json:"parallelism"`
+	EnvIdle     int           `json:"envIdle"`
+	EnvTotal    int           `json:"envTotal"`
+	FileCount   int           `json:"fileCount"`
+	FileSize    int64         `json:"fileSize"`
+	Draining    bool          `json:"draining"`
+	Requests    []RequestStat `json:"requests"`
+}
+
+// RequestStat defines the state of a request queued or running
+type RequestStat struct {
+	RequestID string    `json:"requestId"`
+	Namespace string    `json:"namespace,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Phase     EventType `json:"phase"`
+	StartTime time.Time `json:"startTime"`
+	Elapsed   uint64    `json:"elapsed"`
+}
+
+// CollectStat collects the statistic visible to the namespace. Requests and
+// files of the other namespaces are only visible to the default namespace.
+func CollectStat(w worker.Worker, fs filestore.FileStore, ns string) Stat {
+	s := w.Stat()
+	if ns != "" {
+		fs = filestore.ForNamespace(fs, ns)
+	}
+	u := filestore.StatUsage(fs)
+	rt := Stat{
+		Queue:       s.Queue,
+		Running:     s.Running,
+		Parallelism: s.Parallelism,
+		EnvIdle:     s.Pool.Idle,
+		EnvTotal:    s.Pool.Total,
+		FileCount:   u.Files,
+		FileSize:    u.Bytes,
+		Draining:    s.Draining,
+		Requests:    make([]RequestStat, 0),
+	}
+	for _, r := range worker.StatRequests(w) {
+		if ns != "" && r.Namespace != ns {
+			continue
+		}
+		rt.Requests = append(rt.Requests, RequestStat{
+			RequestID: r.RequestID,
+			Namespace: r.Namespace,
+			Tags:      r.Tags,
+			Phase:     eventTypes[r.Phase],
+			StartTime: r.StartTime,
+			Elapsed:   uint64(r.Elapsed),
+		})
+	}
+	return rt
+}
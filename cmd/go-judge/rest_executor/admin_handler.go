@@ -0,0 +1,109 @@
+package restexecutor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+type adminHandle struct {
+	fs     filestore.FileStore
+	worker worker.Worker
+	drain  func(draining bool)
+}
+
+// NewAdminHandle creates a new admin handle, which is only accessible from
+// the default namespace. drain is called to start or stop draining the server.
+func NewAdminHandle(fs filestore.FileStore, worker worker.Worker, drain func(draining bool)) Register {
+	return &adminHandle{
+		fs:     fs,
+		worker: worker,
+		drain:  drain,
+	}
+}
+
+func (h *adminHandle) Register(r gin.IRouter) {
+	// Admin handle
+	g := r.Group("/admin", adminOnly)
+	g.GET("/snapshot", h.snapshotGet)
+	g.POST("/snapshot", h.snapshotPost)
+	g.POST("/cancel", h.cancelPost)
+	g.POST("/drain", h.drainPost)
+	g.DELETE("/drain", h.drainDelete)
+}
+
+// adminOnly rejects requests from namespaced tokens or namespaces
+func adminOnly(c *gin.Context) {
+	if filestore.NamespaceFromContext(c.Request.Context()) != "" {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	c.Next()
+}
+
+func (h *adminHandle) snapshotGet(c *gin.Context) {
+	name := fmt.Sprintf("go-judge-snapshot-%s.tar", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	c.Status(http.StatusOK)
+	// status is already sent, thus the error is only recorded
+	if err := filestore.Export(h.fs, c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+func (h *adminHandle) snapshotPost(c *gin.Context) {
+	mode := filestore.ConflictMode(c.DefaultQuery("conflict", string(filestore.ConflictFail)))
+	res, err := filestore.Import(h.fs, c.Request.Body, mode)
+	switch {
+	case errors.Is(err, filestore.ErrSnapshotConflict):
+		c.AbortWithError(http.StatusConflict, err)
+		return
+	case errors.Is(err, filestore.ErrQuotaExceeded):
+		c.AbortWithError(http.StatusRequestEntityTooLarge, err)
+		return
+	case err != nil:
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// cancelPost cancels the selected requests and responds with their partial
+// results after they are finished
+func (h *adminHandle) cancelPost(c *gin.Context) {
+	var req model.CancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	res, err := model.CancelRequests(c.Request.Context(), h.worker, &req)
+	switch {
+	case errors.Is(err, model.ErrCancelSelectorRequired):
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	case err != nil:
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// drainPost starts draining without exiting, new requests are rejected while
+// the requests in flight keep running
+func (h *adminHandle) drainPost(c *gin.Context) {
+	h.drain(true)
+	c.JSON(http.StatusOK, model.CollectStat(h.worker, h.fs, ""))
+}
+
+// drainDelete resumes accepting new requests
+func (h *adminHandle) drainDelete(c *gin.Context) {
+	h.drain(false)
+	c.JSON(http.StatusOK, model.CollectStat(h.worker, h.fs, ""))
+}
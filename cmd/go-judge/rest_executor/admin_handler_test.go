@@ -0,0 +1,151 @@
+package restexecutor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminSnapshot(t *testing.T) {
+	src := filestore.NewFileLocalStore(t.TempDir())
+	f, err := src.New()
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	f.WriteString("content")
+	f.Close()
+	id, err := src.Add("a.txt", f.Name())
+	if err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+
+	router := gin.New()
+	NewAdminHandle(src, nil, nil).Register(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/snapshot", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	snapshot := w.Body.Bytes()
+
+	dst := filestore.NewFileLocalStore(t.TempDir())
+	router = gin.New()
+	NewAdminHandle(dst, nil, nil).Register(router)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/snapshot", bytes.NewReader(snapshot)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if name := dst.List()[id]; name != "a.txt" {
+		t.Fatalf("Expected file %q imported, got %q", id, name)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/snapshot?conflict=fail", bytes.NewReader(snapshot)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestAdminSnapshotNamespaced(t *testing.T) {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(filestore.WithNamespace(c.Request.Context(), "team"))
+	})
+	NewAdminHandle(filestore.NewFileLocalStore(t.TempDir()), nil, nil).Register(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/snapshot", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+type nopEnvPool struct{}
+
+func (nopEnvPool) Get() (envexec.Environment, error) { return nil, errors.New("no environment") }
+func (nopEnvPool) Put(envexec.Environment)           {}
+func (nopEnvPool) Destroy()                          {}
+
+func TestAdminCancel(t *testing.T) {
+	// no parallelism thus requests are kept queued
+	work := worker.New(worker.Config{EnvironmentPool: nopEnvPool{}})
+	work.Start()
+	defer work.Shutdown()
+	cmd := []worker.Cmd{{Args: []string{"true"}}}
+	rtCh, _ := work.Submit(context.Background(), &worker.Request{RequestID: "1", Namespace: "team", Cmd: cmd, Tags: []string{"t"}})
+	work.Submit(context.Background(), &worker.Request{RequestID: "2", Namespace: "team", Cmd: cmd})
+
+	router := gin.New()
+	NewAdminHandle(filestore.NewFileLocalStore(t.TempDir()), work, nil).Register(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/cancel", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/cancel", strings.NewReader(`{"namespace":"team","tag":"t"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var res []model.CancelResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(res) != 1 || res[0].RequestID != "1" || res[0].Phase != model.EventQueued ||
+		!strings.Contains(res[0].Response.ErrorMsg, worker.ErrCancelled.Error()) {
+		t.Fatalf("Unexpected cancel result %+v", res)
+	}
+	if rt := <-rtCh; !errors.Is(rt.Error, worker.ErrCancelled) {
+		t.Fatalf("Expected submitter to receive cancelled response, got %+v", rt)
+	}
+	if reqs := worker.StatRequests(work); len(reqs) != 1 || reqs[0].RequestID != "2" {
+		t.Fatalf("Expected the other request kept, got %+v", reqs)
+	}
+}
+
+func TestAdminDrain(t *testing.T) {
+	work := worker.New(worker.Config{EnvironmentPool: nopEnvPool{}})
+	work.Start()
+	defer work.Shutdown()
+
+	router := gin.New()
+	NewAdminHandle(filestore.NewFileLocalStore(t.TempDir()), work, func(d bool) { worker.DrainRequests(work, d) }).Register(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/drain", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var st model.Stat
+	if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !st.Draining {
+		t.Fatalf("Expected draining, got %+v", st)
+	}
+	cmd := []worker.Cmd{{Args: []string{"true"}}}
+	rtCh, _ := work.Submit(context.Background(), &worker.Request{Cmd: cmd})
+	if rt := <-rtCh; !errors.Is(rt.Error, worker.ErrDraining) {
+		t.Fatalf("Expected submit rejected when draining, got %+v", rt)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/admin/drain", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if work.Stat().Draining {
+		t.Fatal("Expected drain resumed")
+	}
+}
@@ -1,41 +1,62 @@
 package restexecutor
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/filestore"
 	"github.com/criyle/go-judge/worker"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// retryAfter is the seconds for clients to wait before retrying the request
+// rejected by draining
+const retryAfter = "1"
+
 type cmdHandle struct {
-	worker    worker.Worker
-	srcPrefix []string
-	logger    *zap.Logger
+	worker          worker.Worker
+	srcPrefix       []string
+	decompressLimit int64
+	logger          *zap.Logger
 }
 
-// NewCmdHandle creates a new command handle
-func NewCmdHandle(worker worker.Worker, srcPrefix []string, logger *zap.Logger) Register {
+// NewCmdHandle creates a new command handle, compressed file contents are
+// decompressed up to decompressLimit (0 for unlimited)
+func NewCmdHandle(worker worker.Worker, srcPrefix []string, decompressLimit int64, logger *zap.Logger) Register {
 	return &cmdHandle{
-		worker:    worker,
-		srcPrefix: srcPrefix,
-		logger:    logger,
+		worker:          worker,
+		srcPrefix:       srcPrefix,
+		decompressLimit: decompressLimit,
+		logger:          logger,
 	}
 }
 
-func (c *cmdHandle) Register(r *gin.Engine) {
+func (c *cmdHandle) Register(r gin.IRouter) {
 	// Run handle
 	r.POST("/run", c.handleRun)
 }
 
 func (c *cmdHandle) handleRun(ctx *gin.Context) {
+	// JSON is the default format for both request and response, and the
+	// response format follows the request if not accepted explicitly
+	reqFormat, _ := model.ParseFormat(ctx.ContentType())
+	respFormat, ok := model.ParseFormat(ctx.GetHeader("Accept"))
+	if !ok {
+		respFormat = reqFormat
+	}
+
 	var req model.Request
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := reqFormat.Decode(ctx.Request.Body, &req); err != nil {
 		ctx.Error(err)
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		code := http.StatusBadRequest
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		ctx.AbortWithStatusJSON(code, err.Error())
 		return
 	}
 
@@ -43,29 +64,46 @@ func (c *cmdHandle) handleRun(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, "no cmd provided")
 		return
 	}
-	r, err := model.ConvertRequest(&req, c.srcPrefix)
+	r, err := model.ConvertRequest(&req, c.srcPrefix, c.decompressLimit)
 	if err != nil {
 		ctx.Error(err)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
 		return
 	}
+	r.Namespace = filestore.NamespaceFromContext(ctx.Request.Context())
 	if ce := c.logger.Check(zap.DebugLevel, "request"); ce != nil {
 		ce.Write(zap.String("body", fmt.Sprintf("%+v", r)))
 	}
+	if accepts(ctx.GetHeader("Accept"), mimeEventStream) {
+		c.handleRunEvents(ctx, &req, r)
+		return
+	}
 	rtCh, _ := c.worker.Submit(ctx.Request.Context(), r)
 	rt := <-rtCh
 	if ce := c.logger.Check(zap.DebugLevel, "response"); ce != nil {
 		ce.Write(zap.String("body", fmt.Sprintf("%+v", rt)))
 	}
+	if errors.Is(rt.Error, worker.ErrDraining) {
+		// retriable on other instances or after the drain is over
+		ctx.Header("Retry-After", retryAfter)
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, rt.Error.Error())
+		return
+	}
 	if rt.Error != nil {
 		ctx.Error(rt.Error)
 		ctx.AbortWithStatusJSON(http.StatusInternalServerError, rt.Error.Error())
 		return
 	}
+	if accepts(ctx.GetHeader("Accept"), mimeMultipartMixed) {
+		if err := writeMultipart(ctx, &req, rt, respFormat); err != nil {
+			ctx.Error(err)
+		}
+		return
+	}
 
-	// encode json directly to avoid allocation
+	// encode directly to avoid allocation
 	ctx.Status(http.StatusOK)
-	ctx.Header("Content-Type", "application/json; charset=utf-8")
+	ctx.Header("Content-Type", respFormat.ContentType())
 
 	res, err := model.ConvertResponse(rt, true)
 	if err != nil {
@@ -74,8 +112,16 @@ func (c *cmdHandle) handleRun(ctx *gin.Context) {
 		return
 	}
 	defer res.Close()
+	if err := res.CompressFiles(&req); err != nil {
+		ctx.Error(err)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !respFormat.Binary() {
+		res.EncodeFiles(&req)
+	}
 
-	if err := json.NewEncoder(ctx.Writer).Encode(res.Results); err != nil {
+	if err := respFormat.EncodeResults(ctx.Writer, res.Results); err != nil {
 		ctx.Error(err)
 	}
 }
@@ -3,18 +3,24 @@ package restexecutor
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/criyle/go-judge/cmd/go-judge/model"
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/worker"
 	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
 	"go.uber.org/zap/zaptest"
 	"io"
 	"maps"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,6 +34,18 @@ type mockWorker struct {
 
 func (m *mockWorker) Submit(_ context.Context, req *worker.Request) (<-chan worker.Response, <-chan struct{}) {
 	// Mock implementation
+	if req.Progress != nil {
+		req.Progress(worker.Event{Type: worker.EventQueued, Position: 1})
+		req.Progress(worker.Event{Type: worker.EventStarted})
+		for i, c := range req.Cmd {
+			for _, f := range c.Files {
+				if lc, ok := f.(*worker.Collector); ok && lc.Live {
+					req.Progress(worker.Event{Type: worker.EventOutput, Index: i, Name: lc.Name, Content: []byte("hello")})
+				}
+			}
+		}
+		req.Progress(worker.Event{Type: worker.EventProcessFinished, Result: &worker.Result{Status: m.Result.Status}})
+	}
 	rtCh := make(chan worker.Response, 1)
 	rtCh <- worker.Response{
 		RequestID: req.RequestID,
@@ -107,7 +125,7 @@ func TestHandleRun(t *testing.T) {
 	// Create a logger
 	logger := zaptest.NewLogger(t)
 	// Create a new command handle
-	cmdHandle := NewCmdHandle(mockWorker, nil, logger)
+	cmdHandle := NewCmdHandle(mockWorker, nil, 0, logger)
 	cmdHandle.Register(router)
 
 	// Create a test request
@@ -180,3 +198,265 @@ func TestHandleRun(t *testing.T) {
 		t.Fatalf("Expected result to match, but got error: %v", err)
 	}
 }
+
+// TestHandleRunMsgPack tests the handleRun method with MessagePack request and response
+func TestHandleRunMsgPack(t *testing.T) {
+	router := gin.New()
+	mockWorker := &mockWorker{
+		Result: worker.Result{
+			Status: envexec.StatusAccepted,
+			Time:   time.Millisecond,
+		},
+	}
+	NewCmdHandle(mockWorker, nil, 0, zaptest.NewLogger(t)).Register(router)
+
+	var body []byte
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true
+	err := codec.NewEncoderBytes(&body, h).Encode(map[string]any{
+		"cmd": []any{map[string]any{
+			"args":  []string{"/bin/cat"},
+			"files": []any{map[string]any{"content": []byte{0, 1, 2}}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	testReq := httptest.NewRequest("POST", "/run", bytes.NewReader(body))
+	testReq.Header.Set("Content-Type", model.MIMEMsgPack)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testReq)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	if ct := recorder.Header().Get("Content-Type"); ct != model.MIMEMsgPack {
+		t.Fatalf("Expected content type %q, got %q", model.MIMEMsgPack, ct)
+	}
+	var response []map[string]any
+	if err := codec.NewDecoder(recorder.Body, h).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response) != 1 || response[0]["status"] != "Accepted" {
+		t.Fatalf("Unexpected response %v", response)
+	}
+}
+
+// TestHandleRunMultipart tests the handleRun method with multipart/mixed response
+func TestHandleRunMultipart(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	f.WriteString("hello world")
+
+	router := gin.New()
+	mockWorker := &mockWorker{
+		Result: worker.Result{
+			Status: envexec.StatusAccepted,
+			Files:  map[string]*os.File{"stdout": f},
+		},
+	}
+	NewCmdHandle(mockWorker, nil, 0, zaptest.NewLogger(t)).Register(router)
+
+	req := model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/echo"}}}}
+	testReq := httptest.NewRequest("POST", "/run", requestToReader(req))
+	testReq.Header.Set("Accept", "multipart/mixed")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testReq)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	mediaType, params, err := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, got %q %v", mediaType, err)
+	}
+	mr := multipart.NewReader(recorder.Body, params["boundary"])
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read results part: %v", err)
+	}
+	var response []model.Result
+	if err := json.NewDecoder(p).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode results: %v", err)
+	}
+	if len(response) != 1 || len(response[0].Files) != 0 || response[0].FileRefs["stdout"] != "1" {
+		t.Fatalf("Unexpected results %+v", response)
+	}
+	p, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read file part: %v", err)
+	}
+	if id := p.Header.Get("Content-ID"); id != "<1>" || p.FileName() != "stdout" {
+		t.Fatalf("Unexpected file part %v", p.Header)
+	}
+	if b, _ := io.ReadAll(p); string(b) != "hello world" {
+		t.Fatalf("Expected file content, got %q", b)
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatalf("Expected end of parts, got %v", err)
+	}
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Fatalf("Expected file removed, got %v", err)
+	}
+}
+
+// TestHandleRunMultipartMsgPackBase64 tests the multipart/mixed response honours
+// MessagePack Accept and base64 encoding
+func TestHandleRunMultipartMsgPackBase64(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	f.WriteString("hello world")
+
+	router := gin.New()
+	mockWorker := &mockWorker{
+		Result: worker.Result{
+			Status: envexec.StatusAccepted,
+			Files:  map[string]*os.File{"stdout": f},
+		},
+	}
+	NewCmdHandle(mockWorker, nil, 0, zaptest.NewLogger(t)).Register(router)
+
+	req := model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/echo"}}}, Encoding: "base64"}
+	testReq := httptest.NewRequest("POST", "/run", requestToReader(req))
+	testReq.Header.Set("Accept", "multipart/mixed, "+model.MIMEMsgPack)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testReq)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	_, params, _ := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	mr := multipart.NewReader(recorder.Body, params["boundary"])
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read results part: %v", err)
+	}
+	if ct := p.Header.Get("Content-Type"); ct != model.MIMEMsgPack {
+		t.Fatalf("Expected content type %q, got %q", model.MIMEMsgPack, ct)
+	}
+	var response []map[string]any
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true
+	if err := codec.NewDecoder(p, h).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode results: %v", err)
+	}
+	if len(response) != 1 || response[0]["status"] != "Accepted" {
+		t.Fatalf("Unexpected results %v", response)
+	}
+	p, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read file part: %v", err)
+	}
+	if te := p.Header.Get("Content-Transfer-Encoding"); te != "base64" {
+		t.Fatalf("Expected base64 transfer encoding, got %q", te)
+	}
+	if b, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, p)); string(b) != "hello world" {
+		t.Fatalf("Expected file content, got %q", b)
+	}
+}
+
+// TestHandleRunEvents tests the handleRun method with server-sent events
+func TestHandleRunEvents(t *testing.T) {
+	router := gin.New()
+	mockWorker := &mockWorker{
+		Result: worker.Result{Status: envexec.StatusAccepted},
+	}
+	NewCmdHandle(mockWorker, nil, 0, zaptest.NewLogger(t)).Register(router)
+
+	req := model.Request{RequestID: "qwq", Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}
+	testReq := httptest.NewRequest("POST", "/run", requestToReader(req))
+	testReq.Header.Set("Accept", "text/event-stream")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testReq)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Expected event stream, got %q", ct)
+	}
+
+	var names []string
+	var last string
+	for line := range strings.Lines(recorder.Body.String()) {
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			names = append(names, strings.TrimSpace(name))
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			last = data
+		}
+	}
+	expected := []string{"queued", "started", "processFinished", "response"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("Expected events %v, got %v", expected, names)
+	}
+	var resp model.Response
+	if err := json.Unmarshal([]byte(last), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RequestID != "qwq" || len(resp.Results) != 1 || resp.Results[0].Status != model.Status(envexec.StatusAccepted) {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+}
+
+func TestHandleRunEventsDraining(t *testing.T) {
+	work := worker.New(worker.Config{EnvironmentPool: nopEnvPool{}})
+	work.Start()
+	defer work.Shutdown()
+	worker.DrainRequests(work, true)
+
+	router := gin.New()
+	NewCmdHandle(work, nil, 0, zaptest.NewLogger(t)).Register(router)
+
+	req := model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}
+	testReq := httptest.NewRequest("POST", "/run", requestToReader(req))
+	testReq.Header.Set("Accept", "text/event-stream")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testReq)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Fatal("Expected Retry-After header")
+	}
+}
+
+func TestHandleRunLiveOutput(t *testing.T) {
+	router := gin.New()
+	mockWorker := &mockWorker{
+		Result: worker.Result{Status: envexec.StatusAccepted},
+	}
+	NewCmdHandle(mockWorker, nil, 0, zaptest.NewLogger(t)).Register(router)
+
+	req := model.Request{Cmd: []model.Cmd{{
+		Args:  []string{"/bin/echo", "hello"},
+		Files: []*model.CmdFile{nil, {Name: ptr("stdout"), Max: ptr(int64(1024)), Live: true}},
+	}}}
+	testReq := httptest.NewRequest("POST", "/run", requestToReader(req))
+	testReq.Header.Set("Accept", "text/event-stream")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testReq)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var output *model.Event
+	var name string
+	for line := range strings.Lines(recorder.Body.String()) {
+		if n, ok := strings.CutPrefix(line, "event:"); ok {
+			name = strings.TrimSpace(n)
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok && name == "output" {
+			output = new(model.Event)
+			if err := json.Unmarshal([]byte(data), output); err != nil {
+				t.Fatalf("Failed to decode event: %v", err)
+			}
+		}
+	}
+	if output == nil || output.Name != "stdout" || string(output.Content) != "hello" || output.Index == nil || *output.Index != 0 {
+		t.Fatalf("Unexpected output event %+v", output)
+	}
+}
@@ -0,0 +1,104 @@
+package restexecutor
+
+import (
+	"net/http"
+
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
+	"github.com/gin-gonic/gin"
+)
+
+// Compression decompresses gzip / zstd request bodies by Content-Encoding,
+// with the decompressed size limited by limit (<= 0 for unlimited), and
+// compresses response bodies when accepted by Accept-Encoding. It is meant
+// for the REST routes only, streamed server-sent events are left as is
+func Compression(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ce := c.GetHeader("Content-Encoding"); ce != "" {
+			a, err := compress.Parse(ce)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, err.Error())
+				return
+			}
+			if a != compress.Identity {
+				r, err := compress.NewReader(c.Request.Body, a)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+					return
+				}
+				defer r.Close()
+				if limit > 0 {
+					r = http.MaxBytesReader(c.Writer, r, limit)
+				}
+				c.Request.Body = r
+				c.Request.ContentLength = -1
+				c.Request.Header.Del("Content-Encoding")
+				c.Request.Header.Del("Content-Length")
+			}
+		}
+
+		// event stream and range request are not compressed
+		a := compress.Negotiate(c.GetHeader("Accept-Encoding"))
+		if a == compress.Identity || c.GetHeader("Range") != "" || accepts(c.GetHeader("Accept"), mimeEventStream) {
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, algorithm: a}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// compressWriter compresses the response body on first write unless the
+// response is already encoded
+type compressWriter struct {
+	gin.ResponseWriter
+	algorithm compress.Algorithm
+	w         compress.Writer
+	bypass    bool
+}
+
+func (w *compressWriter) init() {
+	if w.w != nil || w.bypass {
+		return
+	}
+	h := w.Header()
+	if w.Written() || h.Get("Content-Encoding") != "" {
+		w.bypass = true
+		return
+	}
+	cw, err := compress.NewWriter(w.ResponseWriter, w.algorithm)
+	if err != nil {
+		w.bypass = true
+		return
+	}
+	h.Set("Content-Encoding", string(w.algorithm))
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+	w.w = cw
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	w.init()
+	if w.bypass {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.w.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	if w.w != nil {
+		w.w.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) close() {
+	if w.w != nil {
+		w.w.Close()
+	}
+}
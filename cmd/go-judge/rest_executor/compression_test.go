@@ -0,0 +1,78 @@
+package restexecutor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompression(t *testing.T) {
+	router := gin.New()
+	router.Use(Compression(1 << 10))
+	router.POST("/echo", func(c *gin.Context) {
+		b, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Data(http.StatusOK, "text/plain", b)
+	})
+
+	content := strings.Repeat("1 2\n", 100)
+	body, err := compress.Compress([]byte(content), compress.Gzip)
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/echo", bytes.NewReader(body))
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "zstd" {
+		t.Fatalf("Expected zstd response, got %q", ce)
+	}
+	b, err := compress.Decompress(w.Body.Bytes(), compress.Zstd, 0)
+	if err != nil || string(b) != content {
+		t.Fatalf("Expected response %q, got %q %v", content, b, err)
+	}
+
+	// event stream is not compressed
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(content))
+	req.Header.Set("Accept", mimeEventStream)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if ce := w.Header().Get("Content-Encoding"); ce != "" || w.Body.String() != content {
+		t.Fatalf("Expected identity response, got %q %q", ce, w.Body.String())
+	}
+
+	// decompression bomb
+	body, err = compress.Compress(make([]byte, 1<<20), compress.Zstd)
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	req = httptest.NewRequest("POST", "/echo", bytes.NewReader(body))
+	req.Header.Set("Content-Encoding", "zstd")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(content))
+	req.Header.Set("Content-Encoding", "br")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
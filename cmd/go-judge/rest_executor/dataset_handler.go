@@ -0,0 +1,72 @@
+package restexecutor
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/criyle/go-judge/filestore"
+	"github.com/gin-gonic/gin"
+)
+
+type datasetHandle struct {
+	d *filestore.Datasets
+}
+
+type datasetInfo struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Size  int64  `json:"size"`
+}
+
+type datasetEntry struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// NewDatasetHandle creates a new dataset handle
+func NewDatasetHandle(d *filestore.Datasets) Register {
+	return &datasetHandle{
+		d: d,
+	}
+}
+
+func (h *datasetHandle) Register(r gin.IRouter) {
+	// Dataset handle
+	r.GET("/dataset", h.datasetGet)
+	r.GET("/dataset/:name", h.datasetNameGet)
+}
+
+func (h *datasetHandle) datasetGet(c *gin.Context) {
+	list := h.d.List()
+	rt := make([]datasetInfo, 0, len(list))
+	for _, d := range list {
+		rt = append(rt, datasetInfo{Name: d.Name, Count: d.Count, Size: d.Size})
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+func (h *datasetHandle) datasetNameGet(c *gin.Context) {
+	type datasetURI struct {
+		Name string `uri:"name" binding:"required"`
+	}
+	var uri datasetURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	files, err := h.d.Files(uri.Name, c.Query("prefix"))
+	if errors.Is(err, filestore.ErrDatasetNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	rt := make([]datasetEntry, 0, len(files))
+	for _, f := range files {
+		rt = append(rt, datasetEntry{Path: f.Path, Size: f.Size})
+	}
+	c.JSON(http.StatusOK, rt)
+}
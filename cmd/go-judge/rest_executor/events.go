@@ -0,0 +1,64 @@
+package restexecutor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const mimeEventStream = "text/event-stream"
+
+// handleRunEvents runs the request and sends progress events as server-sent
+// events named by the event type, the last event is response with the same
+// content as the response of websocket
+func (c *cmdHandle) handleRunEvents(ctx *gin.Context, req *model.Request, r *worker.Request) {
+	evCh := r.BufferEvents()
+	rtCh, _ := c.worker.Submit(ctx.Request.Context(), r)
+
+	ctx.Status(http.StatusOK)
+	send := func(e worker.Event) {
+		me := model.ConvertEvent(e)
+		ctx.SSEvent(string(me.Type), me)
+		ctx.Writer.Flush()
+	}
+	var rt worker.Response
+loop:
+	for {
+		select {
+		case e := <-evCh:
+			send(e)
+		case rt = <-rtCh:
+			break loop
+		}
+	}
+	// events are sent before the result
+	for len(evCh) > 0 {
+		send(<-evCh)
+	}
+	if ce := c.logger.Check(zap.DebugLevel, "response"); ce != nil {
+		ce.Write(zap.String("body", fmt.Sprintf("%+v", rt)))
+	}
+	// rejected before any event so that the status is not yet written
+	if errors.Is(rt.Error, worker.ErrDraining) && !ctx.Writer.Written() {
+		ctx.Header("Retry-After", retryAfter)
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, rt.Error.Error())
+		return
+	}
+
+	res, err := model.ConvertResponse(rt, false)
+	if err != nil {
+		ctx.Error(err)
+		res = model.Response{RequestID: rt.RequestID, ErrorMsg: err.Error()}
+	} else if err := res.CompressFiles(req); err != nil {
+		ctx.Error(err)
+		res = model.Response{RequestID: rt.RequestID, ErrorMsg: err.Error()}
+	}
+	res.EncodeFiles(req)
+	ctx.SSEvent(string(model.EventResponse), res)
+	ctx.Writer.Flush()
+}
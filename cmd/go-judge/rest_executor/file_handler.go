@@ -1,6 +1,7 @@
 package restexecutor
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -24,16 +25,22 @@ func NewFileHandle(fs filestore.FileStore) Register {
 	}
 }
 
-func (f *fileHandle) Register(r *gin.Engine) {
+func (f *fileHandle) Register(r gin.IRouter) {
 	// File handle
 	r.GET("/file", f.fileGet)
 	r.POST("/file", f.filePost)
+	r.POST("/dir", f.dirPost)
 	r.GET("/file/:fid", f.fileIDGet)
 	r.DELETE("/file/:fid", f.fileIDDelete)
 }
 
+// store returns the file store scoped to the namespace of the request
+func (f *fileHandle) store(c *gin.Context) filestore.FileStore {
+	return filestore.ForNamespace(f.fs, filestore.NamespaceFromContext(c.Request.Context()))
+}
+
 func (f *fileHandle) fileGet(c *gin.Context) {
-	ids := f.fs.List()
+	ids := f.store(c).List()
 	c.JSON(http.StatusOK, ids)
 }
 
@@ -50,7 +57,9 @@ func (f *fileHandle) filePost(c *gin.Context) {
 		return
 	}
 	defer fi.Close()
-	sf, err := f.fs.New()
+
+	fs := f.store(c)
+	sf, err := fs.New()
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
@@ -66,8 +75,41 @@ func (f *fileHandle) filePost(c *gin.Context) {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
-	id, err := f.fs.Add(fh.Filename, sf.Name())
+	id, err := fs.Add(fh.Filename, sf.Name())
+	if errors.Is(err, filestore.ErrQuotaExceeded) {
+		c.AbortWithError(http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, id)
+}
+
+func (f *fileHandle) dirPost(c *gin.Context) {
+	fh, err := c.FormFile("file")
 	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	fi, err := fh.Open()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	defer fi.Close()
+
+	id, err := filestore.AddDir(f.store(c), fh.Filename, fi, fh.Size)
+	switch {
+	case errors.Is(err, filestore.ErrInvalidArchive):
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	case errors.Is(err, filestore.ErrQuotaExceeded):
+		c.AbortWithError(http.StatusRequestEntityTooLarge, err)
+		return
+	case err != nil:
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
@@ -84,7 +126,7 @@ func (f *fileHandle) fileIDGet(c *gin.Context) {
 		return
 	}
 
-	name, file := f.fs.Get(uri.FileID)
+	name, file := f.store(c).Get(uri.FileID)
 	if file == nil {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
@@ -124,7 +166,7 @@ func (f *fileHandle) fileIDDelete(c *gin.Context) {
 		return
 	}
 
-	ok := f.fs.Remove(uri.FileID)
+	ok := f.store(c).Remove(uri.FileID)
 	if !ok {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
@@ -0,0 +1,168 @@
+package restexecutor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/criyle/go-judge/cmd/go-judge/compress"
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+const mimeMultipartMixed = "multipart/mixed"
+
+// accepts returns whether the media type is accepted explicitly
+func accepts(accept, mediaType string) bool {
+	for v := range strings.SplitSeq(accept, ",") {
+		t, _, err := mime.ParseMediaType(strings.TrimSpace(v))
+		if err == nil && t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+type filePart struct {
+	ref  string
+	name string
+	f    *os.File
+	a    compress.Algorithm
+	b64  bool
+}
+
+// writeMultipart writes the response as multipart/mixed. The first part is the
+// results encoded by format with files referenced by fileRefs, followed by one
+// part for each file streamed from the collected file without buffering in
+// memory. Collector with compression is compressed on the fly with
+// Content-Encoding of the part, and file with base64 encoding is sent with
+// base64 Content-Transfer-Encoding.
+func writeMultipart(ctx *gin.Context, req *model.Request, rt worker.Response, format model.Format) error {
+	// detach files so that they are not read into memory
+	var parts []filePart
+	refs := make([]map[string]string, len(rt.Results))
+	results := make([]worker.Result, 0, len(rt.Results))
+	for i, r := range rt.Results {
+		var (
+			c  map[string]compress.Algorithm
+			fe map[string]string
+		)
+		if i < len(req.Cmd) {
+			c = req.Cmd[i].Compressions()
+			fe = req.Cmd[i].FileEncodings
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.Files)) {
+			p := filePart{
+				ref:  strconv.Itoa(len(parts) + 1),
+				name: name,
+				f:    r.Files[name],
+				a:    c[name],
+				b64:  fileEncoding(req.Encoding, fe, name) == model.EncodingBase64,
+			}
+			if refs[i] == nil {
+				refs[i] = make(map[string]string)
+			}
+			refs[i][name] = p.ref
+			parts = append(parts, p)
+		}
+		r.Files = nil
+		results = append(results, r)
+	}
+	defer func() {
+		for _, p := range parts {
+			p.f.Close()
+			os.Remove(p.f.Name())
+		}
+	}()
+	rt.Results = results
+
+	res, err := model.ConvertResponse(rt, false)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		return err
+	}
+	for i := range res.Results {
+		res.Results[i].FileRefs = refs[i]
+	}
+
+	mw := multipart.NewWriter(ctx.Writer)
+	ctx.Header("Content-Type", mimeMultipartMixed+"; boundary="+mw.Boundary())
+	ctx.Status(http.StatusOK)
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", format.ContentType())
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if err := format.EncodeResults(w, res.Results); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if err := writeFilePart(mw, p); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func writeFilePart(mw *multipart.Writer, p filePart) error {
+	if _, err := p.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", "application/octet-stream")
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": p.name}))
+	h.Set("Content-ID", "<"+p.ref+">")
+	if p.b64 {
+		h.Set("Content-Transfer-Encoding", "base64")
+	}
+	if p.a != compress.Identity {
+		h.Set("Content-Encoding", string(p.a))
+	} else if fi, err := p.f.Stat(); err == nil && !p.b64 {
+		h.Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	}
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = pw
+	var bw io.WriteCloser
+	if p.b64 {
+		bw = base64.NewEncoder(base64.StdEncoding, pw)
+		w = bw
+	}
+	cw, err := compress.NewWriter(w, p.a)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, p.f); err != nil {
+		return fmt.Errorf("write part %s: %w", p.name, err)
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	if bw != nil {
+		return bw.Close()
+	}
+	return nil
+}
+
+// fileEncoding returns the encoding of the named file, the file encoding
+// overrides the encoding of the request
+func fileEncoding(def string, fileEncodings map[string]string, name string) model.Encoding {
+	if fe, ok := fileEncodings[name]; ok {
+		return model.Encoding(fe)
+	}
+	return model.Encoding(def)
+}
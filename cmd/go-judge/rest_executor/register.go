@@ -4,5 +4,5 @@ import "github.com/gin-gonic/gin"
 
 // Register registers executor the handler
 type Register interface {
-	Register(*gin.Engine)
+	Register(gin.IRouter)
 }
@@ -0,0 +1,34 @@
+package restexecutor
+
+import (
+	"net/http"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+type statHandle struct {
+	worker worker.Worker
+	fs     filestore.FileStore
+}
+
+// NewStatHandle creates a new stat handle, namespaced requests only see their
+// own requests and files
+func NewStatHandle(worker worker.Worker, fs filestore.FileStore) Register {
+	return &statHandle{
+		worker: worker,
+		fs:     fs,
+	}
+}
+
+func (h *statHandle) Register(r gin.IRouter) {
+	// Stat handle
+	r.GET("/stat", h.statGet)
+}
+
+func (h *statHandle) statGet(c *gin.Context) {
+	ns := filestore.NamespaceFromContext(c.Request.Context())
+	c.JSON(http.StatusOK, model.CollectStat(h.worker, h.fs, ns))
+}
@@ -0,0 +1,75 @@
+package restexecutor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+type statWorker struct {
+	worker.Worker
+}
+
+func (statWorker) Stat() worker.Stat {
+	return worker.Stat{Queue: 1, Running: 2, Parallelism: 4, Pool: worker.PoolStat{Idle: 1, Total: 3}}
+}
+
+func (statWorker) Requests() []worker.RequestStat {
+	return []worker.RequestStat{
+		{RequestID: "a", Phase: worker.EventProcessStarted, Elapsed: time.Second},
+		{RequestID: "b", Namespace: "ns", Phase: worker.EventQueued},
+	}
+}
+
+func TestStatGet(t *testing.T) {
+	fs := filestore.NewNamespaced(filestore.NewFileLocalStore(t.TempDir()), 0)
+	f, err := fs.New()
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	f.WriteString("content")
+	f.Close()
+	if _, err := fs.Add("a.txt", f.Name()); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+
+	get := func(ns string) model.Stat {
+		t.Helper()
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(filestore.WithNamespace(c.Request.Context(), ns))
+		})
+		NewStatHandle(statWorker{}, fs).Register(router)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/stat", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var s model.Stat
+		if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+			t.Fatalf("Failed to decode stat: %v", err)
+		}
+		return s
+	}
+
+	s := get("")
+	if s.Queue != 1 || s.Running != 2 || s.Parallelism != 4 || s.EnvIdle != 1 || s.EnvTotal != 3 ||
+		s.FileCount != 1 || s.FileSize != 7 || len(s.Requests) != 2 {
+		t.Fatalf("Unexpected stat %+v", s)
+	}
+	if r := s.Requests[0]; r.RequestID != "a" || r.Phase != model.EventProcessStarted || r.Elapsed != uint64(time.Second) {
+		t.Fatalf("Unexpected request %+v", r)
+	}
+
+	s = get("ns")
+	if s.FileCount != 0 || len(s.Requests) != 1 || s.Requests[0].RequestID != "b" || s.Requests[0].Phase != model.EventQueued {
+		t.Fatalf("Unexpected namespaced stat %+v", s)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const snapshotUsage = `usage: go-judge snapshot <export|import> [flags]
+
+Export or import the file store of a running go-judge instance with -enable-admin.
+`
+
+// runSnapshot runs the snapshot subcommand and returns the exit code
+func runSnapshot(args []string) int {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprint(os.Stderr, snapshotUsage)
+		return 2
+	}
+	cmd := args[0]
+
+	fset := flag.NewFlagSet("snapshot "+cmd, flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(fset.Output(), snapshotUsage)
+		fset.PrintDefaults()
+	}
+	addr := fset.String("addr", "http://localhost:5050", "address of the go-judge REST endpoint")
+	token := fset.String("token", "", "bearer token auth")
+	file := fset.String("file", "-", "snapshot file, - for stdout / stdin")
+	conflict := fset.String("conflict", "fail", "import conflict handling (skip, overwrite, fail)")
+	if err := fset.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var err error
+	switch cmd {
+	case "export":
+		err = snapshotExport(*addr, *token, *file)
+	case "import":
+		err = snapshotImport(*addr, *token, *file, *conflict)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot %s: %v\n", cmd, err)
+		return 1
+	}
+	return 0
+}
+
+func snapshotExport(addr, token, file string) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/admin/snapshot", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doSnapshotRequest(req, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	w := io.Writer(os.Stdout)
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok && file != "-" {
+		return c.Close()
+	}
+	return nil
+}
+
+func snapshotImport(addr, token, file, conflict string) error {
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	u := strings.TrimSuffix(addr, "/") + "/admin/snapshot?conflict=" + url.QueryEscape(conflict)
+	req, err := http.NewRequest(http.MethodPost, u, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err := doSnapshotRequest(req, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(os.Stdout, resp.Body)
+	fmt.Println()
+	return err
+}
+
+func doSnapshotRequest(req *http.Request, token string) (*http.Response, error) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
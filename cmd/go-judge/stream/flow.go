@@ -0,0 +1,289 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// inputWindow is the max bytes of input of a stream in flight without
+// acknowledgement when flow control is enabled
+const inputWindow = 256 << 10
+
+// maxPendingCredits is the max number of executions granted credit before
+// their exec requests on a stream
+const maxPendingCredits = 64
+
+var (
+	errInputWindowExceeded   = errors.New("input exceeds the flow control window")
+	errInvalidCredit         = errors.New("credit must be positive")
+	errTooManyPendingCredits = errors.New("too many executions granted credit before exec request")
+)
+
+// FlowStat stores the statistic of the stream flow control
+type FlowStat struct {
+	OutputStalled   int           // output streams waiting for credit
+	OutputStalls    uint64        // times output waited for credit
+	OutputStallTime time.Duration // total time output waited for credit
+	InputStalled    int           // input streams waiting for the program to read
+	InputStalls     uint64        // times input waited for the program to read
+	InputStallTime  time.Duration // total time input waited for the program to read
+	InputOverruns   uint64        // input exceeded the window
+}
+
+// Flow records the flow control statistic of the streams started with it, nil
+// Flow records nothing
+type Flow struct {
+	mu   sync.Mutex
+	stat FlowStat
+}
+
+// Stat returns the flow control statistic of the streams
+func (f *Flow) Stat() FlowStat {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stat
+}
+
+// stall records a stall of output or input until the returned func is called
+func (f *Flow) stall(output bool) func() {
+	if f == nil {
+		return func() {}
+	}
+	start := time.Now()
+	f.mu.Lock()
+	if output {
+		f.stat.OutputStalled++
+		f.stat.OutputStalls++
+	} else {
+		f.stat.InputStalled++
+		f.stat.InputStalls++
+	}
+	f.mu.Unlock()
+	return func() {
+		d := time.Since(start)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if output {
+			f.stat.OutputStalled--
+			f.stat.OutputStallTime += d
+		} else {
+			f.stat.InputStalled--
+			f.stat.InputStallTime += d
+		}
+	}
+}
+
+func (f *Flow) overrun() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	f.stat.InputOverruns++
+	f.mu.Unlock()
+}
+
+// pendingCredits keeps the credit granted before the exec request by ExecID.
+// Zero credit marks the id of a finished execution, credit granted to it is
+// sent before the remote received the response thus dropped rather than
+// inherited by the reuse of the id.
+type pendingCredits map[uint32]int64
+
+func (p pendingCredits) grant(id uint32, n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("%w: %d", errInvalidCredit, n)
+	}
+	c, ok := p[id]
+	switch {
+	case ok && c == 0:
+		return nil
+	case !ok && !p.reserve():
+		return fmt.Errorf("%w: %d", errTooManyPendingCredits, id)
+	}
+	p[id] = addCredit(c, n)
+	return nil
+}
+
+// finish marks the id of the finished execution if there is room
+func (p pendingCredits) finish(id uint32) {
+	if p.reserve() {
+		p[id] = 0
+	}
+}
+
+// reserve makes room for a new id by evicting a finished id if full
+func (p pendingCredits) reserve() bool {
+	if len(p) < maxPendingCredits {
+		return true
+	}
+	for id, c := range p {
+		if c == 0 {
+			delete(p, id)
+			return true
+		}
+	}
+	return false
+}
+
+// take removes the credit of the execution, it returns nil if not granted
+func (p pendingCredits) take(id uint32, flow *Flow) *credit {
+	n := p[id]
+	delete(p, id)
+	if n == 0 {
+		return nil
+	}
+	return newCredit(n, flow)
+}
+
+// addCredit adds the credit without overflow
+func addCredit(a, n int64) int64 {
+	if a > math.MaxInt64-n {
+		return math.MaxInt64
+	}
+	return a + n
+}
+
+// credit is the output window in bytes granted by the remote shared by the
+// output streams of an execution
+type credit struct {
+	flow   *Flow
+	mu     sync.Mutex
+	avail  int64
+	notify chan struct{}
+}
+
+func newCredit(n int64, flow *Flow) *credit {
+	return &credit{flow: flow, avail: n, notify: make(chan struct{}, 1)}
+}
+
+func (c *credit) grant(n int64) {
+	c.mu.Lock()
+	c.avail = addCredit(c.avail, n)
+	c.mu.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// acquire waits until credit is available and takes up to n bytes of it
+func (c *credit) acquire(ctx context.Context, n int) (int, error) {
+	var done func()
+	defer func() {
+		if done != nil {
+			done()
+		}
+	}()
+	for {
+		c.mu.Lock()
+		if c.avail > 0 {
+			k := min(c.avail, int64(n))
+			c.avail -= k
+			c.mu.Unlock()
+			return int(k), nil
+		}
+		c.mu.Unlock()
+
+		if done == nil {
+			done = c.flow.stall(true)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-c.notify:
+		}
+	}
+}
+
+// inputQueue writes input to the program asynchronously and acknowledges the
+// bytes consumed so that the remote keeps at most inputWindow bytes in flight
+type inputQueue struct {
+	f      *fileStreamIn
+	flow   *Flow
+	notify chan struct{}
+
+	mu      sync.Mutex
+	buf     []byte // queued input
+	pending int    // queued and being written
+}
+
+func newInputQueue(f *fileStreamIn, flow *Flow) *inputQueue {
+	return &inputQueue{f: f, flow: flow, notify: make(chan struct{}, 1)}
+}
+
+func (q *inputQueue) push(b []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending+len(b) > inputWindow {
+		q.flow.overrun()
+		return fmt.Errorf("%w: %d/%d", errInputWindowExceeded, q.f.index, q.f.fd)
+	}
+	q.buf = append(q.buf, b...)
+	q.pending += len(b)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// writeLoop writes queued input and sends the acknowledgements. Input after
+// the program closed the input is discarded and acknowledged.
+func (q *inputQueue) writeLoop(ctx context.Context, s Stream) error {
+	var (
+		closed bool
+		done   func()
+	)
+	defer func() {
+		if done != nil {
+			done()
+		}
+	}()
+	for {
+		q.mu.Lock()
+		b := q.buf
+		q.buf = nil
+		q.mu.Unlock()
+		if len(b) == 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-q.notify:
+			}
+			continue
+		}
+
+		if !closed {
+			_, err := q.f.Write(b)
+			if err == io.EOF {
+				closed = true
+			} else if err != nil {
+				return fmt.Errorf("write to input %d/%d: %w", q.f.index, q.f.fd, err)
+			}
+		}
+
+		// input queued while writing means the program is slower than the remote
+		q.mu.Lock()
+		q.pending -= len(b)
+		more := len(q.buf) > 0
+		q.mu.Unlock()
+		switch {
+		case more && done == nil:
+			done = q.flow.stall(false)
+		case !more && done != nil:
+			done()
+			done = nil
+		}
+		if err := s.Send(Response{InputAck: &InputAckResponse{
+			Index: q.f.index,
+			Fd:    q.f.fd,
+			Bytes: len(b),
+		}}); err != nil {
+			return err
+		}
+	}
+}
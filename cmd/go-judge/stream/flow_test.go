@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"go.uber.org/zap"
+)
+
+func TestCreditAcquire(t *testing.T) {
+	flow := new(Flow)
+	c := newCredit(4, flow)
+	if n, err := c.acquire(context.Background(), 10); err != nil || n != 4 {
+		t.Fatalf("expected 4 bytes of credit, got %d %v", n, err)
+	}
+
+	got := make(chan int, 1)
+	go func() {
+		n, _ := c.acquire(context.Background(), 10)
+		got <- n
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.grant(16)
+	if n := <-got; n != 10 {
+		t.Fatalf("expected 10 bytes of credit after grant, got %d", n)
+	}
+	if s := flow.Stat(); s.OutputStalls != 1 || s.OutputStalled != 0 || s.OutputStallTime == 0 {
+		t.Fatalf("unexpected stat %+v", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c = newCredit(0, nil)
+	if _, err := c.acquire(ctx, 10); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled, got %v", err)
+	}
+}
+
+func TestInputQueue(t *testing.T) {
+	f := newFileStreamIn(0, 0, false)
+	f.Close() // input closed by the program is discarded
+	q := newInputQueue(f, nil)
+	if err := q.push(make([]byte, inputWindow+1)); !errors.Is(err, errInputWindowExceeded) {
+		t.Fatalf("expected window exceeded, got %v", err)
+	}
+	if err := q.push([]byte("abc")); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	s := &recordingStream{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- q.writeLoop(ctx, s) }()
+	for len(s.responses()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("writeLoop returned error: %v", err)
+	}
+	if a := s.responses()[0].InputAck; a == nil || a.Bytes != 3 {
+		t.Fatalf("expected ack of 3 bytes, got %#v", s.responses())
+	}
+}
+
+func TestStartCreditBeforeExec(t *testing.T) {
+	s := &scriptedStream{reqs: make(chan *Request, 3)}
+	s.reqs <- &Request{Credit: &CreditRequest{Bytes: 1024}}
+	s.reqs <- &Request{Request: &model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}}
+	close(s.reqs)
+	if err := Start(context.Background(), s, executeWorker{}, nil, 0, nil, zap.NewNop()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if sent := s.responses(); len(sent) != 1 || sent[0].Response == nil {
+		t.Fatalf("unexpected sends %#v", sent)
+	}
+}
+
+func TestPendingCredits(t *testing.T) {
+	p := make(pendingCredits)
+	if err := p.grant(1, 0); !errors.Is(err, errInvalidCredit) {
+		t.Fatalf("expected invalid credit, got %v", err)
+	}
+	if err := p.grant(1, -1); !errors.Is(err, errInvalidCredit) {
+		t.Fatalf("expected invalid credit, got %v", err)
+	}
+	for id := range uint32(maxPendingCredits) {
+		if err := p.grant(id, 1); err != nil {
+			t.Fatalf("grant %d: %v", id, err)
+		}
+	}
+	if err := p.grant(maxPendingCredits, 1); !errors.Is(err, errTooManyPendingCredits) {
+		t.Fatalf("expected too many pending credits, got %v", err)
+	}
+	if err := p.grant(0, math.MaxInt64); err != nil || p[0] != math.MaxInt64 {
+		t.Fatalf("expected saturated credit, got %d %v", p[0], err)
+	}
+
+	// credit granted to the finished execution is not inherited
+	if c := p.take(1, nil); c == nil || c.avail != 1 {
+		t.Fatalf("expected credit of 1, got %+v", c)
+	}
+	p.finish(1)
+	if err := p.grant(1, 10); err != nil {
+		t.Fatalf("grant finished: %v", err)
+	}
+	if c := p.take(1, nil); c != nil {
+		t.Fatalf("expected no credit for the reused id, got %+v", c)
+	}
+
+	// finished id makes room for the new id
+	p.finish(1)
+	if err := p.grant(maxPendingCredits, 1); err != nil {
+		t.Fatalf("expected finished id evicted, got %v", err)
+	}
+}
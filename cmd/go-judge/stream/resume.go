@@ -0,0 +1,326 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/filestore"
+)
+
+// messageOverhead is the size accounted for each buffered message besides its
+// output content
+const messageOverhead = 64
+
+var (
+	errSessionNotExist = errors.New("stream session does not exist or expired")
+
+	// ErrTooManySessions is returned by Start when the max number of sessions
+	// are kept
+	ErrTooManySessions = errors.New("too many stream sessions")
+)
+
+// SessionResponse defines the session token sent on attach, Offset is the
+// offset of the first message replayed
+type SessionResponse struct {
+	Token  string `json:"token"`
+	Offset uint64 `json:"offset"`
+}
+
+// Sessions keeps resumable stream sessions by token. The execution of a session
+// keeps running for the grace period after the transport is lost, and the
+// messages sent are kept in a bounded buffer to be replayed on resume.
+type Sessions struct {
+	grace       time.Duration
+	bufferSize  int
+	maxSessions int
+
+	mu       sync.Mutex
+	sessions map[string]*resumable
+}
+
+// NewSessions creates the sessions with grace period and buffer size in bytes
+// for each session, at most maxSessions sessions are kept (<= 0 for unlimited)
+func NewSessions(grace time.Duration, bufferSize, maxSessions int) *Sessions {
+	return &Sessions{
+		grace:       grace,
+		bufferSize:  bufferSize,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]*resumable),
+	}
+}
+
+// Start starts a resumable session on the transport with run. It blocks until
+// the transport is detached from the session, the session keeps running after
+// it returns if it is not finished.
+func (s *Sessions) Start(ctx context.Context, st Stream, run func(context.Context, Stream) error) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	r := &resumable{
+		sessions:  s,
+		token:     token,
+		namespace: filestore.NamespaceFromContext(ctx),
+		buf:       ring{size: s.bufferSize},
+	}
+	r.ctx, r.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	s.mu.Lock()
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		s.mu.Unlock()
+		return ErrTooManySessions
+	}
+	s.sessions[token] = r
+	s.mu.Unlock()
+
+	a, err := r.attach(st, 0)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := run(r.ctx, r)
+		r.finish(err)
+	}()
+	return r.wait(ctx, a)
+}
+
+// Resume attaches the transport to the session of the token and replays the
+// messages from offset. It blocks until the transport is detached.
+func (s *Sessions) Resume(ctx context.Context, st Stream, token string, offset uint64) error {
+	s.mu.Lock()
+	r := s.sessions[token]
+	s.mu.Unlock()
+	if r == nil || r.namespace != filestore.NamespaceFromContext(ctx) {
+		return errSessionNotExist
+	}
+	a, err := r.attach(st, offset)
+	if err != nil {
+		return err
+	}
+	return r.wait(ctx, a)
+}
+
+func (s *Sessions) remove(r *resumable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[r.token] == r {
+		delete(s.sessions, r.token)
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// resumable is the Stream of a session that outlives its transport. Messages
+// are numbered from 0 and kept in the buffer, they are dropped rather than
+// blocking the execution when detached.
+type resumable struct {
+	sessions  *Sessions
+	token     string
+	namespace string
+	ctx       context.Context // cancelled when expired
+	cancel    context.CancelFunc
+
+	sendMu sync.Mutex // orders sends and replays
+
+	mu       sync.Mutex
+	buf      ring
+	att      *attachment
+	attachCh chan struct{} // closed on attach when detached
+	timer    *time.Timer   // expires the session when detached or finished
+	finished bool
+	expired  bool
+	err      error
+}
+
+type attachment struct {
+	st   Stream
+	done chan struct{}
+}
+
+func (r *resumable) attach(st Stream, offset uint64) (*attachment, error) {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	r.mu.Lock()
+	if r.expired {
+		r.mu.Unlock()
+		return nil, errSessionNotExist
+	}
+	// finished session keeps its expiry so that resumes could not extend it
+	if r.timer != nil && !r.finished {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	old := r.att
+	a := &attachment{st: st, done: make(chan struct{})}
+	r.att = a
+	if r.attachCh != nil {
+		close(r.attachCh)
+		r.attachCh = nil
+	}
+	from, msgs := r.buf.since(offset)
+	finished := r.finished
+	r.mu.Unlock()
+
+	if old != nil {
+		close(old.done)
+	}
+	err := st.Send(Response{Session: &SessionResponse{Token: r.token, Offset: from}})
+	for _, m := range msgs {
+		if err != nil {
+			break
+		}
+		err = st.Send(m)
+	}
+	if err != nil || finished {
+		r.detach(a)
+	}
+	return a, nil
+}
+
+// detach removes the attachment if it is the current one and expires the
+// session after the grace period unless the expiry is already set
+func (r *resumable) detach(a *attachment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.att != a {
+		return
+	}
+	r.att = nil
+	r.attachCh = make(chan struct{})
+	if r.timer == nil && !r.expired {
+		r.timer = time.AfterFunc(r.sessions.grace, r.expire)
+	}
+	close(a.done)
+}
+
+// expire removes the session, running session is not expired while attached
+func (r *resumable) expire() {
+	r.mu.Lock()
+	if (r.att != nil && !r.finished) || r.timer == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.timer = nil
+	r.expired = true
+	r.mu.Unlock()
+
+	r.cancel()
+	r.sessions.remove(r)
+}
+
+// wait blocks until the attachment is detached or ctx is done
+func (r *resumable) wait(ctx context.Context, a *attachment) error {
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		r.detach(a)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finished {
+		return r.err
+	}
+	return nil
+}
+
+// finish detaches the transport after all messages are sent, the session is
+// kept for the grace period for replay
+func (r *resumable) finish(err error) {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	r.mu.Lock()
+	r.finished = true
+	r.err = err
+	a := r.att
+	r.mu.Unlock()
+	if a != nil {
+		r.detach(a)
+	}
+}
+
+func (r *resumable) Send(resp Response) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	r.mu.Lock()
+	r.buf.push(resp)
+	a := r.att
+	r.mu.Unlock()
+	if a == nil {
+		return nil
+	}
+	if err := a.st.Send(resp); err != nil {
+		r.detach(a)
+	}
+	return nil
+}
+
+// Recv receives from the attached transport, when the transport is lost it
+// waits for the resume until the session expires
+func (r *resumable) Recv() (*Request, error) {
+	for {
+		r.mu.Lock()
+		a, ch := r.att, r.attachCh
+		r.mu.Unlock()
+		if a == nil {
+			select {
+			case <-ch:
+				continue
+			case <-r.ctx.Done():
+				return nil, r.ctx.Err()
+			}
+		}
+		req, err := a.st.Recv()
+		if err == nil {
+			return req, nil
+		}
+		r.detach(a)
+	}
+}
+
+// ring keeps the latest messages up to size bytes
+type ring struct {
+	size  int
+	used  int
+	msgs  []Response
+	first uint64 // offset of msgs[0]
+	next  uint64 // offset of the next message
+}
+
+func (b *ring) push(r Response) {
+	b.msgs = append(b.msgs, r)
+	b.used += messageSize(r)
+	b.next++
+	for len(b.msgs) > 1 && b.used > b.size {
+		b.used -= messageSize(b.msgs[0])
+		b.msgs[0] = Response{}
+		b.msgs = b.msgs[1:]
+		b.first++
+	}
+}
+
+// since returns messages from offset, or from the first kept message if the
+// messages before are dropped
+func (b *ring) since(offset uint64) (uint64, []Response) {
+	offset = min(max(offset, b.first), b.next)
+	return offset, append([]Response(nil), b.msgs[offset-b.first:]...)
+}
+
+func messageSize(r Response) int {
+	if r.Output != nil {
+		return messageOverhead + len(r.Output.Content)
+	}
+	return messageOverhead
+}
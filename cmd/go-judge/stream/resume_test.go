@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+)
+
+func (r *recordingStream) responses() []Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Response(nil), r.sends...)
+}
+
+func output(s string) Response {
+	return Response{Output: &OutputResponse{Content: []byte(s)}}
+}
+
+func TestSessionsResume(t *testing.T) {
+	sessions := NewSessions(time.Second, 1<<20, 0)
+	step := make(chan struct{})
+	run := func(ctx context.Context, s Stream) error {
+		s.Send(output("a"))
+		<-step
+		s.Send(output("b"))
+		s.Send(output("c"))
+		req, err := s.Recv()
+		if err != nil {
+			return err
+		}
+		return s.Send(Response{Response: &model.Response{RequestID: req.Request.RequestID}})
+	}
+
+	t1 := &scriptedStream{reqs: make(chan *Request)}
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- sessions.Start(ctx1, t1, run) }()
+	for len(t1.responses()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel1() // transport lost
+	if err := <-started; err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	close(step)
+
+	sent := t1.responses()
+	if sent[0].Session == nil || sent[0].Session.Offset != 0 || string(sent[1].Output.Content) != "a" {
+		t.Fatalf("unexpected sends before disconnect %#v", sent)
+	}
+	t2 := &scriptedStream{reqs: make(chan *Request, 1)}
+	t2.reqs <- &Request{Request: &model.Request{RequestID: "resumed"}}
+	if err := sessions.Resume(context.Background(), t2, sent[0].Session.Token, 1); err != nil {
+		t.Fatalf("Resume returned error: %v", err)
+	}
+	sent = t2.responses()
+	if len(sent) != 4 || sent[0].Session == nil || sent[0].Session.Offset != 1 ||
+		string(sent[1].Output.Content) != "b" || string(sent[2].Output.Content) != "c" ||
+		sent[3].Response == nil || sent[3].Response.RequestID != "resumed" {
+		t.Fatalf("unexpected sends after resume %#v", sent)
+	}
+}
+
+func TestSessionsExpire(t *testing.T) {
+	sessions := NewSessions(10*time.Millisecond, 1<<20, 0)
+	done := make(chan error, 1)
+	run := func(ctx context.Context, s Stream) error {
+		<-ctx.Done()
+		done <- ctx.Err()
+		return ctx.Err()
+	}
+	t1 := &scriptedStream{reqs: make(chan *Request)}
+	close(t1.reqs) // transport lost on receive
+	go sessions.Start(context.Background(), t1, func(ctx context.Context, s Stream) error {
+		s.Recv()
+		return run(ctx, s)
+	})
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected session cancelled, got %v", err)
+	}
+	token := t1.responses()[0].Session.Token
+	if err := sessions.Resume(context.Background(), &scriptedStream{}, token, 0); !errors.Is(err, errSessionNotExist) {
+		t.Fatalf("expected expired session, got %v", err)
+	}
+}
+
+func TestSessionsFinishedExpire(t *testing.T) {
+	sessions := NewSessions(50*time.Millisecond, 1<<20, 0)
+	t1 := &scriptedStream{reqs: make(chan *Request)}
+	if err := sessions.Start(context.Background(), t1, func(ctx context.Context, s Stream) error {
+		return s.Send(Response{Response: &model.Response{}})
+	}); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	token := t1.responses()[0].Session.Token
+
+	// resumes do not extend the expiry of the finished session
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := sessions.Resume(context.Background(), &scriptedStream{}, token, 0)
+		if errors.Is(err, errSessionNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected finished session expired, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionsMax(t *testing.T) {
+	sessions := NewSessions(time.Second, 1<<20, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	t1 := &scriptedStream{reqs: make(chan *Request)}
+	go sessions.Start(ctx, t1, func(ctx context.Context, s Stream) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	for len(t1.responses()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	err := sessions.Start(ctx, &scriptedStream{}, func(context.Context, Stream) error { return nil })
+	if !errors.Is(err, ErrTooManySessions) {
+		t.Fatalf("expected too many sessions, got %v", err)
+	}
+}
+
+func TestRingDropsOldest(t *testing.T) {
+	b := ring{size: 2 * (messageOverhead + 1)}
+	for _, s := range []string{"a", "b", "c"} {
+		b.push(output(s))
+	}
+	from, msgs := b.since(0)
+	if from != 1 || len(msgs) != 2 || string(msgs[0].Output.Content) != "b" {
+		t.Fatalf("unexpected replay from %d: %#v", from, msgs)
+	}
+	if from, msgs = b.since(5); from != 3 || len(msgs) != 0 {
+		t.Fatalf("unexpected replay from %d: %#v", from, msgs)
+	}
+}
@@ -0,0 +1,290 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/worker"
+	"go.uber.org/zap"
+)
+
+// muxQueueLen is the number of responses buffered for each execution before
+// the execution is blocked
+const muxQueueLen = 8
+
+var (
+	errExecIDRequired = errors.New("exec id is required for multiplexed stream")
+	errExecExists     = errors.New("execution already exists")
+	errExecNotExist   = errors.New("execution does not exist")
+)
+
+// session runs executions multiplexed on the stream by ExecID
+type session struct {
+	ctx             context.Context
+	w               worker.Worker
+	srcPrefix       []string
+	decompressLimit int64
+	flow            *Flow
+	logger          *zap.Logger
+	out             *mux
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	execs   map[uint32]*execution
+	credits pendingCredits // granted before the exec request
+}
+
+// startSession starts executions by their exec requests and dispatches the
+// other requests by ExecID until the remote closes the stream. Invalid requests
+// on an execution cancel it with the error in its response.
+func startSession(baseCtx context.Context, s Stream, first *Request, credits pendingCredits, w worker.Worker, srcPrefix []string, decompressLimit int64, flow *Flow, logger *zap.Logger) error {
+	ctx, cancel := context.WithCancel(baseCtx)
+	defer cancel()
+
+	ss := &session{
+		ctx:             ctx,
+		w:               w,
+		srcPrefix:       srcPrefix,
+		decompressLimit: decompressLimit,
+		flow:            flow,
+		logger:          logger,
+		out:             newMux(s),
+		execs:           make(map[uint32]*execution),
+		credits:         credits,
+	}
+	done := make(chan struct{})
+	writeErr := make(chan error, 1)
+	go func() {
+		err := ss.out.writeLoop(ctx, done)
+		if err != nil {
+			cancel()
+		}
+		writeErr <- err
+	}()
+
+	err := ss.handle(first)
+	for err == nil {
+		var req *Request
+		req, err = s.Recv()
+		if err == io.EOF {
+			// remote finished sending, wait for the running executions
+			err = nil
+			break
+		}
+		if err == nil {
+			err = ss.handle(req)
+		}
+	}
+	if err != nil {
+		cancel()
+	}
+	ss.wg.Wait()
+	close(done)
+	return errors.Join(err, <-writeErr)
+}
+
+func (ss *session) handle(req *Request) error {
+	if req.ExecID == 0 {
+		return errExecIDRequired
+	}
+	if req.Request != nil {
+		ss.start(req)
+		return nil
+	}
+	ss.mu.Lock()
+	e := ss.execs[req.ExecID]
+	if e == nil && req.Credit != nil {
+		err := ss.credits.grant(req.ExecID, req.Credit.Bytes)
+		ss.mu.Unlock()
+		if err != nil {
+			ss.reject(req.ExecID, err)
+		}
+		return nil
+	}
+	ss.mu.Unlock()
+	if e == nil {
+		ss.reject(req.ExecID, fmt.Errorf("%w: %d", errExecNotExist, req.ExecID))
+		return nil
+	}
+	if _, err := e.handle(req); err != nil {
+		e.fail(err)
+	}
+	return nil
+}
+
+func (ss *session) start(req *Request) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	id := req.ExecID
+	if _, ok := ss.execs[id]; ok {
+		ss.reject(id, fmt.Errorf("%w: %d", errExecExists, id))
+		return
+	}
+	c := ss.credits.take(id, ss.flow)
+	e, err := newExecution(ss.ctx, id, req.Request, ss.srcPrefix, ss.decompressLimit, c)
+	if err != nil {
+		// the id is not in use so the error is the response of the execution
+		ss.out.send(ss.ctx, ss.out.open(), Response{
+			ExecID:   id,
+			Response: &model.Response{ErrorMsg: fmt.Sprintf("convert exec request: %v", err)},
+		})
+		return
+	}
+	// input of an execution must not block the receive loop shared by others
+	e.queueInput(ss.flow)
+	ss.execs[id] = e
+	q := ss.out.open()
+	ss.wg.Go(func() {
+		defer e.close()
+		es := &execStream{ss: ss, e: e, q: q}
+		switch err := e.run(ss.ctx, es, ss.w, ss.logger); {
+		case err == nil || ss.ctx.Err() != nil:
+		case errors.Is(err, worker.ErrDraining):
+			// rejected rather than failed so that it could be retried
+			es.Send(Response{Reject: &RejectResponse{Error: err.Error()}})
+		default:
+			es.Send(Response{Response: &model.Response{ErrorMsg: err.Error()}})
+		}
+	})
+}
+
+// reject reports the request is rejected without affecting the execution of
+// the same id
+func (ss *session) reject(id uint32, err error) {
+	ss.logger.Debug("stream request rejected", zap.Uint32("execId", id), zap.Error(err))
+	ss.out.send(ss.ctx, ss.out.open(), Response{
+		ExecID: id,
+		Reject: &RejectResponse{Error: err.Error()},
+	})
+}
+
+// finish removes the execution before its response is sent so that the id
+// could be reused once the response is received, credit granted to the id
+// afterwards is dropped so that it is not inherited by the reuse
+func (ss *session) finish(e *execution) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.execs[e.id] == e {
+		delete(ss.execs, e.id)
+		ss.credits.finish(e.id)
+	}
+}
+
+// execStream sends responses of the execution through the mux
+type execStream struct {
+	ss *session
+	e  *execution
+	q  *muxQueue
+}
+
+func (es *execStream) Send(r Response) error {
+	r.ExecID = es.e.id
+	if r.Reject != nil {
+		es.ss.finish(es.e)
+	}
+	if r.Response != nil {
+		es.ss.finish(es.e)
+		if err := es.e.failure(); err != nil && r.Response.ErrorMsg == "" {
+			r.Response.ErrorMsg = err.Error()
+		}
+	}
+	return es.ss.out.send(es.ss.ctx, es.q, r)
+}
+
+func (es *execStream) Recv() (*Request, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// mux sends responses of the executions over the stream in round-robin. Each
+// execution has its own bounded queue so that a chatty execution only blocks
+// itself rather than starving the others.
+type mux struct {
+	s      Stream
+	notify chan struct{}
+
+	mu     sync.Mutex
+	queues []*muxQueue
+	next   int
+}
+
+type muxQueue struct {
+	ch chan Response
+}
+
+func newMux(s Stream) *mux {
+	return &mux{s: s, notify: make(chan struct{}, 1)}
+}
+
+// open creates the queue for the execution, the queue is removed after the
+// final response or reject is taken
+func (m *mux) open() *muxQueue {
+	q := &muxQueue{ch: make(chan Response, muxQueueLen)}
+	m.mu.Lock()
+	m.queues = append(m.queues, q)
+	m.mu.Unlock()
+	return q
+}
+
+func (m *mux) send(ctx context.Context, q *muxQueue, r Response) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case q.ch <- r:
+	}
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// take picks a response from the next non-empty queue
+func (m *mux) take() (Response, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for range len(m.queues) {
+		i := m.next % len(m.queues)
+		m.next = i + 1
+		select {
+		case r := <-m.queues[i].ch:
+			if r.Response != nil || r.Reject != nil {
+				m.queues = slices.Delete(m.queues, i, i+1)
+				m.next = i
+			}
+			return r, true
+		default:
+		}
+	}
+	return Response{}, false
+}
+
+// writeLoop sends queued responses until done is closed and all queued
+// responses are sent
+func (m *mux) writeLoop(ctx context.Context, done <-chan struct{}) error {
+	closing := false
+	for {
+		if r, ok := m.take(); ok {
+			if err := m.s.Send(r); err != nil {
+				return err
+			}
+			continue
+		}
+		if closing {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.notify:
+		case <-done:
+			closing = true
+		}
+	}
+}
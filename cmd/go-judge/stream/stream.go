@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/criyle/go-judge/cmd/go-judge/model"
 	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
 	"github.com/criyle/go-judge/worker"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -25,18 +27,26 @@ type Stream interface {
 	Recv() (*Request, error)
 }
 
-// Request defines operations receive from the remote
+// Request defines operations receive from the remote. ExecID identifies the
+// execution when multiple executions are multiplexed on the stream
 type Request struct {
+	ExecID  uint32
 	Request *model.Request
 	Resize  *ResizeRequest
 	Input   *InputRequest
 	Cancel  *struct{}
+	Credit  *CreditRequest
 }
 
-// Response defines response to the remote
+// Response defines response to the remote. Session is sent by resumable
+// session on attach and it is not counted in the message offset.
 type Response struct {
+	ExecID   uint32
 	Response *model.Response
 	Output   *OutputResponse
+	InputAck *InputAckResponse
+	Reject   *RejectResponse
+	Session  *SessionResponse
 }
 
 // ResizeRequest defines resize operation to the virtual terminal
@@ -56,6 +66,27 @@ type InputRequest struct {
 	Content []byte
 }
 
+// CreditRequest grants positive output credit in bytes to the execution.
+// Output flow control is enabled when credit is granted before the exec
+// request.
+type CreditRequest struct {
+	Bytes int64 `json:"bytes"`
+}
+
+// InputAckResponse acknowledges the input bytes consumed when flow control is
+// enabled
+type InputAckResponse struct {
+	Index int
+	Fd    int
+	Bytes int
+}
+
+// RejectResponse reports a request rejected without affecting the execution
+// of the same ExecID, e.g. exec request of a running ExecID
+type RejectResponse struct {
+	Error string `json:"error"`
+}
+
 // OutputResponse defines output result to the remote
 type OutputResponse struct {
 	Index   int
@@ -67,60 +98,126 @@ var (
 	errFirstMustBeExec = errors.New("the first stream request must be exec request")
 )
 
-// Start initiate a interactive execution on the worker and transmit the request and response over Stream transport layer
-func Start(baseCtx context.Context, s Stream, w worker.Worker, srcPrefix []string, logger *zap.Logger) error {
+// Start initiate a interactive execution on the worker and transmit the request and response over Stream transport layer.
+// If the first request has non-zero ExecID, executions are multiplexed on the stream until the remote closes it.
+// Compressed file contents are decompressed up to decompressLimit (0 for unlimited).
+// Flow control statistic is recorded to flow if it is not nil.
+func Start(baseCtx context.Context, s Stream, w worker.Worker, srcPrefix []string, decompressLimit int64, flow *Flow, logger *zap.Logger) error {
+	// credits granted before exec request enable flow control
+	credits := make(pendingCredits)
 	req, err := s.Recv()
+	for err == nil && req.Credit != nil {
+		if err = credits.grant(req.ExecID, req.Credit.Bytes); err == nil {
+			req, err = s.Recv()
+		}
+	}
 	if err != nil {
 		return err
 	}
 	if req.Request == nil {
 		return errFirstMustBeExec
 	}
-	rq, streamIn, streamOut, err := convertStreamRequest(req.Request, srcPrefix)
+	if req.ExecID != 0 {
+		return startSession(baseCtx, s, req, credits, w, srcPrefix, decompressLimit, flow, logger)
+	}
+	c := credits.take(0, flow)
+	e, err := newExecution(baseCtx, 0, req.Request, srcPrefix, decompressLimit, c)
 	if err != nil {
 		return fmt.Errorf("convert exec request: %w", err)
 	}
-	closeFunc := func() {
-		for _, f := range streamIn {
-			f.Close()
-		}
-		streamIn = nil
-		for _, f := range streamOut {
-			f.Close()
-		}
-		streamOut = nil
-	}
-	defer closeFunc()
-
-	if ce := logger.Check(zap.DebugLevel, "request"); ce != nil {
-		ce.Write(zap.String("body", fmt.Sprintf("%+v", rq)))
+	defer e.close()
+	if c != nil {
+		e.queueInput(flow)
 	}
 
 	var wg errgroup.Group
-	var outWG errgroup.Group
-	execCtx, execCancel := context.WithCancel(baseCtx)
-	defer execCancel()
-
 	ctx, cancel := context.WithCancel(baseCtx)
 	defer cancel()
 
 	// stream in
 	wg.Go(func() error {
-		if err := streamInput(ctx, s, streamIn, execCancel); err != nil {
+		if err := streamInput(ctx, s, e); err != nil {
 			cancel()
 			return err
 		}
 		return nil
 	})
 
+	err = e.run(ctx, s, w, logger)
+
+	cancel()
+	e.close()
+	err2 := wg.Wait()
+	return errors.Join(err, err2)
+}
+
+// execution is an exec request with its stream files
+type execution struct {
+	id        uint32
+	req       *model.Request
+	rq        *worker.Request
+	streamIn  map[int]*fileStreamIn // by index<<8|fd
+	streamOut []*fileStreamOut
+
+	// output flow control is enabled when credit is not nil
+	credit *credit
+	// input is queued and acknowledged when inputs is not nil
+	inputs   map[int]*inputQueue // by index<<8|fd
+	stopOnce sync.Once
+	inputWG  sync.WaitGroup
+
+	ctx       context.Context // cancelled by cancel request
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+
+	mu  sync.Mutex
+	err error // error of operations that cancelled the execution
+}
+
+func newExecution(ctx context.Context, id uint32, m *model.Request, srcPrefix []string, decompressLimit int64, c *credit) (*execution, error) {
+	rq, streamIn, streamOut, err := convertStreamRequest(m, srcPrefix, decompressLimit)
+	if err != nil {
+		return nil, err
+	}
+	rq.Namespace = filestore.NamespaceFromContext(ctx)
+	e := &execution{
+		id:        id,
+		req:       m,
+		rq:        rq,
+		streamIn:  make(map[int]*fileStreamIn, len(streamIn)),
+		streamOut: streamOut,
+		credit:    c,
+	}
+	for _, f := range streamIn {
+		e.streamIn[f.index<<8|f.fd] = f
+	}
+	e.ctx, e.cancel = context.WithCancel(ctx)
+	return e, nil
+}
+
+// queueInput makes input written by a goroutine for each input stream so that
+// handle never blocks on a program not reading its input
+func (e *execution) queueInput(flow *Flow) {
+	e.inputs = make(map[int]*inputQueue, len(e.streamIn))
+	for k, f := range e.streamIn {
+		e.inputs[k] = newInputQueue(f, flow)
+	}
+}
+
+// run executes the request and sends the outputs followed by the result
+func (e *execution) run(ctx context.Context, s Stream, w worker.Worker, logger *zap.Logger) error {
+	if ce := logger.Check(zap.DebugLevel, "request"); ce != nil {
+		ce.Write(zap.String("body", fmt.Sprintf("%+v", e.rq)))
+	}
+
 	// stream out
-	outCh := make(chan *OutputResponse, len(streamOut))
+	var outWG errgroup.Group
+	outCh := make(chan *OutputResponse, len(e.streamOut))
 	outDone := make(chan error, 1)
-	if len(streamOut) > 0 {
-		for _, so := range streamOut {
-			so := so
+	if len(e.streamOut) > 0 {
+		for _, so := range e.streamOut {
 			outWG.Go(func() error {
-				return streamOutput(ctx, outCh, so)
+				return streamOutput(ctx, outCh, so, e.credit)
 			})
 		}
 		go func() {
@@ -135,16 +232,136 @@ func Start(baseCtx context.Context, s Stream, w worker.Worker, srcPrefix []strin
 		close(outDone)
 	}
 
-	rtCh := w.Execute(execCtx, rq)
-	err = sendLoop(ctx, s, outCh, outDone, rtCh, logger)
+	// input is written asynchronously and stopped before the final response
+	inCtx, inCancel := context.WithCancel(e.ctx)
+	defer inCancel()
+	for _, q := range e.inputs {
+		e.inputWG.Go(func() {
+			if err := q.writeLoop(inCtx, s); err != nil && inCtx.Err() == nil {
+				e.fail(err)
+			}
+		})
+	}
+	if len(e.inputs) > 0 {
+		s = &finalStream{Stream: s, before: func() { e.stopInput(inCancel) }}
+	}
+	defer e.stopInput(inCancel)
 
-	cancel()
-	closeFunc()
-	err2 := wg.Wait()
-	return errors.Join(err, err2)
+	rtCh := w.Execute(e.ctx, e.rq)
+	return sendLoop(ctx, s, e.req, outCh, outDone, rtCh, logger)
 }
 
-func sendLoop(ctx context.Context, s Stream, outCh <-chan *OutputResponse, outDone <-chan error, rtCh <-chan worker.Response, logger *zap.Logger) error {
+// stopInput stops the input writers, the inputs are closed since the program
+// could not read them after the result
+func (e *execution) stopInput(cancel context.CancelFunc) {
+	e.stopOnce.Do(func() {
+		cancel()
+		for _, f := range e.streamIn {
+			f.Close()
+		}
+		e.inputWG.Wait()
+	})
+}
+
+// finalStream calls before ahead of sending the final response
+type finalStream struct {
+	Stream
+	before func()
+}
+
+func (s *finalStream) Send(r Response) error {
+	if r.Response != nil {
+		s.before()
+	}
+	return s.Stream.Send(r)
+}
+
+// handle applies the input, resize or cancel request to the execution, done is
+// reported when no more input is expected
+func (e *execution) handle(in *Request) (done bool, err error) {
+	switch {
+	case in.Input != nil && e.inputs != nil:
+		q, ok := e.inputs[in.Input.Index<<8|in.Input.Fd]
+		if !ok {
+			return false, fmt.Errorf("input does not exist: %d/%d", in.Input.Index, in.Input.Fd)
+		}
+		return false, q.push(in.Input.Content)
+
+	case in.Input != nil:
+		f, ok := e.streamIn[in.Input.Index<<8|in.Input.Fd]
+		if !ok {
+			return false, fmt.Errorf("input does not exist: %d/%d", in.Input.Index, in.Input.Fd)
+		}
+		_, err := f.Write(in.Input.Content)
+		if err == io.EOF { // file closed with io.EOF
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("write to input %d/%d: %w", in.Input.Index, in.Input.Fd, err)
+		}
+
+	case in.Resize != nil:
+		f, ok := e.streamIn[in.Resize.Index<<8|in.Resize.Fd]
+		if !ok {
+			return false, fmt.Errorf("input does not exist: %d/%d", in.Resize.Index, in.Resize.Fd)
+		}
+		if err := f.SetSize(&envexec.TerminalSize{
+			Cols: uint16(in.Resize.Cols),
+			Rows: uint16(in.Resize.Rows),
+			X:    uint16(in.Resize.X),
+			Y:    uint16(in.Resize.Y),
+		}); err != nil {
+			return false, fmt.Errorf("resize %d/%d: %w", in.Resize.Index, in.Resize.Fd, err)
+		}
+
+	case in.Cancel != nil:
+		e.cancel()
+		return true, nil
+
+	case in.Credit != nil:
+		if in.Credit.Bytes <= 0 {
+			return false, fmt.Errorf("%w: %d", errInvalidCredit, in.Credit.Bytes)
+		}
+		// ignored when flow control is not enabled
+		if e.credit != nil {
+			e.credit.grant(in.Credit.Bytes)
+		}
+
+	default:
+		return false, fmt.Errorf("invalid request")
+	}
+	return false, nil
+}
+
+// fail cancels the execution with the error reported in its response
+func (e *execution) fail(err error) {
+	e.mu.Lock()
+	if e.err == nil {
+		e.err = err
+	}
+	e.mu.Unlock()
+	e.cancel()
+}
+
+func (e *execution) failure() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+func (e *execution) close() {
+	e.closeOnce.Do(func() {
+		e.cancel()
+		for _, f := range e.streamIn {
+			f.Close()
+		}
+		for _, f := range e.streamOut {
+			f.Close()
+		}
+	})
+}
+
+func sendLoop(ctx context.Context, s Stream, m *model.Request, outCh <-chan *OutputResponse, outDone <-chan error, rtCh <-chan worker.Response, logger *zap.Logger) error {
 	var (
 		outClosed    bool
 		resultReady  bool
@@ -181,10 +398,18 @@ func sendLoop(ctx context.Context, s Stream, outCh <-chan *OutputResponse, outDo
 			if ce := logger.Check(zap.DebugLevel, "response"); ce != nil {
 				ce.Write(zap.String("body", fmt.Sprintf("%+v", rt)))
 			}
+			// not executed, the front end reports it as retriable
+			if errors.Is(rt.Error, worker.ErrDraining) {
+				return rt.Error
+			}
 			ret, err := model.ConvertResponse(rt, false)
 			if err != nil {
 				return fmt.Errorf("convert response: %w", err)
 			}
+			if err := ret.CompressFiles(m); err != nil {
+				return fmt.Errorf("compress response: %w", err)
+			}
+			ret.EncodeFiles(m)
 			resultReady = true
 			resultSend = &model.Response{Results: ret.Results}
 			rtCh = nil
@@ -192,7 +417,7 @@ func sendLoop(ctx context.Context, s Stream, outCh <-chan *OutputResponse, outDo
 	}
 }
 
-func convertStreamRequest(m *model.Request, srcPrefix []string) (req *worker.Request, streamIn []*fileStreamIn, streamOut []*fileStreamOut, err error) {
+func convertStreamRequest(m *model.Request, srcPrefix []string, decompressLimit int64) (req *worker.Request, streamIn []*fileStreamIn, streamOut []*fileStreamOut, err error) {
 	type cmdStream struct {
 		index int
 		fd    int
@@ -229,7 +454,7 @@ func convertStreamRequest(m *model.Request, srcPrefix []string) (req *worker.Req
 			}
 		}
 	}
-	req, err = model.ConvertRequest(m, srcPrefix)
+	req, err = model.ConvertRequest(m, srcPrefix, decompressLimit)
 	if err != nil {
 		return req, streamIn, streamOut, err
 	}
@@ -239,11 +464,7 @@ func convertStreamRequest(m *model.Request, srcPrefix []string) (req *worker.Req
 	return
 }
 
-func streamInput(ctx context.Context, s Stream, si []*fileStreamIn, execCancel func()) error {
-	inf := make(map[int]*fileStreamIn)
-	for _, f := range si {
-		inf[f.index<<8|f.fd] = f
-	}
+func streamInput(ctx context.Context, s Stream, e *execution) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -258,46 +479,13 @@ func streamInput(ctx context.Context, s Stream, si []*fileStreamIn, execCancel f
 		if err != nil {
 			return err
 		}
-
-		switch {
-		case in.Input != nil:
-			f, ok := inf[in.Input.Index<<8|in.Input.Fd]
-			if !ok {
-				return fmt.Errorf("input does not exist: %d/%d", in.Input.Index, in.Input.Fd)
-			}
-			_, err := f.Write(in.Input.Content)
-			if err == io.EOF { // file closed with io.EOF
-				return nil
-			}
-			if err != nil {
-				return fmt.Errorf("write to input %d/%d: %w", in.Input.Index, in.Input.Fd, err)
-			}
-
-		case in.Resize != nil:
-			f, ok := inf[in.Resize.Index<<8|in.Resize.Fd]
-			if !ok {
-				return fmt.Errorf("input does not exist: %d/%d", in.Resize.Index, in.Resize.Fd)
-			}
-			if err = f.SetSize(&envexec.TerminalSize{
-				Cols: uint16(in.Resize.Cols),
-				Rows: uint16(in.Resize.Rows),
-				X:    uint16(in.Resize.X),
-				Y:    uint16(in.Resize.Y),
-			}); err != nil {
-				return fmt.Errorf("resize %d/%d: %w", in.Resize.Index, in.Resize.Fd, err)
-			}
-
-		case in.Cancel != nil:
-			execCancel()
-			return nil
-
-		default:
-			return fmt.Errorf("invalid request")
+		if done, err := e.handle(in); done || err != nil {
+			return err
 		}
 	}
 }
 
-func streamOutput(ctx context.Context, outCh chan *OutputResponse, so *fileStreamOut) error {
+func streamOutput(ctx context.Context, outCh chan *OutputResponse, so *fileStreamOut, c *credit) error {
 	var buf []byte
 	for {
 		select {
@@ -309,7 +497,18 @@ func streamOutput(ctx context.Context, outCh chan *OutputResponse, so *fileStrea
 			buf = make([]byte, newBuffLen)
 		}
 
-		n, err := so.Read(buf)
+		// read no more than the credit so that the program is blocked
+		want := len(buf)
+		if c != nil {
+			var err error
+			if want, err = c.acquire(ctx, want); err != nil {
+				return nil
+			}
+		}
+		n, err := so.Read(buf[:want])
+		if c != nil && n < want {
+			c.grant(int64(want - n))
+		}
 		if err != nil { // file closed with io.EOF
 			return nil
 		}
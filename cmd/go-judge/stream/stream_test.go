@@ -2,9 +2,14 @@ package stream
 
 import (
 	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 
+	"github.com/criyle/go-judge/cmd/go-judge/model"
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/worker"
 	"go.uber.org/zap"
@@ -40,7 +45,7 @@ func TestSendLoopDrainsOutputBeforeFinalResponse(t *testing.T) {
 	}
 
 	go func() {
-		errCh <- sendLoop(context.Background(), s, outCh, outDone, rtCh, zap.NewNop())
+		errCh <- sendLoop(context.Background(), s, &model.Request{}, outCh, outDone, rtCh, zap.NewNop())
 	}()
 
 	outCh <- &OutputResponse{Index: 0, Fd: 1, Content: []byte("tail")}
@@ -62,3 +67,168 @@ func TestSendLoopDrainsOutputBeforeFinalResponse(t *testing.T) {
 		t.Fatalf("expected final response second, got %#v", s.sends[1])
 	}
 }
+
+type scriptedStream struct {
+	recordingStream
+	reqs chan *Request
+}
+
+func (s *scriptedStream) Recv() (*Request, error) {
+	r, ok := <-s.reqs
+	if !ok {
+		return nil, io.EOF
+	}
+	return r, nil
+}
+
+type executeWorker struct {
+	worker.Worker
+}
+
+func (executeWorker) Execute(ctx context.Context, r *worker.Request) <-chan worker.Response {
+	ch := make(chan worker.Response, 1)
+	ch <- worker.Response{Results: []worker.Result{{Status: envexec.StatusAccepted}}}
+	return ch
+}
+
+func TestStartMultiplexed(t *testing.T) {
+	s := &scriptedStream{reqs: make(chan *Request, 4)}
+	exec := &model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}
+	s.reqs <- &Request{ExecID: 1, Request: exec}
+	s.reqs <- &Request{ExecID: 2, Request: exec}
+	s.reqs <- &Request{ExecID: 3, Cancel: &struct{}{}}
+	close(s.reqs)
+
+	if err := Start(context.Background(), s, executeWorker{}, nil, 0, nil, zap.NewNop()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	responses := make(map[uint32]*model.Response)
+	var reject *Response
+	for _, r := range s.sends {
+		if r.Reject != nil && reject == nil {
+			reject = &r
+			continue
+		}
+		if r.Response == nil || responses[r.ExecID] != nil {
+			t.Fatalf("unexpected send %#v", r)
+		}
+		responses[r.ExecID] = r.Response
+	}
+	for _, id := range []uint32{1, 2} {
+		if r := responses[id]; r == nil || len(r.Results) != 1 || r.Results[0].Status != model.Status(envexec.StatusAccepted) {
+			t.Fatalf("unexpected response of exec %d: %#v", id, r)
+		}
+	}
+	if reject == nil || reject.ExecID != 3 || !strings.Contains(reject.Reject.Error, errExecNotExist.Error()) {
+		t.Fatalf("expected reject of exec 3, got %#v", reject)
+	}
+}
+
+type drainingWorker struct {
+	worker.Worker
+}
+
+func (drainingWorker) Execute(ctx context.Context, r *worker.Request) <-chan worker.Response {
+	ch := make(chan worker.Response, 1)
+	ch <- worker.Response{Error: worker.ErrDraining}
+	return ch
+}
+
+func TestStartDraining(t *testing.T) {
+	exec := &model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}
+	s := &scriptedStream{reqs: make(chan *Request, 1)}
+	s.reqs <- &Request{Request: exec}
+	close(s.reqs)
+	if err := Start(context.Background(), s, drainingWorker{}, nil, 0, nil, zap.NewNop()); !errors.Is(err, worker.ErrDraining) {
+		t.Fatalf("expected draining error, got %v", err)
+	}
+
+	s = &scriptedStream{reqs: make(chan *Request, 1)}
+	s.reqs <- &Request{ExecID: 1, Request: exec}
+	close(s.reqs)
+	if err := Start(context.Background(), s, drainingWorker{}, nil, 0, nil, zap.NewNop()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if len(s.sends) != 1 || s.sends[0].ExecID != 1 || s.sends[0].Reject == nil {
+		t.Fatalf("expected reject of exec 1, got %#v", s.sends)
+	}
+}
+
+type blockingWorker struct {
+	worker.Worker
+	release chan struct{}
+}
+
+func (w blockingWorker) Execute(ctx context.Context, r *worker.Request) <-chan worker.Response {
+	ch := make(chan worker.Response, 1)
+	go func() {
+		<-w.release
+		ch <- worker.Response{Results: []worker.Result{{Status: envexec.StatusAccepted}}}
+	}()
+	return ch
+}
+
+// releaseStream releases the worker once the remote finished sending
+type releaseStream struct {
+	scriptedStream
+	release chan struct{}
+}
+
+func (s *releaseStream) Recv() (*Request, error) {
+	r, err := s.scriptedStream.Recv()
+	if err == io.EOF {
+		close(s.release)
+	}
+	return r, err
+}
+
+func TestStartMultiplexedDuplicate(t *testing.T) {
+	s := &releaseStream{scriptedStream: scriptedStream{reqs: make(chan *Request, 2)}, release: make(chan struct{})}
+	exec := &model.Request{Cmd: []model.Cmd{{Args: []string{"/bin/true"}}}}
+	s.reqs <- &Request{ExecID: 1, Request: exec}
+	s.reqs <- &Request{ExecID: 1, Request: exec}
+	close(s.reqs)
+
+	if err := Start(context.Background(), s, blockingWorker{release: s.release}, nil, 0, nil, zap.NewNop()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if len(s.sends) != 2 {
+		t.Fatalf("expected 2 sends, got %#v", s.sends)
+	}
+	// queues of the reject and the execution are served in round-robin
+	reject, resp := s.sends[0], s.sends[1]
+	if reject.Reject == nil {
+		reject, resp = resp, reject
+	}
+	if reject.ExecID != 1 || reject.Reject == nil || !strings.Contains(reject.Reject.Error, errExecExists.Error()) {
+		t.Fatalf("expected reject of duplicate exec, got %#v", reject)
+	}
+	if resp.ExecID != 1 || resp.Response == nil || resp.Response.ErrorMsg != "" {
+		t.Fatalf("expected response of the running exec, got %#v", resp)
+	}
+}
+
+func TestMuxRoundRobin(t *testing.T) {
+	s := &recordingStream{}
+	m := newMux(s)
+	ctx := context.Background()
+	q1, q2 := m.open(), m.open()
+	for range 3 {
+		m.send(ctx, q1, Response{ExecID: 1, Output: &OutputResponse{}})
+	}
+	m.send(ctx, q2, Response{ExecID: 2, Output: &OutputResponse{}})
+	m.send(ctx, q2, Response{ExecID: 2, Response: &model.Response{}})
+
+	done := make(chan struct{})
+	close(done)
+	if err := m.writeLoop(ctx, done); err != nil {
+		t.Fatalf("writeLoop returned error: %v", err)
+	}
+	var ids []uint32
+	for _, r := range s.sends {
+		ids = append(ids, r.ExecID)
+	}
+	if expected := []uint32{1, 2, 1, 2, 1}; !slices.Equal(ids, expected) {
+		t.Fatalf("expected order %v, got %v", expected, ids)
+	}
+}
@@ -2,6 +2,7 @@ package wsexecutor
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,10 +18,15 @@ var _ stream.Stream = &streamWrapper{}
 
 const maxPackedStreamField = 15
 
+// execIDFlag in the type code marks the frame is followed by 4 bytes big
+// endian exec id of the multiplexed execution
+const execIDFlag = 0x80
+
 var errStreamFieldTooLarge = errors.New("stream index or fd exceeds websocket wire limit")
 
 type streamWrapper struct {
 	ctx    context.Context
+	cancel context.CancelFunc // cancels ctx when the connection is lost
 	conn   *websocket.Conn
 	sendCh chan stream.Response
 }
@@ -28,6 +34,7 @@ type streamWrapper struct {
 func (w *streamWrapper) sendLoop() {
 	conn := w.conn
 	defer conn.Close()
+	defer w.cancel()
 
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
@@ -43,7 +50,7 @@ func (w *streamWrapper) sendLoop() {
 				if err != nil {
 					return
 				}
-				if _, err := w.Write([]byte{1}); err != nil {
+				if _, err := w.Write(frameHeader(1, r.ExecID)); err != nil {
 					return
 				}
 				if err := json.NewEncoder(w).Encode(r.Response); err != nil {
@@ -52,8 +59,47 @@ func (w *streamWrapper) sendLoop() {
 				if err := w.Close(); err != nil {
 					return
 				}
-				conn.Close()
-				return
+				// multiplexed stream is kept until the remote closes it
+				if r.ExecID == 0 {
+					conn.Close()
+					return
+				}
+			case r.Session != nil:
+				w, err := conn.NextWriter(websocket.BinaryMessage)
+				if err != nil {
+					return
+				}
+				if _, err := w.Write([]byte{3}); err != nil {
+					return
+				}
+				if err := json.NewEncoder(w).Encode(r.Session); err != nil {
+					return
+				}
+				if err := w.Close(); err != nil {
+					return
+				}
+			case r.Reject != nil:
+				w, err := conn.NextWriter(websocket.BinaryMessage)
+				if err != nil {
+					return
+				}
+				if _, err := w.Write(frameHeader(5, r.ExecID)); err != nil {
+					return
+				}
+				if err := json.NewEncoder(w).Encode(r.Reject); err != nil {
+					return
+				}
+				if err := w.Close(); err != nil {
+					return
+				}
+			case r.InputAck != nil:
+				if r.InputAck.Index > maxPackedStreamField || r.InputAck.Fd > maxPackedStreamField {
+					return
+				}
+				b := append(frameHeader(4, r.ExecID), byte(r.InputAck.Index<<4|r.InputAck.Fd))
+				if err := conn.WriteMessage(websocket.BinaryMessage, binary.BigEndian.AppendUint32(b, uint32(r.InputAck.Bytes))); err != nil {
+					return
+				}
 			case r.Output != nil:
 				if r.Output.Index > maxPackedStreamField || r.Output.Fd > maxPackedStreamField {
 					return
@@ -62,7 +108,7 @@ func (w *streamWrapper) sendLoop() {
 				if err != nil {
 					return
 				}
-				if _, err := w.Write([]byte{2, byte(r.Output.Index<<4 | r.Output.Fd)}); err != nil {
+				if _, err := w.Write(append(frameHeader(2, r.ExecID), byte(r.Output.Index<<4|r.Output.Fd))); err != nil {
 					return
 				}
 				if _, err := w.Write(r.Output.Content); err != nil {
@@ -100,10 +146,18 @@ func (w *streamWrapper) Recv() (*stream.Request, error) {
 		return nil, io.ErrUnexpectedEOF
 	}
 	var req stream.Request
-	switch buf[0] {
+	typ, buf := buf[0], buf[1:]
+	if typ&execIDFlag != 0 {
+		if len(buf) < 4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		req.ExecID = binary.BigEndian.Uint32(buf)
+		typ, buf = typ&^execIDFlag, buf[4:]
+	}
+	switch typ {
 	case 1:
 		req.Request = new(model.Request)
-		if err := json.Unmarshal(buf[1:], req.Request); err != nil {
+		if err := json.Unmarshal(buf, req.Request); err != nil {
 			return nil, err
 		}
 		if err := validateStreamRequestLimits(req.Request); err != nil {
@@ -111,25 +165,38 @@ func (w *streamWrapper) Recv() (*stream.Request, error) {
 		}
 	case 2:
 		req.Resize = new(stream.ResizeRequest)
-		if err := json.Unmarshal(buf[1:], req.Resize); err != nil {
+		if err := json.Unmarshal(buf, req.Resize); err != nil {
 			return nil, err
 		}
 	case 3:
-		if len(buf) < 2 {
+		if len(buf) < 1 {
 			return nil, io.ErrUnexpectedEOF
 		}
 		req.Input = new(stream.InputRequest)
-		req.Input.Index = int(buf[1]>>4) & 0xf
-		req.Input.Fd = int(buf[1]) & 0xf
-		req.Input.Content = buf[2:]
+		req.Input.Index = int(buf[0]>>4) & 0xf
+		req.Input.Fd = int(buf[0]) & 0xf
+		req.Input.Content = buf[1:]
 	case 4:
 		req.Cancel = new(struct{})
+	case 5:
+		req.Credit = new(stream.CreditRequest)
+		if err := json.Unmarshal(buf, req.Credit); err != nil {
+			return nil, err
+		}
 	default:
-		return nil, fmt.Errorf("invalid type code: %d", buf[0])
+		return nil, fmt.Errorf("invalid type code: %d", typ)
 	}
 	return &req, nil
 }
 
+// frameHeader returns the type code followed by the exec id if it is not zero
+func frameHeader(typ byte, execID uint32) []byte {
+	if execID == 0 {
+		return []byte{typ}
+	}
+	return binary.BigEndian.AppendUint32([]byte{typ | execIDFlag}, execID)
+}
+
 func validateStreamRequestLimits(req *model.Request) error {
 	for i, c := range req.Cmd {
 		if i > maxPackedStreamField {
@@ -1,10 +1,16 @@
 package wsexecutor
 
 import (
+	"bytes"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/criyle/go-judge/cmd/go-judge/model"
+	"github.com/criyle/go-judge/cmd/go-judge/stream"
+	"github.com/gorilla/websocket"
 )
 
 func TestValidateStreamRequestLimitsRejectsLargeCommandIndex(t *testing.T) {
@@ -44,3 +50,38 @@ func TestValidateStreamRequestLimitsAllowsBoundaryValues(t *testing.T) {
 		t.Fatalf("expected boundary values to pass, got %v", err)
 	}
 }
+
+func TestStreamFrameExecID(t *testing.T) {
+	if h := frameHeader(2, 0); !bytes.Equal(h, []byte{2}) {
+		t.Fatalf("unexpected header without exec id: %v", h)
+	}
+	if h := frameHeader(2, 5); !bytes.Equal(h, []byte{2 | execIDFlag, 0, 0, 0, 5}) {
+		t.Fatalf("unexpected header with exec id: %v", h)
+	}
+
+	reqCh := make(chan *stream.Request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, _ := (&streamWrapper{conn: conn}).Recv()
+		reqCh <- req
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	frame := append(frameHeader(3, 5), 0<<4|1, 'h', 'i')
+	if err := conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	req := <-reqCh
+	if req == nil || req.ExecID != 5 || req.Input == nil || req.Input.Fd != 1 || string(req.Input.Content) != "hi" {
+		t.Fatalf("unexpected request %#v", req)
+	}
+}
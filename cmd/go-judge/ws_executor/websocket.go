@@ -7,11 +7,13 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/criyle/go-judge/cmd/go-judge/model"
 	"github.com/criyle/go-judge/cmd/go-judge/stream"
+	"github.com/criyle/go-judge/filestore"
 	"github.com/criyle/go-judge/worker"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -22,15 +24,21 @@ var _ Register = &wsHandle{}
 
 // Register registers web socket handle /ws
 type Register interface {
-	Register(*gin.Engine)
+	Register(gin.IRouter)
 }
 
-// New creates new websocket handle
-func New(worker worker.Worker, srcPrefix []string, logger *zap.Logger) Register {
+// New creates new websocket handle, stream sessions are resumable when
+// sessions is not nil. Compressed file contents are decompressed up to
+// decompressLimit (0 for unlimited) and stream flow control statistic is
+// recorded to flow if it is not nil
+func New(worker worker.Worker, srcPrefix []string, decompressLimit int64, sessions *stream.Sessions, flow *stream.Flow, logger *zap.Logger) Register {
 	return &wsHandle{
-		worker:    worker,
-		srcPrefix: srcPrefix,
-		logger:    logger,
+		worker:          worker,
+		srcPrefix:       srcPrefix,
+		decompressLimit: decompressLimit,
+		sessions:        sessions,
+		flow:            flow,
+		logger:          logger,
 	}
 }
 
@@ -44,33 +52,63 @@ const (
 	writeWait  = 10 * time.Second
 	pongWait   = 60 * time.Second
 	pingPeriod = 50 * time.Second
+
+	// retryAfter is the seconds for clients to wait before retrying the
+	// request rejected when draining
+	retryAfter = "1"
 )
 
 type wsHandle struct {
-	worker    worker.Worker
-	srcPrefix []string
-	logger    *zap.Logger
+	worker          worker.Worker
+	srcPrefix       []string
+	decompressLimit int64
+	sessions        *stream.Sessions
+	flow            *stream.Flow
+	logger          *zap.Logger
 }
 
 type wsRequest struct {
 	model.Request
 	CancelRequestID string `json:"cancelRequestId"`
+	// Progress sends progress events as responses with event before the result
+	Progress bool `json:"progress"`
 }
 
-func (h *wsHandle) Register(r *gin.Engine) {
+func (h *wsHandle) Register(r gin.IRouter) {
 	r.GET("/ws", h.handleWS)
 	r.GET("/stream", h.handleStream)
 }
 
+// rejectDraining responds the upgrade request with 503 when the worker is
+// draining so that the client retries on other instances
+func (h *wsHandle) rejectDraining(c *gin.Context) bool {
+	if !h.worker.Stat().Draining {
+		return false
+	}
+	c.Header("Retry-After", retryAfter)
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, worker.ErrDraining.Error())
+	return true
+}
+
 func (h *wsHandle) handleWS(c *gin.Context) {
+	if h.rejectDraining(c) {
+		return
+	}
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		c.Error(err)
 		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
 		return
 	}
+	// message format follows Accept or Content-Type of the upgrade request,
+	// binary formats are framed as binary messages
+	format, ok := model.ParseFormat(c.GetHeader("Accept"))
+	if !ok {
+		format, _ = model.ParseFormat(c.ContentType())
+	}
 	resultCh := make(chan model.Response, 128)
 	cm := newContextMap()
+	ns := filestore.NamespaceFromContext(c.Request.Context())
 
 	handleRequest := func(baseCtx context.Context, req *wsRequest) error {
 		if req.CancelRequestID != "" {
@@ -78,10 +116,11 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 			cm.Remove(req.CancelRequestID)
 			return nil
 		}
-		r, err := model.ConvertRequest(&req.Request, h.srcPrefix)
+		r, err := model.ConvertRequest(&req.Request, h.srcPrefix, h.decompressLimit)
 		if err != nil {
 			return fmt.Errorf("ws convert error: %w", err)
 		}
+		r.Namespace = ns
 
 		ctx, cancel := context.WithCancel(baseCtx)
 		if err := cm.Add(r.RequestID, cancel); err != nil {
@@ -97,6 +136,18 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 			return nil
 		}
 
+		if req.Progress {
+			r.Progress = func(e worker.Event) {
+				me := model.ConvertEvent(e)
+				// progress is dropped rather than blocking the worker when
+				// the connection falls behind
+				select {
+				case resultCh <- model.Response{RequestID: r.RequestID, Event: &me}:
+				default:
+				}
+			}
+		}
+
 		go func() {
 			defer cm.Remove(r.RequestID)
 
@@ -131,6 +182,15 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 					ErrorMsg:  resp.ErrorMsg,
 				}
 			}
+			if err := resp.CompressFiles(&req.Request); err != nil {
+				resp = model.Response{
+					RequestID: r.RequestID,
+					ErrorMsg:  err.Error(),
+				}
+			}
+			if !format.Binary() {
+				resp.EncodeFiles(&req.Request)
+			}
 			select {
 			case <-baseCtx.Done():
 			case resultCh <- resp:
@@ -153,7 +213,7 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 
 		for {
 			req := new(wsRequest)
-			if err := conn.ReadJSON(req); err != nil {
+			if err := readRequest(conn, format, req); err != nil {
 				if !isExpectedWSClose(err) {
 					h.logger.Info("ws read error", zap.Error(err))
 				}
@@ -175,7 +235,7 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 			select {
 			case r := <-resultCh:
 				conn.SetWriteDeadline(time.Now().Add(writeWait))
-				if err := conn.WriteJSON(r); err != nil {
+				if err := writeResponse(conn, format, r); err != nil {
 					if !isExpectedWSClose(err) {
 						h.logger.Info("ws write error", zap.Error(err))
 					}
@@ -191,7 +251,37 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 	}()
 }
 
+func readRequest(conn *websocket.Conn, format model.Format, req *wsRequest) error {
+	if !format.Binary() {
+		return conn.ReadJSON(req)
+	}
+	_, r, err := conn.NextReader()
+	if err != nil {
+		return err
+	}
+	return format.Decode(r, req)
+}
+
+func writeResponse(conn *websocket.Conn, format model.Format, resp model.Response) error {
+	if !format.Binary() {
+		return conn.WriteJSON(resp)
+	}
+	w, err := conn.NextWriter(websocket.BinaryMessage)
+	if err != nil {
+		return err
+	}
+	if err := format.EncodeResponse(w, resp); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 func (h *wsHandle) handleStream(c *gin.Context) {
+	// resumed sessions are kept to receive the results in flight
+	if c.Query("resume") == "" && h.rejectDraining(c) {
+		return
+	}
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		c.Error(err)
@@ -203,12 +293,39 @@ func (h *wsHandle) handleStream(c *gin.Context) {
 		conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
-	ctx, cancel := context.WithCancel(context.TODO())
+	ctx, cancel := context.WithCancel(filestore.WithNamespace(context.TODO(), filestore.NamespaceFromContext(c.Request.Context())))
 	defer cancel()
 
-	w := &streamWrapper{ctx: ctx, conn: conn, sendCh: make(chan stream.Response)}
+	w := &streamWrapper{ctx: ctx, cancel: cancel, conn: conn, sendCh: make(chan stream.Response)}
 	go w.sendLoop()
-	if err := stream.Start(ctx, w, h.worker, h.srcPrefix, h.logger); err != nil {
+
+	run := func(ctx context.Context, s stream.Stream) error {
+		return stream.Start(ctx, s, h.worker, h.srcPrefix, h.decompressLimit, h.flow, h.logger)
+	}
+	switch token := c.Query("resume"); {
+	case h.sessions != nil && token != "":
+		var offset uint64
+		if o := c.Query("offset"); o != "" {
+			offset, err = strconv.ParseUint(o, 10, 64)
+		}
+		if err == nil {
+			err = h.sessions.Resume(ctx, w, token, offset)
+		}
+		if err != nil {
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(writeWait))
+		}
+	case h.sessions != nil && c.Query("resumable") == "true":
+		err = h.sessions.Start(ctx, w, run)
+		if errors.Is(err, stream.ErrTooManySessions) {
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()), time.Now().Add(writeWait))
+		}
+	default:
+		err = run(ctx, w)
+	}
+	if errors.Is(err, worker.ErrDraining) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()), time.Now().Add(writeWait))
+	}
+	if err != nil {
 		if !isExpectedWSClose(err) {
 			h.logger.Debug("stream start", zap.Error(err))
 			c.Error(err)
@@ -14,4 +14,5 @@ type Config struct {
 	EnableCPURate      bool
 	CPUCfsPeriod       time.Duration
 	NoFallback         bool
+	BindDir            string // host directory to stage zero-copy copyIn files, empty to copy (linux only)
 }
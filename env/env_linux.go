@@ -24,6 +24,7 @@ const (
 	containerName  = "executor_server"
 	defaultWorkDir = "/w"
 	containerCred  = 1000
+	bindTarget     = "/.bind"
 )
 
 // NewBuilder build a environment builder
@@ -32,6 +33,13 @@ func NewBuilder(c Config, logger *zap.Logger) (pool.EnvBuilder, map[string]any,
 	if err != nil {
 		return nil, nil, err
 	}
+	var bindDir *linuxcontainer.BindDir
+	if c.BindDir != "" {
+		if bindDir, err = linuxcontainer.NewBindDir(c.BindDir, bindTarget); err != nil {
+			return nil, nil, err
+		}
+		mountBuilder.WithBind(c.BindDir, bindTarget, true)
+	}
 	m := mountBuilder.FilterNotExist().Mounts
 
 	seccomp, err := prepareSeccomp(c, logger)
@@ -82,9 +90,13 @@ func NewBuilder(c Config, logger *zap.Logger) (pool.EnvBuilder, map[string]any,
 		"uid":               cUID,
 		"gid":               cGID,
 		"cgroupControllers": cgroupControllers,
+		"separateCred":      credGen != nil,
+	}
+	if bindDir != nil {
+		conf["bindDir"] = c.BindDir
 	}
 
-	if tryClone3Builder := tryClone3(c, b, cgb, cgroupType, cgroupPool, workDir, seccomp, logger); tryClone3Builder != nil {
+	if tryClone3Builder := tryClone3(c, b, cgb, cgroupType, cgroupPool, workDir, seccomp, bindDir, logger); tryClone3Builder != nil {
 		conf["clone3"] = true
 		return tryClone3Builder, conf, nil
 	}
@@ -95,6 +107,7 @@ func NewBuilder(c Config, logger *zap.Logger) (pool.EnvBuilder, map[string]any,
 		WorkDir:    workDir,
 		CPURate:    c.EnableCPURate,
 		Seccomp:    seccomp,
+		BindDir:    bindDir,
 	}), conf, nil
 }
 
@@ -212,6 +225,7 @@ func tryClone3(
 	cgroupPool linuxcontainer.CgroupPool,
 	workDir string,
 	seccomp []syscall.SockFilter,
+	bindDir *linuxcontainer.BindDir,
 	logger *zap.Logger,
 ) pool.EnvBuilder {
 	major, minor := kernelVersion()
@@ -228,6 +242,7 @@ func tryClone3(
 		CPURate:    c.EnableCPURate,
 		Seccomp:    seccomp,
 		CgroupFd:   true,
+		BindDir:    bindDir,
 	})
 	e, err := b.Build()
 	if err != nil {
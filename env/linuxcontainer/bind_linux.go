@@ -0,0 +1,299 @@
+package linuxcontainer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"golang.org/x/sys/unix"
+)
+
+var _ envexec.BindEnvironment = &environ{}
+
+// BindDir is the host directory mounted read-only into every container so
+// that files staged in it are visible to the programs without copying. Each
+// environment stages its files in its own sub-directory, which is not listable
+// from other containers, and removes them on reset.
+//
+// Files on the same file system are staged as hard links. Other files (e.g.
+// sealed memfd of the file cache) are staged by one copy shared by all the
+// environments until none of them binds it.
+type BindDir struct {
+	source string // host path
+	target string // container-visible path of source
+
+	mu     sync.Mutex
+	shared map[bindKey]*sharedStage
+}
+
+// bindKey identifies the content of a file, the file is kept open by the
+// shared stage so that the inode number is not reused
+type bindKey struct {
+	dev, ino   uint64
+	size       int64
+	mtim, ctim unix.Timespec
+}
+
+type sharedStage struct {
+	src  *os.File // pins the inode of the source
+	refs int
+
+	done chan struct{} // closed once copied
+	name string        // host path of the staged copy
+	err  error
+}
+
+// NewBindDir creates the staging directory on the host which must be mounted
+// read-only at the container-visible target, files left by previous runs are
+// removed
+func NewBindDir(source, target string) (*BindDir, error) {
+	if err := removeStaged(source); err != nil {
+		return nil, fmt.Errorf("bind dir: %w", err)
+	}
+	// traversable but not listable by the programs
+	if err := os.MkdirAll(filepath.Join(source, "shared"), 0o711); err != nil {
+		return nil, fmt.Errorf("bind dir: %w", err)
+	}
+	return &BindDir{
+		source: source,
+		target: target,
+		shared: make(map[bindKey]*sharedStage),
+	}, nil
+}
+
+const stageNameLen = 16
+
+// bindStage is the sub-directory of an environment
+type bindStage struct {
+	name   string // random name of the sub-directory
+	n      int
+	shared []bindKey
+}
+
+// Bind stages the files in the sub-directory of the environment and links the
+// paths to them inside the container
+func (c *environ) Bind(params []envexec.BindParam) ([]error, error) {
+	errs := make([]error, len(params))
+	if c.bindDir == nil {
+		for i := range errs {
+			errs[i] = envexec.ErrBindNotSupported
+		}
+		return errs, nil
+	}
+	if c.bind == nil {
+		s, err := c.bindDir.newStage()
+		if err != nil {
+			return nil, err
+		}
+		c.bind = s
+	}
+
+	var (
+		links []container.SymbolicLink
+		index []int
+	)
+	for i, p := range params {
+		name, err := c.bindDir.stage(c.bind, p.File)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		links = append(links, container.SymbolicLink{
+			LinkPath: p.Path,
+			Target:   path.Join(c.bindDir.target, c.bind.name, name),
+		})
+		index = append(index, i)
+	}
+	if len(links) == 0 {
+		return errs, nil
+	}
+	lerrs, err := c.Environment.Symlink(links)
+	if err != nil {
+		return nil, err
+	}
+	for i, err := range lerrs {
+		errs[index[i]] = err
+	}
+	return errs, nil
+}
+
+// resetBind removes the files staged by the environment
+func (c *environ) resetBind() error {
+	if c.bind == nil {
+		return nil
+	}
+	s := c.bind
+	c.bind = nil
+	return c.bindDir.release(s)
+}
+
+func (d *BindDir) newStage() (*bindStage, error) {
+	b := make([]byte, stageNameLen)
+	rand.Read(b)
+	s := &bindStage{name: hex.EncodeToString(b)}
+	if err := os.Mkdir(filepath.Join(d.source, s.name), 0o711); err != nil {
+		return nil, fmt.Errorf("bind: %w", err)
+	}
+	return s, nil
+}
+
+// stage hard links the file into the sub-directory of the environment, the
+// file is linked directly if it is readable by the programs and on the same
+// file system, otherwise its shared copy is linked
+func (d *BindDir) stage(s *bindStage, f *os.File) (string, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		return "", fmt.Errorf("bind: %w", err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFREG {
+		return "", envexec.ErrBindNotSupported
+	}
+	name := strconv.Itoa(s.n)
+	s.n++
+	dst := filepath.Join(d.source, s.name, name)
+	if st.Mode&0o004 != 0 && linkFile(f, dst) == nil {
+		return name, nil
+	}
+
+	key := bindKey{dev: st.Dev, ino: st.Ino, size: st.Size, mtim: st.Mtim, ctim: st.Ctim}
+	src, err := d.acquire(key, f)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Link(src, dst); err != nil {
+		d.mu.Lock()
+		d.put(key)
+		d.mu.Unlock()
+		return "", fmt.Errorf("bind: %w", err)
+	}
+	s.shared = append(s.shared, key)
+	return name, nil
+}
+
+// acquire returns the shared copy of the file, the file is copied by the
+// first one and others wait for it
+func (d *BindDir) acquire(key bindKey, f *os.File) (string, error) {
+	d.mu.Lock()
+	ss, ok := d.shared[key]
+	if ok {
+		ss.refs++
+		d.mu.Unlock()
+		<-ss.done
+	} else {
+		src, err := dupFile(f)
+		if err != nil {
+			d.mu.Unlock()
+			return "", fmt.Errorf("%w: %v", envexec.ErrBindNotSupported, err)
+		}
+		ss = &sharedStage{src: src, refs: 1, done: make(chan struct{})}
+		d.shared[key] = ss
+		d.mu.Unlock()
+
+		ss.name, ss.err = d.copyShared(src, key.size)
+		close(ss.done)
+	}
+	if ss.err != nil {
+		d.mu.Lock()
+		d.put(key)
+		d.mu.Unlock()
+		return "", ss.err
+	}
+	return ss.name, nil
+}
+
+// copyShared copies the file into the shared directory, the file is copied in
+// as usual if it fails (e.g. out of space)
+func (d *BindDir) copyShared(src *os.File, size int64) (string, error) {
+	dst, err := os.CreateTemp(filepath.Join(d.source, "shared"), "")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", envexec.ErrBindNotSupported, err)
+	}
+	defer dst.Close()
+	if _, err = io.Copy(dst, io.NewSectionReader(src, 0, size)); err == nil {
+		err = dst.Chmod(0o444)
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("%w: copy: %v", envexec.ErrBindNotSupported, err)
+	}
+	return dst.Name(), nil
+}
+
+// put releases one reference of the shared copy, it must be called with lock
+func (d *BindDir) put(key bindKey) {
+	ss := d.shared[key]
+	if ss.refs--; ss.refs > 0 {
+		return
+	}
+	delete(d.shared, key)
+	if ss.name != "" {
+		os.Remove(ss.name)
+	}
+	ss.src.Close()
+}
+
+func (d *BindDir) release(s *bindStage) error {
+	err := os.RemoveAll(filepath.Join(d.source, s.name))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range s.shared {
+		d.put(key)
+	}
+	return err
+}
+
+// removeStaged removes the shared directory and the sub-directories of
+// environments in the staging directory
+func removeStaged(source string) error {
+	entries, err := os.ReadDir(source)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if !e.IsDir() || !isStageName(e.Name()) {
+			continue
+		}
+		errs = append(errs, os.RemoveAll(filepath.Join(source, e.Name())))
+	}
+	return errors.Join(errs...)
+}
+
+func isStageName(name string) bool {
+	if name == "shared" {
+		return true
+	}
+	b, err := hex.DecodeString(name)
+	return err == nil && len(b) == stageNameLen
+}
+
+// linkFile hard links the opened file through /proc so that it works without
+// CAP_DAC_READ_SEARCH, it fails with EXDEV for file on other file system
+// (including memfd)
+func linkFile(f *os.File, dst string) error {
+	src := "/proc/self/fd/" + strconv.Itoa(int(f.Fd()))
+	return unix.Linkat(unix.AT_FDCWD, src, unix.AT_FDCWD, dst, unix.AT_SYMLINK_FOLLOW)
+}
+
+// dupFile duplicates the file so that it is kept open after the caller
+// closes it
+func dupFile(f *os.File) (*os.File, error) {
+	fd, err := unix.FcntlInt(f.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), f.Name()), nil
+}
@@ -4,6 +4,7 @@ import (
 	"syscall"
 
 	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-sandbox/container"
 )
 
 // Config specifies configuration to build environment builder
@@ -13,7 +14,8 @@ type Config struct {
 	WorkDir    string
 	Seccomp    []syscall.SockFilter
 	CPURate    bool
-	CgroupFd   bool // whether to enable cgroup fd with clone3, kernel >= 5.7
+	CgroupFd   bool     // whether to enable cgroup fd with clone3, kernel >= 5.7
+	BindDir    *BindDir // staging directory mounted into the containers for zero-copy copyIn, nil to copy
 }
 
 type environmentBuilder struct {
@@ -23,6 +25,7 @@ type environmentBuilder struct {
 	seccomp []syscall.SockFilter
 	cpuRate bool
 	cgFd    bool
+	bindDir *BindDir
 }
 
 // NewEnvBuilder creates builder for linux container pools
@@ -34,21 +37,44 @@ func NewEnvBuilder(c Config) pool.EnvBuilder {
 		seccomp: c.Seccomp,
 		cpuRate: c.CPURate,
 		cgFd:    c.CgroupFd,
+		bindDir: c.BindDir,
 	}
 }
 
 // Build creates linux container
 func (b *environmentBuilder) Build() (pool.Environment, error) {
-	m, err := b.builder.Build()
+	builder, cred := b.prepareCred()
+	m, err := builder.Build()
 	if err != nil {
 		return nil, err
 	}
 	return &environ{
 		Environment: m,
+		cred:        cred,
 		cgPool:      b.cgPool,
 		workDir:     b.workDir,
 		cpuRate:     b.cpuRate,
 		seccomp:     b.seccomp,
 		cgFd:        b.cgFd,
+		bindDir:     b.bindDir,
 	}, nil
 }
+
+// prepareCred generates the credential of the container ahead of build so
+// that files could be owned by the user running inside the container
+func (b *environmentBuilder) prepareCred() (EnvironmentBuilder, *syscall.Credential) {
+	cb, ok := b.builder.(*container.Builder)
+	if !ok || cb.CredGenerator == nil {
+		return b.builder, nil
+	}
+	cred := cb.CredGenerator.Get()
+	nb := *cb
+	nb.CredGenerator = fixedCred(cred)
+	return &nb, &cred
+}
+
+type fixedCred syscall.Credential
+
+func (c fixedCred) Get() syscall.Credential {
+	return syscall.Credential(c)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,25 +18,43 @@ import (
 	"github.com/criyle/go-sandbox/runner"
 )
 
-var _ envexec.Environment = &environ{}
+var _ envexec.DirEnvironment = &environ{}
+
+const (
+	walkDirTimeout     = 10 * time.Second
+	walkDirOutputLimit = 64 << 20 // 64 MiB
+)
+
+// helper commands required in the container rootfs by MkdirAll and WalkDir
+const (
+	mkdirRequires   = "/bin/mkdir"
+	walkDirRequires = "/bin/sh, find, stat and readlink"
+)
+
+// ErrHelperNotFound is returned when the helper command for directory
+// operations is missing from the container rootfs (e.g. /bin is not mounted)
+var ErrHelperNotFound = errors.New("helper command not found in the container rootfs")
 
 // environ defines interface to access container resources
 type environ struct {
 	container.Environment
+	cred    *syscall.Credential // host credential of the container user, nil if same as container root
 	cgPool  CgroupPool
 	workDir string
 	seccomp []syscall.SockFilter
 	cpuRate bool
 	cgFd    bool
+	bindDir *BindDir   // nil if bind is not supported
+	bind    *bindStage // files bound since last reset
 }
 
 // Destroy destroys the environment
 func (c *environ) Destroy() error {
-	return c.Environment.Destroy()
+	return errors.Join(c.Environment.Destroy(), c.resetBind())
 }
 
 func (c *environ) Reset() error {
-	return c.Environment.Reset()
+	return errors.Join(c.Environment.Reset(), c.resetBind())
 }
 
 // Execve execute process inside the environment
@@ -142,7 +163,15 @@ func (c *environ) Open(params []envexec.OpenParam) ([]envexec.OpenResult, error)
 		return nil, err
 	}
 	ret := make([]envexec.OpenResult, 0, len(rt))
-	for _, r := range rt {
+	for i, r := range rt {
+		if r.Err == nil && params[i].Owner == envexec.OwnerUser && c.cred != nil {
+			// files are created by container root and the container does
+			// not support chown, thus change the owner through host
+			if err := r.File.Chown(int(c.cred.Uid), int(c.cred.Gid)); err != nil {
+				r.File.Close()
+				r.File, r.Err = nil, fmt.Errorf("chown %q: %w", params[i].Path, err)
+			}
+		}
 		ret = append(ret, envexec.OpenResult{
 			File: r.File,
 			Err:  r.Err,
@@ -162,6 +191,200 @@ func (c *environ) Symlink(params []envexec.SymlinkParam) ([]error, error) {
 	return c.Environment.Symlink(symlink)
 }
 
+// MkdirAll creates directories by running mkdir inside the container. The
+// directories are created in one run and only retried one by one to find out
+// the failed ones when the run fails.
+func (c *environ) MkdirAll(paths []string) ([]error, error) {
+	errs := make([]error, len(paths))
+	if len(paths) == 0 {
+		return errs, nil
+	}
+	err := c.runHelper(append([]string{"/bin/mkdir", "-p", "--"}, paths...), mkdirRequires, nil)
+	if err == nil {
+		return errs, nil
+	}
+	if errors.Is(err, ErrHelperNotFound) {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	for i, p := range paths {
+		if err := c.runHelper([]string{"/bin/mkdir", "-p", "--", p}, mkdirRequires, nil); err != nil {
+			errs[i] = fmt.Errorf("mkdir %q: %w", p, err)
+		}
+	}
+	return errs, nil
+}
+
+// walkDirScript prints entries under $1 in format of "<hex mode> <size>
+// <path>\0<symlink target>\0" with only POSIX sh, find, stat and readlink so
+// that it works with both GNU and busybox. It exits with 127 as the shell does
+// when any of the commands is not found.
+const walkDirScript = `for c in find stat readlink; do
+	command -v "$c" >/dev/null || exit 127
+done
+cd -- "$1" || exit 1
+exec find . -mindepth 1 -exec sh -c '
+for f; do
+	s=$(stat -c "%f %s" -- "$f") || exit 1
+	t=
+	if [ -L "$f" ]; then
+		t=$(readlink -- "$f") || exit 1
+	fi
+	printf "%s %s\0%s\0" "$s" "${f#./}" "$t"
+done' sh {} +`
+
+// WalkDir lists the directory tree by running find inside the container since
+// the container file system is not visible to the host
+func (c *environ) WalkDir(root string) ([]envexec.DirEntry, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	type readResult struct {
+		b   []byte
+		err error
+	}
+	readCh := make(chan readResult, 1)
+	go func() {
+		b, err := io.ReadAll(io.LimitReader(r, walkDirOutputLimit+1))
+		io.Copy(io.Discard, r)
+		readCh <- readResult{b: b, err: err}
+	}()
+
+	err = c.runHelper([]string{"/bin/sh", "-c", walkDirScript, "sh", root}, walkDirRequires, w)
+	w.Close()
+	out := <-readCh
+
+	if err != nil {
+		return nil, fmt.Errorf("walk dir %q: %w", root, err)
+	}
+	if out.err != nil {
+		return nil, fmt.Errorf("walk dir: read output: %w", out.err)
+	}
+	if len(out.b) > walkDirOutputLimit {
+		return nil, fmt.Errorf("walk dir: output exceeds limit (%d)", walkDirOutputLimit)
+	}
+	return parseWalkDirOutput(out.b)
+}
+
+// runHelper runs the helper command inside the container with stdout, it
+// returns error unless the command exits normally with status 0. The commands
+// in requires are reported when the helper is not found.
+func (c *environ) runHelper(args []string, requires string, stdout *os.File) error {
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer null.Close()
+	if stdout == nil {
+		stdout = null
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), walkDirTimeout)
+	defer cancel()
+
+	rLimits := rlimit.RLimits{
+		CPU:         uint64(walkDirTimeout / time.Second),
+		Data:        walkDirOutputLimit,
+		DisableCore: true,
+	}
+	rt := c.Environment.Execve(ctx, container.ExecveParam{
+		Args:          args,
+		Env:           []string{"PATH=/usr/local/bin:/usr/bin:/bin"},
+		Files:         []uintptr{null.Fd(), stdout.Fd(), null.Fd()},
+		RLimits:       rLimits.PrepareRLimit(),
+		Seccomp:       c.seccomp,
+		SyncAfterExec: true,
+	})
+	if rt.Status != runner.StatusNormal || rt.ExitStatus != 0 {
+		e := &helperError{Args: args[:1], Status: rt.Status, ExitStatus: rt.ExitStatus, Err: rt.Error}
+		if helperNotFound(rt) {
+			e.Requires = requires
+		}
+		return e
+	}
+	return nil
+}
+
+// helperNotFound reports whether the helper failed to execve with ENOENT or
+// the shell failed to find a command
+func helperNotFound(rt runner.Result) bool {
+	switch rt.Status {
+	case runner.StatusRunnerError:
+		return strings.Contains(rt.Error, "execve: "+syscall.ENOENT.Error())
+	case runner.StatusNormal:
+		return rt.ExitStatus == 127
+	}
+	return false
+}
+
+// helperError is the failure of a helper command run inside the container
+type helperError struct {
+	Args       []string
+	Status     runner.Status
+	ExitStatus int
+	Err        string
+	Requires   string // set if the helper is not found
+}
+
+func (e *helperError) Error() string {
+	if e.Requires != "" {
+		return fmt.Sprintf("%s: %v: %s must be mounted into the container", e.Args[0], ErrHelperNotFound, e.Requires)
+	}
+	if e.Err != "" {
+		return fmt.Sprintf("%s: %v (exit status %d): %s", e.Args[0], e.Status, e.ExitStatus, e.Err)
+	}
+	return fmt.Sprintf("%s: %v (exit status %d)", e.Args[0], e.Status, e.ExitStatus)
+}
+
+func (e *helperError) Unwrap() error {
+	if e.Requires != "" {
+		return ErrHelperNotFound
+	}
+	return nil
+}
+
+// parseWalkDirOutput parses entries in format of walkDirScript
+func parseWalkDirOutput(b []byte) ([]envexec.DirEntry, error) {
+	fields := strings.Split(string(b), "\x00")
+	if len(fields)%2 != 1 {
+		return nil, fmt.Errorf("walk dir: invalid output")
+	}
+	entries := make([]envexec.DirEntry, 0, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		s := strings.SplitN(fields[i], " ", 3)
+		if len(s) != 3 {
+			return nil, fmt.Errorf("walk dir: invalid entry %q", fields[i])
+		}
+		raw, err := strconv.ParseUint(s[0], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("walk dir: invalid mode %q: %w", s[0], err)
+		}
+		size, err := strconv.ParseInt(s[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("walk dir: invalid size %q: %w", s[1], err)
+		}
+		mode := os.FileMode(raw).Perm()
+		switch raw & syscall.S_IFMT {
+		case syscall.S_IFREG:
+		case syscall.S_IFDIR:
+			mode |= os.ModeDir
+		case syscall.S_IFLNK:
+			mode |= os.ModeSymlink
+		default:
+			mode |= os.ModeIrregular
+		}
+		entries = append(entries, envexec.DirEntry{
+			Path:   s[2],
+			Mode:   mode,
+			Size:   size,
+			Target: fields[i+1],
+		})
+	}
+	return entries, nil
+}
+
 func (c *environ) setCgroupLimit(cg Cgroup, limit envexec.Limit) error {
 	cpuSet := limit.CPUSet
 	if cpuSet != "" {
@@ -17,7 +17,10 @@ import (
 
 const outputLimit = 256 << 20 // 256M
 
-var _ pool.Environment = &environment{}
+var (
+	_ pool.Environment       = &environment{}
+	_ envexec.DirEnvironment = &environment{}
+)
 
 type environment struct {
 	profile string
@@ -165,6 +168,20 @@ func (e *environment) Symlink(params []envexec.SymlinkParam) ([]error, error) {
 	return errs, nil
 }
 
+// MkdirAll creates directories inside the environment
+func (e *environment) MkdirAll(paths []string) ([]error, error) {
+	errs := make([]error, len(paths))
+	for i, p := range paths {
+		errs[i] = os.MkdirAll(filepath.Join(e.wdPath, p), 0755)
+	}
+	return errs, nil
+}
+
+// WalkDir lists the directory tree inside the environment
+func (e *environment) WalkDir(root string) ([]envexec.DirEntry, error) {
+	return envexec.WalkHostDir(filepath.Join(e.wdPath, root))
+}
+
 func (e *environment) Destroy() error {
 	e.wd.Close()
 	return os.RemoveAll(e.wdPath)
@@ -22,8 +22,9 @@ type EnvBuilder interface {
 type pool struct {
 	builder EnvBuilder
 
-	env []Environment
-	mu  sync.Mutex
+	env   []Environment
+	total int // environments built and not destroyed
+	mu    sync.Mutex
 }
 
 // NewPool returns a pool for EnvBuilder
@@ -42,7 +43,12 @@ func (p *pool) Get() (envexec.Environment, error) {
 		p.env = p.env[:len(p.env)-1]
 		return rt, nil
 	}
-	return p.builder.Build()
+	e, err := p.builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	p.total++
+	return e, nil
 }
 
 func (p *pool) Put(env envexec.Environment) {
@@ -53,6 +59,9 @@ func (p *pool) Put(env envexec.Environment) {
 	// If contain died after execution, don't put it into pool and destroy it
 	if err := e.Reset(); err != nil {
 		e.Destroy()
+		p.mu.Lock()
+		p.total--
+		p.mu.Unlock()
 		return
 	}
 
@@ -62,6 +71,17 @@ func (p *pool) Put(env envexec.Environment) {
 	p.env = append(p.env, e)
 }
 
+// Discard destroys the environment taken by Get rather than putting it back
+func (p *pool) Discard(env envexec.Environment) {
+	if e, ok := env.(Environment); ok {
+		e.Destroy()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.total--
+}
+
 func (p *pool) Destroy() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -69,4 +89,16 @@ func (p *pool) Destroy() {
 	for _, e := range p.env {
 		e.Destroy()
 	}
+	p.total -= len(p.env)
+	p.env = nil
+}
+
+func (p *pool) Stat() worker.PoolStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return worker.PoolStat{
+		Idle:  len(p.env),
+		Total: p.total,
+	}
 }
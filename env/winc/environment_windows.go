@@ -19,7 +19,10 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-var _ pool.Environment = &Environment{}
+var (
+	_ pool.Environment       = &Environment{}
+	_ envexec.DirEnvironment = &Environment{}
+)
 
 var (
 	errFileCount = errors.New("windows requires std handle to be 3")
@@ -310,6 +313,20 @@ func (e *Environment) Symlink(params []envexec.SymlinkParam) ([]error, error) {
 	return errs, nil
 }
 
+// MkdirAll creates directories inside the environment
+func (e *Environment) MkdirAll(paths []string) ([]error, error) {
+	errs := make([]error, len(paths))
+	for i, p := range paths {
+		errs[i] = os.MkdirAll(filepath.Join(e.root, p), 0755)
+	}
+	return errs, nil
+}
+
+// WalkDir lists the directory tree inside the environment
+func (e *Environment) WalkDir(root string) ([]envexec.DirEntry, error) {
+	return envexec.WalkHostDir(filepath.Join(e.root, root))
+}
+
 // Destroy destroys the environment
 func (e *Environment) Destroy() error {
 	// error is ignorable for destroy operation
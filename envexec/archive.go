@@ -0,0 +1,194 @@
+package envexec
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1f, 0x8b}
+)
+
+// maxSymlinkTarget limits the size of symlink target stored in zip entry
+const maxSymlinkTarget = 4096
+
+// ArchiveFormat defines the format of archive for directory copyOut
+type ArchiveFormat string
+
+// ArchiveFormat enums
+const (
+	ArchiveTar ArchiveFormat = "tar"
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// ArchiveLimit defines the limits to extract an archive, zero value for unlimited
+type ArchiveLimit struct {
+	Size  Size // Size limits the total uncompressed size of regular files
+	Count int  // Count limits the number of entries
+	Depth int  // Depth limits the number of components of entry path
+}
+
+// WalkArchive calls fn for each entry of the archive in tar, tar.gz or zip
+// format, which is detected by its content. Entries of zip archive are
+// converted into tar headers with content of symlinks as link target.
+func WalkArchive(r io.ReaderAt, size int64, fn func(*tar.Header, io.Reader) error) error {
+	magic := make([]byte, 4)
+	n, err := r.ReadAt(magic, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, zipMagic), bytes.HasPrefix(magic, zipEmptyMagic):
+		return walkZip(r, size, fn)
+
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		return walkTar(gr, fn)
+
+	default:
+		return walkTar(io.NewSectionReader(r, 0, size), fn)
+	}
+}
+
+func walkTar(r io.Reader, fn func(*tar.Header, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(r io.ReaderAt, size int64, fn func(*tar.Header, io.Reader) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if err := walkZipFile(f, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkZipFile(f *zip.File, fn func(*tar.Header, io.Reader) error) error {
+	mode := f.Mode()
+	hdr := &tar.Header{
+		Name:    f.Name,
+		Mode:    int64(mode.Perm()),
+		ModTime: f.Modified,
+	}
+	switch {
+	case mode.IsDir():
+		hdr.Typeflag = tar.TypeDir
+		return fn(hdr, bytes.NewReader(nil))
+
+	case mode&os.ModeSymlink != 0:
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		target, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTarget))
+		if err != nil {
+			return err
+		}
+		hdr.Typeflag = tar.TypeSymlink
+		hdr.Linkname = string(target)
+		return fn(hdr, bytes.NewReader(nil))
+
+	case mode.IsRegular():
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(f.UncompressedSize64)
+		return fn(hdr, rc)
+
+	default:
+		hdr.Typeflag = tar.TypeChar
+		return fn(hdr, bytes.NewReader(nil))
+	}
+}
+
+// CleanArchivePath cleans the entry path inside an archive and rejects
+// entries that escape the extraction root. The root itself is returned as empty
+func CleanArchivePath(name string) (string, error) {
+	p := strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(p) {
+		return "", fmt.Errorf("archive entry %q is absolute", name)
+	}
+	p = path.Clean(p)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("archive entry %q escapes the root", name)
+	}
+	if p == "." {
+		return "", nil
+	}
+	return p, nil
+}
+
+// archiveEntryType returns the normalized type of archive entry, only
+// directories, regular files and symbolic links are supported
+func archiveEntryType(hdr *tar.Header) (byte, error) {
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeRegA:
+		return tar.TypeReg, nil
+	case tar.TypeDir, tar.TypeSymlink, tar.TypeXGlobalHeader:
+		return hdr.Typeflag, nil
+	default:
+		return 0, fmt.Errorf("archive entry %q has unsupported type %q", hdr.Name, hdr.Typeflag)
+	}
+}
+
+// archiveCounter checks the entries against the limits while the archive is
+// read, thus the rest of an archive exceeding them is never read
+type archiveCounter struct {
+	limit ArchiveLimit
+	count int
+	size  int64
+}
+
+// add validates the entry with cleaned name against the limits
+func (c *archiveCounter) add(name string, hdr *tar.Header) error {
+	c.count++
+	if c.limit.Count > 0 && c.count > c.limit.Count {
+		return fmt.Errorf("archive entry count exceeds limit (%d)", c.limit.Count)
+	}
+	if d := strings.Count(name, "/") + 1; c.limit.Depth > 0 && d > c.limit.Depth {
+		return fmt.Errorf("archive entry %q depth (%d) exceeds limit (%d)", name, d, c.limit.Depth)
+	}
+	if hdr.Typeflag == tar.TypeReg {
+		c.size += hdr.Size
+	}
+	if c.limit.Size > 0 && c.size > int64(c.limit.Size) {
+		return fmt.Errorf("archive size exceeds limit (%d)", c.limit.Size)
+	}
+	return nil
+}
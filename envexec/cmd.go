@@ -49,10 +49,23 @@ type Cmd struct {
 	// return true to as TLE and false as normal exits (context finished)
 	Waiter func(context.Context, Process) bool
 
+	// Progress is called when the cmd finishes a stage (optional).
+	// The result without files is provided for StageProcessFinished
+	Progress func(Stage, *Result)
+
 	// file names to copyout after exec
 	CopyOut         []CmdCopyOutFile
-	CopyOutMax      Size // file size limit
+	CopyOutMax      Size   // file size limit, total size limit for files matched by glob
+	CopyOutMaxFiles uint64 // limit number of files matched by glob
 	CopyOutTruncate bool
+	CopyOutDigest   bool // computes size and SHA-256 of copy out and collected files
+
+	// CopyOutTruncateTail keeps the last bytes out of CopyOutMax for truncated
+	// copy out files, 0 keeps the beginning only and CopyOutMax keeps the end only
+	CopyOutTruncateTail Size
+
+	// archive format for copyOut directory, tar if empty
+	CopyOutDirFormat ArchiveFormat
 
 	// additional memory option
 	AddressSpaceLimit bool
@@ -63,6 +76,8 @@ type Cmd struct {
 type CmdCopyOutFile struct {
 	Name     string // Name is the file out to copyOut
 	Optional bool   // Optional ignores the file if not exists
+	Dir      bool   // Dir copies out the directory tree as tar archive
+	Glob     bool   // Glob copies out all regular files matched by Name as pattern
 }
 
 // Result defines the running result for single Cmd
@@ -81,10 +96,30 @@ type Result struct {
 	// Files stores copy out files
 	Files map[string]*os.File
 
+	// FileDigests stores size and SHA-256 of Files if CopyOutDigest is specified
+	FileDigests map[string]FileDigest
+
 	// FileError stores file errors details
 	FileError []FileError
 }
 
+// FileDigest defines the size and SHA-256 of a copy out file
+type FileDigest struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // hex encoded
+}
+
+// Stage defines the execution stage of a cmd reported by Cmd.Progress
+type Stage int
+
+// Stage enums
+const (
+	StageCopyInDone      Stage = iota + 1 // files are copied in
+	StageProcessStarted                   // process is started
+	StageProcessFinished                  // process exits
+	StageCopyOutDone                      // files are copied out and collected
+)
+
 // FileErrorType defines the location that file operation fails
 type FileErrorType int
 
@@ -101,6 +136,8 @@ const (
 	ErrCopyOutCopyContent
 	ErrCollectSizeExceeded
 	ErrSymlink
+	ErrCopyInExtract
+	ErrCopyOutCountExceeded
 )
 
 // FileError defines the location, file name and the detailed message for a failed file operation
@@ -108,6 +145,7 @@ type FileError struct {
 	Name    string        `json:"name"`
 	Type    FileErrorType `json:"type"`
 	Message string        `json:"message,omitempty"`
+	Size    int64         `json:"size,omitempty"` // original size of the truncated file if known
 }
 
 var fileErrorString = []string{
@@ -122,6 +160,8 @@ var fileErrorString = []string{
 	"CopyOutCopyContent",
 	"CollectSizeExceeded",
 	"Symlink",
+	"CopyInExtract",
+	"CopyOutCountExceeded",
 }
 
 var fileErrorStringReverse = make(map[string]FileErrorType)
@@ -17,6 +17,9 @@ var (
 	_ File = &FileCollector{}
 	_ File = &FileWriter{}
 	_ File = &FileOpened{}
+	_ File = &FileDir{}
+	_ File = &FileAttr{}
+	_ File = &FileBind{}
 )
 
 // File defines interface of envexec files
@@ -25,7 +28,7 @@ type File interface {
 }
 
 // FileReader represent file input which can be fully read before exec
-// or piped into exec
+// or piped into exec. The reader is closed once read if it is an io.Closer
 type FileReader struct {
 	Reader io.Reader
 	Stream bool
@@ -198,6 +201,11 @@ type FileCollector struct {
 	Name  string
 	Limit Size
 	Pipe  bool
+	Tail  Size // Tail keeps the last bytes out of Limit when the output exceeds Limit
+
+	// Live receives a copy of the collected output as it is written (pipe is
+	// used), it should not block nor fail
+	Live io.Writer
 }
 
 func (*FileCollector) isFile() {}
@@ -232,6 +240,65 @@ func NewFileOpened(f *os.File) File {
 	return &FileOpened{File: f}
 }
 
+// FileDir represent a directory tree archived in tar, tar.gz or zip format
+// which will be extracted to the target directory with file modes and
+// symlinks preserved
+type FileDir struct {
+	Archive File
+	Limit   ArchiveLimit
+}
+
+func (*FileDir) isFile() {}
+
+// NewFileDir creates directory tree from archive
+func NewFileDir(archive File) File {
+	return &FileDir{Archive: archive}
+}
+
+// FileAttr represent a copyIn file with its mode, owner and read-only flag.
+// Read-only file has all write permission bits removed
+type FileAttr struct {
+	File     File
+	Mode     os.FileMode // Mode of the file, 0 for default
+	Owner    FileOwner
+	ReadOnly bool
+}
+
+func (*FileAttr) isFile() {}
+
+// NewFileAttr creates copyIn file with attributes
+func NewFileAttr(f File, mode os.FileMode, owner FileOwner, readOnly bool) File {
+	return &FileAttr{File: f, Mode: mode, Owner: owner, ReadOnly: readOnly}
+}
+
+// FileBind represent a read-only copyIn file exposed to the program without
+// copying its content when the environment implements BindEnvironment, it is
+// copied in as usual otherwise
+type FileBind struct {
+	File File
+}
+
+func (*FileBind) isFile() {}
+
+// NewFileBind creates zero-copy copyIn file
+func NewFileBind(f File) File {
+	return &FileBind{File: f}
+}
+
+// perm returns the permission bits of the file to be set after copy
+func (f *FileAttr) perm() os.FileMode {
+	switch {
+	case f.Mode != 0 && f.ReadOnly:
+		return f.Mode.Perm() &^ 0222
+	case f.Mode != 0:
+		return f.Mode.Perm()
+	case f.ReadOnly:
+		return 0444
+	default:
+		return defaultCopyInPerm
+	}
+}
+
 // FileToReader get a Reader from underlying file
 // the reader need to be closed by caller explicitly
 func FileToReader(f File) (io.ReadCloser, error) {
@@ -240,6 +307,9 @@ func FileToReader(f File) (io.ReadCloser, error) {
 		return f.File, nil
 
 	case *FileReader:
+		if rc, ok := f.Reader.(io.ReadCloser); ok {
+			return rc, nil
+		}
 		return io.NopCloser(f.Reader), nil
 
 	case *FileInput:
@@ -254,6 +324,13 @@ func FileToReader(f File) (io.ReadCloser, error) {
 	}
 }
 
+// closeReader closes the reader of FileReader if it is an io.Closer
+func closeReader(r io.Reader) {
+	if c, ok := r.(io.Closer); ok {
+		c.Close()
+	}
+}
+
 type sharedFile struct {
 	mu    sync.Mutex
 	f     *os.File
@@ -0,0 +1,107 @@
+package envexec
+
+import (
+	"errors"
+	"fmt"
+	"path"
+)
+
+// bindCopyIn binds FileBind files into the environment without copying their
+// content. Files are returned to be copied in as usual when the environment or
+// the file does not support it.
+func bindCopyIn(m Environment, copyIn map[string]File) (map[string]File, []FileError, error) {
+	rest := make(map[string]File, len(copyIn))
+	var binds []string
+	for n, f := range copyIn {
+		if _, ok := f.(*FileBind); ok {
+			binds = append(binds, n)
+			continue
+		}
+		rest[n] = f
+	}
+	if len(binds) == 0 {
+		return copyIn, nil, nil
+	}
+
+	bm, ok := m.(BindEnvironment)
+	if !ok {
+		for _, n := range binds {
+			rest[n] = copyIn[n].(*FileBind).File
+		}
+		return rest, nil, nil
+	}
+
+	var (
+		params []BindParam
+		dirs   []string
+	)
+	defer func() {
+		for _, p := range params {
+			p.File.Close()
+		}
+	}()
+	// the source is read once copied in, thus it is closed only if bound
+	closeSource := func(n string) {
+		if r, ok := copyIn[n].(*FileBind).File.(*FileReader); ok {
+			closeReader(r.Reader)
+		}
+	}
+	fail := func(fe []FileError, err error) (map[string]File, []FileError, error) {
+		for _, p := range params {
+			closeSource(p.Path)
+		}
+		return nil, fe, err
+	}
+	for _, n := range binds {
+		inner := copyIn[n].(*FileBind).File
+		f, err := bindFile(inner)
+		if err != nil {
+			// fallback to copy
+			rest[n] = inner
+			continue
+		}
+		params = append(params, BindParam{Path: n, File: f})
+		if d := path.Dir(n); d != "." && d != "/" {
+			dirs = append(dirs, d)
+		}
+	}
+	if len(params) == 0 {
+		return rest, nil, nil
+	}
+
+	if dm, ok := m.(DirEnvironment); ok && len(dirs) > 0 {
+		errs, err := dm.MkdirAll(dirs)
+		if err != nil {
+			return fail(nil, fmt.Errorf("copyin: bind mkdir failed: %w", err))
+		}
+		for i, err := range errs {
+			if err != nil {
+				return fail([]FileError{{Name: dirs[i], Type: ErrCopyInCreateDir, Message: err.Error()}}, err)
+			}
+		}
+	}
+	errs, err := bm.Bind(params)
+	if err != nil {
+		return fail(nil, fmt.Errorf("copyin: bind failed: %w", err))
+	}
+	var fe []FileError
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			closeSource(params[i].Path)
+		case errors.Is(err, ErrBindNotSupported):
+			rest[params[i].Path] = copyIn[params[i].Path].(*FileBind).File
+		default:
+			closeSource(params[i].Path)
+			fe = append(fe, FileError{
+				Name:    params[i].Path,
+				Type:    ErrCopyInCreateFile,
+				Message: fmt.Sprintf("copyin: bind %q: %s", params[i].Path, err.Error()),
+			})
+		}
+	}
+	if len(fe) > 0 {
+		return nil, fe, fmt.Errorf("copyin: bind failed: %s", fe[0].Message)
+	}
+	return rest, nil, nil
+}
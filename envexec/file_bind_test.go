@@ -0,0 +1,139 @@
+package envexec
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-sandbox/pkg/memfd"
+)
+
+type bindStubEnvironment struct {
+	stubEnvironment
+	bindFunc func([]BindParam) ([]error, error)
+}
+
+func (s bindStubEnvironment) Bind(params []BindParam) ([]error, error) {
+	return s.bindFunc(params)
+}
+
+func writeBindInput(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestBindCopyInFallback(t *testing.T) {
+	files := map[string]File{
+		"a": NewFileBind(NewFileReader(strings.NewReader("a"))),
+		"b": NewFileReader(strings.NewReader("b")),
+	}
+	rest, fe, err := bindCopyIn(stubEnvironment{}, files)
+	if err != nil || len(fe) > 0 {
+		t.Fatalf("unexpected error: %v %v", err, fe)
+	}
+	if len(rest) != 2 {
+		t.Fatalf("expected all files copied, got %d", len(rest))
+	}
+	if _, ok := rest["a"].(*FileReader); !ok {
+		t.Fatalf("expected unwrapped file, got %T", rest["a"])
+	}
+}
+
+func TestBindCopyInNotSupported(t *testing.T) {
+	env := bindStubEnvironment{bindFunc: func(p []BindParam) ([]error, error) {
+		errs := make([]error, len(p))
+		for i := range errs {
+			errs[i] = ErrBindNotSupported
+		}
+		return errs, nil
+	}}
+	files := map[string]File{
+		"in.txt": NewFileBind(NewFileInput(writeBindInput(t, "input"))),
+	}
+	rest, fe, err := bindCopyIn(env, files)
+	if err != nil || len(fe) > 0 {
+		t.Fatalf("unexpected error: %v %v", err, fe)
+	}
+	if _, ok := rest["in.txt"].(*FileInput); !ok {
+		t.Fatalf("expected fallback to copy, got %T", rest["in.txt"])
+	}
+}
+
+func TestBindCopyIn(t *testing.T) {
+	var bound []string
+	env := bindStubEnvironment{bindFunc: func(p []BindParam) ([]error, error) {
+		for _, b := range p {
+			c, err := io.ReadAll(b.File)
+			if err != nil {
+				return nil, err
+			}
+			bound = append(bound, b.Path+":"+string(c))
+		}
+		return make([]error, len(p)), nil
+	}}
+	files := map[string]File{
+		"in.txt": NewFileBind(NewFileInput(writeBindInput(t, "input"))),
+		"b":      NewFileReader(strings.NewReader("b")),
+	}
+	rest, fe, err := bindCopyIn(env, files)
+	if err != nil || len(fe) > 0 {
+		t.Fatalf("unexpected error: %v %v", err, fe)
+	}
+
+	if runtime.GOOS != "linux" {
+		if len(bound) != 0 || len(rest) != 2 {
+			t.Fatalf("expected fallback to copy, got bound %v rest %d", bound, len(rest))
+		}
+		return
+	}
+	if len(rest) != 1 || rest["b"] == nil {
+		t.Fatalf("expected only unbound file copied, got %v", rest)
+	}
+	if len(bound) != 1 || bound[0] != "in.txt:input" {
+		t.Fatalf("unexpected bound %v", bound)
+	}
+}
+
+func TestBindCopyInError(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("bind is linux only")
+	}
+	env := bindStubEnvironment{bindFunc: func(p []BindParam) ([]error, error) {
+		return []error{errors.New("exists")}, nil
+	}}
+	files := map[string]File{
+		"in.txt": NewFileBind(NewFileInput(writeBindInput(t, "input"))),
+	}
+	_, fe, err := bindCopyIn(env, files)
+	if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyInCreateFile || fe[0].Name != "in.txt" {
+		t.Fatalf("expected file error, got %v %v", err, fe)
+	}
+}
+
+func TestBindFileSharesSealedMemfd(t *testing.T) {
+	mf, err := memfd.DupToMemfd("test", strings.NewReader("content"))
+	if err != nil {
+		t.Skipf("memfd not supported: %v", err)
+	}
+	defer mf.Close()
+
+	f, err := bindFile(NewFileReader(io.NewSectionReader(mf, 0, 7)))
+	if err != nil {
+		t.Fatalf("bindFile error: %v", err)
+	}
+	defer f.Close()
+
+	s1, _ := mf.Stat()
+	s2, _ := f.Stat()
+	if !os.SameFile(s1, s2) {
+		t.Fatalf("expected sealed memfd shared without copy")
+	}
+}
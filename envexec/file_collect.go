@@ -10,8 +10,9 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// copyOutAndCollect reads file and pipes in parallel from container
-func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile NewStoreFile) (map[string]*os.File, []FileError, error) {
+// copyOutAndCollect reads file and pipes in parallel from container, and
+// computes digests of the collected files when required
+func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile NewStoreFile) (map[string]*os.File, map[string]FileDigest, []FileError, error) {
 	var (
 		g         errgroup.Group
 		l, le     sync.Mutex
@@ -45,19 +46,41 @@ func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile
 		})
 	}
 	waitErr := g.Wait()
+
+	var digests map[string]FileDigest
+	if c.CopyOutDigest {
+		digests = digestFiles(rt, addError)
+	}
 	if copyOutErr != nil {
-		return rt, fileError, copyOutErr
+		return rt, digests, fileError, copyOutErr
 	}
-	return rt, fileError, waitErr
+	return rt, digests, fileError, waitErr
 }
 
 func copyOutFiles(g *errgroup.Group, m Environment, c *Cmd, newStoreFile NewStoreFile, put func(*os.File, string), addError func(FileError)) error {
-	if len(c.CopyOut) == 0 {
+	var (
+		copyOut []CmdCopyOutFile
+		dirs    []CmdCopyOutFile
+		globs   []CmdCopyOutFile
+	)
+	for _, n := range c.CopyOut {
+		switch {
+		case n.Dir:
+			dirs = append(dirs, n)
+		case n.Glob:
+			globs = append(globs, n)
+		default:
+			copyOut = append(copyOut, n)
+		}
+	}
+	copyOutDirs(g, m, c, dirs, newStoreFile, put, addError)
+	copyOutGlobs(g, m, c, globs, newStoreFile, put, addError)
+	if len(copyOut) == 0 {
 		return nil
 	}
 
-	cmds := make([]OpenParam, 0, len(c.CopyOut))
-	for _, n := range c.CopyOut {
+	cmds := make([]OpenParam, 0, len(copyOut))
+	for _, n := range copyOut {
 		cmds = append(cmds, OpenParam{
 			Path: n.Name,
 			Flag: os.O_RDONLY,
@@ -67,7 +90,7 @@ func copyOutFiles(g *errgroup.Group, m Environment, c *Cmd, newStoreFile NewStor
 
 	results, err := m.Open(cmds)
 	if err != nil {
-		for _, n := range c.CopyOut {
+		for _, n := range copyOut {
 			addError(FileError{
 				Name:    n.Name,
 				Type:    ErrCopyOutOpen,
@@ -78,7 +101,7 @@ func copyOutFiles(g *errgroup.Group, m Environment, c *Cmd, newStoreFile NewStor
 	}
 
 	for i, res := range results {
-		n := c.CopyOut[i]
+		n := copyOut[i]
 
 		if res.Err != nil {
 			// IPC method cannot differentiate OS.NotExists, thus ignore error if optional
@@ -112,12 +135,14 @@ func copyOutFileStream(
 	addError func(FileError),
 ) (err error) {
 	t := ErrCopyOutOpen
+	var size int64
 	defer func() {
 		if err != nil {
 			addError(FileError{
 				Name:    n.Name,
 				Type:    t,
 				Message: err.Error(),
+				Size:    size,
 			})
 		}
 	}()
@@ -141,6 +166,7 @@ func copyOutFileStream(
 			t = ErrCopyOutSizeExceeded
 			return fmt.Errorf("copyout: %q size (%d) exceeds limit (%d)", n.Name, s, c.CopyOutMax)
 		}
+		size = s
 		s = int64(c.CopyOutMax)
 		limitExceeded = true
 	}
@@ -153,7 +179,12 @@ func copyOutFileStream(
 	}
 
 	// Stream content
-	written, err := buf.ReadFrom(io.LimitReader(cf, s))
+	var written int64
+	if limitExceeded && c.CopyOutTruncateTail > 0 {
+		_, err = copyFileTail(buf, cf, size, c.CopyOutMax, c.CopyOutTruncateTail)
+	} else {
+		written, err = buf.ReadFrom(io.LimitReader(cf, s))
+	}
 	if err != nil {
 		t = ErrCopyOutCopyContent
 		buf.Close()
@@ -176,17 +207,34 @@ func collectPipe(
 	addError func(FileError),
 ) (err error) {
 	errType := ErrCopyOutOpen
+	var size int64
 	defer func() {
 		if err != nil {
 			addError(FileError{
 				Name:    p.name,
 				Type:    errType,
 				Message: err.Error(),
+				Size:    size,
 			})
 		}
 	}()
 	<-p.done
 	if p.storage {
+		if p.tailWriter != nil {
+			// the kept tail is written before the buffer is handed out
+			n, err := p.tailWriter.finish()
+			put(p.buffer, p.name)
+			if err != nil {
+				errType = ErrCopyOutCopyContent
+				return fmt.Errorf("collect: failed to copy content for %q: %w", p.name, err)
+			}
+			if n > int64(p.limit) {
+				size = n
+				errType = ErrCollectSizeExceeded
+				return runner.StatusOutputLimitExceeded
+			}
+			return nil
+		}
 		put(p.buffer, p.name)
 		if fi, err := p.buffer.Stat(); err == nil && fi.Size() > int64(p.limit) {
 			p.buffer.Truncate(int64(p.limit) + 1)
@@ -195,23 +243,120 @@ func collectPipe(
 		}
 	} else {
 		defer p.buffer.Close()
+		fi, err := p.buffer.Stat()
+		if err != nil {
+			return fmt.Errorf("collect: stat %q: %w", p.name, err)
+		}
 		buf, err := newStoreFile()
 		if err != nil {
 			errType = ErrCopyOutCreateFile
 			return fmt.Errorf("collect: failed to create store file for %q: %w", p.name, err)
 		}
 		// Ensure not copy over file size
-		_, err = buf.ReadFrom(io.LimitReader(p.buffer, int64(p.limit)+1))
+		if p.tail > 0 && fi.Size() > int64(p.limit) {
+			_, err = copyFileTail(buf, p.buffer, fi.Size(), p.limit, p.tail)
+		} else {
+			_, err = buf.ReadFrom(io.LimitReader(p.buffer, int64(p.limit)+1))
+		}
 		if err != nil {
 			errType = ErrCopyOutCopyContent
 			buf.Close()
 			return fmt.Errorf("collect: failed to copy content for %q: %w", p.name, err)
 		}
 		put(buf, p.name)
-		if fi, err := p.buffer.Stat(); err == nil && fi.Size() > int64(p.limit) {
+		if fi.Size() > int64(p.limit) {
+			size = fi.Size()
 			errType = ErrCollectSizeExceeded
 			return runner.StatusOutputLimitExceeded
 		}
 	}
 	return nil
 }
+
+// elidedMarker replaces the elided bytes between the head and the tail of the
+// truncated output
+const elidedMarker = "\n... %d bytes elided ...\n"
+
+// tailWriter writes the first limit - tail bytes to w and keeps the last tail
+// bytes in memory. finish appends the kept bytes after the marker if any
+// bytes are elided, so that at most limit bytes of content are kept.
+type tailWriter struct {
+	w    io.Writer
+	head int64
+	ring []byte
+	pos  int   // next write position in ring
+	n    int64 // total bytes written
+}
+
+func newTailWriter(w io.Writer, limit, tail Size) *tailWriter {
+	tail = min(tail, limit)
+	return &tailWriter{w: w, head: int64(limit - tail), ring: make([]byte, tail)}
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	l := len(p)
+	if t.n < t.head {
+		k := min(int64(len(p)), t.head-t.n)
+		if _, err := t.w.Write(p[:k]); err != nil {
+			return 0, err
+		}
+		t.n += k
+		p = p[k:]
+	}
+	t.n += int64(len(p))
+	if len(p) >= len(t.ring) {
+		copy(t.ring, p[len(p)-len(t.ring):])
+		t.pos = 0
+		return l, nil
+	}
+	for len(p) > 0 {
+		k := copy(t.ring[t.pos:], p)
+		t.pos = (t.pos + k) % len(t.ring)
+		p = p[k:]
+	}
+	return l, nil
+}
+
+// skip counts n bytes as written without keeping them, it must be followed by
+// at least len(ring) bytes written
+func (t *tailWriter) skip(n int64) {
+	t.n += n
+}
+
+// finish writes the kept tail and returns the original size
+func (t *tailWriter) finish() (int64, error) {
+	rest := t.n - t.head
+	if rest <= 0 {
+		return t.n, nil
+	}
+	if rest < int64(len(t.ring)) {
+		_, err := t.w.Write(t.ring[:rest])
+		return t.n, err
+	}
+	if rest > int64(len(t.ring)) {
+		if _, err := fmt.Fprintf(t.w, elidedMarker, rest-int64(len(t.ring))); err != nil {
+			return t.n, err
+		}
+	}
+	if _, err := t.w.Write(t.ring[t.pos:]); err != nil {
+		return t.n, err
+	}
+	_, err := t.w.Write(t.ring[:t.pos])
+	return t.n, err
+}
+
+// copyFileTail copies the head and the tail of the file with the given size
+// to w with only the kept parts read
+func copyFileTail(w io.Writer, r io.ReaderAt, size int64, limit, tail Size) (int64, error) {
+	tw := newTailWriter(w, limit, tail)
+	head := min(tw.head, size)
+	if _, err := io.Copy(tw, io.NewSectionReader(r, 0, head)); err != nil {
+		return 0, err
+	}
+	keep := min(size-head, int64(len(tw.ring)))
+	tw.skip(size - head - keep)
+	if _, err := io.Copy(tw, io.NewSectionReader(r, size-keep, keep)); err != nil {
+		return 0, err
+	}
+	return tw.finish()
+}
@@ -0,0 +1,144 @@
+package envexec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-sandbox/runner"
+)
+
+func TestTailWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		limit, tail Size
+		chunk       int
+		want        string
+	}{
+		{"fit", "abcdef", 10, 4, 1, "abcdef"},
+		{"exact", "abcdefghij", 10, 4, 3, "abcdefghij"},
+		{"head and tail", "abcdefghijkl", 6, 3, 1, "abc\n... 6 bytes elided ...\njkl"},
+		{"tail only", "abcdefghijkl", 4, 4, 5, "\n... 8 bytes elided ...\nijkl"},
+		{"large write", "abcdefghijkl", 6, 3, 100, "abc\n... 6 bytes elided ...\njkl"},
+		{"tail over limit", "abcdefghijkl", 2, 4, 2, "\n... 10 bytes elided ...\nkl"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := newTailWriter(&buf, tc.limit, tc.tail)
+			for s := tc.input; len(s) > 0; {
+				k := min(tc.chunk, len(s))
+				if _, err := tw.Write([]byte(s[:k])); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+				s = s[k:]
+			}
+			n, err := tw.finish()
+			if err != nil {
+				t.Fatalf("finish: %v", err)
+			}
+			if n != int64(len(tc.input)) {
+				t.Errorf("expected size %d, got %d", len(tc.input), n)
+			}
+			if buf.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestCopyFileTail(t *testing.T) {
+	input := strings.Repeat("a", 100) + strings.Repeat("b", 1000) + "end"
+	var buf bytes.Buffer
+	n, err := copyFileTail(&buf, strings.NewReader(input), int64(len(input)), 10, 5)
+	if err != nil {
+		t.Fatalf("copyFileTail: %v", err)
+	}
+	if n != int64(len(input)) {
+		t.Errorf("expected size %d, got %d", len(input), n)
+	}
+	if want := "aaaaa\n... 1093 bytes elided ...\nbbend"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestCollectPipeTail(t *testing.T) {
+	dir := t.TempDir()
+	newStoreFile := func() (*os.File, error) {
+		return os.CreateTemp(dir, "")
+	}
+	b, err := newPipeBuffer(8, 4, 1<<20, nil, newStoreFile)
+	if err != nil {
+		t.Fatalf("newPipeBuffer: %v", err)
+	}
+	if _, err := io.WriteString(b.W, strings.Repeat("x", 1<<16)+"stderr end"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	b.W.Close()
+
+	var (
+		got *os.File
+		fe  []FileError
+	)
+	p := pipeCollector{b.Done, b.Buffer, b.Limit, "stderr", true, 4, b.Tail}
+	err = collectPipe(p, newStoreFile, func(f *os.File, _ string) { got = f }, func(e FileError) { fe = append(fe, e) })
+	if !errors.Is(err, runner.StatusOutputLimitExceeded) {
+		t.Fatalf("expected output limit exceeded, got %v", err)
+	}
+	defer got.Close()
+	if len(fe) != 1 || fe[0].Type != ErrCollectSizeExceeded || fe[0].Size != 1<<16+10 {
+		t.Fatalf("unexpected file error %+v", fe)
+	}
+	c, err := os.ReadFile(got.Name())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := "xxxx\n... 65538 bytes elided ...\n end"; string(c) != want {
+		t.Fatalf("expected %q, got %q", want, c)
+	}
+}
+
+func TestCollectPipeTailReadLimit(t *testing.T) {
+	dir := t.TempDir()
+	newStoreFile := func() (*os.File, error) {
+		return os.CreateTemp(dir, "")
+	}
+	b, err := newPipeBuffer(8, 4, 16, nil, newStoreFile)
+	if err != nil {
+		t.Fatalf("newPipeBuffer: %v", err)
+	}
+	// reading stops at the output limit while the writer is still open
+	if _, err := io.WriteString(b.W, strings.Repeat("x", 12)+"tail"+strings.Repeat("y", 1<<10)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	defer b.W.Close()
+
+	var (
+		got string
+		fe  []FileError
+	)
+	p := pipeCollector{b.Done, b.Buffer, b.Limit, "stderr", true, 4, b.Tail}
+	put := func(f *os.File, _ string) {
+		// the buffer is complete once handed out
+		c, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Errorf("read: %v", err)
+		}
+		got = string(c)
+		f.Close()
+	}
+	err = collectPipe(p, newStoreFile, put, func(e FileError) { fe = append(fe, e) })
+	if !errors.Is(err, runner.StatusOutputLimitExceeded) {
+		t.Fatalf("expected output limit exceeded, got %v", err)
+	}
+	if len(fe) != 1 || fe[0].Size != 16 {
+		t.Fatalf("unexpected file error %+v", fe)
+	}
+	if want := "xxxx\n... 8 bytes elided ...\ntail"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
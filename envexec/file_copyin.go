@@ -9,7 +9,10 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-const fileCopyParallelism = 8
+const (
+	fileCopyParallelism = 8
+	defaultCopyInPerm   = 0777
+)
 
 // copyIn copied file from host to container in parallel
 func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
@@ -22,14 +25,19 @@ func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
 
 	names := make([]string, 0, len(copyIn))
 	cmds := make([]OpenParam, 0, len(copyIn))
-	for n := range copyIn {
-		names = append(names, n)
-		cmds = append(cmds, OpenParam{
+	for n, f := range copyIn {
+		p := OpenParam{
 			Path:     n,
 			Flag:     os.O_CREATE | os.O_WRONLY | os.O_TRUNC,
-			Perm:     0777,
+			Perm:     defaultCopyInPerm,
 			MkdirAll: true,
-		})
+		}
+		if a, ok := f.(*FileAttr); ok {
+			p.Perm = a.perm()
+			p.Owner = a.Owner
+		}
+		names = append(names, n)
+		cmds = append(cmds, p)
 	}
 
 	results, err := m.Open(cmds)
@@ -64,6 +72,10 @@ func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
 			remoteFile := res.File
 			defer remoteFile.Close()
 
+			attr, hasAttr := sourceFile.(*FileAttr)
+			if hasAttr {
+				sourceFile = attr.File
+			}
 			hf, err := FileToReader(sourceFile)
 			if err != nil {
 				mu.Lock()
@@ -83,6 +95,18 @@ func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
 				mu.Unlock()
 				return err
 			}
+
+			// permission set by open is subjected to umask
+			if hasAttr {
+				if err := remoteFile.Chmod(attr.perm()); err != nil {
+					mu.Lock()
+					fileErrors = append(fileErrors, FileError{
+						Name: fileName, Type: ErrCopyInCreateFile, Message: err.Error(),
+					})
+					mu.Unlock()
+					return err
+				}
+			}
 			return nil
 		})
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -82,3 +83,44 @@ func TestCopyOutFilesSkipsEmptyBatch(t *testing.T) {
 		t.Fatalf("expected no files, got %d", len(gotFiles))
 	}
 }
+
+func TestCopyInFileAttr(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	fileErrors, err := copyIn(env, map[string]File{
+		"ans.txt":  NewFileAttr(NewFileReader(strings.NewReader("ans")), 0, OwnerRoot, true),
+		"run.sh":   NewFileAttr(NewFileReader(strings.NewReader("run")), 0750, OwnerRoot, false),
+		"data.txt": NewFileAttr(NewFileReader(strings.NewReader("data")), 0664, OwnerRoot, true),
+	})
+	if err != nil {
+		t.Fatalf("copyIn error: %v %v", err, fileErrors)
+	}
+	for name, want := range map[string]os.FileMode{
+		"ans.txt":  0444,
+		"run.sh":   0750,
+		"data.txt": 0444,
+	} {
+		fi, err := os.Stat(filepath.Join(env.root, name))
+		if err != nil {
+			t.Fatalf("Stat error: %v", err)
+		}
+		if fi.Mode().Perm() != want {
+			t.Errorf("expected %s mode %v, got %v", name, want, fi.Mode().Perm())
+		}
+	}
+}
+
+func TestCopyInFileAttrOwner(t *testing.T) {
+	var got []OpenParam
+	env := stubEnvironment{
+		openFunc: func(params []OpenParam) ([]OpenResult, error) {
+			got = params
+			return nil, errors.New("open failed")
+		},
+	}
+	copyIn(env, map[string]File{
+		"out.txt": NewFileAttr(NewFileReader(strings.NewReader("out")), 0, OwnerUser, false),
+	})
+	if len(got) != 1 || got[0].Owner != OwnerUser || got[0].Perm != defaultCopyInPerm {
+		t.Fatalf("expected open with user owner, got %+v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package envexec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// digestFiles computes size and SHA-256 of the collected files in parallel.
+// Files are read with ReadAt so that their offsets are not changed.
+func digestFiles(files map[string]*os.File, addError func(FileError)) map[string]FileDigest {
+	var (
+		g  errgroup.Group
+		mu sync.Mutex
+	)
+	g.SetLimit(fileCopyParallelism)
+	rt := make(map[string]FileDigest, len(files))
+	for name, f := range files {
+		if f == nil {
+			continue
+		}
+		g.Go(func() error {
+			d, err := digestFile(f)
+			if err != nil {
+				addError(FileError{
+					Name:    name,
+					Type:    ErrCopyOutCopyContent,
+					Message: fmt.Sprintf("digest %q: %v", name, err),
+				})
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			rt[name] = d
+			return nil
+		})
+	}
+	g.Wait()
+	return rt
+}
+
+func digestFile(f *os.File) (FileDigest, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return FileDigest{}, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, io.NewSectionReader(f, 0, fi.Size()))
+	if err != nil {
+		return FileDigest{}, err
+	}
+	return FileDigest{Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
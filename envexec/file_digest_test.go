@@ -0,0 +1,39 @@
+package envexec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDigestFiles(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var fe []FileError
+	d := digestFiles(map[string]*os.File{"stdout": f}, func(e FileError) {
+		fe = append(fe, e)
+	})
+	if len(fe) > 0 {
+		t.Fatalf("unexpected file error %v", fe)
+	}
+	sum := sha256.Sum256([]byte("hello"))
+	want := FileDigest{Size: 5, SHA256: hex.EncodeToString(sum[:])}
+	if d["stdout"] != want {
+		t.Fatalf("expected %+v, got %+v", want, d["stdout"])
+	}
+
+	// offset is not changed by digest
+	if off, _ := f.Seek(0, io.SeekCurrent); off != 5 {
+		t.Fatalf("unexpected offset %d", off)
+	}
+}
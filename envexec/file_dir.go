@@ -0,0 +1,489 @@
+package envexec
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// splitDirs separates directory trees from copyIn files
+func splitDirs(copyIn map[string]File) (map[string]File, map[string]*FileDir) {
+	var dirs map[string]*FileDir
+	files := make(map[string]File, len(copyIn))
+	for n, f := range copyIn {
+		if d, ok := f.(*FileDir); ok {
+			if dirs == nil {
+				dirs = make(map[string]*FileDir)
+			}
+			dirs[n] = d
+			continue
+		}
+		files[n] = f
+	}
+	return files, dirs
+}
+
+// copyInDirs extracts directory trees into the container
+func copyInDirs(m Environment, dirs map[string]*FileDir) ([]FileError, error) {
+	var fileErrors []FileError
+	for n, d := range dirs {
+		fileErrors = append(fileErrors, copyInDir(m, n, d)...)
+	}
+	if len(fileErrors) > 0 {
+		return fileErrors, fmt.Errorf("copyin: %d directory operations failed", len(fileErrors))
+	}
+	return nil, nil
+}
+
+// copyInDir extracts the archive into dir. The archive is read twice, the
+// first pass collects the tree structure to batch open all files in the
+// container and the second pass copies file content
+func copyInDir(m Environment, dir string, d *FileDir) []FileError {
+	dirError := func(t FileErrorType, err error) []FileError {
+		return []FileError{{Name: dir, Type: t, Message: err.Error()}}
+	}
+
+	ra, size, closer, err := openArchive(d.Archive)
+	if err != nil {
+		return dirError(ErrCopyInOpenFile, fmt.Errorf("copyin: open archive for %q: %w", dir, err))
+	}
+	defer closer.Close()
+
+	var (
+		entries []*tar.Header
+		counter = archiveCounter{limit: d.Limit}
+	)
+	seen := make(map[string]byte)
+	if err := WalkArchive(ra, size, func(hdr *tar.Header, _ io.Reader) error {
+		name, err := CleanArchivePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag, err = archiveEntryType(hdr); err != nil {
+			return err
+		}
+		// skipped entries are counted as well to bound the work
+		if err := counter.add(name, hdr); err != nil {
+			return err
+		}
+		if name == "" || hdr.Typeflag == tar.TypeXGlobalHeader {
+			return nil
+		}
+		// files are opened by name, thus only directories could repeat
+		if t, ok := seen[name]; ok && (t != tar.TypeDir || hdr.Typeflag != tar.TypeDir) {
+			return fmt.Errorf("archive entry %q is duplicated", hdr.Name)
+		}
+		seen[name] = hdr.Typeflag
+		hdr.Name = name
+		entries = append(entries, hdr)
+		return nil
+	}); err != nil {
+		return dirError(ErrCopyInExtract, fmt.Errorf("copyin: read archive for %q: %w", dir, err))
+	}
+
+	var (
+		dirs     = []string{dir}
+		cmds     []OpenParam
+		regular  []*tar.Header
+		symlinks = make(map[string]string)
+	)
+	for _, hdr := range entries {
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dirs = append(dirs, path.Join(dir, hdr.Name))
+		case tar.TypeReg:
+			regular = append(regular, hdr)
+			cmds = append(cmds, OpenParam{
+				Path:     path.Join(dir, hdr.Name),
+				Flag:     os.O_CREATE | os.O_WRONLY | os.O_TRUNC,
+				Perm:     os.FileMode(hdr.Mode).Perm(),
+				MkdirAll: true,
+			})
+		case tar.TypeSymlink:
+			dirs = append(dirs, path.Join(dir, path.Dir(hdr.Name)))
+			symlinks[path.Join(dir, hdr.Name)] = hdr.Linkname
+		}
+	}
+
+	// parent directories of regular files are created by open, thus only
+	// empty directories and parents of symlinks depend on the environment
+	var fileErrors []FileError
+	if dm, ok := m.(DirEnvironment); ok {
+		errs, err := dm.MkdirAll(dirs)
+		if err != nil {
+			return dirError(ErrCopyInCreateDir, fmt.Errorf("copyin: batch mkdir failed: %w", err))
+		}
+		for i, err := range errs {
+			if err != nil {
+				fileErrors = append(fileErrors, FileError{
+					Name: dirs[i], Type: ErrCopyInCreateDir, Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	var results []OpenResult
+	if len(cmds) > 0 {
+		var err error
+		if results, err = m.Open(cmds); err != nil {
+			return dirError(ErrCopyInCreateFile, fmt.Errorf("copyin: batch open failed: %w", err))
+		}
+	}
+
+	files := make(map[string]*os.File, len(regular))
+	for i, r := range results {
+		name := regular[i].Name
+		if r.Err != nil {
+			fileErrors = append(fileErrors, FileError{
+				Name: path.Join(dir, name), Type: ErrCopyInCreateFile, Message: r.Err.Error(),
+			})
+			continue
+		}
+		files[name] = r.File
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	if err := WalkArchive(ra, size, func(hdr *tar.Header, r io.Reader) error {
+		name, _ := CleanArchivePath(hdr.Name)
+		f, ok := files[name]
+		if !ok {
+			return nil
+		}
+		delete(files, name)
+		defer f.Close()
+
+		// size is checked against limit thus content is bounded by it
+		if _, err := io.Copy(f, io.LimitReader(r, hdr.Size)); err != nil {
+			return fmt.Errorf("%q: %w", name, err)
+		}
+		// permission set by open is subjected to umask
+		return f.Chmod(os.FileMode(hdr.Mode).Perm())
+	}); err != nil {
+		fileErrors = append(fileErrors, FileError{
+			Name: dir, Type: ErrCopyInCopyContent, Message: fmt.Sprintf("copyin: extract %q: %s", dir, err.Error()),
+		})
+	}
+
+	fe, _ := symlink(m, symlinks)
+	return append(fileErrors, fe...)
+}
+
+// openArchive opens the archive for random access thus it could be read multiple times
+func openArchive(f File) (io.ReaderAt, int64, io.Closer, error) {
+	switch f := f.(type) {
+	case *FileInput:
+		file, err := os.Open(f.Path)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, nil, err
+		}
+		return file, stat.Size(), file, nil
+
+	case *FileReader:
+		if r, ok := f.Reader.(interface {
+			io.ReaderAt
+			Size() int64
+		}); ok {
+			closer, ok := f.Reader.(io.Closer)
+			if !ok {
+				closer = io.NopCloser(nil)
+			}
+			return r, r.Size(), closer, nil
+		}
+
+	case *FileOpened:
+		stat, err := f.File.Stat()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		return f.File, stat.Size(), io.NopCloser(nil), nil
+	}
+	return nil, 0, nil, fmt.Errorf("archive does not support random access: %T", f)
+}
+
+// WalkHostDir lists the directory tree on the host file system. It is used
+// by environments that maps container paths onto a host directory
+func WalkHostDir(root string) ([]DirEntry, error) {
+	var entries []DirEntry
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e := DirEntry{Path: filepath.ToSlash(rel), Mode: fi.Mode(), Size: fi.Size()}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if e.Target, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// copyOutDirs archives directory trees from the container
+func copyOutDirs(g *errgroup.Group, m Environment, c *Cmd, dirs []CmdCopyOutFile, newStoreFile NewStoreFile, put func(*os.File, string), addError func(FileError)) {
+	dm, ok := m.(DirEnvironment)
+	for _, n := range dirs {
+		if !ok {
+			addError(FileError{
+				Name:    n.Name,
+				Type:    ErrCopyOutOpen,
+				Message: fmt.Sprintf("copyout: directory %q is not supported by the environment", n.Name),
+			})
+			continue
+		}
+		g.Go(func() error {
+			return copyOutDir(dm, c, n, newStoreFile, put, addError)
+		})
+	}
+}
+
+// copyOutDir writes the directory tree as archive into a store file
+func copyOutDir(
+	m DirEnvironment,
+	c *Cmd,
+	n CmdCopyOutFile,
+	newStoreFile NewStoreFile,
+	put func(*os.File, string),
+	addError func(FileError),
+) (err error) {
+	t := ErrCopyOutOpen
+	defer func() {
+		if err != nil {
+			addError(FileError{
+				Name:    n.Name,
+				Type:    t,
+				Message: err.Error(),
+			})
+		}
+	}()
+
+	entries, err := m.WalkDir(n.Name)
+	if err != nil {
+		// IPC method cannot differentiate OS.NotExists, thus ignore error if optional
+		if n.Optional {
+			return nil
+		}
+		return fmt.Errorf("copyout: walk %q: %w", n.Name, err)
+	}
+
+	// open all regular files in batch
+	var (
+		cmds    []OpenParam
+		regular []int
+	)
+	for i, e := range entries {
+		if e.Mode.IsRegular() {
+			cmds = append(cmds, OpenParam{Path: path.Join(n.Name, e.Path), Flag: os.O_RDONLY})
+			regular = append(regular, i)
+		}
+	}
+	files := make(map[int]*os.File, len(regular))
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	if len(cmds) > 0 {
+		results, err := m.Open(cmds)
+		if err != nil {
+			return fmt.Errorf("copyout: batch open failed %q: %w", n.Name, err)
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				return fmt.Errorf("copyout: open %q: %w", cmds[i].Path, r.Err)
+			}
+			files[regular[i]] = r.File
+		}
+	}
+
+	// size of the files decides the header of tar entries
+	var total int64
+	for i, f := range files {
+		stat, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("copyout: stat %q: %w", path.Join(n.Name, entries[i].Path), err)
+		}
+		entries[i].Size = stat.Size()
+		total += stat.Size()
+	}
+	// tar archive is never smaller than its content thus fail early
+	isTar := c.CopyOutDirFormat == "" || c.CopyOutDirFormat == ArchiveTar
+	if isTar && !c.CopyOutTruncate && c.CopyOutMax > 0 && total > int64(c.CopyOutMax) {
+		t = ErrCopyOutSizeExceeded
+		return fmt.Errorf("copyout: %q size (%d) exceeds limit (%d)", n.Name, total, c.CopyOutMax)
+	}
+
+	buf, err := newStoreFile()
+	if err != nil {
+		t = ErrCopyOutCreateFile
+		return fmt.Errorf("copyout: failed to create store file for %q: %w", n.Name, err)
+	}
+
+	// the limit applies to the archive as a whole
+	var w io.Writer = buf
+	if c.CopyOutMax > 0 {
+		w = &sizeLimitWriter{w: buf, n: int64(c.CopyOutMax)}
+	}
+	err = writeDirArchive(w, c.CopyOutDirFormat, entries, files)
+	switch {
+	case errors.Is(err, errArchiveSizeExceeded):
+		t = ErrCopyOutSizeExceeded
+		if c.CopyOutTruncate {
+			put(buf, n.Name)
+		} else {
+			buf.Close()
+			os.Remove(buf.Name())
+		}
+		return fmt.Errorf("copyout: %q archive size exceeds limit (%d)", n.Name, c.CopyOutMax)
+
+	case err != nil:
+		t = ErrCopyOutCopyContent
+		buf.Close()
+		os.Remove(buf.Name())
+		return fmt.Errorf("copyout: failed to archive %q: %w", n.Name, err)
+	}
+	put(buf, n.Name)
+	return nil
+}
+
+// errArchiveSizeExceeded is returned by sizeLimitWriter when the limit is reached
+var errArchiveSizeExceeded = errors.New("archive size exceeds limit")
+
+// sizeLimitWriter writes at most n bytes into w and the rest is dropped
+type sizeLimitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *sizeLimitWriter) Write(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errArchiveSizeExceeded
+	}
+	exceeded := false
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+		exceeded = true
+	}
+	n, err := l.w.Write(p)
+	l.n -= int64(n)
+	if err == nil && exceeded {
+		err = errArchiveSizeExceeded
+	}
+	return n, err
+}
+
+// writeDirArchive writes entries in the archive format, files are indexed by the entries
+func writeDirArchive(w io.Writer, format ArchiveFormat, entries []DirEntry, files map[int]*os.File) error {
+	switch format {
+	case "", ArchiveTar:
+		return writeDirTar(w, entries, files)
+	case ArchiveZip:
+		return writeDirZip(w, entries, files)
+	default:
+		return fmt.Errorf("unknown archive format %q", format)
+	}
+}
+
+// writeDirTar writes entries in tar format
+func writeDirTar(w io.Writer, entries []DirEntry, files map[int]*os.File) error {
+	tw := tar.NewWriter(w)
+	for i, e := range entries {
+		hdr := &tar.Header{
+			Name:   e.Path,
+			Mode:   int64(e.Mode.Perm()),
+			Format: tar.FormatPAX,
+		}
+		switch {
+		case e.Mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		case e.Mode&os.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.Target
+		case e.Mode.IsRegular():
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = e.Size
+		default:
+			// devices, pipes and sockets are not archived
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if f, ok := files[i]; ok {
+			if _, err := io.Copy(tw, io.LimitReader(f, e.Size)); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// writeDirZip writes entries in zip format, symlinks are stored with their
+// target as content as the convention of zip
+func writeDirZip(w io.Writer, entries []DirEntry, files map[int]*os.File) error {
+	zw := zip.NewWriter(w)
+	for i, e := range entries {
+		hdr := &zip.FileHeader{
+			Name:   e.Path,
+			Method: zip.Deflate,
+		}
+		switch {
+		case e.Mode.IsDir():
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+		case e.Mode&os.ModeSymlink != 0:
+			hdr.Method = zip.Store
+		case e.Mode.IsRegular():
+		default:
+			continue
+		}
+		hdr.SetMode(e.Mode)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		switch {
+		case e.Mode&os.ModeSymlink != 0:
+			if _, err := io.WriteString(fw, e.Target); err != nil {
+				return err
+			}
+		case e.Mode.IsRegular():
+			if f, ok := files[i]; ok {
+				if _, err := io.Copy(fw, io.LimitReader(f, e.Size)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return zw.Close()
+}
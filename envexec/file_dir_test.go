@@ -0,0 +1,387 @@
+package envexec
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hostEnvironment maps container paths onto a host directory
+type hostEnvironment struct {
+	root string
+}
+
+func (h hostEnvironment) Execve(context.Context, ExecveParam) (Process, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (h hostEnvironment) Open(params []OpenParam) ([]OpenResult, error) {
+	rt := make([]OpenResult, 0, len(params))
+	for _, p := range params {
+		fullPath := filepath.Join(h.root, p.Path)
+		if p.MkdirAll {
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				rt = append(rt, OpenResult{Err: err})
+				continue
+			}
+		}
+		f, err := os.OpenFile(fullPath, p.Flag, p.Perm)
+		rt = append(rt, OpenResult{File: f, Err: err})
+	}
+	return rt, nil
+}
+
+func (h hostEnvironment) Symlink(params []SymlinkParam) ([]error, error) {
+	rt := make([]error, 0, len(params))
+	for _, p := range params {
+		rt = append(rt, os.Symlink(p.Target, filepath.Join(h.root, p.LinkPath)))
+	}
+	return rt, nil
+}
+
+func (h hostEnvironment) MkdirAll(paths []string) ([]error, error) {
+	rt := make([]error, 0, len(paths))
+	for _, p := range paths {
+		rt = append(rt, os.MkdirAll(filepath.Join(h.root, p), 0755))
+	}
+	return rt, nil
+}
+
+func (h hostEnvironment) WalkDir(root string) ([]DirEntry, error) {
+	return WalkHostDir(filepath.Join(h.root, root))
+}
+
+func buildTar(t *testing.T, hdrs []*tar.Header, content map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range hdrs {
+		c := content[hdr.Name]
+		hdr.Size = int64(len(c))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader error: %v", err)
+		}
+		if _, err := tw.Write([]byte(c)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCopyInDirRoundTrip(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	archive := buildTar(t, []*tar.Header{
+		{Name: "empty/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "src/main.sh", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "src/data.txt", Typeflag: tar.TypeReg, Mode: 0600},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "src/data.txt"},
+	}, map[string]string{
+		"src/main.sh":  "#!/bin/sh\n",
+		"src/data.txt": "data",
+	})
+
+	fe, err := copyInDirs(env, map[string]*FileDir{
+		"proj": {Archive: NewFileReader(bytes.NewReader(archive))},
+	})
+	if err != nil {
+		t.Fatalf("copyInDirs error: %v %v", err, fe)
+	}
+
+	root := filepath.Join(env.root, "proj")
+	fi, err := os.Stat(filepath.Join(root, "src/main.sh"))
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %v", fi.Mode())
+	}
+	if fi, err := os.Stat(filepath.Join(root, "src/data.txt")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v %v", fi, err)
+	}
+	if fi, err := os.Stat(filepath.Join(root, "empty")); err != nil || !fi.IsDir() {
+		t.Errorf("expected empty directory, got %v %v", fi, err)
+	}
+	if target, err := os.Readlink(filepath.Join(root, "link")); err != nil || target != "src/data.txt" {
+		t.Errorf("expected symlink, got %q %v", target, err)
+	}
+
+	var stored *os.File
+	cmd := &Cmd{}
+	newStoreFile := func() (*os.File, error) {
+		return os.CreateTemp(t.TempDir(), "")
+	}
+	err = copyOutDir(env, cmd, CmdCopyOutFile{Name: "proj", Dir: true}, newStoreFile, func(f *os.File, n string) {
+		stored = f
+	}, func(e FileError) {
+		t.Errorf("unexpected file error: %v", e)
+	})
+	if err != nil {
+		t.Fatalf("copyOutDir error: %v", err)
+	}
+	defer stored.Close()
+
+	got := make(map[string]*tar.Header)
+	stat, err := stored.Stat()
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if err := WalkArchive(stored, stat.Size(), func(hdr *tar.Header, r io.Reader) error {
+		got[hdr.Name] = hdr
+		b, err := io.ReadAll(r)
+		if err == nil && hdr.Name == "src/data.txt" && string(b) != "data" {
+			t.Errorf("unexpected content %q", b)
+		}
+		return err
+	}); err != nil {
+		t.Fatalf("walkArchive error: %v", err)
+	}
+	if hdr := got["src/main.sh"]; hdr == nil || hdr.Mode != 0755 {
+		t.Errorf("expected executable entry, got %+v", hdr)
+	}
+	if hdr := got["link"]; hdr == nil || hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "src/data.txt" {
+		t.Errorf("expected symlink entry, got %+v", hdr)
+	}
+	if hdr := got["empty/"]; hdr == nil || hdr.Typeflag != tar.TypeDir {
+		t.Errorf("expected directory entry, got %+v", hdr)
+	}
+}
+
+func TestCopyInDirRejectsTraversal(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	archive := buildTar(t, []*tar.Header{
+		{Name: "../escape.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	}, map[string]string{"../escape.txt": "x"})
+
+	fe, err := copyInDirs(env, map[string]*FileDir{
+		"proj": {Archive: NewFileReader(bytes.NewReader(archive))},
+	})
+	if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyInExtract {
+		t.Fatalf("expected traversal to be rejected, got %v %v", err, fe)
+	}
+	if _, err := os.Stat(filepath.Join(env.root, "escape.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected no file extracted, got %v", err)
+	}
+}
+
+func TestCopyInDirRejectsDuplicate(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	archive := buildTar(t, []*tar.Header{
+		{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "a/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "a/x.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "./a/x.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	}, map[string]string{"a/x.txt": "1", "./a/x.txt": "2"})
+
+	fe, err := copyInDirs(env, map[string]*FileDir{
+		"proj": {Archive: NewFileReader(bytes.NewReader(archive))},
+	})
+	if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyInExtract || !strings.Contains(fe[0].Message, "duplicated") {
+		t.Fatalf("expected duplicate to be rejected, got %v %v", err, fe)
+	}
+}
+
+func TestCopyInDirZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fh := &zip.FileHeader{Name: "a/b/run.sh"}
+	fh.SetMode(0755)
+	w, err := zw.CreateHeader(fh)
+	if err != nil {
+		t.Fatalf("CreateHeader error: %v", err)
+	}
+	w.Write([]byte("echo"))
+	zw.Close()
+
+	env := hostEnvironment{root: t.TempDir()}
+	fe, err := copyInDirs(env, map[string]*FileDir{
+		"sub": {Archive: NewFileReader(bytes.NewReader(buf.Bytes()))},
+	})
+	if err != nil {
+		t.Fatalf("copyInDirs error: %v %v", err, fe)
+	}
+	b, err := os.ReadFile(filepath.Join(env.root, "sub/a/b/run.sh"))
+	if err != nil || string(b) != "echo" {
+		t.Fatalf("unexpected content %q %v", b, err)
+	}
+}
+
+func TestCopyInDirLimit(t *testing.T) {
+	archive := buildTar(t, []*tar.Header{
+		{Name: "a/b/c/d.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "e.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	}, map[string]string{
+		"a/b/c/d.txt": "1234",
+		"e.txt":       "5678",
+	})
+	for _, l := range []ArchiveLimit{
+		{Size: 7},
+		{Count: 1},
+		{Depth: 3},
+	} {
+		env := hostEnvironment{root: t.TempDir()}
+		fe, err := copyInDirs(env, map[string]*FileDir{
+			"out": {Archive: NewFileReader(bytes.NewReader(archive)), Limit: l},
+		})
+		if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyInExtract {
+			t.Fatalf("expected limit %+v exceeded, got %v %v", l, err, fe)
+		}
+		if _, err := os.Stat(filepath.Join(env.root, "out", "e.txt")); !os.IsNotExist(err) {
+			t.Fatalf("expected nothing extracted, got %v", err)
+		}
+	}
+}
+
+// readAtCounter counts the bytes read from the archive
+type readAtCounter struct {
+	*bytes.Reader
+	n int64
+}
+
+func (r *readAtCounter) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	r.n += int64(n)
+	return n, err
+}
+
+func TestCopyInDirLimitStopsReading(t *testing.T) {
+	hdrs := make([]*tar.Header, 20000)
+	for i := range hdrs {
+		hdrs[i] = &tar.Header{Name: fmt.Sprintf("d%d/", i), Typeflag: tar.TypeDir, Mode: 0755}
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(buildTar(t, hdrs, nil))
+	zw.Close()
+
+	r := &readAtCounter{Reader: bytes.NewReader(buf.Bytes())}
+	env := hostEnvironment{root: t.TempDir()}
+	fe, err := copyInDirs(env, map[string]*FileDir{
+		"out": {Archive: NewFileReader(r), Limit: ArchiveLimit{Count: 10}},
+	})
+	if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyInExtract {
+		t.Fatalf("expected count exceeded, got %v %v", err, fe)
+	}
+	if r.n >= int64(buf.Len())/2 {
+		t.Fatalf("expected archive not fully read, read %d of %d", r.n, buf.Len())
+	}
+}
+
+func TestCopyOutDirSizeExceeded(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(env.root, "out"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(env.root, "out", "big"), make([]byte, 16), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	var fe []FileError
+	err := copyOutDir(env, &Cmd{CopyOutMax: 8}, CmdCopyOutFile{Name: "out", Dir: true}, nil, func(*os.File, string) {
+		t.Fatal("put should not be called")
+	}, func(e FileError) {
+		fe = append(fe, e)
+	})
+	if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyOutSizeExceeded {
+		t.Fatalf("expected size exceeded, got %v %v", err, fe)
+	}
+}
+
+func TestCopyOutDirZip(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	root := filepath.Join(env.root, "out")
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "run.sh"), []byte("echo"), 0755); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if err := os.Symlink("run.sh", filepath.Join(root, "link")); err != nil {
+		t.Fatalf("Symlink error: %v", err)
+	}
+
+	var stored *os.File
+	newStoreFile := func() (*os.File, error) {
+		return os.CreateTemp(t.TempDir(), "")
+	}
+	err := copyOutDir(env, &Cmd{CopyOutDirFormat: ArchiveZip}, CmdCopyOutFile{Name: "out", Dir: true}, newStoreFile, func(f *os.File, n string) {
+		stored = f
+	}, func(e FileError) {
+		t.Errorf("unexpected file error: %v", e)
+	})
+	if err != nil {
+		t.Fatalf("copyOutDir error: %v", err)
+	}
+	defer stored.Close()
+
+	stat, err := stored.Stat()
+	if err != nil {
+		t.Fatalf("Stat error: %v", err)
+	}
+	if _, err := zip.NewReader(stored, stat.Size()); err != nil {
+		t.Fatalf("expected zip archive: %v", err)
+	}
+	got := make(map[string]*tar.Header)
+	if err := WalkArchive(stored, stat.Size(), func(hdr *tar.Header, r io.Reader) error {
+		got[hdr.Name] = hdr
+		b, err := io.ReadAll(r)
+		if err == nil && hdr.Name == "run.sh" && string(b) != "echo" {
+			t.Errorf("unexpected content %q", b)
+		}
+		return err
+	}); err != nil {
+		t.Fatalf("WalkArchive error: %v", err)
+	}
+	if hdr := got["run.sh"]; hdr == nil || hdr.Mode != 0755 {
+		t.Errorf("expected executable entry, got %+v", hdr)
+	}
+	if hdr := got["link"]; hdr == nil || hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "run.sh" {
+		t.Errorf("expected symlink entry, got %+v", hdr)
+	}
+	if hdr := got["empty/"]; hdr == nil || hdr.Typeflag != tar.TypeDir {
+		t.Errorf("expected directory entry, got %+v", hdr)
+	}
+}
+
+func TestCopyOutDirTruncate(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(env.root, "out"), 0755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(env.root, "out", "big"), make([]byte, 4096), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	var (
+		stored *os.File
+		fe     []FileError
+	)
+	newStoreFile := func() (*os.File, error) {
+		return os.CreateTemp(t.TempDir(), "")
+	}
+	c := &Cmd{CopyOutMax: 1024, CopyOutTruncate: true}
+	err := copyOutDir(env, c, CmdCopyOutFile{Name: "out", Dir: true}, newStoreFile, func(f *os.File, n string) {
+		stored = f
+	}, func(e FileError) {
+		fe = append(fe, e)
+	})
+	if err == nil || len(fe) != 1 || fe[0].Type != ErrCopyOutSizeExceeded {
+		t.Fatalf("expected size exceeded, got %v %v", err, fe)
+	}
+	if stored == nil {
+		t.Fatal("expected truncated archive")
+	}
+	defer stored.Close()
+	if stat, err := stored.Stat(); err != nil || stat.Size() != 1024 {
+		t.Fatalf("expected archive truncated to 1024 bytes, got %v %v", stat, err)
+	}
+}
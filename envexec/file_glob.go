@@ -0,0 +1,299 @@
+package envexec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// globMeta are the special characters of the glob pattern
+const globMeta = "*?["
+
+// IsGlob reports whether the copyOut name is a glob pattern
+func IsGlob(name string) bool {
+	return strings.ContainsAny(name, globMeta)
+}
+
+// MatchGlob reports whether name matches the pattern. Each path component is
+// matched by path.Match and the component "**" matches zero or more directories
+func MatchGlob(pattern, name string) (bool, error) {
+	return matchGlobParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobParts(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchGlobParts(pattern[1:], name[i:]); err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// globRoot returns the leading directory of the pattern without special characters
+func globRoot(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, p := range parts {
+		if IsGlob(p) {
+			if i == 0 {
+				return "."
+			}
+			if i == 1 && parts[0] == "" {
+				return "/"
+			}
+			return strings.Join(parts[:i], "/")
+		}
+	}
+	return path.Dir(pattern)
+}
+
+type globMatch struct {
+	name    string
+	pattern string
+}
+
+// copyOutGlobs expands glob patterns inside the environment and copies out
+// matched regular files. CopyOutMax is applied as the total size budget and
+// CopyOutMaxFiles limits the number of files, files beyond limits are dropped
+// in the order of their names
+func copyOutGlobs(g *errgroup.Group, m Environment, c *Cmd, globs []CmdCopyOutFile, newStoreFile NewStoreFile, put func(*os.File, string), addError func(FileError)) {
+	if len(globs) == 0 {
+		return
+	}
+	dm, ok := m.(DirEnvironment)
+	if !ok {
+		for _, n := range globs {
+			addError(FileError{
+				Name:    n.Name,
+				Type:    ErrCopyOutOpen,
+				Message: fmt.Sprintf("copyout: glob %q is not supported by the environment", n.Name),
+			})
+		}
+		return
+	}
+
+	matches := expandGlobs(dm, globs, addError)
+	if c.CopyOutMaxFiles > 0 && uint64(len(matches)) > c.CopyOutMaxFiles {
+		addError(FileError{
+			Name:    matches[c.CopyOutMaxFiles].pattern,
+			Type:    ErrCopyOutCountExceeded,
+			Message: fmt.Sprintf("copyout: %d files matched, exceeds limit (%d)", len(matches), c.CopyOutMaxFiles),
+		})
+		matches = matches[:c.CopyOutMaxFiles]
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	cmds := make([]OpenParam, 0, len(matches))
+	for _, mt := range matches {
+		cmds = append(cmds, OpenParam{Path: mt.name, Flag: os.O_RDONLY})
+	}
+	results, err := m.Open(cmds)
+	if err != nil {
+		for _, n := range globs {
+			addError(FileError{
+				Name:    n.Name,
+				Type:    ErrCopyOutOpen,
+				Message: fmt.Sprintf("copyout: batch open failed %q: %s", n.Name, err.Error()),
+			})
+		}
+		return
+	}
+
+	// sizes are decided before copy so that budget is deterministic
+	budget := int64(c.CopyOutMax)
+	exceeded := false
+	for i, r := range results {
+		mt := matches[i]
+		if r.Err != nil {
+			addError(FileError{Name: mt.name, Type: ErrCopyOutOpen, Message: r.Err.Error()})
+			continue
+		}
+		if exceeded {
+			r.File.Close()
+			continue
+		}
+		stat, err := r.File.Stat()
+		if err != nil {
+			r.File.Close()
+			addError(FileError{Name: mt.name, Type: ErrCopyOutOpen, Message: err.Error()})
+			continue
+		}
+		if !stat.Mode().IsRegular() {
+			// file was replaced after the walk
+			r.File.Close()
+			continue
+		}
+		size := stat.Size()
+		if c.CopyOutMax > 0 {
+			if size > budget {
+				exceeded = true
+				addError(FileError{
+					Name:    mt.name,
+					Type:    ErrCopyOutSizeExceeded,
+					Message: fmt.Sprintf("copyout: total size of %q exceeds limit (%d)", mt.pattern, c.CopyOutMax),
+				})
+				if !c.CopyOutTruncate {
+					r.File.Close()
+					continue
+				}
+				size = budget
+			}
+			budget -= size
+		}
+		g.Go(func() error {
+			defer r.File.Close()
+			return copyOutGlobFile(r.File, mt.name, size, newStoreFile, put, addError)
+		})
+	}
+}
+
+// expandGlobs walks the roots of the patterns and returns the matched regular
+// files sorted by name without duplication. Roots nested in another root are
+// served by the walk of the outer one so that the tree is walked only once.
+func expandGlobs(m DirEnvironment, globs []CmdCopyOutFile, addError func(FileError)) []globMatch {
+	patterns := make([]string, len(globs))
+	roots := make([]string, len(globs))
+	for i, n := range globs {
+		patterns[i] = path.Clean(n.Name)
+		roots[i] = globRoot(patterns[i])
+	}
+	walks := slices.Clone(roots)
+	slices.Sort(walks)
+	walks = slices.Compact(walks)
+	walks = slices.DeleteFunc(walks, func(r string) bool {
+		return slices.ContainsFunc(walks, func(o string) bool { return o != r && isUnderRoot(r, o) })
+	})
+
+	seen := make(map[string]bool)
+	found := make([]bool, len(globs))
+	invalid := make([]bool, len(globs))
+	var matches []globMatch
+	for _, root := range walks {
+		var idx []int
+		for i, r := range roots {
+			if r == root || isUnderRoot(r, root) {
+				idx = append(idx, i)
+			}
+		}
+		entries, err := m.WalkDir(root)
+		if err != nil {
+			for _, i := range idx {
+				// IPC method cannot differentiate OS.NotExists, thus ignore error if optional
+				invalid[i] = true
+				if !globs[i].Optional {
+					addError(FileError{
+						Name:    globs[i].Name,
+						Type:    ErrCopyOutOpen,
+						Message: fmt.Sprintf("copyout: walk %q: %s", root, err.Error()),
+					})
+				}
+			}
+			continue
+		}
+		for _, e := range entries {
+			if !e.Mode.IsRegular() {
+				continue
+			}
+			name := path.Join(root, e.Path)
+			for _, i := range idx {
+				if invalid[i] {
+					continue
+				}
+				ok, err := MatchGlob(patterns[i], name)
+				if err != nil {
+					invalid[i] = true
+					addError(FileError{
+						Name:    globs[i].Name,
+						Type:    ErrCopyOutOpen,
+						Message: fmt.Sprintf("copyout: invalid pattern %q: %s", globs[i].Name, err.Error()),
+					})
+					continue
+				}
+				if !ok {
+					continue
+				}
+				found[i] = true
+				if !seen[name] {
+					seen[name] = true
+					matches = append(matches, globMatch{name: name, pattern: globs[i].Name})
+				}
+			}
+		}
+	}
+	for i, n := range globs {
+		if !found[i] && !invalid[i] && !n.Optional {
+			addError(FileError{
+				Name:    n.Name,
+				Type:    ErrCopyOutOpen,
+				Message: fmt.Sprintf("copyout: no file matches %q", n.Name),
+			})
+		}
+	}
+	slices.SortFunc(matches, func(a, b globMatch) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return matches
+}
+
+// isUnderRoot reports whether the cleaned path p is inside the walk root
+func isUnderRoot(p, root string) bool {
+	switch root {
+	case ".":
+		return !path.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+	case "/":
+		return path.IsAbs(p)
+	}
+	return strings.HasPrefix(p, root+"/")
+}
+
+func copyOutGlobFile(
+	cf *os.File,
+	name string,
+	size int64,
+	newStoreFile NewStoreFile,
+	put func(*os.File, string),
+	addError func(FileError),
+) (err error) {
+	t := ErrCopyOutCreateFile
+	defer func() {
+		if err != nil {
+			addError(FileError{
+				Name:    name,
+				Type:    t,
+				Message: err.Error(),
+			})
+		}
+	}()
+
+	buf, err := newStoreFile()
+	if err != nil {
+		return fmt.Errorf("copyout: failed to create store file for %q: %w", name, err)
+	}
+	if _, err := buf.ReadFrom(io.LimitReader(cf, size)); err != nil {
+		t = ErrCopyOutCopyContent
+		buf.Close()
+		os.Remove(buf.Name())
+		return fmt.Errorf("copyout: failed to copy content for %q: %w", name, err)
+	}
+	put(buf, name)
+	return nil
+}
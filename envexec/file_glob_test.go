@@ -0,0 +1,212 @@
+package envexec
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.class", "Main.class", true},
+		{"*.class", "out/Main.class", false},
+		{"out/**", "out/a/b/c.txt", true},
+		{"out/**", "other/c.txt", false},
+		{"**/*.class", "Main.class", true},
+		{"**/*.class", "a/b/Main.class", true},
+		{"a/**/b/*.txt", "a/b/x.txt", true},
+		{"a/**/b/*.txt", "a/c/d/b/x.txt", true},
+		{"a/**/b/*.txt", "a/c/x.txt", false},
+		{"/w/*.c", "/w/a.c", true},
+	}
+	for _, tc := range tests {
+		got, err := MatchGlob(tc.pattern, tc.name)
+		if err != nil {
+			t.Fatalf("MatchGlob(%q, %q) error: %v", tc.pattern, tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+	if _, err := MatchGlob("[", "a"); err == nil {
+		t.Error("expected bad pattern error")
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := map[string]string{
+		"*.class":    ".",
+		"out/**":     "out",
+		"a/b/*/c":    "a/b",
+		"/*.c":       "/",
+		"/w/out/*.c": "/w/out",
+	}
+	for pattern, want := range tests {
+		if got := globRoot(pattern); got != want {
+			t.Errorf("globRoot(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func runCopyOutGlobs(t *testing.T, env hostEnvironment, c *Cmd) (map[string]int64, []FileError) {
+	t.Helper()
+	var (
+		g  errgroup.Group
+		mu sync.Mutex
+		fe []FileError
+	)
+	files := make(map[string]int64)
+	newStoreFile := func() (*os.File, error) {
+		return os.CreateTemp(t.TempDir(), "")
+	}
+	put := func(f *os.File, n string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stat, err := f.Stat()
+		if err != nil {
+			t.Errorf("Stat error: %v", err)
+		}
+		files[n] = stat.Size()
+		f.Close()
+	}
+	addError := func(e FileError) {
+		mu.Lock()
+		defer mu.Unlock()
+		fe = append(fe, e)
+	}
+	copyOutGlobs(&g, env, c, c.CopyOut, newStoreFile, put, addError)
+	g.Wait()
+	return files, fe
+}
+
+func writeGlobFiles(t *testing.T, root string, files map[string]int) {
+	t.Helper()
+	for name, size := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("MkdirAll error: %v", err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+	}
+}
+
+func TestCopyOutGlobs(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	writeGlobFiles(t, env.root, map[string]int{
+		"Main.class":       1,
+		"Util.class":       2,
+		"Main.java":        3,
+		"out/a.txt":        4,
+		"out/sub/b.txt":    5,
+		"other/Dep.class":  6,
+		"other/notes.text": 7,
+	})
+	files, fe := runCopyOutGlobs(t, env, &Cmd{CopyOut: []CmdCopyOutFile{
+		{Name: "*.class", Glob: true},
+		{Name: "out/**", Glob: true},
+		{Name: "missing/*", Glob: true, Optional: true},
+	}})
+	if len(fe) != 0 {
+		t.Fatalf("unexpected file errors: %v", fe)
+	}
+	want := map[string]int64{
+		"Main.class":    1,
+		"Util.class":    2,
+		"out/a.txt":     4,
+		"out/sub/b.txt": 5,
+	}
+	if len(files) != len(want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+	for n, s := range want {
+		if files[n] != s {
+			t.Errorf("expected %q size %d, got %d", n, s, files[n])
+		}
+	}
+
+	_, fe = runCopyOutGlobs(t, env, &Cmd{CopyOut: []CmdCopyOutFile{
+		{Name: "missing/*", Glob: true},
+	}})
+	if len(fe) != 1 || fe[0].Type != ErrCopyOutOpen {
+		t.Fatalf("expected no match error, got %v", fe)
+	}
+}
+
+func TestCopyOutGlobsLimit(t *testing.T) {
+	env := hostEnvironment{root: t.TempDir()}
+	writeGlobFiles(t, env.root, map[string]int{
+		"out/1": 4,
+		"out/2": 4,
+		"out/3": 4,
+	})
+	glob := []CmdCopyOutFile{{Name: "out/*", Glob: true}}
+
+	files, fe := runCopyOutGlobs(t, env, &Cmd{CopyOut: glob, CopyOutMaxFiles: 2})
+	if len(fe) != 1 || fe[0].Type != ErrCopyOutCountExceeded {
+		t.Fatalf("expected count exceeded, got %v", fe)
+	}
+	if len(files) != 2 || files["out/1"] != 4 || files["out/2"] != 4 {
+		t.Fatalf("expected first 2 files, got %v", files)
+	}
+
+	files, fe = runCopyOutGlobs(t, env, &Cmd{CopyOut: glob, CopyOutMax: 10})
+	if len(fe) != 1 || fe[0].Type != ErrCopyOutSizeExceeded || fe[0].Name != "out/3" {
+		t.Fatalf("expected size exceeded on out/3, got %v", fe)
+	}
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "out/1" || names[1] != "out/2" {
+		t.Fatalf("expected files within budget, got %v", files)
+	}
+
+	files, fe = runCopyOutGlobs(t, env, &Cmd{CopyOut: glob, CopyOutMax: 10, CopyOutTruncate: true})
+	if len(fe) != 1 || fe[0].Type != ErrCopyOutSizeExceeded {
+		t.Fatalf("expected size exceeded, got %v", fe)
+	}
+	if len(files) != 3 || files["out/3"] != 2 {
+		t.Fatalf("expected out/3 truncated to 2 bytes, got %v", files)
+	}
+}
+
+// walkCountEnvironment counts the walks of the host environment
+type walkCountEnvironment struct {
+	hostEnvironment
+	walks []string
+}
+
+func (w *walkCountEnvironment) WalkDir(root string) ([]DirEntry, error) {
+	w.walks = append(w.walks, root)
+	return w.hostEnvironment.WalkDir(root)
+}
+
+func TestExpandGlobsWalkOnce(t *testing.T) {
+	env := &walkCountEnvironment{hostEnvironment: hostEnvironment{root: t.TempDir()}}
+	writeGlobFiles(t, env.root, map[string]int{
+		"a.class":     1,
+		"out/b.class": 1,
+		"out/c.txt":   1,
+	})
+	matches := expandGlobs(env, []CmdCopyOutFile{
+		{Name: "out/*.txt", Glob: true},
+		{Name: "*.class", Glob: true},
+		{Name: "out/**/*.class", Glob: true},
+	}, func(fe FileError) { t.Errorf("unexpected error: %v", fe) })
+	if len(env.walks) != 1 || env.walks[0] != "." {
+		t.Fatalf("expected a single walk of the outer root, got %v", env.walks)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 matches, got %v", matches)
+	}
+}
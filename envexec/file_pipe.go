@@ -10,14 +10,17 @@ type pipeBuffer struct {
 	Buffer *os.File
 	Done   <-chan struct{}
 	Limit  Size
+	Tail   *tailWriter
 }
 
 type pipeCollector struct {
-	done    <-chan struct{}
-	buffer  *os.File
-	limit   Size
-	name    string
-	storage bool
+	done       <-chan struct{}
+	buffer     *os.File
+	limit      Size
+	name       string
+	storage    bool
+	tail       Size
+	tailWriter *tailWriter // collects storage output when tail is kept
 }
 
 func newPipe(writer io.Writer, limit Size) (<-chan struct{}, *os.File, error) {
@@ -36,12 +39,34 @@ func newPipe(writer io.Writer, limit Size) (<-chan struct{}, *os.File, error) {
 	return done, w, nil
 }
 
-func newPipeBuffer(limit Size, newFile NewStoreFile) (*pipeBuffer, error) {
+// collectReadLimit returns the number of bytes read from the output by the
+// collector. Reading stops once the limit is reached, unless the tail is kept,
+// which reads through the output up to the output limit of the command.
+func collectReadLimit(limit, tail, outputLimit Size) Size {
+	if tail > 0 && outputLimit > limit {
+		return outputLimit
+	}
+	return limit + 1
+}
+
+func newPipeBuffer(limit, tail, outputLimit Size, live io.Writer, newFile NewStoreFile) (*pipeBuffer, error) {
 	buffer, err := newFile()
 	if err != nil {
 		return nil, err
 	}
-	done, w, err := newPipe(buffer, limit+1)
+	var (
+		writer io.Writer = buffer
+		tw     *tailWriter
+		max    = collectReadLimit(limit, tail, outputLimit)
+	)
+	if tail > 0 {
+		tw = newTailWriter(buffer, limit, tail)
+		writer = tw
+	}
+	if live != nil {
+		writer = io.MultiWriter(writer, live)
+	}
+	done, w, err := newPipe(writer, max)
 	if err != nil {
 		buffer.Close()
 		return nil, err
@@ -51,5 +76,6 @@ func newPipeBuffer(limit Size, newFile NewStoreFile) (*pipeBuffer, error) {
 		Buffer: buffer,
 		Done:   done,
 		Limit:  limit,
+		Tail:   tw,
 	}, nil
 }
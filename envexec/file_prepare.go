@@ -79,6 +79,7 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			sf.Acquire()
 			go func() {
 				defer sf.Close()
+				defer closeReader(t.Reader)
 				io.Copy(fPty, t.Reader)
 			}()
 
@@ -102,13 +103,25 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			if err != nil {
 				return nil, nil, fmt.Errorf("tty: create store file: %w", err)
 			}
-			pipeToCollect = append(pipeToCollect, pipeCollector{done, buf, t.Limit, t.Name, true})
+			var (
+				w  io.Writer = buf
+				tw *tailWriter
+				n  = int64(collectReadLimit(t.Limit, t.Tail, c.OutputLimit))
+			)
+			if t.Tail > 0 {
+				tw = newTailWriter(buf, t.Limit, t.Tail)
+				w = tw
+			}
+			if t.Live != nil {
+				w = io.MultiWriter(w, t.Live)
+			}
+			pipeToCollect = append(pipeToCollect, pipeCollector{done, buf, t.Limit, t.Name, true, t.Tail, tw})
 
 			sf.Acquire()
 			go func() {
 				defer close(done)
 				defer sf.Close()
-				io.CopyN(buf, fPty, int64(t.Limit)+1)
+				io.CopyN(w, fPty, n)
 			}()
 
 		case *FileWriter:
@@ -228,18 +241,18 @@ func prepareCmdFdNoTty(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.Fi
 			return f, nil
 		}
 
-		if t.Pipe {
-			b, err := newPipeBuffer(t.Limit, newFileStore)
+		if t.Pipe || t.Live != nil {
+			b, err := newPipeBuffer(t.Limit, t.Tail, c.OutputLimit, t.Live, newFileStore)
 			if err != nil {
 				return nil, fmt.Errorf("pipe: create: %w", err)
 			}
 			cf[t.Name] = b.W
-			pipeToCollect = append(pipeToCollect, pipeCollector{b.Done, b.Buffer, t.Limit, t.Name, true})
+			pipeToCollect = append(pipeToCollect, pipeCollector{b.Done, b.Buffer, t.Limit, t.Name, true, t.Tail, b.Tail})
 			return b.W, nil
 		} else {
 			res := batchMap[t.Name]
 			cf[t.Name] = res.writePart
-			pipeToCollect = append(pipeToCollect, pipeCollector{closedChan, res.readPart, t.Limit, t.Name, false})
+			pipeToCollect = append(pipeToCollect, pipeCollector{closedChan, res.readPart, t.Limit, t.Name, false, t.Tail, nil})
 			delete(batchMap, t.Name)
 			return res.writePart, nil
 		}
@@ -1,11 +1,13 @@
 package envexec
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"sync/atomic"
 
 	"github.com/criyle/go-sandbox/pkg/memfd"
+	"golang.org/x/sys/unix"
 )
 
 const memfdName = "input"
@@ -16,6 +18,7 @@ func readerToFile(reader io.Reader) (*os.File, error) {
 	if enableMemFd.Load() == 0 {
 		f, err := memfd.DupToMemfd(memfdName, reader)
 		if err == nil {
+			closeReader(reader)
 			return f, err
 		}
 		enableMemFd.Store(1)
@@ -26,7 +29,61 @@ func readerToFile(reader io.Reader) (*os.File, error) {
 	}
 	go func() {
 		defer w.Close()
+		defer closeReader(reader)
 		w.ReadFrom(reader)
 	}()
 	return r, nil
 }
+
+// bindFile returns a read-only file to be bound into the environment. Host
+// files are opened as is and in-memory content is shared only if it is kept in
+// a sealed memfd (e.g. cached by -file-cache-memfd) so that it never changes
+// while bound, other files are copied in as usual.
+func bindFile(f File) (*os.File, error) {
+	switch f := f.(type) {
+	case *FileInput:
+		bf, err := os.Open(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if fi, err := bf.Stat(); err != nil || !fi.Mode().IsRegular() {
+			bf.Close()
+			return nil, fmt.Errorf("bind: %q is not a regular file", f.Path)
+		}
+		return bf, nil
+
+	case *FileReader:
+		return dupSealedMemfd(f.Reader)
+	}
+	return nil, fmt.Errorf("bind: file type %T is not supported", f)
+}
+
+// dupSealedMemfd duplicates the memfd if the reader reads the whole content
+// of a write sealed memfd
+func dupSealedMemfd(r io.Reader) (*os.File, error) {
+	sr, ok := r.(interface {
+		Outer() (io.ReaderAt, int64, int64)
+	})
+	if !ok {
+		return nil, fmt.Errorf("bind: not a section reader")
+	}
+	outer, off, n := sr.Outer()
+	mf, ok := outer.(*os.File)
+	if !ok || off != 0 {
+		return nil, fmt.Errorf("bind: not a file")
+	}
+	seals, err := unix.FcntlInt(mf.Fd(), unix.F_GET_SEALS, 0)
+	const roSeal = unix.F_SEAL_WRITE | unix.F_SEAL_SHRINK
+	if err != nil || seals&roSeal != roSeal {
+		return nil, fmt.Errorf("bind: not a sealed memfd")
+	}
+	var st unix.Stat_t
+	if err := unix.Fstat(int(mf.Fd()), &st); err != nil || st.Size != n {
+		return nil, fmt.Errorf("bind: partial memfd")
+	}
+	fd, err := unix.FcntlInt(mf.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), mf.Name()), nil
+}
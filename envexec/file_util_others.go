@@ -3,6 +3,7 @@
 package envexec
 
 import (
+	"errors"
 	"io"
 	"os"
 )
@@ -14,7 +15,12 @@ func readerToFile(reader io.Reader) (*os.File, error) {
 	}
 	go func() {
 		defer w.Close()
+		defer closeReader(reader)
 		w.ReadFrom(reader)
 	}()
 	return r, nil
 }
+
+func bindFile(File) (*os.File, error) {
+	return nil, errors.New("bind: not supported")
+}
@@ -2,6 +2,7 @@ package envexec
 
 import (
 	"context"
+	"errors"
 	"os"
 	"time"
 )
@@ -54,12 +55,22 @@ type Process interface {
 	Usage() Usage          // Usage retrieves the process usage during the run time
 }
 
+// FileOwner defines the owner of the file created by open call
+type FileOwner int
+
+// FileOwner enums
+const (
+	OwnerRoot FileOwner = iota // OwnerRoot is the container root
+	OwnerUser                  // OwnerUser is the user that runs programs inside the container
+)
+
 // OpenParam represent a open call in the environment
 type OpenParam struct {
 	Path     string
 	Flag     int
 	Perm     os.FileMode
 	MkdirAll bool
+	Owner    FileOwner // Owner of the created file, only supported on linux
 }
 
 // OpenResult represent a result from a open call, it should
@@ -86,5 +97,47 @@ type Environment interface {
 	Symlink([]SymlinkParam) ([]error, error)
 }
 
+// DirEntry represent an entry inside a directory tree of the environment
+type DirEntry struct {
+	Path   string      // Path relative to the walked root, slash separated
+	Mode   os.FileMode // Mode contains file type and permission bits
+	Size   int64       // Size of regular file
+	Target string      // Target of symbolic link
+}
+
+// DirEnvironment defines the optional interface for Environment that is able
+// to create and list directory tree, which is required to copy in empty
+// directories and to copy out a directory
+type DirEnvironment interface {
+	Environment
+	// MkdirAll creates directories by container-visible path along with parents
+	MkdirAll([]string) ([]error, error)
+	// WalkDir lists all entries under the container-visible directory recursively
+	WalkDir(root string) ([]DirEntry, error)
+}
+
+// BindParam defines the read-only host file to be exposed at the
+// container-visible path
+type BindParam struct {
+	Path string
+	File *os.File
+}
+
+// BindEnvironment defines the optional interface for Environment that is able
+// to expose host files read-only inside the environment without copying the
+// content, which enables zero-copy copyIn. The bound files are removed on reset
+// as other copyIn files.
+type BindEnvironment interface {
+	Environment
+	// Bind exposes the files at the paths, the file that could not be bound
+	// reports ErrBindNotSupported so that it is copied in instead. The files
+	// are not retained after Bind returns.
+	Bind([]BindParam) ([]error, error)
+}
+
+// ErrBindNotSupported is reported by BindEnvironment for the file that could
+// not be bound
+var ErrBindNotSupported = errors.New("bind is not supported")
+
 // NewStoreFile creates a new file in storage
 type NewStoreFile func() (*os.File, error)
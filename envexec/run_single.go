@@ -24,8 +24,14 @@ func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector,
 		closePipes(ptc)
 		closeFiles(fds...)
 	}
+	// zero-copy files are bound before the rest are copied in
+	copyIn, fe, err := bindCopyIn(m, c.CopyIn)
+	if err != nil {
+		resultFileError(err, fe)
+		return result, nil
+	}
 	// copyin
-	if fe, err := runSingleCopyIn(m, c.CopyIn); err != nil {
+	if fe, err := runSingleCopyIn(m, copyIn); err != nil {
 		resultFileError(err, fe)
 		return result, nil
 	}
@@ -35,21 +41,38 @@ func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector,
 		return result, nil
 	}
 
+	c.progress(StageCopyInDone, nil)
+
 	// run cmd and wait for result
 	rt := runSingleWait(pc, m, c, fds)
+	if c.Progress != nil {
+		r := Result{
+			Status:     convertStatus(rt.Status),
+			ExitStatus: rt.ExitStatus,
+			Error:      rt.Error,
+			Time:       rt.Time,
+			RunTime:    rt.RunningTime,
+			Memory:     rt.Memory,
+			ProcPeak:   rt.ProcPeak,
+		}
+		r.checkLimits(c)
+		c.Progress(StageProcessFinished, &r)
+	}
 
 	// collect result
-	files, fe, err := copyOutAndCollect(m, c, ptc, newStoreFile)
+	files, digests, fe, err := copyOutAndCollect(m, c, ptc, newStoreFile)
+	c.progress(StageCopyOutDone, nil)
 	result = Result{
-		Status:     convertStatus(rt.Status),
-		ExitStatus: rt.ExitStatus,
-		Error:      rt.Error,
-		Time:       rt.Time,
-		RunTime:    rt.RunningTime,
-		Memory:     rt.Memory,
-		ProcPeak:   rt.ProcPeak,
-		Files:      files,
-		FileError:  fe,
+		Status:      convertStatus(rt.Status),
+		ExitStatus:  rt.ExitStatus,
+		Error:       rt.Error,
+		Time:        rt.Time,
+		RunTime:     rt.RunningTime,
+		Memory:      rt.Memory,
+		ProcPeak:    rt.ProcPeak,
+		Files:       files,
+		FileDigests: digests,
+		FileError:   fe,
 	}
 	// collect error (only if the process exits normally)
 	if rt.Status == runner.StatusNormal && result.Error == "" {
@@ -66,20 +89,39 @@ func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector,
 			result.Error = fe[0].Message
 		}
 	}
-	if result.Time > c.TimeLimit {
-		result.Status = StatusTimeLimitExceeded
+	result.checkLimits(c)
+	return result, nil
+}
+
+// checkLimits overrides the status when time or memory limit exceeded
+func (r *Result) checkLimits(c *Cmd) {
+	if r.Time > c.TimeLimit {
+		r.Status = StatusTimeLimitExceeded
+	}
+	if r.Memory > c.MemoryLimit {
+		r.Status = StatusMemoryLimitExceeded
 	}
-	if result.Memory > c.MemoryLimit {
-		result.Status = StatusMemoryLimitExceeded
+}
+
+func (c *Cmd) progress(s Stage, r *Result) {
+	if c.Progress != nil {
+		c.Progress(s, r)
 	}
-	return result, nil
 }
 
 func runSingleCopyIn(m Environment, copyInFiles map[string]File) ([]FileError, error) {
 	if len(copyInFiles) == 0 {
 		return nil, nil
 	}
-	return copyIn(m, copyInFiles)
+	// directory trees are extracted first so that files could override their content
+	files, dirs := splitDirs(copyInFiles)
+	if fe, err := copyInDirs(m, dirs); err != nil {
+		return fe, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return copyIn(m, files)
 }
 
 func runSingleWait(pc context.Context, m Environment, c *Cmd, fds []*os.File) RunnerResult {
@@ -95,6 +137,8 @@ func runSingleWait(pc context.Context, m Environment, c *Cmd, fds []*os.File) Ru
 		}
 	}
 
+	c.progress(StageProcessStarted, nil)
+
 	// starts waiter to periodically check cpu usage
 	c.Waiter(ctx, process)
 	// cancel the process as waiter exits
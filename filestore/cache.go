@@ -0,0 +1,278 @@
+package filestore
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/pkg/memfd"
+)
+
+var _ FileStore = &Cache{}
+
+// CacheConfig defines the policy of the memory tier of Cache
+type CacheConfig struct {
+	Limit       envexec.Size // Limit is the total bytes kept in memory
+	MaxFileSize envexec.Size // MaxFileSize is the largest file to be promoted, 0 for Limit
+	PromoteHits int          // PromoteHits is the number of accesses before a file is promoted
+	Memfd       bool         // Memfd keeps content in sealed memfd instead of heap when supported
+}
+
+// CacheStat is the statistics of the memory tier of Cache
+type CacheStat struct {
+	Hits       uint64
+	Misses     uint64
+	Promotions uint64
+	Demotions  uint64
+	Files      int
+	Bytes      int64
+}
+
+// Cache is a two-tier file store. Files that are accessed frequently and are
+// small enough are promoted into the byte-bounded memory tier and served as
+// in-memory FileReader, while large or cold files are served by the underlying
+// file store. The least recently used files are demoted when the memory tier
+// is full.
+type Cache struct {
+	mu sync.Mutex
+	FileStore
+	conf    CacheConfig
+	lru     *list.List               // front is the most recently used
+	entries map[string]*list.Element // id to *cacheEntry
+	access  map[string]int           // id to access count of files not in memory
+	stat    CacheStat
+}
+
+// maxAccess is the number of access counts kept before they are decayed
+const maxAccess = 4096
+
+type cacheEntry struct {
+	id      string
+	name    string
+	content io.ReaderAt
+	size    int64
+	refs    int  // readers returned by Get and not yet released
+	evicted bool // content is closed once the last reader is released
+}
+
+// cacheReader is the reader of the content of an entry returned by Get. It
+// releases the entry once closed or, if never closed, once unreachable.
+type cacheReader struct {
+	*io.SectionReader
+	ref     *cacheRef
+	cleanup runtime.Cleanup
+}
+
+type cacheRef struct {
+	once sync.Once
+	c    *Cache
+	ent  *cacheEntry
+}
+
+func (r *cacheReader) Close() error {
+	r.cleanup.Stop()
+	r.ref.release()
+	return nil
+}
+
+func (r *cacheRef) release() {
+	r.once.Do(func() {
+		r.c.mu.Lock()
+		defer r.c.mu.Unlock()
+
+		r.ent.refs--
+		if r.ent.evicted && r.ent.refs == 0 {
+			closeContent(r.ent.content)
+		}
+	})
+}
+
+// NewCache creates a file store with the memory tier on top of the given file store
+func NewCache(fs FileStore, conf CacheConfig) *Cache {
+	if conf.MaxFileSize <= 0 || conf.MaxFileSize > conf.Limit {
+		conf.MaxFileSize = conf.Limit
+	}
+	if conf.PromoteHits <= 0 {
+		conf.PromoteHits = 1
+	}
+	return &Cache{
+		FileStore: fs,
+		conf:      conf,
+		lru:       list.New(),
+		entries:   make(map[string]*list.Element),
+		access:    make(map[string]int),
+	}
+}
+
+func (c *Cache) Get(id string) (string, envexec.File) {
+	c.mu.Lock()
+	if e, ok := c.entries[id]; ok {
+		c.lru.MoveToFront(e)
+		c.stat.Hits++
+		ent := e.Value.(*cacheEntry)
+		ent.refs++
+		c.mu.Unlock()
+		// section reader reads with ReadAt thus it is safe to share the content
+		r := &cacheReader{
+			SectionReader: io.NewSectionReader(ent.content, 0, ent.size),
+			ref:           &cacheRef{c: c, ent: ent},
+		}
+		r.cleanup = runtime.AddCleanup(r, (*cacheRef).release, r.ref)
+		return ent.name, envexec.NewFileReader(r)
+	}
+	c.stat.Misses++
+	if len(c.access) >= maxAccess {
+		c.decay()
+	}
+	c.access[id]++
+	hits := c.access[id]
+	c.mu.Unlock()
+
+	name, file := c.FileStore.Get(id)
+	if file == nil {
+		c.mu.Lock()
+		delete(c.access, id)
+		c.mu.Unlock()
+		return name, file
+	}
+	if hits >= c.conf.PromoteHits {
+		c.promote(id, name, file)
+	}
+	return name, file
+}
+
+func (c *Cache) Remove(id string) bool {
+	success := c.FileStore.Remove(id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.access, id)
+	if e, ok := c.entries[id]; ok {
+		c.evict(e)
+	}
+	return success
+}
+
+// Close drops the memory tier and closes the underlying file store
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	for e := c.lru.Front(); e != nil; e = c.lru.Front() {
+		c.evict(e)
+	}
+	clear(c.access)
+	c.mu.Unlock()
+
+	return c.FileStore.Close()
+}
+
+// Unwrap returns the underlying file store
+func (c *Cache) Unwrap() FileStore {
+	return c.FileStore
+}
+
+// Stat returns the statistics of the memory tier
+func (c *Cache) Stat() CacheStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stat
+}
+
+// promote loads the file into memory if it is small enough. Only files backed
+// by the file system are promoted.
+func (c *Cache) promote(id, name string, file envexec.File) {
+	fi, ok := file.(*envexec.FileInput)
+	if !ok {
+		return
+	}
+	f, err := os.Open(fi.Path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil || !stat.Mode().IsRegular() || stat.Size() > int64(c.conf.MaxFileSize) {
+		// not promotable, drop the count so that it is not kept forever
+		c.mu.Lock()
+		delete(c.access, id)
+		c.mu.Unlock()
+		return
+	}
+	content, err := c.load(name, f, stat.Size())
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// removed or promoted by others during load
+	if _, ok := c.access[id]; !ok {
+		closeContent(content)
+		return
+	}
+	delete(c.access, id)
+	ent := &cacheEntry{id: id, name: name, content: content, size: stat.Size()}
+	c.entries[id] = c.lru.PushFront(ent)
+	c.stat.Files++
+	c.stat.Bytes += ent.size
+	c.stat.Promotions++
+
+	for c.stat.Bytes > int64(c.conf.Limit) {
+		e := c.lru.Back()
+		c.evict(e)
+		c.stat.Demotions++
+	}
+}
+
+func (c *Cache) load(name string, f *os.File, size int64) (io.ReaderAt, error) {
+	if c.conf.Memfd {
+		if m, err := memfd.DupToMemfd(name, f); err == nil {
+			return m, nil
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// decay halves the access counts and drops the files that are accessed only
+// once since the last decay so that cold files do not accumulate
+func (c *Cache) decay() {
+	for id, n := range c.access {
+		if n /= 2; n == 0 {
+			delete(c.access, id)
+		} else {
+			c.access[id] = n
+		}
+	}
+}
+
+// evict removes the entry from the memory tier. The memfd is closed once the
+// readers returned by Get are all released.
+func (c *Cache) evict(e *list.Element) {
+	ent := c.lru.Remove(e).(*cacheEntry)
+	delete(c.entries, ent.id)
+	c.stat.Files--
+	c.stat.Bytes -= ent.size
+	ent.evicted = true
+	if ent.refs == 0 {
+		closeContent(ent.content)
+	}
+}
+
+func closeContent(content io.ReaderAt) {
+	if c, ok := content.(io.Closer); ok {
+		c.Close()
+	}
+}
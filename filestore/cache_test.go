@@ -0,0 +1,190 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func readCacheFile(t *testing.T, fs FileStore, id string) (envexec.File, string) {
+	t.Helper()
+	_, file := fs.Get(id)
+	if file == nil {
+		t.Fatalf("expected file %q to exist", id)
+	}
+	r, err := envexec.FileToReader(file)
+	if err != nil {
+		t.Fatalf("FileToReader error: %v", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll error: %v", err)
+	}
+	return file, string(b)
+}
+
+func TestCachePromoteAndDemote(t *testing.T) {
+	for _, useMemfd := range []bool{false, true} {
+		c := NewCache(NewFileLocalStore(t.TempDir()), CacheConfig{Limit: 10, PromoteHits: 2, Memfd: useMemfd})
+		a, err := addTestFile(t, c, "a", "aaaaaa")
+		if err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+		b, err := addTestFile(t, c, "b", "bbbbbb")
+		if err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+
+		if file, _ := readCacheFile(t, c, a); !isFileInput(file) {
+			t.Fatalf("expected cold file served by underlying store, got %T", file)
+		}
+		readCacheFile(t, c, a)
+		file, content := readCacheFile(t, c, a)
+		if isFileInput(file) || content != "aaaaaa" {
+			t.Fatalf("expected promoted file served from memory, got %T %q", file, content)
+		}
+
+		// promoting b exceeds the limit and demotes a
+		readCacheFile(t, c, b)
+		readCacheFile(t, c, b)
+		if file, _ := readCacheFile(t, c, a); !isFileInput(file) {
+			t.Fatalf("expected demoted file served by underlying store, got %T", file)
+		}
+		if file, content := readCacheFile(t, c, b); isFileInput(file) || content != "bbbbbb" {
+			t.Fatalf("expected promoted file served from memory, got %T %q", file, content)
+		}
+
+		s := c.Stat()
+		if s.Files != 1 || s.Bytes != 6 || s.Promotions != 2 || s.Demotions != 1 || s.Hits != 2 || s.Misses != 5 {
+			t.Fatalf("unexpected stat %+v", s)
+		}
+		c.Close()
+	}
+}
+
+func TestCacheLargeFileAndRemove(t *testing.T) {
+	c := NewCache(NewFileLocalStore(t.TempDir()), CacheConfig{Limit: 16, MaxFileSize: 4, PromoteHits: 1})
+	large, err := addTestFile(t, c, "large", "12345")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	small, err := addTestFile(t, c, "small", "1234")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+
+	readCacheFile(t, c, large)
+	if file, _ := readCacheFile(t, c, large); !isFileInput(file) {
+		t.Fatalf("expected large file not promoted, got %T", file)
+	}
+	readCacheFile(t, c, small)
+	if file, _ := readCacheFile(t, c, small); isFileInput(file) {
+		t.Fatalf("expected small file promoted")
+	}
+
+	if !c.Remove(small) {
+		t.Fatalf("expected remove to succeed")
+	}
+	if _, file := c.Get(small); file != nil {
+		t.Fatalf("expected removed file not served from memory")
+	}
+	if s := c.Stat(); s.Files != 0 || s.Bytes != 0 {
+		t.Fatalf("unexpected stat after remove %+v", s)
+	}
+}
+
+func TestCacheEvictClosesContent(t *testing.T) {
+	c := NewCache(NewFileLocalStore(t.TempDir()), CacheConfig{Limit: 6, PromoteHits: 1, Memfd: true})
+	a, err := addTestFile(t, c, "a", "aaaaaa")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	b, err := addTestFile(t, c, "b", "bbbbbb")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	readCacheFile(t, c, a)
+	mf, ok := c.entries[a].Value.(*cacheEntry).content.(*os.File)
+	if !ok {
+		t.Skip("memfd is not supported")
+	}
+
+	// a is referenced by the reader and kept open after demoted
+	_, file := c.Get(a)
+	readCacheFile(t, c, b)
+	if _, ok := c.entries[a]; ok {
+		t.Fatalf("expected a demoted")
+	}
+	if _, err := mf.Stat(); err != nil {
+		t.Fatalf("expected referenced content kept open, got %v", err)
+	}
+	r, err := envexec.FileToReader(file)
+	if err != nil {
+		t.Fatalf("FileToReader error: %v", err)
+	}
+	if b, err := io.ReadAll(r); err != nil || string(b) != "aaaaaa" {
+		t.Fatalf("expected content readable, got %q %v", b, err)
+	}
+	r.Close()
+	if _, err := mf.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected content closed once released, got %v", err)
+	}
+
+	// b is not referenced and closed on eviction
+	mf = c.entries[b].Value.(*cacheEntry).content.(*os.File)
+	c.Close()
+	if _, err := mf.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected content closed on close, got %v", err)
+	}
+}
+
+type fixedFileStore struct {
+	FileStore
+}
+
+func (fixedFileStore) Get(id string) (string, envexec.File) {
+	return id, envexec.NewFileInput("/nonexistent/" + id)
+}
+
+func TestCacheAccessBounded(t *testing.T) {
+	c := NewCache(NewFileLocalStore(t.TempDir()), CacheConfig{Limit: 16, MaxFileSize: 4, PromoteHits: 2})
+	large, err := addTestFile(t, c, "large", "12345")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	for range 4 {
+		readCacheFile(t, c, large)
+	}
+	if len(c.access) != 0 {
+		t.Fatalf("expected access count of oversize file dropped, got %v", c.access)
+	}
+
+	c = NewCache(fixedFileStore{}, CacheConfig{Limit: 16, PromoteHits: 100})
+	for i := range 3 * maxAccess {
+		c.Get(strconv.Itoa(i))
+	}
+	if n := len(c.access); n > maxAccess {
+		t.Fatalf("expected access counts bounded by %d, got %d", maxAccess, n)
+	}
+}
+
+func TestCacheNamespacedStat(t *testing.T) {
+	c := NewCache(NewFileLocalStore(t.TempDir()), CacheConfig{Limit: 16, PromoteHits: 1})
+	if _, err := addTestFile(t, c, "a", "aaaa"); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	NewNamespaced(c, 0)
+	if s := c.Stat(); s != (CacheStat{}) {
+		t.Fatalf("expected namespaced store not affecting cache stat, got %+v", s)
+	}
+}
+
+func isFileInput(f envexec.File) bool {
+	_, ok := f.(*envexec.FileInput)
+	return ok
+}
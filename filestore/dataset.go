@@ -0,0 +1,215 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrDatasetNotFound is returned when the dataset or the file inside it does not exist
+var ErrDatasetNotFound = errors.New("dataset file not found")
+
+// DatasetStore defines a file store that also serves files from read-only datasets
+type DatasetStore interface {
+	FileStore
+	OpenDataset(name, path string) (envexec.File, error)
+}
+
+// Datasets is a registry of read-only host directories addressed by name.
+// Files are indexed when created and on Reindex, and only regular files
+// found by the index inside the dataset root are served.
+type Datasets struct {
+	mu    sync.RWMutex
+	roots map[string]string
+	index map[string]map[string]int64 // dataset name to path to file size
+}
+
+// DatasetInfo is the summary of a dataset
+type DatasetInfo struct {
+	Name  string
+	Count int
+	Size  int64
+}
+
+// DatasetEntry is a file inside a dataset
+type DatasetEntry struct {
+	Path string
+	Size int64
+}
+
+type datasetStore struct {
+	FileStore
+	d *Datasets
+}
+
+// NewDatasets creates the registry from dataset name to host directory and
+// indexes all the datasets
+func NewDatasets(roots map[string]string) (*Datasets, error) {
+	d := &Datasets{roots: make(map[string]string)}
+	for name, root := range roots {
+		if name == "" || strings.ContainsAny(name, "/\\") {
+			return nil, fmt.Errorf("dataset name %q is not valid", name)
+		}
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("dataset %q: %w", name, err)
+		}
+		if abs, err = filepath.EvalSymlinks(abs); err != nil {
+			return nil, fmt.Errorf("dataset %q: %w", name, err)
+		}
+		d.roots[name] = abs
+	}
+	if err := d.Reindex(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// WithDatasets returns a file store that also serves files from the datasets
+func WithDatasets(fs FileStore, d *Datasets) FileStore {
+	if d == nil {
+		return fs
+	}
+	return &datasetStore{FileStore: fs, d: d}
+}
+
+// OpenDataset opens the file inside dataset if the file store serves datasets
+func OpenDataset(fs FileStore, name, path string) (envexec.File, error) {
+	s, ok := fs.(DatasetStore)
+	if !ok {
+		return nil, fmt.Errorf("dataset %q: %w", name, ErrDatasetNotFound)
+	}
+	return s.OpenDataset(name, path)
+}
+
+func (s *datasetStore) OpenDataset(name, path string) (envexec.File, error) {
+	return s.d.Open(name, path)
+}
+
+// Reindex walks all the dataset roots and replaces the index. The previous
+// index is kept if any of the datasets fails to index.
+func (d *Datasets) Reindex() error {
+	d.mu.RLock()
+	roots := maps.Clone(d.roots)
+	d.mu.RUnlock()
+
+	index := make(map[string]map[string]int64, len(roots))
+	for name, root := range roots {
+		files := make(map[string]int64)
+		err := filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !e.Type().IsRegular() {
+				return nil
+			}
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = info.Size()
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("index dataset %q: %w", name, err)
+		}
+		index[name] = files
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.index = index
+	return nil
+}
+
+// Open returns the file inside the dataset. The path is resolved within the
+// dataset root and must be an indexed regular file.
+func (d *Datasets) Open(name, p string) (envexec.File, error) {
+	d.mu.RLock()
+	root, ok := d.roots[name]
+	files := d.index[name]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("dataset %q: %w", name, ErrDatasetNotFound)
+	}
+
+	rel, err := envexec.CleanArchivePath(p)
+	if err != nil {
+		return nil, fmt.Errorf("dataset %q: %w", name, err)
+	}
+	if _, ok := files[rel]; !ok || rel == "" {
+		return nil, fmt.Errorf("dataset %q file %q: %w", name, p, ErrDatasetNotFound)
+	}
+
+	// the file could be replaced by a symlink after indexed
+	full, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, fmt.Errorf("dataset %q file %q: %w", name, p, err)
+	}
+	if r, err := filepath.Rel(root, full); err != nil || !filepath.IsLocal(r) {
+		return nil, fmt.Errorf("dataset %q file %q escapes the root", name, p)
+	}
+	fi, err := os.Stat(full)
+	if err != nil {
+		return nil, fmt.Errorf("dataset %q file %q: %w", name, p, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("dataset %q file %q is not a regular file", name, p)
+	}
+	return envexec.NewFileInput(full), nil
+}
+
+// List returns the summary of all the datasets sorted by name
+func (d *Datasets) List() []DatasetInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rt := make([]DatasetInfo, 0, len(d.roots))
+	for _, name := range slices.Sorted(maps.Keys(d.roots)) {
+		info := DatasetInfo{Name: name, Count: len(d.index[name])}
+		for _, s := range d.index[name] {
+			info.Size += s
+		}
+		rt = append(rt, info)
+	}
+	return rt
+}
+
+// Files returns the indexed files of the dataset under the directory prefix
+// sorted by path
+func (d *Datasets) Files(name, prefix string) ([]DatasetEntry, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	files, ok := d.index[name]
+	if !ok {
+		return nil, fmt.Errorf("dataset %q: %w", name, ErrDatasetNotFound)
+	}
+	dir, err := envexec.CleanArchivePath(prefix)
+	if err != nil {
+		return nil, err
+	}
+	rt := make([]DatasetEntry, 0)
+	for p, s := range files {
+		if dir != "" && p != dir && !strings.HasPrefix(p, dir+"/") {
+			continue
+		}
+		rt = append(rt, DatasetEntry{Path: p, Size: s})
+	}
+	slices.SortFunc(rt, func(a, b DatasetEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return rt, nil
+}
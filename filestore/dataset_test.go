@@ -0,0 +1,93 @@
+package filestore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func newTestDatasets(t *testing.T) (*Datasets, string) {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "tests")
+	if err := os.MkdirAll(filepath.Join(root, "1"), 0o755); err != nil {
+		t.Fatalf("MkdirAll error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "1", "in.txt"), []byte("input"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	d, err := NewDatasets(map[string]string{"tests": root})
+	if err != nil {
+		t.Fatalf("NewDatasets error: %v", err)
+	}
+	return d, root
+}
+
+func TestDatasetsOpen(t *testing.T) {
+	d, root := newTestDatasets(t)
+	fs := WithDatasets(NewFileLocalStore(t.TempDir()), d)
+
+	file, err := OpenDataset(fs, "tests", "1/in.txt")
+	if err != nil {
+		t.Fatalf("OpenDataset error: %v", err)
+	}
+	if f, ok := file.(*envexec.FileInput); !ok || filepath.Base(f.Path) != "in.txt" {
+		t.Fatalf("unexpected file %+v", file)
+	}
+
+	for _, p := range []string{"../secret", "/1/in.txt", "1", "1/missing", ""} {
+		if _, err := d.Open("tests", p); err == nil {
+			t.Errorf("expected open %q to fail", p)
+		}
+	}
+	if _, err := d.Open("missing", "1/in.txt"); !errors.Is(err, ErrDatasetNotFound) {
+		t.Errorf("expected dataset not found, got %v", err)
+	}
+	if _, err := OpenDataset(NewFileLocalStore(t.TempDir()), "tests", "1/in.txt"); !errors.Is(err, ErrDatasetNotFound) {
+		t.Errorf("expected dataset not found without datasets, got %v", err)
+	}
+
+	// indexed file replaced by symlink escaping the root
+	p := filepath.Join(root, "1", "in.txt")
+	os.Remove(p)
+	if err := os.Symlink(filepath.Join(root, "..", "secret"), p); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+	if _, err := d.Open("tests", "1/in.txt"); err == nil {
+		t.Errorf("expected symlink escape to be rejected")
+	}
+}
+
+func TestDatasetsReindex(t *testing.T) {
+	d, root := newTestDatasets(t)
+	if err := os.WriteFile(filepath.Join(root, "2.txt"), []byte("22"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := d.Open("tests", "2.txt"); err == nil {
+		t.Fatalf("expected file not indexed before reindex")
+	}
+	if err := d.Reindex(); err != nil {
+		t.Fatalf("Reindex error: %v", err)
+	}
+	if _, err := d.Open("tests", "2.txt"); err != nil {
+		t.Fatalf("Open error after reindex: %v", err)
+	}
+
+	list := d.List()
+	if len(list) != 1 || list[0].Name != "tests" || list[0].Count != 2 || list[0].Size != 7 {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	files, err := d.Files("tests", "1")
+	if err != nil {
+		t.Fatalf("Files error: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "1/in.txt" || files[0].Size != 5 {
+		t.Fatalf("unexpected files %+v", files)
+	}
+}
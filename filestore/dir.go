@@ -0,0 +1,89 @@
+package filestore
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrInvalidArchive is returned by AddDir when the archive cannot be parsed
+var ErrInvalidArchive = errors.New("invalid directory archive")
+
+// AddDir adds a directory tree into the file store as one object. The archive
+// could be in tar, tar.gz or zip format and it is normalized into tar format
+// with file modes and symbolic links preserved.
+func AddDir(fs FileStore, name string, r io.ReaderAt, size int64) (string, error) {
+	f, err := fs.New()
+	if err != nil {
+		return "", err
+	}
+	if err := normalizeArchive(f, r, size); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("add dir %q: %w: %v", name, ErrInvalidArchive, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return fs.Add(name, f.Name())
+}
+
+func normalizeArchive(w io.Writer, r io.ReaderAt, size int64) error {
+	tw := tar.NewWriter(w)
+	if err := envexec.WalkArchive(r, size, func(hdr *tar.Header, r io.Reader) error {
+		var t byte
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			t = tar.TypeDir
+		case tar.TypeReg, tar.TypeRegA:
+			t = tar.TypeReg
+		case tar.TypeSymlink:
+			t = tar.TypeSymlink
+		case tar.TypeXGlobalHeader:
+			return nil
+		default:
+			return fmt.Errorf("entry %q has unsupported type %q", hdr.Name, hdr.Typeflag)
+		}
+		return writeTarEntry(tw, hdr.Name, t, os.FileMode(hdr.Mode), hdr.Size, hdr.Linkname, r)
+	}); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func writeTarEntry(tw *tar.Writer, name string, t byte, mode os.FileMode, size int64, link string, r io.Reader) error {
+	p, err := envexec.CleanArchivePath(name)
+	if err != nil {
+		return err
+	}
+	if p == "" {
+		return nil
+	}
+	hdr := &tar.Header{
+		Typeflag: t,
+		Name:     p,
+		Mode:     int64(mode.Perm()),
+		Linkname: link,
+		Format:   tar.FormatPAX,
+	}
+	switch t {
+	case tar.TypeDir:
+		hdr.Name += "/"
+	case tar.TypeReg:
+		hdr.Size = size
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if t == tar.TypeReg {
+		if _, err := io.Copy(tw, r); err != nil {
+			return fmt.Errorf("entry %q: %w", name, err)
+		}
+	}
+	return nil
+}
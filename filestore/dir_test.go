@@ -0,0 +1,93 @@
+package filestore
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func readTarEntries(t *testing.T, fs FileStore, id string) map[string]*tar.Header {
+	t.Helper()
+	_, file := fs.Get(id)
+	if file == nil {
+		t.Fatalf("expected dir %q in store", id)
+	}
+	f, err := os.Open(file.(*envexec.FileInput).Path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer f.Close()
+
+	rt := make(map[string]*tar.Header)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return rt
+		}
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		rt[hdr.Name] = hdr
+	}
+}
+
+func TestAddDirFromZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fh := &zip.FileHeader{Name: "bin/run"}
+	fh.SetMode(0755)
+	w, err := zw.CreateHeader(fh)
+	if err != nil {
+		t.Fatalf("CreateHeader error: %v", err)
+	}
+	w.Write([]byte("run"))
+	lh := &zip.FileHeader{Name: "latest"}
+	lh.SetMode(os.ModeSymlink | 0777)
+	w, err = zw.CreateHeader(lh)
+	if err != nil {
+		t.Fatalf("CreateHeader error: %v", err)
+	}
+	w.Write([]byte("bin/run"))
+	zw.Close()
+
+	fs := NewFileLocalStore(t.TempDir())
+	id, err := AddDir(fs, "proj.zip", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("AddDir error: %v", err)
+	}
+	entries := readTarEntries(t, fs, id)
+	if hdr := entries["bin/run"]; hdr == nil || hdr.Mode != 0755 || hdr.Size != 3 {
+		t.Errorf("unexpected regular entry: %+v", hdr)
+	}
+	if hdr := entries["latest"]; hdr == nil || hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "bin/run" {
+		t.Errorf("unexpected symlink entry: %+v", hdr)
+	}
+}
+
+func TestAddDirRejectsTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../../etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 1})
+	tw.Write([]byte("x"))
+	tw.Close()
+
+	dir := t.TempDir()
+	fs := NewFileLocalStore(dir)
+	_, err := AddDir(fs, "evil.tar", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !errors.Is(err, ErrInvalidArchive) {
+		t.Fatalf("expected invalid archive, got %v", err)
+	}
+	if len(fs.List()) != 0 {
+		t.Fatalf("expected nothing added")
+	}
+	if names, _ := os.ReadDir(dir); len(names) != 0 {
+		t.Fatalf("expected temporary file removed, got %v", names)
+	}
+}
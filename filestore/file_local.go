@@ -109,6 +109,28 @@ func (s *fileLocalStore) New() (*os.File, error) {
 	return nil, errUniqueIDNotGenerated
 }
 
+// restore moves the file created by New to the path of the id, thus it is
+// added with the id preserved
+func (s *fileLocalStore) restore(id, path string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validID(id) {
+		return "", fmt.Errorf("restore: file id %q is not valid", id)
+	}
+	if s.dir != filepath.Dir(path) {
+		return "", fmt.Errorf("restore: %s does not have prefix %s", path, s.dir)
+	}
+	p := filepath.Join(s.dir, id)
+	if _, err := os.Lstat(p); err == nil {
+		return "", fmt.Errorf("restore: file %q already exists", id)
+	}
+	if err := os.Rename(path, p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
 func (s *fileLocalStore) Close() error {
 	return nil
 }
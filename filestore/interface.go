@@ -21,6 +21,12 @@ type FileStore interface {
 	Close() error                          // Close releases resources owned by the file store
 }
 
+// Unwrapper is implemented by the file store wrappers so that the file stores
+// underneath are reachable, e.g. to read files without affecting the wrappers
+type Unwrapper interface {
+	Unwrap() FileStore
+}
+
 func generateID() (string, error) {
 	const randIDLength = 5
 	b := make([]byte, randIDLength)
@@ -0,0 +1,278 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrQuotaExceeded is returned by Add when the namespace runs out of quota
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+var (
+	_ Namespaced = &namespacedStore{}
+	_ FileStore  = &namespaceView{}
+)
+
+// Namespaced defines a file store that partitions files by namespace.
+// The Namespaced itself operates on all files regardless of their namespace
+// while the FileStore returned by Namespace only sees files owned by the namespace.
+type Namespaced interface {
+	FileStore
+	Namespace(string) FileStore
+}
+
+type namespacedStore struct {
+	mu sync.Mutex
+	FileStore
+	quota envexec.Size      // maximum bytes per namespace, 0 for unlimited
+	owner map[string]string // id to namespace mapping
+	size  map[string]int64  // id to file size mapping
+	usage map[string]int64  // namespace to total file size mapping
+}
+
+type namespaceView struct {
+	s  *namespacedStore
+	ns string
+}
+
+// NewNamespaced creates a namespaced file store with per-namespace quota
+// on top of the given file store. Existing files belong to the default namespace.
+func NewNamespaced(fs FileStore, quota envexec.Size) Namespaced {
+	s := &namespacedStore{
+		FileStore: fs,
+		quota:     quota,
+		owner:     make(map[string]string),
+		size:      make(map[string]int64),
+		usage:     make(map[string]int64),
+	}
+	// stat underneath the wrappers so that the cache and timeout are not affected
+	_, base := metaStores(fs)
+	for id := range fs.List() {
+		_, file := base.Get(id)
+		s.track("", id, fileSize(file))
+	}
+	return s
+}
+
+// ForNamespace returns the view of the namespace if the file store is namespaced,
+// otherwise the file store itself is returned.
+func ForNamespace(fs FileStore, ns string) FileStore {
+	if n, ok := fs.(Namespaced); ok {
+		return n.Namespace(ns)
+	}
+	return fs
+}
+
+type namespaceKey struct{}
+
+// WithNamespace returns a copy of ctx that carries the namespace
+func WithNamespace(ctx context.Context, ns string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, ns)
+}
+
+// NamespaceFromContext returns the namespace carried by ctx, or the default namespace
+func NamespaceFromContext(ctx context.Context) string {
+	ns, _ := ctx.Value(namespaceKey{}).(string)
+	return ns
+}
+
+func (s *namespacedStore) Namespace(ns string) FileStore {
+	return &namespaceView{s: s, ns: ns}
+}
+
+func (s *namespacedStore) Add(name, path string) (string, error) {
+	return s.add("", name, path)
+}
+
+func (s *namespacedStore) Remove(id string) bool {
+	s.mu.Lock()
+	s.untrack(id)
+	s.mu.Unlock()
+
+	return s.FileStore.Remove(id)
+}
+
+// removeOwned removes the file if it is owned by the namespace. The file is
+// untracked under lock before removal so that it is removed at most once.
+func (s *namespacedStore) removeOwned(ns, id string) bool {
+	s.mu.Lock()
+	if owner, ok := s.owner[id]; !ok || owner != ns {
+		s.mu.Unlock()
+		return false
+	}
+	s.untrack(id)
+	s.mu.Unlock()
+
+	return s.FileStore.Remove(id)
+}
+
+func (s *namespacedStore) add(ns, name, path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	size := fi.Size()
+
+	// reserve the quota under lock and add to the underlying store outside
+	// it so that uploads are not serialized
+	if err := s.reserve(ns, name, size); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	id, err := s.FileStore.Add(name, path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usage[ns] -= size
+	if err != nil {
+		if s.usage[ns] <= 0 {
+			delete(s.usage, ns)
+		}
+		return "", err
+	}
+	s.track(ns, id, size)
+	return id, nil
+}
+
+// reserve adds size to the usage of the namespace if it is within quota. Files
+// removed underlying (e.g. timeout) are only pruned when the quota is exceeded.
+func (s *namespacedStore) reserve(ns, name string, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.quota > 0 && s.usage[ns]+size > int64(s.quota) {
+		s.prune()
+		if s.usage[ns]+size > int64(s.quota) {
+			return fmt.Errorf("add %q: %w (used %d, size %d, quota %d)", name, ErrQuotaExceeded, s.usage[ns], size, s.quota)
+		}
+	}
+	s.usage[ns] += size
+	return nil
+}
+
+func (s *namespacedStore) Unwrap() FileStore {
+	return s.FileStore
+}
+
+func (s *namespacedStore) fileMeta(m *FileMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.Namespace = s.owner[m.ID]
+}
+
+// restoreMeta does nothing since the file is added to its namespace by Import
+func (s *namespacedStore) restoreMeta(FileMeta) {}
+
+func (s *namespacedStore) owns(ns, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner, ok := s.owner[id]
+	return ok && owner == ns
+}
+
+// prune removes accounting for files that were removed underlying (e.g. timeout)
+func (s *namespacedStore) prune() {
+	ids := s.FileStore.List()
+	for id := range s.owner {
+		if _, ok := ids[id]; !ok {
+			s.untrack(id)
+		}
+	}
+}
+
+func (s *namespacedStore) track(ns, id string, size int64) {
+	s.untrack(id)
+	s.owner[id] = ns
+	s.size[id] = size
+	s.usage[ns] += size
+}
+
+func (s *namespacedStore) untrack(id string) {
+	ns, ok := s.owner[id]
+	if !ok {
+		return
+	}
+	s.usage[ns] -= s.size[id]
+	if s.usage[ns] <= 0 {
+		delete(s.usage, ns)
+	}
+	delete(s.owner, id)
+	delete(s.size, id)
+}
+
+func (v *namespaceView) Add(name, path string) (string, error) {
+	return v.s.add(v.ns, name, path)
+}
+
+func (v *namespaceView) Remove(id string) bool {
+	return v.s.removeOwned(v.ns, id)
+}
+
+func (v *namespaceView) Get(id string) (string, envexec.File) {
+	if !v.s.owns(v.ns, id) {
+		return "", nil
+	}
+	name, file := v.s.FileStore.Get(id)
+	if file == nil {
+		v.s.mu.Lock()
+		v.s.untrack(id)
+		v.s.mu.Unlock()
+	}
+	return name, file
+}
+
+func (v *namespaceView) List() map[string]string {
+	all := v.s.FileStore.List()
+
+	v.s.mu.Lock()
+	defer v.s.mu.Unlock()
+
+	names := make(map[string]string)
+	for id, owner := range v.s.owner {
+		if owner != v.ns {
+			continue
+		}
+		name, ok := all[id]
+		if !ok {
+			v.s.untrack(id)
+			continue
+		}
+		names[id] = name
+	}
+	return names
+}
+
+func (v *namespaceView) New() (*os.File, error) {
+	return v.s.FileStore.New()
+}
+
+// Close does nothing since the underlying file store is owned by the Namespaced
+func (v *namespaceView) Close() error {
+	return nil
+}
+
+func fileSize(file envexec.File) int64 {
+	if f, ok := file.(*envexec.FileReader); ok {
+		if r, ok := f.Reader.(interface{ Size() int64 }); ok {
+			return r.Size()
+		}
+		return 0
+	}
+	f, ok := file.(*envexec.FileInput)
+	if !ok {
+		return 0
+	}
+	fi, err := os.Stat(f.Path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
@@ -0,0 +1,126 @@
+package filestore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func addTestFile(t *testing.T, fs FileStore, name, content string) (string, error) {
+	t.Helper()
+	f, err := fs.New()
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("WriteString error: %v", err)
+	}
+	f.Close()
+	return fs.Add(name, f.Name())
+}
+
+func TestNamespacedIsolation(t *testing.T) {
+	ns := NewNamespaced(NewFileLocalStore(t.TempDir()), 0)
+	a, b := ns.Namespace("a"), ns.Namespace("b")
+
+	id, err := addTestFile(t, a, "file", "content")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if _, file := a.Get(id); file == nil {
+		t.Fatalf("expected file to be visible in its namespace")
+	}
+	if _, file := b.Get(id); file != nil {
+		t.Fatalf("expected file to be hidden from other namespace")
+	}
+	if _, ok := b.List()[id]; ok {
+		t.Fatalf("expected file not listed in other namespace")
+	}
+	if name := a.List()[id]; name != "file" {
+		t.Fatalf("unexpected list name %q", name)
+	}
+	if _, ok := ns.List()[id]; !ok {
+		t.Fatalf("expected file listed in the underlying store")
+	}
+	if b.Remove(id) {
+		t.Fatalf("expected remove from other namespace to fail")
+	}
+	if !a.Remove(id) {
+		t.Fatalf("expected remove from owning namespace to succeed")
+	}
+}
+
+func TestNamespacedQuota(t *testing.T) {
+	ns := NewNamespaced(NewFileLocalStore(t.TempDir()), 8)
+	a, b := ns.Namespace("a"), ns.Namespace("b")
+
+	id, err := addTestFile(t, a, "first", "12345")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if _, err := addTestFile(t, a, "second", "12345"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if _, err := addTestFile(t, b, "other", "12345"); err != nil {
+		t.Fatalf("expected quota to be per namespace, got %v", err)
+	}
+	if !a.Remove(id) {
+		t.Fatalf("Remove failed")
+	}
+	if _, err := addTestFile(t, a, "second", "12345"); err != nil {
+		t.Fatalf("expected quota to be released after remove, got %v", err)
+	}
+}
+
+func TestNamespacedQuotaConcurrent(t *testing.T) {
+	ns := NewNamespaced(NewFileLocalStore(t.TempDir()), 10)
+	a := ns.Namespace("a")
+
+	var (
+		wg sync.WaitGroup
+		ok atomic.Int32
+	)
+	for range 8 {
+		f, err := a.New()
+		if err != nil {
+			t.Fatalf("New error: %v", err)
+		}
+		f.WriteString("12345")
+		f.Close()
+		wg.Go(func() {
+			if _, err := a.Add("file", f.Name()); err == nil {
+				ok.Add(1)
+			} else if !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := ok.Load(); n != 2 {
+		t.Fatalf("expected 2 files within quota, got %d", n)
+	}
+}
+
+func TestNamespacedExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ABCDEFGH"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	ns := NewNamespaced(NewFileLocalStore(dir), 0)
+	if _, file := ns.Namespace("").Get("ABCDEFGH"); file == nil {
+		t.Fatalf("expected existing file in default namespace")
+	}
+	if _, file := ns.Namespace("a").Get("ABCDEFGH"); file != nil {
+		t.Fatalf("expected existing file hidden from other namespace")
+	}
+}
+
+func TestForNamespacePassthrough(t *testing.T) {
+	fs := NewFileLocalStore(t.TempDir())
+	if ForNamespace(fs, "a") != fs {
+		t.Fatalf("expected non-namespaced store to be returned as is")
+	}
+}
@@ -0,0 +1,327 @@
+package filestore
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+const (
+	snapshotVersion  = 1
+	snapshotManifest = "manifest.json"
+	snapshotFilesDir = "files/"
+)
+
+// ErrSnapshotConflict is returned by Import with ConflictFail when a file id
+// in the snapshot already exists
+var ErrSnapshotConflict = errors.New("snapshot file id conflicts")
+
+// ConflictMode defines how Import handles files with an existing id
+type ConflictMode string
+
+// ConflictMode enums
+const (
+	ConflictSkip      ConflictMode = "skip"      // keep the existing file
+	ConflictOverwrite ConflictMode = "overwrite" // replace the existing file
+	ConflictFail      ConflictMode = "fail"      // import nothing
+)
+
+// FileMeta is the metadata of a file recorded in the snapshot
+type FileMeta struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Namespace string    `json:"namespace,omitempty"`
+	Expires   time.Time `json:"expires,omitzero"` // zero if the file does not expire
+}
+
+// ImportResult is the summary of Import
+type ImportResult struct {
+	Imported    int `json:"imported"`
+	Skipped     int `json:"skipped"`
+	Overwritten int `json:"overwritten"`
+}
+
+type snapshotManifestFile struct {
+	Version int        `json:"version"`
+	Created time.Time  `json:"created"`
+	Files   []FileMeta `json:"files"`
+}
+
+// idRestorer is implemented by the file store that derives the file id from
+// the path, which is able to move the file to the path of the id
+type idRestorer interface {
+	restore(id, path string) (string, error)
+}
+
+// metaStore is implemented by the file store wrappers that keep metadata
+// of files in addition to the underlying file store
+type metaStore interface {
+	fileMeta(m *FileMeta)
+	restoreMeta(m FileMeta)
+}
+
+// metaStores returns the metadata wrappers of the file store and the file
+// store underneath all the wrappers
+func metaStores(fs FileStore) ([]metaStore, FileStore) {
+	var rt []metaStore
+	for {
+		if m, ok := fs.(metaStore); ok {
+			rt = append(rt, m)
+		}
+		u, ok := fs.(Unwrapper)
+		if !ok {
+			return rt, fs
+		}
+		fs = u.Unwrap()
+	}
+}
+
+// Export writes all files of the file store as a tar stream. Each file is
+// stored as files/<id> followed by manifest.json with the metadata of files.
+// Files removed during export are not included. Files are read underneath
+// the wrappers so that their access time and statistics are not affected.
+func Export(fs FileStore, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	ms, base := metaStores(fs)
+	manifest := snapshotManifestFile{Version: snapshotVersion, Created: time.Now(), Files: make([]FileMeta, 0)}
+	for id, name := range fs.List() {
+		m := FileMeta{ID: id, Name: name}
+		for _, s := range ms {
+			s.fileMeta(&m)
+		}
+		ok, err := exportFile(tw, base, &m)
+		if err != nil {
+			return fmt.Errorf("export %q: %w", id, err)
+		}
+		if !ok {
+			continue
+		}
+		manifest.Files = append(manifest.Files, m)
+	}
+
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     snapshotManifest,
+		Mode:     0644,
+		Size:     int64(len(b)),
+		ModTime:  manifest.Created,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func exportFile(tw *tar.Writer, fs FileStore, m *FileMeta) (bool, error) {
+	name, file := fs.Get(m.ID)
+	if file == nil {
+		return false, nil
+	}
+	if m.Name == "" {
+		m.Name = name
+	}
+	r, err := envexec.FileToReader(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer r.Close()
+
+	var size int64
+	switch f := r.(type) {
+	case *os.File:
+		fi, err := f.Stat()
+		if err != nil {
+			return false, err
+		}
+		size = fi.Size()
+	default:
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return false, err
+		}
+		size = int64(len(content))
+		r = io.NopCloser(bytes.NewReader(content))
+	}
+	m.Size = size
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     snapshotFilesDir + m.ID,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}); err != nil {
+		return false, err
+	}
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Import reads the snapshot created by Export into the file store with file
+// ids preserved. Files are staged in the file store before the manifest is
+// read, and nothing is imported when the snapshot is invalid or conflicts
+// under ConflictFail.
+func Import(fs FileStore, r io.Reader, mode ConflictMode) (ImportResult, error) {
+	var res ImportResult
+	switch mode {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return res, fmt.Errorf("import: conflict mode %q is not valid", mode)
+	}
+
+	staged := make(map[string]string) // id to staged path
+	defer func() {
+		for _, p := range staged {
+			os.Remove(p)
+		}
+	}()
+
+	var manifest *snapshotManifestFile
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("import: %w", err)
+		}
+		switch {
+		case hdr.Name == snapshotManifest:
+			manifest = new(snapshotManifestFile)
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return res, fmt.Errorf("import: manifest: %w", err)
+			}
+
+		case strings.HasPrefix(hdr.Name, snapshotFilesDir):
+			id := strings.TrimPrefix(hdr.Name, snapshotFilesDir)
+			if !validID(id) {
+				return res, fmt.Errorf("import: file id %q is not valid", id)
+			}
+			p, err := stageFile(fs, tr)
+			if err != nil {
+				return res, fmt.Errorf("import %q: %w", id, err)
+			}
+			if old, ok := staged[id]; ok {
+				os.Remove(old)
+			}
+			staged[id] = p
+		}
+	}
+	if manifest == nil {
+		return res, fmt.Errorf("import: %s not found", snapshotManifest)
+	}
+	if manifest.Version != snapshotVersion {
+		return res, fmt.Errorf("import: snapshot version %d is not supported", manifest.Version)
+	}
+
+	existing := fs.List()
+	var files []FileMeta
+	for _, m := range manifest.Files {
+		if _, ok := staged[m.ID]; !ok {
+			return res, fmt.Errorf("import: file %q is missing from snapshot", m.ID)
+		}
+		if _, ok := existing[m.ID]; ok {
+			switch mode {
+			case ConflictFail:
+				return res, fmt.Errorf("import %q: %w", m.ID, ErrSnapshotConflict)
+			case ConflictSkip:
+				res.Skipped++
+				continue
+			}
+		}
+		files = append(files, m)
+	}
+
+	ms, _ := metaStores(fs)
+	for _, m := range files {
+		if _, ok := existing[m.ID]; ok {
+			fs.Remove(m.ID)
+			res.Overwritten++
+		}
+		if err := restoreFile(fs, m, staged[m.ID]); err != nil {
+			return res, fmt.Errorf("import %q: %w", m.ID, err)
+		}
+		delete(staged, m.ID)
+		for _, s := range ms {
+			s.restoreMeta(m)
+		}
+		res.Imported++
+	}
+	return res, nil
+}
+
+func stageFile(fs FileStore, r io.Reader) (string, error) {
+	f, err := fs.New()
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// restoreFile moves the staged file to the path of the id by the file store
+// underneath and adds it through the wrappers. The file is charged to its own
+// namespace when the file store is namespaced.
+func restoreFile(fs FileStore, m FileMeta, staged string) error {
+	add := fs.Add
+	for u := fs; ; {
+		if s, ok := u.(*namespacedStore); ok {
+			add = func(name, path string) (string, error) {
+				return s.add(m.Namespace, name, path)
+			}
+			break
+		}
+		w, ok := u.(Unwrapper)
+		if !ok {
+			break
+		}
+		u = w.Unwrap()
+	}
+	_, base := metaStores(fs)
+	r, ok := base.(idRestorer)
+	if !ok {
+		return fmt.Errorf("file store does not preserve id")
+	}
+	p, err := r.restore(m.ID, staged)
+	if err != nil {
+		return err
+	}
+	id, err := add(m.Name, p)
+	if err != nil {
+		os.Remove(p)
+		return err
+	}
+	if id != m.ID {
+		fs.Remove(id)
+		return fmt.Errorf("file store does not preserve id (got %q)", id)
+	}
+	return nil
+}
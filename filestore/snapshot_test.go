@@ -0,0 +1,155 @@
+package filestore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func readStoreFile(t *testing.T, fs FileStore, id string) string {
+	t.Helper()
+	_, file := fs.Get(id)
+	if file == nil {
+		t.Fatalf("expected file %q to exist", id)
+	}
+	r, err := envexec.FileToReader(file)
+	if err != nil {
+		t.Fatalf("FileToReader error: %v", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll error: %v", err)
+	}
+	return string(b)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := NewNamespaced(NewTimeout(NewFileLocalStore(t.TempDir()), time.Hour, time.Hour), 0)
+	defer src.Close()
+	a, err := addTestFile(t, src.Namespace("team"), "a.txt", "aaa")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	b, err := addTestFile(t, src, "b.txt", "bb")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(src, &buf); err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+
+	dst := NewNamespaced(NewTimeout(NewFileLocalStore(t.TempDir()), 2*time.Hour, time.Hour), 0)
+	defer dst.Close()
+	res, err := Import(dst, bytes.NewReader(buf.Bytes()), ConflictFail)
+	if err != nil {
+		t.Fatalf("Import error: %v", err)
+	}
+	if res.Imported != 2 {
+		t.Fatalf("unexpected import result %+v", res)
+	}
+	if got := readStoreFile(t, dst.Namespace("team"), a); got != "aaa" {
+		t.Fatalf("unexpected content %q", got)
+	}
+	if _, file := dst.Namespace("").Get(a); file != nil {
+		t.Fatalf("expected namespace preserved")
+	}
+	if name := dst.List()[b]; name != "b.txt" {
+		t.Fatalf("unexpected name %q", name)
+	}
+
+	// expire time is preserved regardless of the timeout of the target
+	var m FileMeta
+	m.ID = b
+	ms, _ := metaStores(dst)
+	for _, s := range ms {
+		s.fileMeta(&m)
+	}
+	if d := time.Until(m.Expires); d > time.Hour || d < 59*time.Minute {
+		t.Fatalf("unexpected expire time %v", m.Expires)
+	}
+}
+
+func TestSnapshotConflict(t *testing.T) {
+	src := NewFileLocalStore(t.TempDir())
+	id, err := addTestFile(t, src, "a.txt", "new")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Export(src, &buf); err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+
+	dst := NewFileLocalStore(t.TempDir())
+	if _, err := Import(dst, bytes.NewReader(buf.Bytes()), ConflictFail); err != nil {
+		t.Fatalf("Import error: %v", err)
+	}
+	if _, err := Import(dst, bytes.NewReader(buf.Bytes()), ConflictFail); !errors.Is(err, ErrSnapshotConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if n := len(dst.List()); n != 1 {
+		t.Fatalf("expected staged files removed, got %d files", n)
+	}
+	res, err := Import(dst, bytes.NewReader(buf.Bytes()), ConflictSkip)
+	if err != nil || res.Skipped != 1 || res.Imported != 0 {
+		t.Fatalf("unexpected skip result %+v %v", res, err)
+	}
+	res, err = Import(dst, bytes.NewReader(buf.Bytes()), ConflictOverwrite)
+	if err != nil || res.Overwritten != 1 || res.Imported != 1 {
+		t.Fatalf("unexpected overwrite result %+v %v", res, err)
+	}
+	if got := readStoreFile(t, dst, id); got != "new" {
+		t.Fatalf("unexpected content %q", got)
+	}
+	if _, err := Import(dst, bytes.NewReader(buf.Bytes()), "merge"); err == nil {
+		t.Fatalf("expected invalid conflict mode")
+	}
+}
+
+func TestSnapshotNamespaceQuota(t *testing.T) {
+	src := NewNamespaced(NewFileLocalStore(t.TempDir()), 0)
+	defer src.Close()
+	if _, err := addTestFile(t, src.Namespace("team"), "a.txt", "aaaa"); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Export(src, &buf); err != nil {
+		t.Fatalf("Export error: %v", err)
+	}
+
+	// charged to its own namespace rather than the default one
+	dst := NewNamespaced(NewFileLocalStore(t.TempDir()), 4)
+	defer dst.Close()
+	if _, err := addTestFile(t, dst, "b.txt", "bbbb"); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if _, err := Import(dst, bytes.NewReader(buf.Bytes()), ConflictFail); err != nil {
+		t.Fatalf("Import error: %v", err)
+	}
+	if u := StatUsage(dst.Namespace("team")); u.Files != 1 || u.Bytes != 4 {
+		t.Fatalf("unexpected namespace usage %+v", u)
+	}
+	if u := StatUsage(dst.Namespace("")); u.Files != 1 || u.Bytes != 4 {
+		t.Fatalf("unexpected default namespace usage %+v", u)
+	}
+
+	// rejected when the namespace is over quota
+	full := NewNamespaced(NewFileLocalStore(t.TempDir()), 4)
+	defer full.Close()
+	if _, err := addTestFile(t, full.Namespace("team"), "c.txt", "c"); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if _, err := Import(full, bytes.NewReader(buf.Bytes()), ConflictFail); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if n := len(full.List()); n != 1 {
+		t.Fatalf("expected rejected file removed, got %d files", n)
+	}
+}
@@ -144,6 +144,35 @@ func (t *Timeout) Get(id string) (string, envexec.File) {
 	return name, file
 }
 
+// Unwrap returns the underlying file store
+func (t *Timeout) Unwrap() FileStore {
+	return t.FileStore
+}
+
+func (t *Timeout) fileMeta(m *FileMeta) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if index, ok := t.idToIndex[m.ID]; ok {
+		m.Expires = t.files[index].time.Add(t.timeout)
+	}
+}
+
+// restoreMeta keeps the expire time of the file from the snapshot
+func (t *Timeout) restoreMeta(m FileMeta) {
+	if m.Expires.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if index, ok := t.idToIndex[m.ID]; ok {
+		t.files[index].time = m.Expires.Add(-t.timeout)
+		heap.Fix(t, index)
+	}
+}
+
 func (t *Timeout) New() (*os.File, error) {
 	return t.FileStore.New()
 }
@@ -0,0 +1,58 @@
+package filestore
+
+// Usage stores the number of files and their total size in a file store
+type Usage struct {
+	Files int
+	Bytes int64
+}
+
+// usageTracker is implemented by the file stores that keep account of the
+// size of their files
+type usageTracker interface {
+	usageStat() Usage
+}
+
+// StatUsage returns the usage of the file store. Files are stat-ed underneath
+// the wrappers when the file store does not track the size of its files.
+func StatUsage(fs FileStore) Usage {
+	if t, ok := fs.(usageTracker); ok {
+		return t.usageStat()
+	}
+	_, base := metaStores(fs)
+	var u Usage
+	for id := range fs.List() {
+		_, file := base.Get(id)
+		if file == nil {
+			continue
+		}
+		u.Files++
+		u.Bytes += fileSize(file)
+	}
+	return u
+}
+
+func (s *namespacedStore) usageStat() Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	u := Usage{Files: len(s.owner)}
+	for _, n := range s.usage {
+		u.Bytes += n
+	}
+	return u
+}
+
+func (v *namespaceView) usageStat() Usage {
+	v.s.mu.Lock()
+	defer v.s.mu.Unlock()
+
+	v.s.prune()
+	u := Usage{Bytes: v.s.usage[v.ns]}
+	for _, owner := range v.s.owner {
+		if owner == v.ns {
+			u.Files++
+		}
+	}
+	return u
+}
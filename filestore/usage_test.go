@@ -0,0 +1,38 @@
+package filestore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatUsage(t *testing.T) {
+	fs := NewTimeout(NewFileLocalStore(t.TempDir()), time.Hour, time.Hour)
+	defer fs.Close()
+	for _, c := range []string{"a", "bcd"} {
+		if _, err := addTestFile(t, fs, "file", c); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if u := StatUsage(fs); u != (Usage{Files: 2, Bytes: 4}) {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+}
+
+func TestStatUsageNamespaced(t *testing.T) {
+	ns := NewNamespaced(NewFileLocalStore(t.TempDir()), 0)
+	if _, err := addTestFile(t, ns.Namespace("a"), "file", "content"); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if _, err := addTestFile(t, ns.Namespace("b"), "file", "xy"); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if u := StatUsage(ns); u != (Usage{Files: 2, Bytes: 9}) {
+		t.Fatalf("unexpected usage of all namespaces %+v", u)
+	}
+	if u := StatUsage(ns.Namespace("a")); u != (Usage{Files: 1, Bytes: 7}) {
+		t.Fatalf("unexpected usage of namespace %+v", u)
+	}
+	if u := StatUsage(ns.Namespace("c")); u != (Usage{}) {
+		t.Fatalf("unexpected usage of empty namespace %+v", u)
+	}
+}
@@ -27,11 +27,14 @@ var File_judge_proto protoreflect.FileDescriptor
 const file_judge_proto_rawDesc = "" +
 	"\n" +
 	"\vjudge.proto\x12\x02pb\x1a\x1bgoogle/protobuf/empty.proto\x1a\rrequest.proto\x1a\x0eresponse.proto\x1a\x14stream_request.proto\x1a\x15stream_response.proto\x1a\n" +
-	"file.proto\x1a!google/protobuf/go_features.proto2\x9e\x02\n" +
+	"file.proto\x1a\n" +
+	"stat.proto\x1a!google/protobuf/go_features.proto2\xd0\x03\n" +
 	"\bExecutor\x12!\n" +
 	"\x04Exec\x12\v.pb.Request\x1a\f.pb.Response\x127\n" +
 	"\n" +
-	"ExecStream\x12\x11.pb.StreamRequest\x1a\x12.pb.StreamResponse(\x010\x01\x124\n" +
+	"ExecStream\x12\x11.pb.StreamRequest\x1a\x12.pb.StreamResponse(\x010\x01\x12&\n" +
+	"\n" +
+	"ExecEvents\x12\v.pb.Request\x1a\t.pb.Event0\x01\x124\n" +
 	"\bFileList\x12\x16.google.protobuf.Empty\x1a\x10.pb.FileListType\x12&\n" +
 	"\aFileGet\x12\n" +
 	".pb.FileID\x1a\x0f.pb.FileContent\x12&\n" +
@@ -39,7 +42,11 @@ const file_judge_proto_rawDesc = "" +
 	".pb.FileID\x120\n" +
 	"\n" +
 	"FileDelete\x12\n" +
-	".pb.FileID\x1a\x16.google.protobuf.EmptyB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
+	".pb.FileID\x1a\x16.google.protobuf.Empty\x12%\n" +
+	"\x06DirAdd\x12\x0f.pb.FileContent\x1a\n" +
+	".pb.FileID\x120\n" +
+	"\x04Stat\x12\x16.google.protobuf.Empty\x1a\x10.pb.StatResponse\x12/\n" +
+	"\x06Cancel\x12\x11.pb.CancelRequest\x1a\x12.pb.CancelResponseB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
 
 var file_judge_proto_goTypes = []any{
 	(*Request)(nil),        // 0: pb.Request
@@ -47,28 +54,40 @@ var file_judge_proto_goTypes = []any{
 	(*emptypb.Empty)(nil),  // 2: google.protobuf.Empty
 	(*FileID)(nil),         // 3: pb.FileID
 	(*FileContent)(nil),    // 4: pb.FileContent
-	(*Response)(nil),       // 5: pb.Response
-	(*StreamResponse)(nil), // 6: pb.StreamResponse
-	(*FileListType)(nil),   // 7: pb.FileListType
+	(*CancelRequest)(nil),  // 5: pb.CancelRequest
+	(*Response)(nil),       // 6: pb.Response
+	(*StreamResponse)(nil), // 7: pb.StreamResponse
+	(*Event)(nil),          // 8: pb.Event
+	(*FileListType)(nil),   // 9: pb.FileListType
+	(*StatResponse)(nil),   // 10: pb.StatResponse
+	(*CancelResponse)(nil), // 11: pb.CancelResponse
 }
 var file_judge_proto_depIdxs = []int32{
-	0, // 0: pb.Executor.Exec:input_type -> pb.Request
-	1, // 1: pb.Executor.ExecStream:input_type -> pb.StreamRequest
-	2, // 2: pb.Executor.FileList:input_type -> google.protobuf.Empty
-	3, // 3: pb.Executor.FileGet:input_type -> pb.FileID
-	4, // 4: pb.Executor.FileAdd:input_type -> pb.FileContent
-	3, // 5: pb.Executor.FileDelete:input_type -> pb.FileID
-	5, // 6: pb.Executor.Exec:output_type -> pb.Response
-	6, // 7: pb.Executor.ExecStream:output_type -> pb.StreamResponse
-	7, // 8: pb.Executor.FileList:output_type -> pb.FileListType
-	4, // 9: pb.Executor.FileGet:output_type -> pb.FileContent
-	3, // 10: pb.Executor.FileAdd:output_type -> pb.FileID
-	2, // 11: pb.Executor.FileDelete:output_type -> google.protobuf.Empty
-	6, // [6:12] is the sub-list for method output_type
-	0, // [0:6] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: pb.Executor.Exec:input_type -> pb.Request
+	1,  // 1: pb.Executor.ExecStream:input_type -> pb.StreamRequest
+	0,  // 2: pb.Executor.ExecEvents:input_type -> pb.Request
+	2,  // 3: pb.Executor.FileList:input_type -> google.protobuf.Empty
+	3,  // 4: pb.Executor.FileGet:input_type -> pb.FileID
+	4,  // 5: pb.Executor.FileAdd:input_type -> pb.FileContent
+	3,  // 6: pb.Executor.FileDelete:input_type -> pb.FileID
+	4,  // 7: pb.Executor.DirAdd:input_type -> pb.FileContent
+	2,  // 8: pb.Executor.Stat:input_type -> google.protobuf.Empty
+	5,  // 9: pb.Executor.Cancel:input_type -> pb.CancelRequest
+	6,  // 10: pb.Executor.Exec:output_type -> pb.Response
+	7,  // 11: pb.Executor.ExecStream:output_type -> pb.StreamResponse
+	8,  // 12: pb.Executor.ExecEvents:output_type -> pb.Event
+	9,  // 13: pb.Executor.FileList:output_type -> pb.FileListType
+	4,  // 14: pb.Executor.FileGet:output_type -> pb.FileContent
+	3,  // 15: pb.Executor.FileAdd:output_type -> pb.FileID
+	2,  // 16: pb.Executor.FileDelete:output_type -> google.protobuf.Empty
+	3,  // 17: pb.Executor.DirAdd:output_type -> pb.FileID
+	10, // 18: pb.Executor.Stat:output_type -> pb.StatResponse
+	11, // 19: pb.Executor.Cancel:output_type -> pb.CancelResponse
+	10, // [10:20] is the sub-list for method output_type
+	0,  // [0:10] is the sub-list for method input_type
+	0,  // [0:0] is the sub-list for extension type_name
+	0,  // [0:0] is the sub-list for extension extendee
+	0,  // [0:0] is the sub-list for field type_name
 }
 
 func init() { file_judge_proto_init() }
@@ -81,6 +100,7 @@ func file_judge_proto_init() {
 	file_stream_request_proto_init()
 	file_stream_response_proto_init()
 	file_file_proto_init()
+	file_stat_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
@@ -22,10 +22,14 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	Executor_Exec_FullMethodName       = "/pb.Executor/Exec"
 	Executor_ExecStream_FullMethodName = "/pb.Executor/ExecStream"
+	Executor_ExecEvents_FullMethodName = "/pb.Executor/ExecEvents"
 	Executor_FileList_FullMethodName   = "/pb.Executor/FileList"
 	Executor_FileGet_FullMethodName    = "/pb.Executor/FileGet"
 	Executor_FileAdd_FullMethodName    = "/pb.Executor/FileAdd"
 	Executor_FileDelete_FullMethodName = "/pb.Executor/FileDelete"
+	Executor_DirAdd_FullMethodName     = "/pb.Executor/DirAdd"
+	Executor_Stat_FullMethodName       = "/pb.Executor/Stat"
+	Executor_Cancel_FullMethodName     = "/pb.Executor/Cancel"
 )
 
 // ExecutorClient is the client API for Executor service.
@@ -40,6 +44,10 @@ type ExecutorClient interface {
 	// are execOutput. TTY attribute will create single pty for the program thus
 	// stdout & stderr should have same name
 	ExecStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[StreamRequest, StreamResponse], error)
+	// ExecEvents defines server streaming RPC to run a program and receive
+	// progress events as the request moves through the worker. The last event
+	// must be Finished with the response
+	ExecEvents(ctx context.Context, in *Request, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
 	// FileList lists all files available in the file store
 	FileList(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*FileListType, error)
 	// FileGet download the file from the file store
@@ -48,6 +56,15 @@ type ExecutorClient interface {
 	FileAdd(ctx context.Context, in *FileContent, opts ...grpc.CallOption) (*FileID, error)
 	// FileDelete deletes a file from the file store
 	FileDelete(ctx context.Context, in *FileID, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// DirAdd create a directory tree into the file store from the content of
+	// tar, tar.gz or zip archive
+	DirAdd(ctx context.Context, in *FileContent, opts ...grpc.CallOption) (*FileID, error)
+	// Stat returns the statistic of the worker, environment pool, file store
+	// and the requests in flight
+	Stat(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatResponse, error)
+	// Cancel cancels the requests queued or running and returns their partial
+	// results, it requires admin to be enabled and the default namespace
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
 }
 
 type executorClient struct {
@@ -81,6 +98,25 @@ func (c *executorClient) ExecStream(ctx context.Context, opts ...grpc.CallOption
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Executor_ExecStreamClient = grpc.BidiStreamingClient[StreamRequest, StreamResponse]
 
+func (c *executorClient) ExecEvents(ctx context.Context, in *Request, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Executor_ServiceDesc.Streams[1], Executor_ExecEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Request, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Executor_ExecEventsClient = grpc.ServerStreamingClient[Event]
+
 func (c *executorClient) FileList(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*FileListType, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(FileListType)
@@ -121,6 +157,36 @@ func (c *executorClient) FileDelete(ctx context.Context, in *FileID, opts ...grp
 	return out, nil
 }
 
+func (c *executorClient) DirAdd(ctx context.Context, in *FileContent, opts ...grpc.CallOption) (*FileID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileID)
+	err := c.cc.Invoke(ctx, Executor_DirAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) Stat(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatResponse)
+	err := c.cc.Invoke(ctx, Executor_Stat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *executorClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, Executor_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExecutorServer is the server API for Executor service.
 // All implementations must embed UnimplementedExecutorServer
 // for forward compatibility.
@@ -133,6 +199,10 @@ type ExecutorServer interface {
 	// are execOutput. TTY attribute will create single pty for the program thus
 	// stdout & stderr should have same name
 	ExecStream(grpc.BidiStreamingServer[StreamRequest, StreamResponse]) error
+	// ExecEvents defines server streaming RPC to run a program and receive
+	// progress events as the request moves through the worker. The last event
+	// must be Finished with the response
+	ExecEvents(*Request, grpc.ServerStreamingServer[Event]) error
 	// FileList lists all files available in the file store
 	FileList(context.Context, *emptypb.Empty) (*FileListType, error)
 	// FileGet download the file from the file store
@@ -141,6 +211,15 @@ type ExecutorServer interface {
 	FileAdd(context.Context, *FileContent) (*FileID, error)
 	// FileDelete deletes a file from the file store
 	FileDelete(context.Context, *FileID) (*emptypb.Empty, error)
+	// DirAdd create a directory tree into the file store from the content of
+	// tar, tar.gz or zip archive
+	DirAdd(context.Context, *FileContent) (*FileID, error)
+	// Stat returns the statistic of the worker, environment pool, file store
+	// and the requests in flight
+	Stat(context.Context, *emptypb.Empty) (*StatResponse, error)
+	// Cancel cancels the requests queued or running and returns their partial
+	// results, it requires admin to be enabled and the default namespace
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
 	mustEmbedUnimplementedExecutorServer()
 }
 
@@ -157,6 +236,9 @@ func (UnimplementedExecutorServer) Exec(context.Context, *Request) (*Response, e
 func (UnimplementedExecutorServer) ExecStream(grpc.BidiStreamingServer[StreamRequest, StreamResponse]) error {
 	return status.Error(codes.Unimplemented, "method ExecStream not implemented")
 }
+func (UnimplementedExecutorServer) ExecEvents(*Request, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method ExecEvents not implemented")
+}
 func (UnimplementedExecutorServer) FileList(context.Context, *emptypb.Empty) (*FileListType, error) {
 	return nil, status.Error(codes.Unimplemented, "method FileList not implemented")
 }
@@ -169,6 +251,15 @@ func (UnimplementedExecutorServer) FileAdd(context.Context, *FileContent) (*File
 func (UnimplementedExecutorServer) FileDelete(context.Context, *FileID) (*emptypb.Empty, error) {
 	return nil, status.Error(codes.Unimplemented, "method FileDelete not implemented")
 }
+func (UnimplementedExecutorServer) DirAdd(context.Context, *FileContent) (*FileID, error) {
+	return nil, status.Error(codes.Unimplemented, "method DirAdd not implemented")
+}
+func (UnimplementedExecutorServer) Stat(context.Context, *emptypb.Empty) (*StatResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedExecutorServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Cancel not implemented")
+}
 func (UnimplementedExecutorServer) mustEmbedUnimplementedExecutorServer() {}
 func (UnimplementedExecutorServer) testEmbeddedByValue()                  {}
 
@@ -215,6 +306,17 @@ func _Executor_ExecStream_Handler(srv interface{}, stream grpc.ServerStream) err
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Executor_ExecStreamServer = grpc.BidiStreamingServer[StreamRequest, StreamResponse]
 
+func _Executor_ExecEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Request)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExecutorServer).ExecEvents(m, &grpc.GenericServerStream[Request, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Executor_ExecEventsServer = grpc.ServerStreamingServer[Event]
+
 func _Executor_FileList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -287,6 +389,60 @@ func _Executor_FileDelete_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Executor_DirAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileContent)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).DirAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Executor_DirAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).DirAdd(ctx, req.(*FileContent))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Executor_Stat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).Stat(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Executor_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExecutorServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Executor_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExecutorServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Executor_ServiceDesc is the grpc.ServiceDesc for Executor service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -314,6 +470,18 @@ var Executor_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "FileDelete",
 			Handler:    _Executor_FileDelete_Handler,
 		},
+		{
+			MethodName: "DirAdd",
+			Handler:    _Executor_DirAdd_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _Executor_Stat_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _Executor_Cancel_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -322,6 +490,11 @@ var Executor_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ExecEvents",
+			Handler:       _Executor_ExecEvents_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "judge.proto",
 }
@@ -22,11 +22,54 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Owner defines the owner of the copyIn file inside the container
+type Request_File_Owner int32
+
+const (
+	Request_File_Root Request_File_Owner = 0
+	Request_File_User Request_File_Owner = 1
+)
+
+// Enum value maps for Request_File_Owner.
+var (
+	Request_File_Owner_name = map[int32]string{
+		0: "Root",
+		1: "User",
+	}
+	Request_File_Owner_value = map[string]int32{
+		"Root": 0,
+		"User": 1,
+	}
+)
+
+func (x Request_File_Owner) Enum() *Request_File_Owner {
+	p := new(Request_File_Owner)
+	*p = x
+	return p
+}
+
+func (x Request_File_Owner) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Request_File_Owner) Descriptor() protoreflect.EnumDescriptor {
+	return file_request_proto_enumTypes[0].Descriptor()
+}
+
+func (Request_File_Owner) Type() protoreflect.EnumType {
+	return &file_request_proto_enumTypes[0]
+}
+
+func (x Request_File_Owner) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
 type Request struct {
 	state                  protoimpl.MessageState `protogen:"opaque.v1"`
 	xxx_hidden_RequestID   string                 `protobuf:"bytes,1,opt,name=requestID"`
 	xxx_hidden_Cmd         *[]*Request_CmdType    `protobuf:"bytes,2,rep,name=cmd"`
 	xxx_hidden_PipeMapping *[]*Request_PipeMap    `protobuf:"bytes,3,rep,name=pipeMapping"`
+	xxx_hidden_Tags        []string               `protobuf:"bytes,4,rep,name=tags"`
 	unknownFields          protoimpl.UnknownFields
 	sizeCache              protoimpl.SizeCache
 }
@@ -81,6 +124,13 @@ func (x *Request) GetPipeMapping() []*Request_PipeMap {
 	return nil
 }
 
+func (x *Request) GetTags() []string {
+	if x != nil {
+		return x.xxx_hidden_Tags
+	}
+	return nil
+}
+
 func (x *Request) SetRequestID(v string) {
 	x.xxx_hidden_RequestID = v
 }
@@ -93,12 +143,18 @@ func (x *Request) SetPipeMapping(v []*Request_PipeMap) {
 	x.xxx_hidden_PipeMapping = &v
 }
 
+func (x *Request) SetTags(v []string) {
+	x.xxx_hidden_Tags = v
+}
+
 type Request_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
 	RequestID   string
 	Cmd         []*Request_CmdType
 	PipeMapping []*Request_PipeMap
+	// tags labels the request to be selected by Cancel
+	Tags []string
 }
 
 func (b0 Request_builder) Build() *Request {
@@ -108,9 +164,12 @@ func (b0 Request_builder) Build() *Request {
 	x.xxx_hidden_RequestID = b.RequestID
 	x.xxx_hidden_Cmd = &b.Cmd
 	x.xxx_hidden_PipeMapping = &b.PipeMapping
+	x.xxx_hidden_Tags = b.Tags
 	return m0
 }
 
+// LocalFile with src in form of dataset:<name>/<path> refers to the file
+// inside the read-only dataset
 type Request_LocalFile struct {
 	state          protoimpl.MessageState `protogen:"opaque.v1"`
 	xxx_hidden_Src string                 `protobuf:"bytes,1,opt,name=src"`
@@ -169,10 +228,11 @@ func (b0 Request_LocalFile_builder) Build() *Request_LocalFile {
 }
 
 type Request_MemoryFile struct {
-	state              protoimpl.MessageState `protogen:"opaque.v1"`
-	xxx_hidden_Content []byte                 `protobuf:"bytes,1,opt,name=content"`
-	unknownFields      protoimpl.UnknownFields
-	sizeCache          protoimpl.SizeCache
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Content     []byte                 `protobuf:"bytes,1,opt,name=content"`
+	xxx_hidden_Compression string                 `protobuf:"bytes,2,opt,name=compression"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *Request_MemoryFile) Reset() {
@@ -207,6 +267,13 @@ func (x *Request_MemoryFile) GetContent() []byte {
 	return nil
 }
 
+func (x *Request_MemoryFile) GetCompression() string {
+	if x != nil {
+		return x.xxx_hidden_Compression
+	}
+	return ""
+}
+
 func (x *Request_MemoryFile) SetContent(v []byte) {
 	if v == nil {
 		v = []byte{}
@@ -214,10 +281,16 @@ func (x *Request_MemoryFile) SetContent(v []byte) {
 	x.xxx_hidden_Content = v
 }
 
+func (x *Request_MemoryFile) SetCompression(v string) {
+	x.xxx_hidden_Compression = v
+}
+
 type Request_MemoryFile_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
 	Content []byte
+	// compression of content (gzip / zstd)
+	Compression string
 }
 
 func (b0 Request_MemoryFile_builder) Build() *Request_MemoryFile {
@@ -225,6 +298,7 @@ func (b0 Request_MemoryFile_builder) Build() *Request_MemoryFile {
 	b, x := &b0, m0
 	_, _ = b, x
 	x.xxx_hidden_Content = b.Content
+	x.xxx_hidden_Compression = b.Compression
 	return m0
 }
 
@@ -285,18 +359,79 @@ func (b0 Request_CachedFile_builder) Build() *Request_CachedFile {
 	return m0
 }
 
+// CachedDir is a directory tree stored in the file store as tar archive
+type Request_CachedDir struct {
+	state            protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_DirID string                 `protobuf:"bytes,1,opt,name=dirID"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Request_CachedDir) Reset() {
+	*x = Request_CachedDir{}
+	mi := &file_request_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request_CachedDir) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request_CachedDir) ProtoMessage() {}
+
+func (x *Request_CachedDir) ProtoReflect() protoreflect.Message {
+	mi := &file_request_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *Request_CachedDir) GetDirID() string {
+	if x != nil {
+		return x.xxx_hidden_DirID
+	}
+	return ""
+}
+
+func (x *Request_CachedDir) SetDirID(v string) {
+	x.xxx_hidden_DirID = v
+}
+
+type Request_CachedDir_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	DirID string
+}
+
+func (b0 Request_CachedDir_builder) Build() *Request_CachedDir {
+	m0 := &Request_CachedDir{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_DirID = b.DirID
+	return m0
+}
+
 type Request_PipeCollector struct {
-	state           protoimpl.MessageState `protogen:"opaque.v1"`
-	xxx_hidden_Name string                 `protobuf:"bytes,1,opt,name=name"`
-	xxx_hidden_Max  int64                  `protobuf:"varint,2,opt,name=max"`
-	xxx_hidden_Pipe bool                   `protobuf:"varint,3,opt,name=pipe"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Name        string                 `protobuf:"bytes,1,opt,name=name"`
+	xxx_hidden_Max         int64                  `protobuf:"varint,2,opt,name=max"`
+	xxx_hidden_Pipe        bool                   `protobuf:"varint,3,opt,name=pipe"`
+	xxx_hidden_Tail        int64                  `protobuf:"varint,4,opt,name=tail"`
+	xxx_hidden_Compression string                 `protobuf:"bytes,5,opt,name=compression"`
+	xxx_hidden_Live        bool                   `protobuf:"varint,6,opt,name=live"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *Request_PipeCollector) Reset() {
 	*x = Request_PipeCollector{}
-	mi := &file_request_proto_msgTypes[4]
+	mi := &file_request_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -308,7 +443,7 @@ func (x *Request_PipeCollector) String() string {
 func (*Request_PipeCollector) ProtoMessage() {}
 
 func (x *Request_PipeCollector) ProtoReflect() protoreflect.Message {
-	mi := &file_request_proto_msgTypes[4]
+	mi := &file_request_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -340,6 +475,27 @@ func (x *Request_PipeCollector) GetPipe() bool {
 	return false
 }
 
+func (x *Request_PipeCollector) GetTail() int64 {
+	if x != nil {
+		return x.xxx_hidden_Tail
+	}
+	return 0
+}
+
+func (x *Request_PipeCollector) GetCompression() string {
+	if x != nil {
+		return x.xxx_hidden_Compression
+	}
+	return ""
+}
+
+func (x *Request_PipeCollector) GetLive() bool {
+	if x != nil {
+		return x.xxx_hidden_Live
+	}
+	return false
+}
+
 func (x *Request_PipeCollector) SetName(v string) {
 	x.xxx_hidden_Name = v
 }
@@ -352,12 +508,30 @@ func (x *Request_PipeCollector) SetPipe(v bool) {
 	x.xxx_hidden_Pipe = v
 }
 
+func (x *Request_PipeCollector) SetTail(v int64) {
+	x.xxx_hidden_Tail = v
+}
+
+func (x *Request_PipeCollector) SetCompression(v string) {
+	x.xxx_hidden_Compression = v
+}
+
+func (x *Request_PipeCollector) SetLive(v bool) {
+	x.xxx_hidden_Live = v
+}
+
 type Request_PipeCollector_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
 	Name string
 	Max  int64
 	Pipe bool
+	// tail keeps the last bytes out of max when the output exceeds max
+	Tail int64
+	// compression of the collected output (gzip / zstd)
+	Compression string
+	// live reports collected output as Output events in ExecEvents
+	Live bool
 }
 
 func (b0 Request_PipeCollector_builder) Build() *Request_PipeCollector {
@@ -367,19 +541,27 @@ func (b0 Request_PipeCollector_builder) Build() *Request_PipeCollector {
 	x.xxx_hidden_Name = b.Name
 	x.xxx_hidden_Max = b.Max
 	x.xxx_hidden_Pipe = b.Pipe
+	x.xxx_hidden_Tail = b.Tail
+	x.xxx_hidden_Compression = b.Compression
+	x.xxx_hidden_Live = b.Live
 	return m0
 }
 
 type Request_File struct {
-	state           protoimpl.MessageState `protogen:"opaque.v1"`
-	xxx_hidden_File isRequest_File_File    `protobuf_oneof:"file"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_File     isRequest_File_File    `protobuf_oneof:"file"`
+	xxx_hidden_Extract  bool                   `protobuf:"varint,8,opt,name=extract"`
+	xxx_hidden_Mode     uint32                 `protobuf:"varint,9,opt,name=mode"`
+	xxx_hidden_Owner    Request_File_Owner     `protobuf:"varint,10,opt,name=owner,enum=pb.Request_File_Owner"`
+	xxx_hidden_ReadOnly bool                   `protobuf:"varint,11,opt,name=readOnly"`
+	xxx_hidden_ZeroCopy bool                   `protobuf:"varint,12,opt,name=zeroCopy"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *Request_File) Reset() {
 	*x = Request_File{}
-	mi := &file_request_proto_msgTypes[5]
+	mi := &file_request_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -391,7 +573,7 @@ func (x *Request_File) String() string {
 func (*Request_File) ProtoMessage() {}
 
 func (x *Request_File) ProtoReflect() protoreflect.Message {
-	mi := &file_request_proto_msgTypes[5]
+	mi := &file_request_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -456,6 +638,50 @@ func (x *Request_File) GetStreamOut() *emptypb.Empty {
 	return nil
 }
 
+func (x *Request_File) GetCachedDir() *Request_CachedDir {
+	if x != nil {
+		if x, ok := x.xxx_hidden_File.(*request_File_CachedDir); ok {
+			return x.CachedDir
+		}
+	}
+	return nil
+}
+
+func (x *Request_File) GetExtract() bool {
+	if x != nil {
+		return x.xxx_hidden_Extract
+	}
+	return false
+}
+
+func (x *Request_File) GetMode() uint32 {
+	if x != nil {
+		return x.xxx_hidden_Mode
+	}
+	return 0
+}
+
+func (x *Request_File) GetOwner() Request_File_Owner {
+	if x != nil {
+		return x.xxx_hidden_Owner
+	}
+	return Request_File_Root
+}
+
+func (x *Request_File) GetReadOnly() bool {
+	if x != nil {
+		return x.xxx_hidden_ReadOnly
+	}
+	return false
+}
+
+func (x *Request_File) GetZeroCopy() bool {
+	if x != nil {
+		return x.xxx_hidden_ZeroCopy
+	}
+	return false
+}
+
 func (x *Request_File) SetLocal(v *Request_LocalFile) {
 	if v == nil {
 		x.xxx_hidden_File = nil
@@ -504,6 +730,34 @@ func (x *Request_File) SetStreamOut(v *emptypb.Empty) {
 	x.xxx_hidden_File = &request_File_StreamOut{v}
 }
 
+func (x *Request_File) SetCachedDir(v *Request_CachedDir) {
+	if v == nil {
+		x.xxx_hidden_File = nil
+		return
+	}
+	x.xxx_hidden_File = &request_File_CachedDir{v}
+}
+
+func (x *Request_File) SetExtract(v bool) {
+	x.xxx_hidden_Extract = v
+}
+
+func (x *Request_File) SetMode(v uint32) {
+	x.xxx_hidden_Mode = v
+}
+
+func (x *Request_File) SetOwner(v Request_File_Owner) {
+	x.xxx_hidden_Owner = v
+}
+
+func (x *Request_File) SetReadOnly(v bool) {
+	x.xxx_hidden_ReadOnly = v
+}
+
+func (x *Request_File) SetZeroCopy(v bool) {
+	x.xxx_hidden_ZeroCopy = v
+}
+
 func (x *Request_File) HasFile() bool {
 	if x == nil {
 		return false
@@ -559,6 +813,14 @@ func (x *Request_File) HasStreamOut() bool {
 	return ok
 }
 
+func (x *Request_File) HasCachedDir() bool {
+	if x == nil {
+		return false
+	}
+	_, ok := x.xxx_hidden_File.(*request_File_CachedDir)
+	return ok
+}
+
 func (x *Request_File) ClearFile() {
 	x.xxx_hidden_File = nil
 }
@@ -599,6 +861,12 @@ func (x *Request_File) ClearStreamOut() {
 	}
 }
 
+func (x *Request_File) ClearCachedDir() {
+	if _, ok := x.xxx_hidden_File.(*request_File_CachedDir); ok {
+		x.xxx_hidden_File = nil
+	}
+}
+
 const Request_File_File_not_set_case case_Request_File_File = 0
 const Request_File_Local_case case_Request_File_File = 1
 const Request_File_Memory_case case_Request_File_File = 2
@@ -606,6 +874,7 @@ const Request_File_Cached_case case_Request_File_File = 3
 const Request_File_Pipe_case case_Request_File_File = 4
 const Request_File_StreamIn_case case_Request_File_File = 5
 const Request_File_StreamOut_case case_Request_File_File = 6
+const Request_File_CachedDir_case case_Request_File_File = 7
 
 func (x *Request_File) WhichFile() case_Request_File_File {
 	if x == nil {
@@ -624,6 +893,8 @@ func (x *Request_File) WhichFile() case_Request_File_File {
 		return Request_File_StreamIn_case
 	case *request_File_StreamOut:
 		return Request_File_StreamOut_case
+	case *request_File_CachedDir:
+		return Request_File_CachedDir_case
 	default:
 		return Request_File_File_not_set_case
 	}
@@ -641,7 +912,19 @@ type Request_File_builder struct {
 	StreamIn *emptypb.Empty
 	// streamOut only valid in streaming RPC
 	StreamOut *emptypb.Empty
+	// cachedDir only valid in copyIn
+	CachedDir *Request_CachedDir
 	// -- end of xxx_hidden_File
+	// extract the file as tar, tar.gz or zip archive into a directory,
+	// only valid in copyIn
+	Extract bool
+	// mode (0 for default), owner and readOnly only valid in copyIn
+	Mode     uint32
+	Owner    Request_File_Owner
+	ReadOnly bool
+	// zeroCopy exposes local or cached file to the program as read-only
+	// without copying when supported, only valid in copyIn
+	ZeroCopy bool
 }
 
 func (b0 Request_File_builder) Build() *Request_File {
@@ -666,13 +949,21 @@ func (b0 Request_File_builder) Build() *Request_File {
 	if b.StreamOut != nil {
 		x.xxx_hidden_File = &request_File_StreamOut{b.StreamOut}
 	}
+	if b.CachedDir != nil {
+		x.xxx_hidden_File = &request_File_CachedDir{b.CachedDir}
+	}
+	x.xxx_hidden_Extract = b.Extract
+	x.xxx_hidden_Mode = b.Mode
+	x.xxx_hidden_Owner = b.Owner
+	x.xxx_hidden_ReadOnly = b.ReadOnly
+	x.xxx_hidden_ZeroCopy = b.ZeroCopy
 	return m0
 }
 
 type case_Request_File_File protoreflect.FieldNumber
 
 func (x case_Request_File_File) String() string {
-	md := file_request_proto_msgTypes[5].Descriptor()
+	md := file_request_proto_msgTypes[6].Descriptor()
 	if x == 0 {
 		return "not set"
 	}
@@ -709,6 +1000,11 @@ type request_File_StreamOut struct {
 	StreamOut *emptypb.Empty `protobuf:"bytes,6,opt,name=streamOut,oneof"`
 }
 
+type request_File_CachedDir struct {
+	// cachedDir only valid in copyIn
+	CachedDir *Request_CachedDir `protobuf:"bytes,7,opt,name=cachedDir,oneof"`
+}
+
 func (*request_File_Local) isRequest_File_File() {}
 
 func (*request_File_Memory) isRequest_File_File() {}
@@ -721,35 +1017,42 @@ func (*request_File_StreamIn) isRequest_File_File() {}
 
 func (*request_File_StreamOut) isRequest_File_File() {}
 
+func (*request_File_CachedDir) isRequest_File_File() {}
+
 type Request_CmdType struct {
-	state                        protoimpl.MessageState     `protogen:"opaque.v1"`
-	xxx_hidden_Args              []string                   `protobuf:"bytes,1,rep,name=args"`
-	xxx_hidden_Env               []string                   `protobuf:"bytes,2,rep,name=env"`
-	xxx_hidden_Files             *[]*Request_File           `protobuf:"bytes,3,rep,name=files"`
-	xxx_hidden_Tty               bool                       `protobuf:"varint,13,opt,name=tty"`
-	xxx_hidden_CpuTimeLimit      uint64                     `protobuf:"varint,4,opt,name=cpuTimeLimit"`
-	xxx_hidden_ClockTimeLimit    uint64                     `protobuf:"varint,5,opt,name=clockTimeLimit"`
-	xxx_hidden_MemoryLimit       uint64                     `protobuf:"varint,6,opt,name=memoryLimit"`
-	xxx_hidden_StackLimit        uint64                     `protobuf:"varint,12,opt,name=stackLimit"`
-	xxx_hidden_ProcLimit         uint64                     `protobuf:"varint,7,opt,name=procLimit"`
-	xxx_hidden_CpuRateLimit      uint64                     `protobuf:"varint,15,opt,name=cpuRateLimit"`
-	xxx_hidden_CpuSetLimit       string                     `protobuf:"bytes,17,opt,name=cpuSetLimit"`
-	xxx_hidden_DataSegmentLimit  bool                       `protobuf:"varint,16,opt,name=dataSegmentLimit"`
-	xxx_hidden_AddressSpaceLimit bool                       `protobuf:"varint,19,opt,name=addressSpaceLimit"`
-	xxx_hidden_CopyIn            map[string]*Request_File   `protobuf:"bytes,8,rep,name=copyIn" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	xxx_hidden_Symlinks          map[string]string          `protobuf:"bytes,18,rep,name=symlinks" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	xxx_hidden_CopyOut           *[]*Request_CmdCopyOutFile `protobuf:"bytes,9,rep,name=copyOut"`
-	xxx_hidden_CopyOutCached     *[]*Request_CmdCopyOutFile `protobuf:"bytes,10,rep,name=copyOutCached"`
-	xxx_hidden_CopyOutDir        string                     `protobuf:"bytes,11,opt,name=copyOutDir"`
-	xxx_hidden_CopyOutMax        uint64                     `protobuf:"varint,14,opt,name=copyOutMax"`
-	xxx_hidden_CopyOutTruncate   bool                       `protobuf:"varint,20,opt,name=copyOutTruncate"`
-	unknownFields                protoimpl.UnknownFields
-	sizeCache                    protoimpl.SizeCache
+	state                          protoimpl.MessageState     `protogen:"opaque.v1"`
+	xxx_hidden_Args                []string                   `protobuf:"bytes,1,rep,name=args"`
+	xxx_hidden_Env                 []string                   `protobuf:"bytes,2,rep,name=env"`
+	xxx_hidden_Files               *[]*Request_File           `protobuf:"bytes,3,rep,name=files"`
+	xxx_hidden_Tty                 bool                       `protobuf:"varint,13,opt,name=tty"`
+	xxx_hidden_CpuTimeLimit        uint64                     `protobuf:"varint,4,opt,name=cpuTimeLimit"`
+	xxx_hidden_ClockTimeLimit      uint64                     `protobuf:"varint,5,opt,name=clockTimeLimit"`
+	xxx_hidden_MemoryLimit         uint64                     `protobuf:"varint,6,opt,name=memoryLimit"`
+	xxx_hidden_StackLimit          uint64                     `protobuf:"varint,12,opt,name=stackLimit"`
+	xxx_hidden_ProcLimit           uint64                     `protobuf:"varint,7,opt,name=procLimit"`
+	xxx_hidden_CpuRateLimit        uint64                     `protobuf:"varint,15,opt,name=cpuRateLimit"`
+	xxx_hidden_CpuSetLimit         string                     `protobuf:"bytes,17,opt,name=cpuSetLimit"`
+	xxx_hidden_DataSegmentLimit    bool                       `protobuf:"varint,16,opt,name=dataSegmentLimit"`
+	xxx_hidden_AddressSpaceLimit   bool                       `protobuf:"varint,19,opt,name=addressSpaceLimit"`
+	xxx_hidden_CopyIn              map[string]*Request_File   `protobuf:"bytes,8,rep,name=copyIn" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	xxx_hidden_Symlinks            map[string]string          `protobuf:"bytes,18,rep,name=symlinks" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	xxx_hidden_CopyOut             *[]*Request_CmdCopyOutFile `protobuf:"bytes,9,rep,name=copyOut"`
+	xxx_hidden_CopyOutCached       *[]*Request_CmdCopyOutFile `protobuf:"bytes,10,rep,name=copyOutCached"`
+	xxx_hidden_CopyOutDir          string                     `protobuf:"bytes,11,opt,name=copyOutDir"`
+	xxx_hidden_CopyOutMax          uint64                     `protobuf:"varint,14,opt,name=copyOutMax"`
+	xxx_hidden_CopyOutTruncate     bool                       `protobuf:"varint,20,opt,name=copyOutTruncate"`
+	xxx_hidden_CopyOutMaxFiles     uint64                     `protobuf:"varint,21,opt,name=copyOutMaxFiles"`
+	xxx_hidden_CopyOutDirFormat    string                     `protobuf:"bytes,22,opt,name=copyOutDirFormat"`
+	xxx_hidden_CopyOutDigest       bool                       `protobuf:"varint,23,opt,name=copyOutDigest"`
+	xxx_hidden_CopyOutDigestOnly   bool                       `protobuf:"varint,24,opt,name=copyOutDigestOnly"`
+	xxx_hidden_CopyOutTruncateTail uint64                     `protobuf:"varint,25,opt,name=copyOutTruncateTail"`
+	unknownFields                  protoimpl.UnknownFields
+	sizeCache                      protoimpl.SizeCache
 }
 
 func (x *Request_CmdType) Reset() {
 	*x = Request_CmdType{}
-	mi := &file_request_proto_msgTypes[6]
+	mi := &file_request_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -761,7 +1064,7 @@ func (x *Request_CmdType) String() string {
 func (*Request_CmdType) ProtoMessage() {}
 
 func (x *Request_CmdType) ProtoReflect() protoreflect.Message {
-	mi := &file_request_proto_msgTypes[6]
+	mi := &file_request_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -918,6 +1221,41 @@ func (x *Request_CmdType) GetCopyOutTruncate() bool {
 	return false
 }
 
+func (x *Request_CmdType) GetCopyOutMaxFiles() uint64 {
+	if x != nil {
+		return x.xxx_hidden_CopyOutMaxFiles
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetCopyOutDirFormat() string {
+	if x != nil {
+		return x.xxx_hidden_CopyOutDirFormat
+	}
+	return ""
+}
+
+func (x *Request_CmdType) GetCopyOutDigest() bool {
+	if x != nil {
+		return x.xxx_hidden_CopyOutDigest
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetCopyOutDigestOnly() bool {
+	if x != nil {
+		return x.xxx_hidden_CopyOutDigestOnly
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetCopyOutTruncateTail() uint64 {
+	if x != nil {
+		return x.xxx_hidden_CopyOutTruncateTail
+	}
+	return 0
+}
+
 func (x *Request_CmdType) SetArgs(v []string) {
 	x.xxx_hidden_Args = v
 }
@@ -998,6 +1336,26 @@ func (x *Request_CmdType) SetCopyOutTruncate(v bool) {
 	x.xxx_hidden_CopyOutTruncate = v
 }
 
+func (x *Request_CmdType) SetCopyOutMaxFiles(v uint64) {
+	x.xxx_hidden_CopyOutMaxFiles = v
+}
+
+func (x *Request_CmdType) SetCopyOutDirFormat(v string) {
+	x.xxx_hidden_CopyOutDirFormat = v
+}
+
+func (x *Request_CmdType) SetCopyOutDigest(v bool) {
+	x.xxx_hidden_CopyOutDigest = v
+}
+
+func (x *Request_CmdType) SetCopyOutDigestOnly(v bool) {
+	x.xxx_hidden_CopyOutDigestOnly = v
+}
+
+func (x *Request_CmdType) SetCopyOutTruncateTail(v uint64) {
+	x.xxx_hidden_CopyOutTruncateTail = v
+}
+
 type Request_CmdType_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
@@ -1022,6 +1380,16 @@ type Request_CmdType_builder struct {
 	CopyOutDir      string
 	CopyOutMax      uint64
 	CopyOutTruncate bool
+	// copyOutMaxFiles limits the number of files matched by glob patterns
+	CopyOutMaxFiles uint64
+	// copyOutDirFormat is the archive format (tar / zip) of directory copyOut
+	CopyOutDirFormat string
+	// copyOutDigest returns size and SHA-256 of copyOut / copyOutCached files
+	// in fileDigests, and copyOutDigestOnly also omits the content of copyOut
+	CopyOutDigest     bool
+	CopyOutDigestOnly bool
+	// copyOutTruncateTail keeps the last bytes out of copyOutMax when truncated
+	CopyOutTruncateTail uint64
 }
 
 func (b0 Request_CmdType_builder) Build() *Request_CmdType {
@@ -1048,20 +1416,29 @@ func (b0 Request_CmdType_builder) Build() *Request_CmdType {
 	x.xxx_hidden_CopyOutDir = b.CopyOutDir
 	x.xxx_hidden_CopyOutMax = b.CopyOutMax
 	x.xxx_hidden_CopyOutTruncate = b.CopyOutTruncate
+	x.xxx_hidden_CopyOutMaxFiles = b.CopyOutMaxFiles
+	x.xxx_hidden_CopyOutDirFormat = b.CopyOutDirFormat
+	x.xxx_hidden_CopyOutDigest = b.CopyOutDigest
+	x.xxx_hidden_CopyOutDigestOnly = b.CopyOutDigestOnly
+	x.xxx_hidden_CopyOutTruncateTail = b.CopyOutTruncateTail
 	return m0
 }
 
+// CmdCopyOutFile defines file to copy out, name with glob pattern (e.g.
+// `*.class`, `out/**`) copies out all matched regular files
 type Request_CmdCopyOutFile struct {
 	state               protoimpl.MessageState `protogen:"opaque.v1"`
 	xxx_hidden_Name     string                 `protobuf:"bytes,1,opt,name=name"`
 	xxx_hidden_Optional bool                   `protobuf:"varint,2,opt,name=optional"`
+	xxx_hidden_Dir      bool                   `protobuf:"varint,3,opt,name=dir"`
+	xxx_hidden_Glob     bool                   `protobuf:"varint,4,opt,name=glob"`
 	unknownFields       protoimpl.UnknownFields
 	sizeCache           protoimpl.SizeCache
 }
 
 func (x *Request_CmdCopyOutFile) Reset() {
 	*x = Request_CmdCopyOutFile{}
-	mi := &file_request_proto_msgTypes[7]
+	mi := &file_request_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1073,7 +1450,7 @@ func (x *Request_CmdCopyOutFile) String() string {
 func (*Request_CmdCopyOutFile) ProtoMessage() {}
 
 func (x *Request_CmdCopyOutFile) ProtoReflect() protoreflect.Message {
-	mi := &file_request_proto_msgTypes[7]
+	mi := &file_request_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1098,6 +1475,20 @@ func (x *Request_CmdCopyOutFile) GetOptional() bool {
 	return false
 }
 
+func (x *Request_CmdCopyOutFile) GetDir() bool {
+	if x != nil {
+		return x.xxx_hidden_Dir
+	}
+	return false
+}
+
+func (x *Request_CmdCopyOutFile) GetGlob() bool {
+	if x != nil {
+		return x.xxx_hidden_Glob
+	}
+	return false
+}
+
 func (x *Request_CmdCopyOutFile) SetName(v string) {
 	x.xxx_hidden_Name = v
 }
@@ -1106,11 +1497,23 @@ func (x *Request_CmdCopyOutFile) SetOptional(v bool) {
 	x.xxx_hidden_Optional = v
 }
 
+func (x *Request_CmdCopyOutFile) SetDir(v bool) {
+	x.xxx_hidden_Dir = v
+}
+
+func (x *Request_CmdCopyOutFile) SetGlob(v bool) {
+	x.xxx_hidden_Glob = v
+}
+
 type Request_CmdCopyOutFile_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
 	Name     string
 	Optional bool
+	// dir copies out the directory tree as tar archive
+	Dir bool
+	// glob copies out all regular files matched by name as glob pattern
+	Glob bool
 }
 
 func (b0 Request_CmdCopyOutFile_builder) Build() *Request_CmdCopyOutFile {
@@ -1119,6 +1522,8 @@ func (b0 Request_CmdCopyOutFile_builder) Build() *Request_CmdCopyOutFile {
 	_, _ = b, x
 	x.xxx_hidden_Name = b.Name
 	x.xxx_hidden_Optional = b.Optional
+	x.xxx_hidden_Dir = b.Dir
+	x.xxx_hidden_Glob = b.Glob
 	return m0
 }
 
@@ -1136,7 +1541,7 @@ type Request_PipeMap struct {
 
 func (x *Request_PipeMap) Reset() {
 	*x = Request_PipeMap{}
-	mi := &file_request_proto_msgTypes[8]
+	mi := &file_request_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1148,7 +1553,7 @@ func (x *Request_PipeMap) String() string {
 func (*Request_PipeMap) ProtoMessage() {}
 
 func (x *Request_PipeMap) ProtoReflect() protoreflect.Message {
-	mi := &file_request_proto_msgTypes[8]
+	mi := &file_request_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1281,7 +1686,7 @@ type Request_PipeMap_PipeIndex struct {
 
 func (x *Request_PipeMap_PipeIndex) Reset() {
 	*x = Request_PipeMap_PipeIndex{}
-	mi := &file_request_proto_msgTypes[11]
+	mi := &file_request_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1293,7 +1698,7 @@ func (x *Request_PipeMap_PipeIndex) String() string {
 func (*Request_PipeMap_PipeIndex) ProtoMessage() {}
 
 func (x *Request_PipeMap_PipeIndex) ProtoReflect() protoreflect.Message {
-	mi := &file_request_proto_msgTypes[11]
+	mi := &file_request_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1346,31 +1751,48 @@ var File_request_proto protoreflect.FileDescriptor
 
 const file_request_proto_rawDesc = "" +
 	"\n" +
-	"\rrequest.proto\x12\x02pb\x1a\x1bgoogle/protobuf/empty.proto\x1a!google/protobuf/go_features.proto\"\xe3\x0e\n" +
+	"\rrequest.proto\x12\x02pb\x1a\x1bgoogle/protobuf/empty.proto\x1a!google/protobuf/go_features.proto\"\xf1\x13\n" +
 	"\aRequest\x12\x1c\n" +
 	"\trequestID\x18\x01 \x01(\tR\trequestID\x12%\n" +
 	"\x03cmd\x18\x02 \x03(\v2\x13.pb.Request.CmdTypeR\x03cmd\x125\n" +
-	"\vpipeMapping\x18\x03 \x03(\v2\x13.pb.Request.PipeMapR\vpipeMapping\x1a\x1d\n" +
+	"\vpipeMapping\x18\x03 \x03(\v2\x13.pb.Request.PipeMapR\vpipeMapping\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x1a\x1d\n" +
 	"\tLocalFile\x12\x10\n" +
-	"\x03src\x18\x01 \x01(\tR\x03src\x1a&\n" +
+	"\x03src\x18\x01 \x01(\tR\x03src\x1aH\n" +
 	"\n" +
 	"MemoryFile\x12\x18\n" +
-	"\acontent\x18\x01 \x01(\fR\acontent\x1a$\n" +
+	"\acontent\x18\x01 \x01(\fR\acontent\x12 \n" +
+	"\vcompression\x18\x02 \x01(\tR\vcompression\x1a$\n" +
 	"\n" +
 	"CachedFile\x12\x16\n" +
-	"\x06fileID\x18\x01 \x01(\tR\x06fileID\x1aI\n" +
+	"\x06fileID\x18\x01 \x01(\tR\x06fileID\x1a!\n" +
+	"\tCachedDir\x12\x14\n" +
+	"\x05dirID\x18\x01 \x01(\tR\x05dirID\x1a\x93\x01\n" +
 	"\rPipeCollector\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
 	"\x03max\x18\x02 \x01(\x03R\x03max\x12\x12\n" +
-	"\x04pipe\x18\x03 \x01(\bR\x04pipe\x1a\xc0\x02\n" +
+	"\x04pipe\x18\x03 \x01(\bR\x04pipe\x12\x12\n" +
+	"\x04tail\x18\x04 \x01(\x03R\x04tail\x12 \n" +
+	"\vcompression\x18\x05 \x01(\tR\vcompression\x12\x12\n" +
+	"\x04live\x18\x06 \x01(\bR\x04live\x1a\xa8\x04\n" +
 	"\x04File\x12-\n" +
 	"\x05local\x18\x01 \x01(\v2\x15.pb.Request.LocalFileH\x00R\x05local\x120\n" +
 	"\x06memory\x18\x02 \x01(\v2\x16.pb.Request.MemoryFileH\x00R\x06memory\x120\n" +
 	"\x06cached\x18\x03 \x01(\v2\x16.pb.Request.CachedFileH\x00R\x06cached\x12/\n" +
 	"\x04pipe\x18\x04 \x01(\v2\x19.pb.Request.PipeCollectorH\x00R\x04pipe\x124\n" +
 	"\bstreamIn\x18\x05 \x01(\v2\x16.google.protobuf.EmptyH\x00R\bstreamIn\x126\n" +
-	"\tstreamOut\x18\x06 \x01(\v2\x16.google.protobuf.EmptyH\x00R\tstreamOutB\x06\n" +
-	"\x04file\x1a\x99\a\n" +
+	"\tstreamOut\x18\x06 \x01(\v2\x16.google.protobuf.EmptyH\x00R\tstreamOut\x125\n" +
+	"\tcachedDir\x18\a \x01(\v2\x15.pb.Request.CachedDirH\x00R\tcachedDir\x12\x18\n" +
+	"\aextract\x18\b \x01(\bR\aextract\x12\x12\n" +
+	"\x04mode\x18\t \x01(\rR\x04mode\x12,\n" +
+	"\x05owner\x18\n" +
+	" \x01(\x0e2\x16.pb.Request.File.OwnerR\x05owner\x12\x1a\n" +
+	"\breadOnly\x18\v \x01(\bR\breadOnly\x12\x1a\n" +
+	"\bzeroCopy\x18\f \x01(\bR\bzeroCopy\"\x1b\n" +
+	"\x05Owner\x12\b\n" +
+	"\x04Root\x10\x00\x12\b\n" +
+	"\x04User\x10\x01B\x06\n" +
+	"\x04file\x1a\xf5\b\n" +
 	"\aCmdType\x12\x12\n" +
 	"\x04args\x18\x01 \x03(\tR\x04args\x12\x10\n" +
 	"\x03env\x18\x02 \x03(\tR\x03env\x12&\n" +
@@ -1398,16 +1820,23 @@ const file_request_proto_rawDesc = "" +
 	"\n" +
 	"copyOutMax\x18\x0e \x01(\x04R\n" +
 	"copyOutMax\x12(\n" +
-	"\x0fcopyOutTruncate\x18\x14 \x01(\bR\x0fcopyOutTruncate\x1aK\n" +
+	"\x0fcopyOutTruncate\x18\x14 \x01(\bR\x0fcopyOutTruncate\x12(\n" +
+	"\x0fcopyOutMaxFiles\x18\x15 \x01(\x04R\x0fcopyOutMaxFiles\x12*\n" +
+	"\x10copyOutDirFormat\x18\x16 \x01(\tR\x10copyOutDirFormat\x12$\n" +
+	"\rcopyOutDigest\x18\x17 \x01(\bR\rcopyOutDigest\x12,\n" +
+	"\x11copyOutDigestOnly\x18\x18 \x01(\bR\x11copyOutDigestOnly\x120\n" +
+	"\x13copyOutTruncateTail\x18\x19 \x01(\x04R\x13copyOutTruncateTail\x1aK\n" +
 	"\vCopyInEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
 	"\x05value\x18\x02 \x01(\v2\x10.pb.Request.FileR\x05value:\x028\x01\x1a;\n" +
 	"\rSymlinksEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a@\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1af\n" +
 	"\x0eCmdCopyOutFile\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
-	"\boptional\x18\x02 \x01(\bR\boptional\x1a\x82\x02\n" +
+	"\boptional\x18\x02 \x01(\bR\boptional\x12\x10\n" +
+	"\x03dir\x18\x03 \x01(\bR\x03dir\x12\x12\n" +
+	"\x04glob\x18\x04 \x01(\bR\x04glob\x1a\x82\x02\n" +
 	"\aPipeMap\x12-\n" +
 	"\x02in\x18\x01 \x01(\v2\x1d.pb.Request.PipeMap.PipeIndexR\x02in\x12/\n" +
 	"\x03out\x18\x02 \x01(\v2\x1d.pb.Request.PipeMap.PipeIndexR\x03out\x12\x14\n" +
@@ -1419,44 +1848,49 @@ const file_request_proto_rawDesc = "" +
 	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x0e\n" +
 	"\x02fd\x18\x02 \x01(\x05R\x02fdB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
 
-var file_request_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_request_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_request_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_request_proto_goTypes = []any{
-	(*Request)(nil),                   // 0: pb.Request
-	(*Request_LocalFile)(nil),         // 1: pb.Request.LocalFile
-	(*Request_MemoryFile)(nil),        // 2: pb.Request.MemoryFile
-	(*Request_CachedFile)(nil),        // 3: pb.Request.CachedFile
-	(*Request_PipeCollector)(nil),     // 4: pb.Request.PipeCollector
-	(*Request_File)(nil),              // 5: pb.Request.File
-	(*Request_CmdType)(nil),           // 6: pb.Request.CmdType
-	(*Request_CmdCopyOutFile)(nil),    // 7: pb.Request.CmdCopyOutFile
-	(*Request_PipeMap)(nil),           // 8: pb.Request.PipeMap
-	nil,                               // 9: pb.Request.CmdType.CopyInEntry
-	nil,                               // 10: pb.Request.CmdType.SymlinksEntry
-	(*Request_PipeMap_PipeIndex)(nil), // 11: pb.Request.PipeMap.PipeIndex
-	(*emptypb.Empty)(nil),             // 12: google.protobuf.Empty
+	(Request_File_Owner)(0),           // 0: pb.Request.File.Owner
+	(*Request)(nil),                   // 1: pb.Request
+	(*Request_LocalFile)(nil),         // 2: pb.Request.LocalFile
+	(*Request_MemoryFile)(nil),        // 3: pb.Request.MemoryFile
+	(*Request_CachedFile)(nil),        // 4: pb.Request.CachedFile
+	(*Request_CachedDir)(nil),         // 5: pb.Request.CachedDir
+	(*Request_PipeCollector)(nil),     // 6: pb.Request.PipeCollector
+	(*Request_File)(nil),              // 7: pb.Request.File
+	(*Request_CmdType)(nil),           // 8: pb.Request.CmdType
+	(*Request_CmdCopyOutFile)(nil),    // 9: pb.Request.CmdCopyOutFile
+	(*Request_PipeMap)(nil),           // 10: pb.Request.PipeMap
+	nil,                               // 11: pb.Request.CmdType.CopyInEntry
+	nil,                               // 12: pb.Request.CmdType.SymlinksEntry
+	(*Request_PipeMap_PipeIndex)(nil), // 13: pb.Request.PipeMap.PipeIndex
+	(*emptypb.Empty)(nil),             // 14: google.protobuf.Empty
 }
 var file_request_proto_depIdxs = []int32{
-	6,  // 0: pb.Request.cmd:type_name -> pb.Request.CmdType
-	8,  // 1: pb.Request.pipeMapping:type_name -> pb.Request.PipeMap
-	1,  // 2: pb.Request.File.local:type_name -> pb.Request.LocalFile
-	2,  // 3: pb.Request.File.memory:type_name -> pb.Request.MemoryFile
-	3,  // 4: pb.Request.File.cached:type_name -> pb.Request.CachedFile
-	4,  // 5: pb.Request.File.pipe:type_name -> pb.Request.PipeCollector
-	12, // 6: pb.Request.File.streamIn:type_name -> google.protobuf.Empty
-	12, // 7: pb.Request.File.streamOut:type_name -> google.protobuf.Empty
-	5,  // 8: pb.Request.CmdType.files:type_name -> pb.Request.File
-	9,  // 9: pb.Request.CmdType.copyIn:type_name -> pb.Request.CmdType.CopyInEntry
-	10, // 10: pb.Request.CmdType.symlinks:type_name -> pb.Request.CmdType.SymlinksEntry
-	7,  // 11: pb.Request.CmdType.copyOut:type_name -> pb.Request.CmdCopyOutFile
-	7,  // 12: pb.Request.CmdType.copyOutCached:type_name -> pb.Request.CmdCopyOutFile
-	11, // 13: pb.Request.PipeMap.in:type_name -> pb.Request.PipeMap.PipeIndex
-	11, // 14: pb.Request.PipeMap.out:type_name -> pb.Request.PipeMap.PipeIndex
-	5,  // 15: pb.Request.CmdType.CopyInEntry.value:type_name -> pb.Request.File
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	8,  // 0: pb.Request.cmd:type_name -> pb.Request.CmdType
+	10, // 1: pb.Request.pipeMapping:type_name -> pb.Request.PipeMap
+	2,  // 2: pb.Request.File.local:type_name -> pb.Request.LocalFile
+	3,  // 3: pb.Request.File.memory:type_name -> pb.Request.MemoryFile
+	4,  // 4: pb.Request.File.cached:type_name -> pb.Request.CachedFile
+	6,  // 5: pb.Request.File.pipe:type_name -> pb.Request.PipeCollector
+	14, // 6: pb.Request.File.streamIn:type_name -> google.protobuf.Empty
+	14, // 7: pb.Request.File.streamOut:type_name -> google.protobuf.Empty
+	5,  // 8: pb.Request.File.cachedDir:type_name -> pb.Request.CachedDir
+	0,  // 9: pb.Request.File.owner:type_name -> pb.Request.File.Owner
+	7,  // 10: pb.Request.CmdType.files:type_name -> pb.Request.File
+	11, // 11: pb.Request.CmdType.copyIn:type_name -> pb.Request.CmdType.CopyInEntry
+	12, // 12: pb.Request.CmdType.symlinks:type_name -> pb.Request.CmdType.SymlinksEntry
+	9,  // 13: pb.Request.CmdType.copyOut:type_name -> pb.Request.CmdCopyOutFile
+	9,  // 14: pb.Request.CmdType.copyOutCached:type_name -> pb.Request.CmdCopyOutFile
+	13, // 15: pb.Request.PipeMap.in:type_name -> pb.Request.PipeMap.PipeIndex
+	13, // 16: pb.Request.PipeMap.out:type_name -> pb.Request.PipeMap.PipeIndex
+	7,  // 17: pb.Request.CmdType.CopyInEntry.value:type_name -> pb.Request.File
+	18, // [18:18] is the sub-list for method output_type
+	18, // [18:18] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_request_proto_init() }
@@ -1464,26 +1898,28 @@ func file_request_proto_init() {
 	if File_request_proto != nil {
 		return
 	}
-	file_request_proto_msgTypes[5].OneofWrappers = []any{
+	file_request_proto_msgTypes[6].OneofWrappers = []any{
 		(*request_File_Local)(nil),
 		(*request_File_Memory)(nil),
 		(*request_File_Cached)(nil),
 		(*request_File_Pipe)(nil),
 		(*request_File_StreamIn)(nil),
 		(*request_File_StreamOut)(nil),
+		(*request_File_CachedDir)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_request_proto_rawDesc), len(file_request_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   12,
+			NumEnums:      1,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_request_proto_goTypes,
 		DependencyIndexes: file_request_proto_depIdxs,
+		EnumInfos:         file_request_proto_enumTypes,
 		MessageInfos:      file_request_proto_msgTypes,
 	}.Build()
 	File_request_proto = out.File
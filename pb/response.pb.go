@@ -34,21 +34,25 @@ const (
 	Response_FileError_CopyOutCopyContent    Response_FileError_ErrorType = 7
 	Response_FileError_CollectSizeExceeded   Response_FileError_ErrorType = 8
 	Response_FileError_Symlink               Response_FileError_ErrorType = 9
+	Response_FileError_CopyInExtract         Response_FileError_ErrorType = 10
+	Response_FileError_CopyOutCountExceeded  Response_FileError_ErrorType = 11
 )
 
 // Enum value maps for Response_FileError_ErrorType.
 var (
 	Response_FileError_ErrorType_name = map[int32]string{
-		0: "CopyInOpenFile",
-		1: "CopyInCreateFile",
-		2: "CopyInCopyContent",
-		3: "CopyOutOpen",
-		4: "CopyOutNotRegularFile",
-		5: "CopyOutSizeExceeded",
-		6: "CopyOutCreateFile",
-		7: "CopyOutCopyContent",
-		8: "CollectSizeExceeded",
-		9: "Symlink",
+		0:  "CopyInOpenFile",
+		1:  "CopyInCreateFile",
+		2:  "CopyInCopyContent",
+		3:  "CopyOutOpen",
+		4:  "CopyOutNotRegularFile",
+		5:  "CopyOutSizeExceeded",
+		6:  "CopyOutCreateFile",
+		7:  "CopyOutCopyContent",
+		8:  "CollectSizeExceeded",
+		9:  "Symlink",
+		10: "CopyInExtract",
+		11: "CopyOutCountExceeded",
 	}
 	Response_FileError_ErrorType_value = map[string]int32{
 		"CopyInOpenFile":        0,
@@ -61,6 +65,8 @@ var (
 		"CopyOutCopyContent":    7,
 		"CollectSizeExceeded":   8,
 		"Symlink":               9,
+		"CopyInExtract":         10,
+		"CopyOutCountExceeded":  11,
 	}
 )
 
@@ -163,6 +169,65 @@ func (x Response_Result_StatusType) Number() protoreflect.EnumNumber {
 	return protoreflect.EnumNumber(x)
 }
 
+type Event_Type int32
+
+const (
+	Event_Queued          Event_Type = 0
+	Event_Started         Event_Type = 1
+	Event_CopyInDone      Event_Type = 2
+	Event_ProcessStarted  Event_Type = 3
+	Event_ProcessFinished Event_Type = 4
+	Event_CopyOutDone     Event_Type = 5
+	Event_Finished        Event_Type = 6
+	Event_Output          Event_Type = 7
+)
+
+// Enum value maps for Event_Type.
+var (
+	Event_Type_name = map[int32]string{
+		0: "Queued",
+		1: "Started",
+		2: "CopyInDone",
+		3: "ProcessStarted",
+		4: "ProcessFinished",
+		5: "CopyOutDone",
+		6: "Finished",
+		7: "Output",
+	}
+	Event_Type_value = map[string]int32{
+		"Queued":          0,
+		"Started":         1,
+		"CopyInDone":      2,
+		"ProcessStarted":  3,
+		"ProcessFinished": 4,
+		"CopyOutDone":     5,
+		"Finished":        6,
+		"Output":          7,
+	}
+)
+
+func (x Event_Type) Enum() *Event_Type {
+	p := new(Event_Type)
+	*p = x
+	return p
+}
+
+func (x Event_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Event_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_response_proto_enumTypes[2].Descriptor()
+}
+
+func (Event_Type) Type() protoreflect.EnumType {
+	return &file_response_proto_enumTypes[2]
+}
+
+func (x Event_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
 type Response struct {
 	state                protoimpl.MessageState `protogen:"opaque.v1"`
 	xxx_hidden_RequestID string                 `protobuf:"bytes,1,opt,name=requestID"`
@@ -250,18 +315,191 @@ func (b0 Response_builder) Build() *Response {
 	return m0
 }
 
+// Event is the progress event of a request
+type Event struct {
+	state               protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Type     Event_Type             `protobuf:"varint,1,opt,name=type,enum=pb.Event_Type"`
+	xxx_hidden_Index    int32                  `protobuf:"varint,2,opt,name=index"`
+	xxx_hidden_Position int32                  `protobuf:"varint,3,opt,name=position"`
+	xxx_hidden_Result   *Response_Result       `protobuf:"bytes,4,opt,name=result"`
+	xxx_hidden_Response *Response              `protobuf:"bytes,5,opt,name=response"`
+	xxx_hidden_Name     string                 `protobuf:"bytes,6,opt,name=name"`
+	xxx_hidden_Content  []byte                 `protobuf:"bytes,7,opt,name=content"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_response_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_response_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *Event) GetType() Event_Type {
+	if x != nil {
+		return x.xxx_hidden_Type
+	}
+	return Event_Queued
+}
+
+func (x *Event) GetIndex() int32 {
+	if x != nil {
+		return x.xxx_hidden_Index
+	}
+	return 0
+}
+
+func (x *Event) GetPosition() int32 {
+	if x != nil {
+		return x.xxx_hidden_Position
+	}
+	return 0
+}
+
+func (x *Event) GetResult() *Response_Result {
+	if x != nil {
+		return x.xxx_hidden_Result
+	}
+	return nil
+}
+
+func (x *Event) GetResponse() *Response {
+	if x != nil {
+		return x.xxx_hidden_Response
+	}
+	return nil
+}
+
+func (x *Event) GetName() string {
+	if x != nil {
+		return x.xxx_hidden_Name
+	}
+	return ""
+}
+
+func (x *Event) GetContent() []byte {
+	if x != nil {
+		return x.xxx_hidden_Content
+	}
+	return nil
+}
+
+func (x *Event) SetType(v Event_Type) {
+	x.xxx_hidden_Type = v
+}
+
+func (x *Event) SetIndex(v int32) {
+	x.xxx_hidden_Index = v
+}
+
+func (x *Event) SetPosition(v int32) {
+	x.xxx_hidden_Position = v
+}
+
+func (x *Event) SetResult(v *Response_Result) {
+	x.xxx_hidden_Result = v
+}
+
+func (x *Event) SetResponse(v *Response) {
+	x.xxx_hidden_Response = v
+}
+
+func (x *Event) SetName(v string) {
+	x.xxx_hidden_Name = v
+}
+
+func (x *Event) SetContent(v []byte) {
+	if v == nil {
+		v = []byte{}
+	}
+	x.xxx_hidden_Content = v
+}
+
+func (x *Event) HasResult() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Result != nil
+}
+
+func (x *Event) HasResponse() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Response != nil
+}
+
+func (x *Event) ClearResult() {
+	x.xxx_hidden_Result = nil
+}
+
+func (x *Event) ClearResponse() {
+	x.xxx_hidden_Response = nil
+}
+
+type Event_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Type Event_Type
+	// index of the cmd for cmd events
+	Index int32
+	// position in the queue for Queued
+	Position int32
+	// result without files for ProcessFinished
+	Result *Response_Result
+	// response for Finished
+	Response *Response
+	// collector name and output chunk for Output
+	Name    string
+	Content []byte
+}
+
+func (b0 Event_builder) Build() *Event {
+	m0 := &Event{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Type = b.Type
+	x.xxx_hidden_Index = b.Index
+	x.xxx_hidden_Position = b.Position
+	x.xxx_hidden_Result = b.Result
+	x.xxx_hidden_Response = b.Response
+	x.xxx_hidden_Name = b.Name
+	x.xxx_hidden_Content = b.Content
+	return m0
+}
+
 type Response_FileError struct {
 	state              protoimpl.MessageState       `protogen:"opaque.v1"`
 	xxx_hidden_Name    string                       `protobuf:"bytes,1,opt,name=name"`
 	xxx_hidden_Type    Response_FileError_ErrorType `protobuf:"varint,2,opt,name=type,enum=pb.Response_FileError_ErrorType"`
 	xxx_hidden_Message string                       `protobuf:"bytes,3,opt,name=message"`
+	xxx_hidden_Size    int64                        `protobuf:"varint,4,opt,name=size"`
 	unknownFields      protoimpl.UnknownFields
 	sizeCache          protoimpl.SizeCache
 }
 
 func (x *Response_FileError) Reset() {
 	*x = Response_FileError{}
-	mi := &file_response_proto_msgTypes[1]
+	mi := &file_response_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -273,7 +511,7 @@ func (x *Response_FileError) String() string {
 func (*Response_FileError) ProtoMessage() {}
 
 func (x *Response_FileError) ProtoReflect() protoreflect.Message {
-	mi := &file_response_proto_msgTypes[1]
+	mi := &file_response_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -305,6 +543,13 @@ func (x *Response_FileError) GetMessage() string {
 	return ""
 }
 
+func (x *Response_FileError) GetSize() int64 {
+	if x != nil {
+		return x.xxx_hidden_Size
+	}
+	return 0
+}
+
 func (x *Response_FileError) SetName(v string) {
 	x.xxx_hidden_Name = v
 }
@@ -317,12 +562,18 @@ func (x *Response_FileError) SetMessage(v string) {
 	x.xxx_hidden_Message = v
 }
 
+func (x *Response_FileError) SetSize(v int64) {
+	x.xxx_hidden_Size = v
+}
+
 type Response_FileError_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
 	Name    string
 	Type    Response_FileError_ErrorType
 	Message string
+	// size is the original size of the truncated file if known
+	Size int64
 }
 
 func (b0 Response_FileError_builder) Build() *Response_FileError {
@@ -332,28 +583,102 @@ func (b0 Response_FileError_builder) Build() *Response_FileError {
 	x.xxx_hidden_Name = b.Name
 	x.xxx_hidden_Type = b.Type
 	x.xxx_hidden_Message = b.Message
+	x.xxx_hidden_Size = b.Size
+	return m0
+}
+
+type Response_FileDigest struct {
+	state             protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Size   int64                  `protobuf:"varint,1,opt,name=size"`
+	xxx_hidden_Sha256 string                 `protobuf:"bytes,2,opt,name=sha256"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Response_FileDigest) Reset() {
+	*x = Response_FileDigest{}
+	mi := &file_response_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Response_FileDigest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_FileDigest) ProtoMessage() {}
+
+func (x *Response_FileDigest) ProtoReflect() protoreflect.Message {
+	mi := &file_response_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *Response_FileDigest) GetSize() int64 {
+	if x != nil {
+		return x.xxx_hidden_Size
+	}
+	return 0
+}
+
+func (x *Response_FileDigest) GetSha256() string {
+	if x != nil {
+		return x.xxx_hidden_Sha256
+	}
+	return ""
+}
+
+func (x *Response_FileDigest) SetSize(v int64) {
+	x.xxx_hidden_Size = v
+}
+
+func (x *Response_FileDigest) SetSha256(v string) {
+	x.xxx_hidden_Sha256 = v
+}
+
+type Response_FileDigest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Size int64
+	// hex encoded SHA-256
+	Sha256 string
+}
+
+func (b0 Response_FileDigest_builder) Build() *Response_FileDigest {
+	m0 := &Response_FileDigest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Size = b.Size
+	x.xxx_hidden_Sha256 = b.Sha256
 	return m0
 }
 
 type Response_Result struct {
-	state                 protoimpl.MessageState     `protogen:"opaque.v1"`
-	xxx_hidden_Status     Response_Result_StatusType `protobuf:"varint,1,opt,name=status,enum=pb.Response_Result_StatusType"`
-	xxx_hidden_ExitStatus int32                      `protobuf:"varint,2,opt,name=exitStatus"`
-	xxx_hidden_Error      string                     `protobuf:"bytes,3,opt,name=error"`
-	xxx_hidden_Time       uint64                     `protobuf:"varint,4,opt,name=time"`
-	xxx_hidden_RunTime    uint64                     `protobuf:"varint,8,opt,name=runTime"`
-	xxx_hidden_ProcPeak   uint64                     `protobuf:"varint,10,opt,name=procPeak"`
-	xxx_hidden_Memory     uint64                     `protobuf:"varint,5,opt,name=memory"`
-	xxx_hidden_Files      map[string][]byte          `protobuf:"bytes,6,rep,name=files" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	xxx_hidden_FileIDs    map[string]string          `protobuf:"bytes,7,rep,name=fileIDs" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	xxx_hidden_FileError  *[]*Response_FileError     `protobuf:"bytes,9,rep,name=fileError"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+	state                  protoimpl.MessageState          `protogen:"opaque.v1"`
+	xxx_hidden_Status      Response_Result_StatusType      `protobuf:"varint,1,opt,name=status,enum=pb.Response_Result_StatusType"`
+	xxx_hidden_ExitStatus  int32                           `protobuf:"varint,2,opt,name=exitStatus"`
+	xxx_hidden_Error       string                          `protobuf:"bytes,3,opt,name=error"`
+	xxx_hidden_Time        uint64                          `protobuf:"varint,4,opt,name=time"`
+	xxx_hidden_RunTime     uint64                          `protobuf:"varint,8,opt,name=runTime"`
+	xxx_hidden_ProcPeak    uint64                          `protobuf:"varint,10,opt,name=procPeak"`
+	xxx_hidden_Memory      uint64                          `protobuf:"varint,5,opt,name=memory"`
+	xxx_hidden_Files       map[string][]byte               `protobuf:"bytes,6,rep,name=files" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	xxx_hidden_FileIDs     map[string]string               `protobuf:"bytes,7,rep,name=fileIDs" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	xxx_hidden_FileError   *[]*Response_FileError          `protobuf:"bytes,9,rep,name=fileError"`
+	xxx_hidden_FileDigests map[string]*Response_FileDigest `protobuf:"bytes,11,rep,name=fileDigests" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *Response_Result) Reset() {
 	*x = Response_Result{}
-	mi := &file_response_proto_msgTypes[2]
+	mi := &file_response_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -365,7 +690,7 @@ func (x *Response_Result) String() string {
 func (*Response_Result) ProtoMessage() {}
 
 func (x *Response_Result) ProtoReflect() protoreflect.Message {
-	mi := &file_response_proto_msgTypes[2]
+	mi := &file_response_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -448,6 +773,13 @@ func (x *Response_Result) GetFileError() []*Response_FileError {
 	return nil
 }
 
+func (x *Response_Result) GetFileDigests() map[string]*Response_FileDigest {
+	if x != nil {
+		return x.xxx_hidden_FileDigests
+	}
+	return nil
+}
+
 func (x *Response_Result) SetStatus(v Response_Result_StatusType) {
 	x.xxx_hidden_Status = v
 }
@@ -488,6 +820,10 @@ func (x *Response_Result) SetFileError(v []*Response_FileError) {
 	x.xxx_hidden_FileError = &v
 }
 
+func (x *Response_Result) SetFileDigests(v map[string]*Response_FileDigest) {
+	x.xxx_hidden_FileDigests = v
+}
+
 type Response_Result_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
@@ -501,6 +837,9 @@ type Response_Result_builder struct {
 	Files      map[string][]byte
 	FileIDs    map[string]string
 	FileError  []*Response_FileError
+	// fileDigests contains size and SHA-256 of files if copyOutDigest is
+	// specified
+	FileDigests map[string]*Response_FileDigest
 }
 
 func (b0 Response_Result_builder) Build() *Response_Result {
@@ -517,6 +856,7 @@ func (b0 Response_Result_builder) Build() *Response_Result {
 	x.xxx_hidden_Files = b.Files
 	x.xxx_hidden_FileIDs = b.FileIDs
 	x.xxx_hidden_FileError = &b.FileError
+	x.xxx_hidden_FileDigests = b.FileDigests
 	return m0
 }
 
@@ -524,15 +864,16 @@ var File_response_proto protoreflect.FileDescriptor
 
 const file_response_proto_rawDesc = "" +
 	"\n" +
-	"\x0eresponse.proto\x12\x02pb\x1a!google/protobuf/go_features.proto\"\xe6\t\n" +
+	"\x0eresponse.proto\x12\x02pb\x1a!google/protobuf/go_features.proto\"\x82\f\n" +
 	"\bResponse\x12\x1c\n" +
 	"\trequestID\x18\x01 \x01(\tR\trequestID\x12-\n" +
 	"\aresults\x18\x02 \x03(\v2\x13.pb.Response.ResultR\aresults\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error\x1a\xd8\x02\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x1a\x99\x03\n" +
 	"\tFileError\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x124\n" +
 	"\x04type\x18\x02 \x01(\x0e2 .pb.Response.FileError.ErrorTypeR\x04type\x12\x18\n" +
-	"\amessage\x18\x03 \x01(\tR\amessage\"\xe6\x01\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\"\x93\x02\n" +
 	"\tErrorType\x12\x12\n" +
 	"\x0eCopyInOpenFile\x10\x00\x12\x14\n" +
 	"\x10CopyInCreateFile\x10\x01\x12\x15\n" +
@@ -543,7 +884,14 @@ const file_response_proto_rawDesc = "" +
 	"\x11CopyOutCreateFile\x10\x06\x12\x16\n" +
 	"\x12CopyOutCopyContent\x10\a\x12\x17\n" +
 	"\x13CollectSizeExceeded\x10\b\x12\v\n" +
-	"\aSymlink\x10\t\x1a\x9b\x06\n" +
+	"\aSymlink\x10\t\x12\x11\n" +
+	"\rCopyInExtract\x10\n" +
+	"\x12\x18\n" +
+	"\x14CopyOutCountExceeded\x10\v\x1a8\n" +
+	"\n" +
+	"FileDigest\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x02 \x01(\tR\x06sha256\x1a\xbc\a\n" +
 	"\x06Result\x126\n" +
 	"\x06status\x18\x01 \x01(\x0e2\x1e.pb.Response.Result.StatusTypeR\x06status\x12\x1e\n" +
 	"\n" +
@@ -557,14 +905,18 @@ const file_response_proto_rawDesc = "" +
 	"\x06memory\x18\x05 \x01(\x04R\x06memory\x124\n" +
 	"\x05files\x18\x06 \x03(\v2\x1e.pb.Response.Result.FilesEntryR\x05files\x12:\n" +
 	"\afileIDs\x18\a \x03(\v2 .pb.Response.Result.FileIDsEntryR\afileIDs\x124\n" +
-	"\tfileError\x18\t \x03(\v2\x16.pb.Response.FileErrorR\tfileError\x1a8\n" +
+	"\tfileError\x18\t \x03(\v2\x16.pb.Response.FileErrorR\tfileError\x12F\n" +
+	"\vfileDigests\x18\v \x03(\v2$.pb.Response.Result.FileDigestsEntryR\vfileDigests\x1a8\n" +
 	"\n" +
 	"FilesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\x1a:\n" +
 	"\fFileIDsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa2\x02\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aW\n" +
+	"\x10FileDigestsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.pb.Response.FileDigestR\x05value:\x028\x01\"\xa2\x02\n" +
 	"\n" +
 	"StatusType\x12\v\n" +
 	"\aInvalid\x10\x00\x12\f\n" +
@@ -581,31 +933,60 @@ const file_response_proto_rawDesc = "" +
 	"\x12\x13\n" +
 	"\x0fJudgementFailed\x10\v\x12\x16\n" +
 	"\x12InvalidInteraction\x10\f\x12\x11\n" +
-	"\rInternalError\x10\rB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
+	"\rInternalError\x10\r\"\xe8\x02\n" +
+	"\x05Event\x12\"\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x0e.pb.Event.TypeR\x04type\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x05R\x05index\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\x05R\bposition\x12+\n" +
+	"\x06result\x18\x04 \x01(\v2\x13.pb.Response.ResultR\x06result\x12(\n" +
+	"\bresponse\x18\x05 \x01(\v2\f.pb.ResponseR\bresponse\x12\x12\n" +
+	"\x04name\x18\x06 \x01(\tR\x04name\x12\x18\n" +
+	"\acontent\x18\a \x01(\fR\acontent\"\x83\x01\n" +
+	"\x04Type\x12\n" +
+	"\n" +
+	"\x06Queued\x10\x00\x12\v\n" +
+	"\aStarted\x10\x01\x12\x0e\n" +
+	"\n" +
+	"CopyInDone\x10\x02\x12\x12\n" +
+	"\x0eProcessStarted\x10\x03\x12\x13\n" +
+	"\x0fProcessFinished\x10\x04\x12\x0f\n" +
+	"\vCopyOutDone\x10\x05\x12\f\n" +
+	"\bFinished\x10\x06\x12\n" +
+	"\n" +
+	"\x06Output\x10\aB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
 
-var file_response_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_response_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_response_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_response_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_response_proto_goTypes = []any{
 	(Response_FileError_ErrorType)(0), // 0: pb.Response.FileError.ErrorType
 	(Response_Result_StatusType)(0),   // 1: pb.Response.Result.StatusType
-	(*Response)(nil),                  // 2: pb.Response
-	(*Response_FileError)(nil),        // 3: pb.Response.FileError
-	(*Response_Result)(nil),           // 4: pb.Response.Result
-	nil,                               // 5: pb.Response.Result.FilesEntry
-	nil,                               // 6: pb.Response.Result.FileIDsEntry
+	(Event_Type)(0),                   // 2: pb.Event.Type
+	(*Response)(nil),                  // 3: pb.Response
+	(*Event)(nil),                     // 4: pb.Event
+	(*Response_FileError)(nil),        // 5: pb.Response.FileError
+	(*Response_FileDigest)(nil),       // 6: pb.Response.FileDigest
+	(*Response_Result)(nil),           // 7: pb.Response.Result
+	nil,                               // 8: pb.Response.Result.FilesEntry
+	nil,                               // 9: pb.Response.Result.FileIDsEntry
+	nil,                               // 10: pb.Response.Result.FileDigestsEntry
 }
 var file_response_proto_depIdxs = []int32{
-	4, // 0: pb.Response.results:type_name -> pb.Response.Result
-	0, // 1: pb.Response.FileError.type:type_name -> pb.Response.FileError.ErrorType
-	1, // 2: pb.Response.Result.status:type_name -> pb.Response.Result.StatusType
-	5, // 3: pb.Response.Result.files:type_name -> pb.Response.Result.FilesEntry
-	6, // 4: pb.Response.Result.fileIDs:type_name -> pb.Response.Result.FileIDsEntry
-	3, // 5: pb.Response.Result.fileError:type_name -> pb.Response.FileError
-	6, // [6:6] is the sub-list for method output_type
-	6, // [6:6] is the sub-list for method input_type
-	6, // [6:6] is the sub-list for extension type_name
-	6, // [6:6] is the sub-list for extension extendee
-	0, // [0:6] is the sub-list for field type_name
+	7,  // 0: pb.Response.results:type_name -> pb.Response.Result
+	2,  // 1: pb.Event.type:type_name -> pb.Event.Type
+	7,  // 2: pb.Event.result:type_name -> pb.Response.Result
+	3,  // 3: pb.Event.response:type_name -> pb.Response
+	0,  // 4: pb.Response.FileError.type:type_name -> pb.Response.FileError.ErrorType
+	1,  // 5: pb.Response.Result.status:type_name -> pb.Response.Result.StatusType
+	8,  // 6: pb.Response.Result.files:type_name -> pb.Response.Result.FilesEntry
+	9,  // 7: pb.Response.Result.fileIDs:type_name -> pb.Response.Result.FileIDsEntry
+	5,  // 8: pb.Response.Result.fileError:type_name -> pb.Response.FileError
+	10, // 9: pb.Response.Result.fileDigests:type_name -> pb.Response.Result.FileDigestsEntry
+	6,  // 10: pb.Response.Result.FileDigestsEntry.value:type_name -> pb.Response.FileDigest
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_response_proto_init() }
@@ -618,8 +999,8 @@ func file_response_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_response_proto_rawDesc), len(file_response_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   5,
+			NumEnums:      3,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
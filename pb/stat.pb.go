@@ -0,0 +1,716 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v7.34.1
+// source: stat.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	_ "google.golang.org/protobuf/types/gofeaturespb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StatResponse struct {
+	state                  protoimpl.MessageState   `protogen:"opaque.v1"`
+	xxx_hidden_Queue       uint64                   `protobuf:"varint,1,opt,name=queue"`
+	xxx_hidden_Running     uint64                   `protobuf:"varint,2,opt,name=running"`
+	xxx_hidden_Parallelism uint64                   `protobuf:"varint,3,opt,name=parallelism"`
+	xxx_hidden_EnvIdle     uint64                   `protobuf:"varint,4,opt,name=envIdle"`
+	xxx_hidden_EnvTotal    uint64                   `protobuf:"varint,5,opt,name=envTotal"`
+	xxx_hidden_FileCount   uint64                   `protobuf:"varint,6,opt,name=fileCount"`
+	xxx_hidden_FileSize    uint64                   `protobuf:"varint,7,opt,name=fileSize"`
+	xxx_hidden_Requests    *[]*StatResponse_Request `protobuf:"bytes,8,rep,name=requests"`
+	xxx_hidden_Draining    bool                     `protobuf:"varint,9,opt,name=draining"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *StatResponse) Reset() {
+	*x = StatResponse{}
+	mi := &file_stat_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatResponse) ProtoMessage() {}
+
+func (x *StatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_stat_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StatResponse) GetQueue() uint64 {
+	if x != nil {
+		return x.xxx_hidden_Queue
+	}
+	return 0
+}
+
+func (x *StatResponse) GetRunning() uint64 {
+	if x != nil {
+		return x.xxx_hidden_Running
+	}
+	return 0
+}
+
+func (x *StatResponse) GetParallelism() uint64 {
+	if x != nil {
+		return x.xxx_hidden_Parallelism
+	}
+	return 0
+}
+
+func (x *StatResponse) GetEnvIdle() uint64 {
+	if x != nil {
+		return x.xxx_hidden_EnvIdle
+	}
+	return 0
+}
+
+func (x *StatResponse) GetEnvTotal() uint64 {
+	if x != nil {
+		return x.xxx_hidden_EnvTotal
+	}
+	return 0
+}
+
+func (x *StatResponse) GetFileCount() uint64 {
+	if x != nil {
+		return x.xxx_hidden_FileCount
+	}
+	return 0
+}
+
+func (x *StatResponse) GetFileSize() uint64 {
+	if x != nil {
+		return x.xxx_hidden_FileSize
+	}
+	return 0
+}
+
+func (x *StatResponse) GetRequests() []*StatResponse_Request {
+	if x != nil {
+		if x.xxx_hidden_Requests != nil {
+			return *x.xxx_hidden_Requests
+		}
+	}
+	return nil
+}
+
+func (x *StatResponse) GetDraining() bool {
+	if x != nil {
+		return x.xxx_hidden_Draining
+	}
+	return false
+}
+
+func (x *StatResponse) SetQueue(v uint64) {
+	x.xxx_hidden_Queue = v
+}
+
+func (x *StatResponse) SetRunning(v uint64) {
+	x.xxx_hidden_Running = v
+}
+
+func (x *StatResponse) SetParallelism(v uint64) {
+	x.xxx_hidden_Parallelism = v
+}
+
+func (x *StatResponse) SetEnvIdle(v uint64) {
+	x.xxx_hidden_EnvIdle = v
+}
+
+func (x *StatResponse) SetEnvTotal(v uint64) {
+	x.xxx_hidden_EnvTotal = v
+}
+
+func (x *StatResponse) SetFileCount(v uint64) {
+	x.xxx_hidden_FileCount = v
+}
+
+func (x *StatResponse) SetFileSize(v uint64) {
+	x.xxx_hidden_FileSize = v
+}
+
+func (x *StatResponse) SetRequests(v []*StatResponse_Request) {
+	x.xxx_hidden_Requests = &v
+}
+
+func (x *StatResponse) SetDraining(v bool) {
+	x.xxx_hidden_Draining = v
+}
+
+type StatResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	// requests waiting in the queue and running
+	Queue       uint64
+	Running     uint64
+	Parallelism uint64
+	// environments ready in the pool and created in total
+	EnvIdle  uint64
+	EnvTotal uint64
+	// files and their total size in the file store
+	FileCount uint64
+	FileSize  uint64
+	// requests queued or running ordered by submission
+	Requests []*StatResponse_Request
+	// new requests are rejected with UNAVAILABLE when draining
+	Draining bool
+}
+
+func (b0 StatResponse_builder) Build() *StatResponse {
+	m0 := &StatResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Queue = b.Queue
+	x.xxx_hidden_Running = b.Running
+	x.xxx_hidden_Parallelism = b.Parallelism
+	x.xxx_hidden_EnvIdle = b.EnvIdle
+	x.xxx_hidden_EnvTotal = b.EnvTotal
+	x.xxx_hidden_FileCount = b.FileCount
+	x.xxx_hidden_FileSize = b.FileSize
+	x.xxx_hidden_Requests = &b.Requests
+	x.xxx_hidden_Draining = b.Draining
+	return m0
+}
+
+// CancelRequest selects the requests queued or running by all the fields
+// specified, at least one of requestID, namespace and tag is required
+type CancelRequest struct {
+	state                protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_RequestID string                 `protobuf:"bytes,1,opt,name=requestID"`
+	xxx_hidden_Namespace string                 `protobuf:"bytes,2,opt,name=namespace"`
+	xxx_hidden_Tag       string                 `protobuf:"bytes,3,opt,name=tag"`
+	xxx_hidden_Kill      bool                   `protobuf:"varint,4,opt,name=kill"`
+	xxx_hidden_Timeout   uint64                 `protobuf:"varint,5,opt,name=timeout"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_stat_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_stat_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *CancelRequest) GetRequestID() string {
+	if x != nil {
+		return x.xxx_hidden_RequestID
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetNamespace() string {
+	if x != nil {
+		return x.xxx_hidden_Namespace
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetTag() string {
+	if x != nil {
+		return x.xxx_hidden_Tag
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetKill() bool {
+	if x != nil {
+		return x.xxx_hidden_Kill
+	}
+	return false
+}
+
+func (x *CancelRequest) GetTimeout() uint64 {
+	if x != nil {
+		return x.xxx_hidden_Timeout
+	}
+	return 0
+}
+
+func (x *CancelRequest) SetRequestID(v string) {
+	x.xxx_hidden_RequestID = v
+}
+
+func (x *CancelRequest) SetNamespace(v string) {
+	x.xxx_hidden_Namespace = v
+}
+
+func (x *CancelRequest) SetTag(v string) {
+	x.xxx_hidden_Tag = v
+}
+
+func (x *CancelRequest) SetKill(v bool) {
+	x.xxx_hidden_Kill = v
+}
+
+func (x *CancelRequest) SetTimeout(v uint64) {
+	x.xxx_hidden_Timeout = v
+}
+
+type CancelRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	RequestID string
+	Namespace string
+	Tag       string
+	// kill destroys the containers of the running requests
+	Kill bool
+	// timeout (ns) to wait for the cancelled requests to finish, 10s by default
+	Timeout uint64
+}
+
+func (b0 CancelRequest_builder) Build() *CancelRequest {
+	m0 := &CancelRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_RequestID = b.RequestID
+	x.xxx_hidden_Namespace = b.Namespace
+	x.xxx_hidden_Tag = b.Tag
+	x.xxx_hidden_Kill = b.Kill
+	x.xxx_hidden_Timeout = b.Timeout
+	return m0
+}
+
+type CancelResponse struct {
+	state                protoimpl.MessageState       `protogen:"opaque.v1"`
+	xxx_hidden_Cancelled *[]*CancelResponse_Cancelled `protobuf:"bytes,1,rep,name=cancelled"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *CancelResponse) Reset() {
+	*x = CancelResponse{}
+	mi := &file_stat_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelResponse) ProtoMessage() {}
+
+func (x *CancelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_stat_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *CancelResponse) GetCancelled() []*CancelResponse_Cancelled {
+	if x != nil {
+		if x.xxx_hidden_Cancelled != nil {
+			return *x.xxx_hidden_Cancelled
+		}
+	}
+	return nil
+}
+
+func (x *CancelResponse) SetCancelled(v []*CancelResponse_Cancelled) {
+	x.xxx_hidden_Cancelled = &v
+}
+
+type CancelResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Cancelled []*CancelResponse_Cancelled
+}
+
+func (b0 CancelResponse_builder) Build() *CancelResponse {
+	m0 := &CancelResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Cancelled = &b.Cancelled
+	return m0
+}
+
+type StatResponse_Request struct {
+	state                protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_RequestID string                 `protobuf:"bytes,1,opt,name=requestID"`
+	xxx_hidden_Namespace string                 `protobuf:"bytes,2,opt,name=namespace"`
+	xxx_hidden_Phase     Event_Type             `protobuf:"varint,3,opt,name=phase,enum=pb.Event_Type"`
+	xxx_hidden_StartTime int64                  `protobuf:"varint,4,opt,name=startTime"`
+	xxx_hidden_Elapsed   uint64                 `protobuf:"varint,5,opt,name=elapsed"`
+	xxx_hidden_Tags      []string               `protobuf:"bytes,6,rep,name=tags"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *StatResponse_Request) Reset() {
+	*x = StatResponse_Request{}
+	mi := &file_stat_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatResponse_Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatResponse_Request) ProtoMessage() {}
+
+func (x *StatResponse_Request) ProtoReflect() protoreflect.Message {
+	mi := &file_stat_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StatResponse_Request) GetRequestID() string {
+	if x != nil {
+		return x.xxx_hidden_RequestID
+	}
+	return ""
+}
+
+func (x *StatResponse_Request) GetNamespace() string {
+	if x != nil {
+		return x.xxx_hidden_Namespace
+	}
+	return ""
+}
+
+func (x *StatResponse_Request) GetPhase() Event_Type {
+	if x != nil {
+		return x.xxx_hidden_Phase
+	}
+	return Event_Queued
+}
+
+func (x *StatResponse_Request) GetStartTime() int64 {
+	if x != nil {
+		return x.xxx_hidden_StartTime
+	}
+	return 0
+}
+
+func (x *StatResponse_Request) GetElapsed() uint64 {
+	if x != nil {
+		return x.xxx_hidden_Elapsed
+	}
+	return 0
+}
+
+func (x *StatResponse_Request) GetTags() []string {
+	if x != nil {
+		return x.xxx_hidden_Tags
+	}
+	return nil
+}
+
+func (x *StatResponse_Request) SetRequestID(v string) {
+	x.xxx_hidden_RequestID = v
+}
+
+func (x *StatResponse_Request) SetNamespace(v string) {
+	x.xxx_hidden_Namespace = v
+}
+
+func (x *StatResponse_Request) SetPhase(v Event_Type) {
+	x.xxx_hidden_Phase = v
+}
+
+func (x *StatResponse_Request) SetStartTime(v int64) {
+	x.xxx_hidden_StartTime = v
+}
+
+func (x *StatResponse_Request) SetElapsed(v uint64) {
+	x.xxx_hidden_Elapsed = v
+}
+
+func (x *StatResponse_Request) SetTags(v []string) {
+	x.xxx_hidden_Tags = v
+}
+
+type StatResponse_Request_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	RequestID string
+	Namespace string
+	// latest progress event of the request
+	Phase Event_Type
+	// unix time in nanoseconds when the request is submitted
+	StartTime int64
+	// elapsed time in nanoseconds
+	Elapsed uint64
+	Tags    []string
+}
+
+func (b0 StatResponse_Request_builder) Build() *StatResponse_Request {
+	m0 := &StatResponse_Request{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_RequestID = b.RequestID
+	x.xxx_hidden_Namespace = b.Namespace
+	x.xxx_hidden_Phase = b.Phase
+	x.xxx_hidden_StartTime = b.StartTime
+	x.xxx_hidden_Elapsed = b.Elapsed
+	x.xxx_hidden_Tags = b.Tags
+	return m0
+}
+
+type CancelResponse_Cancelled struct {
+	state                protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_RequestID string                 `protobuf:"bytes,1,opt,name=requestID"`
+	xxx_hidden_Namespace string                 `protobuf:"bytes,2,opt,name=namespace"`
+	xxx_hidden_Tags      []string               `protobuf:"bytes,3,rep,name=tags"`
+	xxx_hidden_Phase     Event_Type             `protobuf:"varint,4,opt,name=phase,enum=pb.Event_Type"`
+	xxx_hidden_Response  *Response              `protobuf:"bytes,5,opt,name=response"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *CancelResponse_Cancelled) Reset() {
+	*x = CancelResponse_Cancelled{}
+	mi := &file_stat_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelResponse_Cancelled) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelResponse_Cancelled) ProtoMessage() {}
+
+func (x *CancelResponse_Cancelled) ProtoReflect() protoreflect.Message {
+	mi := &file_stat_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *CancelResponse_Cancelled) GetRequestID() string {
+	if x != nil {
+		return x.xxx_hidden_RequestID
+	}
+	return ""
+}
+
+func (x *CancelResponse_Cancelled) GetNamespace() string {
+	if x != nil {
+		return x.xxx_hidden_Namespace
+	}
+	return ""
+}
+
+func (x *CancelResponse_Cancelled) GetTags() []string {
+	if x != nil {
+		return x.xxx_hidden_Tags
+	}
+	return nil
+}
+
+func (x *CancelResponse_Cancelled) GetPhase() Event_Type {
+	if x != nil {
+		return x.xxx_hidden_Phase
+	}
+	return Event_Queued
+}
+
+func (x *CancelResponse_Cancelled) GetResponse() *Response {
+	if x != nil {
+		return x.xxx_hidden_Response
+	}
+	return nil
+}
+
+func (x *CancelResponse_Cancelled) SetRequestID(v string) {
+	x.xxx_hidden_RequestID = v
+}
+
+func (x *CancelResponse_Cancelled) SetNamespace(v string) {
+	x.xxx_hidden_Namespace = v
+}
+
+func (x *CancelResponse_Cancelled) SetTags(v []string) {
+	x.xxx_hidden_Tags = v
+}
+
+func (x *CancelResponse_Cancelled) SetPhase(v Event_Type) {
+	x.xxx_hidden_Phase = v
+}
+
+func (x *CancelResponse_Cancelled) SetResponse(v *Response) {
+	x.xxx_hidden_Response = v
+}
+
+func (x *CancelResponse_Cancelled) HasResponse() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Response != nil
+}
+
+func (x *CancelResponse_Cancelled) ClearResponse() {
+	x.xxx_hidden_Response = nil
+}
+
+type CancelResponse_Cancelled_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	RequestID string
+	Namespace string
+	Tags      []string
+	// phase of the request when cancelled
+	Phase Event_Type
+	// partial results without files
+	Response *Response
+}
+
+func (b0 CancelResponse_Cancelled_builder) Build() *CancelResponse_Cancelled {
+	m0 := &CancelResponse_Cancelled{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_RequestID = b.RequestID
+	x.xxx_hidden_Namespace = b.Namespace
+	x.xxx_hidden_Tags = b.Tags
+	x.xxx_hidden_Phase = b.Phase
+	x.xxx_hidden_Response = b.Response
+	return m0
+}
+
+var File_stat_proto protoreflect.FileDescriptor
+
+const file_stat_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"stat.proto\x12\x02pb\x1a!google/protobuf/go_features.proto\x1a\x0eresponse.proto\"\xdc\x03\n" +
+	"\fStatResponse\x12\x14\n" +
+	"\x05queue\x18\x01 \x01(\x04R\x05queue\x12\x18\n" +
+	"\arunning\x18\x02 \x01(\x04R\arunning\x12 \n" +
+	"\vparallelism\x18\x03 \x01(\x04R\vparallelism\x12\x18\n" +
+	"\aenvIdle\x18\x04 \x01(\x04R\aenvIdle\x12\x1a\n" +
+	"\benvTotal\x18\x05 \x01(\x04R\benvTotal\x12\x1c\n" +
+	"\tfileCount\x18\x06 \x01(\x04R\tfileCount\x12\x1a\n" +
+	"\bfileSize\x18\a \x01(\x04R\bfileSize\x124\n" +
+	"\brequests\x18\b \x03(\v2\x18.pb.StatResponse.RequestR\brequests\x12\x1a\n" +
+	"\bdraining\x18\t \x01(\bR\bdraining\x1a\xb7\x01\n" +
+	"\aRequest\x12\x1c\n" +
+	"\trequestID\x18\x01 \x01(\tR\trequestID\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12$\n" +
+	"\x05phase\x18\x03 \x01(\x0e2\x0e.pb.Event.TypeR\x05phase\x12\x1c\n" +
+	"\tstartTime\x18\x04 \x01(\x03R\tstartTime\x12\x18\n" +
+	"\aelapsed\x18\x05 \x01(\x04R\aelapsed\x12\x12\n" +
+	"\x04tags\x18\x06 \x03(\tR\x04tags\"\x8b\x01\n" +
+	"\rCancelRequest\x12\x1c\n" +
+	"\trequestID\x18\x01 \x01(\tR\trequestID\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x10\n" +
+	"\x03tag\x18\x03 \x01(\tR\x03tag\x12\x12\n" +
+	"\x04kill\x18\x04 \x01(\bR\x04kill\x12\x18\n" +
+	"\atimeout\x18\x05 \x01(\x04R\atimeout\"\xfa\x01\n" +
+	"\x0eCancelResponse\x12:\n" +
+	"\tcancelled\x18\x01 \x03(\v2\x1c.pb.CancelResponse.CancelledR\tcancelled\x1a\xab\x01\n" +
+	"\tCancelled\x12\x1c\n" +
+	"\trequestID\x18\x01 \x01(\tR\trequestID\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\x12$\n" +
+	"\x05phase\x18\x04 \x01(\x0e2\x0e.pb.Event.TypeR\x05phase\x12(\n" +
+	"\bresponse\x18\x05 \x01(\v2\f.pb.ResponseR\bresponseB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
+
+var file_stat_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_stat_proto_goTypes = []any{
+	(*StatResponse)(nil),             // 0: pb.StatResponse
+	(*CancelRequest)(nil),            // 1: pb.CancelRequest
+	(*CancelResponse)(nil),           // 2: pb.CancelResponse
+	(*StatResponse_Request)(nil),     // 3: pb.StatResponse.Request
+	(*CancelResponse_Cancelled)(nil), // 4: pb.CancelResponse.Cancelled
+	(Event_Type)(0),                  // 5: pb.Event.Type
+	(*Response)(nil),                 // 6: pb.Response
+}
+var file_stat_proto_depIdxs = []int32{
+	3, // 0: pb.StatResponse.requests:type_name -> pb.StatResponse.Request
+	4, // 1: pb.CancelResponse.cancelled:type_name -> pb.CancelResponse.Cancelled
+	5, // 2: pb.StatResponse.Request.phase:type_name -> pb.Event.Type
+	5, // 3: pb.CancelResponse.Cancelled.phase:type_name -> pb.Event.Type
+	6, // 4: pb.CancelResponse.Cancelled.response:type_name -> pb.Response
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_stat_proto_init() }
+func file_stat_proto_init() {
+	if File_stat_proto != nil {
+		return
+	}
+	file_response_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_stat_proto_rawDesc), len(file_stat_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_stat_proto_goTypes,
+		DependencyIndexes: file_stat_proto_depIdxs,
+		MessageInfos:      file_stat_proto_msgTypes,
+	}.Build()
+	File_stat_proto = out.File
+	file_stat_proto_goTypes = nil
+	file_stat_proto_depIdxs = nil
+}
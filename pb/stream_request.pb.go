@@ -25,6 +25,7 @@ const (
 type StreamRequest struct {
 	state              protoimpl.MessageState  `protogen:"opaque.v1"`
 	xxx_hidden_Request isStreamRequest_Request `protobuf_oneof:"request"`
+	xxx_hidden_ExecId  uint32                  `protobuf:"varint,5,opt,name=execId"`
 	unknownFields      protoimpl.UnknownFields
 	sizeCache          protoimpl.SizeCache
 }
@@ -90,6 +91,22 @@ func (x *StreamRequest) GetExecCancel() *emptypb.Empty {
 	return nil
 }
 
+func (x *StreamRequest) GetExecCredit() *StreamRequest_Credit {
+	if x != nil {
+		if x, ok := x.xxx_hidden_Request.(*streamRequest_ExecCredit); ok {
+			return x.ExecCredit
+		}
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetExecId() uint32 {
+	if x != nil {
+		return x.xxx_hidden_ExecId
+	}
+	return 0
+}
+
 func (x *StreamRequest) SetExecRequest(v *Request) {
 	if v == nil {
 		x.xxx_hidden_Request = nil
@@ -122,6 +139,18 @@ func (x *StreamRequest) SetExecCancel(v *emptypb.Empty) {
 	x.xxx_hidden_Request = &streamRequest_ExecCancel{v}
 }
 
+func (x *StreamRequest) SetExecCredit(v *StreamRequest_Credit) {
+	if v == nil {
+		x.xxx_hidden_Request = nil
+		return
+	}
+	x.xxx_hidden_Request = &streamRequest_ExecCredit{v}
+}
+
+func (x *StreamRequest) SetExecId(v uint32) {
+	x.xxx_hidden_ExecId = v
+}
+
 func (x *StreamRequest) HasRequest() bool {
 	if x == nil {
 		return false
@@ -161,6 +190,14 @@ func (x *StreamRequest) HasExecCancel() bool {
 	return ok
 }
 
+func (x *StreamRequest) HasExecCredit() bool {
+	if x == nil {
+		return false
+	}
+	_, ok := x.xxx_hidden_Request.(*streamRequest_ExecCredit)
+	return ok
+}
+
 func (x *StreamRequest) ClearRequest() {
 	x.xxx_hidden_Request = nil
 }
@@ -189,11 +226,18 @@ func (x *StreamRequest) ClearExecCancel() {
 	}
 }
 
+func (x *StreamRequest) ClearExecCredit() {
+	if _, ok := x.xxx_hidden_Request.(*streamRequest_ExecCredit); ok {
+		x.xxx_hidden_Request = nil
+	}
+}
+
 const StreamRequest_Request_not_set_case case_StreamRequest_Request = 0
 const StreamRequest_ExecRequest_case case_StreamRequest_Request = 1
 const StreamRequest_ExecInput_case case_StreamRequest_Request = 2
 const StreamRequest_ExecResize_case case_StreamRequest_Request = 3
 const StreamRequest_ExecCancel_case case_StreamRequest_Request = 4
+const StreamRequest_ExecCredit_case case_StreamRequest_Request = 6
 
 func (x *StreamRequest) WhichRequest() case_StreamRequest_Request {
 	if x == nil {
@@ -208,6 +252,8 @@ func (x *StreamRequest) WhichRequest() case_StreamRequest_Request {
 		return StreamRequest_ExecResize_case
 	case *streamRequest_ExecCancel:
 		return StreamRequest_ExecCancel_case
+	case *streamRequest_ExecCredit:
+		return StreamRequest_ExecCredit_case
 	default:
 		return StreamRequest_Request_not_set_case
 	}
@@ -221,7 +267,11 @@ type StreamRequest_builder struct {
 	ExecInput   *StreamRequest_Input
 	ExecResize  *StreamRequest_Resize
 	ExecCancel  *emptypb.Empty
+	ExecCredit  *StreamRequest_Credit
 	// -- end of xxx_hidden_Request
+	// execId multiplexes executions on the stream when the first execRequest
+	// has non-zero execId
+	ExecId uint32
 }
 
 func (b0 StreamRequest_builder) Build() *StreamRequest {
@@ -240,6 +290,10 @@ func (b0 StreamRequest_builder) Build() *StreamRequest {
 	if b.ExecCancel != nil {
 		x.xxx_hidden_Request = &streamRequest_ExecCancel{b.ExecCancel}
 	}
+	if b.ExecCredit != nil {
+		x.xxx_hidden_Request = &streamRequest_ExecCredit{b.ExecCredit}
+	}
+	x.xxx_hidden_ExecId = b.ExecId
 	return m0
 }
 
@@ -273,6 +327,10 @@ type streamRequest_ExecCancel struct {
 	ExecCancel *emptypb.Empty `protobuf:"bytes,4,opt,name=execCancel,oneof"`
 }
 
+type streamRequest_ExecCredit struct {
+	ExecCredit *StreamRequest_Credit `protobuf:"bytes,6,opt,name=execCredit,oneof"`
+}
+
 func (*streamRequest_ExecRequest) isStreamRequest_Request() {}
 
 func (*streamRequest_ExecInput) isStreamRequest_Request() {}
@@ -281,6 +339,8 @@ func (*streamRequest_ExecResize) isStreamRequest_Request() {}
 
 func (*streamRequest_ExecCancel) isStreamRequest_Request() {}
 
+func (*streamRequest_ExecCredit) isStreamRequest_Request() {}
+
 type StreamRequest_Input struct {
 	state              protoimpl.MessageState `protogen:"opaque.v1"`
 	xxx_hidden_Index   uint32                 `protobuf:"varint,1,opt,name=index"`
@@ -496,11 +556,70 @@ func (b0 StreamRequest_Resize_builder) Build() *StreamRequest_Resize {
 	return m0
 }
 
+// Credit grants output credit in bytes, flow control is enabled when it is
+// sent before execRequest
+type StreamRequest_Credit struct {
+	state            protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Bytes int64                  `protobuf:"varint,1,opt,name=bytes"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StreamRequest_Credit) Reset() {
+	*x = StreamRequest_Credit{}
+	mi := &file_stream_request_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRequest_Credit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest_Credit) ProtoMessage() {}
+
+func (x *StreamRequest_Credit) ProtoReflect() protoreflect.Message {
+	mi := &file_stream_request_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StreamRequest_Credit) GetBytes() int64 {
+	if x != nil {
+		return x.xxx_hidden_Bytes
+	}
+	return 0
+}
+
+func (x *StreamRequest_Credit) SetBytes(v int64) {
+	x.xxx_hidden_Bytes = v
+}
+
+type StreamRequest_Credit_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Bytes int64
+}
+
+func (b0 StreamRequest_Credit_builder) Build() *StreamRequest_Credit {
+	m0 := &StreamRequest_Credit{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Bytes = b.Bytes
+	return m0
+}
+
 var File_stream_request_proto protoreflect.FileDescriptor
 
 const file_stream_request_proto_rawDesc = "" +
 	"\n" +
-	"\x14stream_request.proto\x12\x02pb\x1a\x1bgoogle/protobuf/empty.proto\x1a\rrequest.proto\x1a!google/protobuf/go_features.proto\"\xb7\x03\n" +
+	"\x14stream_request.proto\x12\x02pb\x1a\x1bgoogle/protobuf/empty.proto\x1a\rrequest.proto\x1a!google/protobuf/go_features.proto\"\xab\x04\n" +
 	"\rStreamRequest\x12/\n" +
 	"\vexecRequest\x18\x01 \x01(\v2\v.pb.RequestH\x00R\vexecRequest\x127\n" +
 	"\texecInput\x18\x02 \x01(\v2\x17.pb.StreamRequest.InputH\x00R\texecInput\x12:\n" +
@@ -509,7 +628,11 @@ const file_stream_request_proto_rawDesc = "" +
 	"execResize\x128\n" +
 	"\n" +
 	"execCancel\x18\x04 \x01(\v2\x16.google.protobuf.EmptyH\x00R\n" +
-	"execCancel\x1aG\n" +
+	"execCancel\x12:\n" +
+	"\n" +
+	"execCredit\x18\x06 \x01(\v2\x18.pb.StreamRequest.CreditH\x00R\n" +
+	"execCredit\x12\x16\n" +
+	"\x06execId\x18\x05 \x01(\rR\x06execId\x1aG\n" +
 	"\x05Input\x12\x14\n" +
 	"\x05index\x18\x01 \x01(\rR\x05index\x12\x0e\n" +
 	"\x02fd\x18\x03 \x01(\rR\x02fd\x12\x18\n" +
@@ -520,27 +643,31 @@ const file_stream_request_proto_rawDesc = "" +
 	"\x04rows\x18\x02 \x01(\rR\x04rows\x12\x12\n" +
 	"\x04cols\x18\x03 \x01(\rR\x04cols\x12\f\n" +
 	"\x01x\x18\x04 \x01(\rR\x01x\x12\f\n" +
-	"\x01y\x18\x05 \x01(\rR\x01yB\t\n" +
+	"\x01y\x18\x05 \x01(\rR\x01y\x1a\x1e\n" +
+	"\x06Credit\x12\x14\n" +
+	"\x05bytes\x18\x01 \x01(\x03R\x05bytesB\t\n" +
 	"\arequestB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
 
-var file_stream_request_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_stream_request_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_stream_request_proto_goTypes = []any{
 	(*StreamRequest)(nil),        // 0: pb.StreamRequest
 	(*StreamRequest_Input)(nil),  // 1: pb.StreamRequest.Input
 	(*StreamRequest_Resize)(nil), // 2: pb.StreamRequest.Resize
-	(*Request)(nil),              // 3: pb.Request
-	(*emptypb.Empty)(nil),        // 4: google.protobuf.Empty
+	(*StreamRequest_Credit)(nil), // 3: pb.StreamRequest.Credit
+	(*Request)(nil),              // 4: pb.Request
+	(*emptypb.Empty)(nil),        // 5: google.protobuf.Empty
 }
 var file_stream_request_proto_depIdxs = []int32{
-	3, // 0: pb.StreamRequest.execRequest:type_name -> pb.Request
+	4, // 0: pb.StreamRequest.execRequest:type_name -> pb.Request
 	1, // 1: pb.StreamRequest.execInput:type_name -> pb.StreamRequest.Input
 	2, // 2: pb.StreamRequest.execResize:type_name -> pb.StreamRequest.Resize
-	4, // 3: pb.StreamRequest.execCancel:type_name -> google.protobuf.Empty
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	5, // 3: pb.StreamRequest.execCancel:type_name -> google.protobuf.Empty
+	3, // 4: pb.StreamRequest.execCredit:type_name -> pb.StreamRequest.Credit
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_stream_request_proto_init() }
@@ -554,6 +681,7 @@ func file_stream_request_proto_init() {
 		(*streamRequest_ExecInput)(nil),
 		(*streamRequest_ExecResize)(nil),
 		(*streamRequest_ExecCancel)(nil),
+		(*streamRequest_ExecCredit)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -561,7 +689,7 @@ func file_stream_request_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_stream_request_proto_rawDesc), len(file_stream_request_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -24,6 +24,7 @@ const (
 type StreamResponse struct {
 	state               protoimpl.MessageState    `protogen:"opaque.v1"`
 	xxx_hidden_Response isStreamResponse_Response `protobuf_oneof:"response"`
+	xxx_hidden_ExecId   uint32                    `protobuf:"varint,3,opt,name=execId"`
 	unknownFields       protoimpl.UnknownFields
 	sizeCache           protoimpl.SizeCache
 }
@@ -71,6 +72,31 @@ func (x *StreamResponse) GetExecOutput() *StreamResponse_Output {
 	return nil
 }
 
+func (x *StreamResponse) GetExecInputAck() *StreamResponse_InputAck {
+	if x != nil {
+		if x, ok := x.xxx_hidden_Response.(*streamResponse_ExecInputAck); ok {
+			return x.ExecInputAck
+		}
+	}
+	return nil
+}
+
+func (x *StreamResponse) GetExecReject() *StreamResponse_Reject {
+	if x != nil {
+		if x, ok := x.xxx_hidden_Response.(*streamResponse_ExecReject); ok {
+			return x.ExecReject
+		}
+	}
+	return nil
+}
+
+func (x *StreamResponse) GetExecId() uint32 {
+	if x != nil {
+		return x.xxx_hidden_ExecId
+	}
+	return 0
+}
+
 func (x *StreamResponse) SetExecResponse(v *Response) {
 	if v == nil {
 		x.xxx_hidden_Response = nil
@@ -87,6 +113,26 @@ func (x *StreamResponse) SetExecOutput(v *StreamResponse_Output) {
 	x.xxx_hidden_Response = &streamResponse_ExecOutput{v}
 }
 
+func (x *StreamResponse) SetExecInputAck(v *StreamResponse_InputAck) {
+	if v == nil {
+		x.xxx_hidden_Response = nil
+		return
+	}
+	x.xxx_hidden_Response = &streamResponse_ExecInputAck{v}
+}
+
+func (x *StreamResponse) SetExecReject(v *StreamResponse_Reject) {
+	if v == nil {
+		x.xxx_hidden_Response = nil
+		return
+	}
+	x.xxx_hidden_Response = &streamResponse_ExecReject{v}
+}
+
+func (x *StreamResponse) SetExecId(v uint32) {
+	x.xxx_hidden_ExecId = v
+}
+
 func (x *StreamResponse) HasResponse() bool {
 	if x == nil {
 		return false
@@ -110,6 +156,22 @@ func (x *StreamResponse) HasExecOutput() bool {
 	return ok
 }
 
+func (x *StreamResponse) HasExecInputAck() bool {
+	if x == nil {
+		return false
+	}
+	_, ok := x.xxx_hidden_Response.(*streamResponse_ExecInputAck)
+	return ok
+}
+
+func (x *StreamResponse) HasExecReject() bool {
+	if x == nil {
+		return false
+	}
+	_, ok := x.xxx_hidden_Response.(*streamResponse_ExecReject)
+	return ok
+}
+
 func (x *StreamResponse) ClearResponse() {
 	x.xxx_hidden_Response = nil
 }
@@ -126,9 +188,23 @@ func (x *StreamResponse) ClearExecOutput() {
 	}
 }
 
+func (x *StreamResponse) ClearExecInputAck() {
+	if _, ok := x.xxx_hidden_Response.(*streamResponse_ExecInputAck); ok {
+		x.xxx_hidden_Response = nil
+	}
+}
+
+func (x *StreamResponse) ClearExecReject() {
+	if _, ok := x.xxx_hidden_Response.(*streamResponse_ExecReject); ok {
+		x.xxx_hidden_Response = nil
+	}
+}
+
 const StreamResponse_Response_not_set_case case_StreamResponse_Response = 0
 const StreamResponse_ExecResponse_case case_StreamResponse_Response = 1
 const StreamResponse_ExecOutput_case case_StreamResponse_Response = 2
+const StreamResponse_ExecInputAck_case case_StreamResponse_Response = 4
+const StreamResponse_ExecReject_case case_StreamResponse_Response = 5
 
 func (x *StreamResponse) WhichResponse() case_StreamResponse_Response {
 	if x == nil {
@@ -139,6 +215,10 @@ func (x *StreamResponse) WhichResponse() case_StreamResponse_Response {
 		return StreamResponse_ExecResponse_case
 	case *streamResponse_ExecOutput:
 		return StreamResponse_ExecOutput_case
+	case *streamResponse_ExecInputAck:
+		return StreamResponse_ExecInputAck_case
+	case *streamResponse_ExecReject:
+		return StreamResponse_ExecReject_case
 	default:
 		return StreamResponse_Response_not_set_case
 	}
@@ -150,7 +230,11 @@ type StreamResponse_builder struct {
 	// Fields of oneof xxx_hidden_Response:
 	ExecResponse *Response
 	ExecOutput   *StreamResponse_Output
+	ExecInputAck *StreamResponse_InputAck
+	ExecReject   *StreamResponse_Reject
 	// -- end of xxx_hidden_Response
+	// execId of the multiplexed execution
+	ExecId uint32
 }
 
 func (b0 StreamResponse_builder) Build() *StreamResponse {
@@ -163,6 +247,13 @@ func (b0 StreamResponse_builder) Build() *StreamResponse {
 	if b.ExecOutput != nil {
 		x.xxx_hidden_Response = &streamResponse_ExecOutput{b.ExecOutput}
 	}
+	if b.ExecInputAck != nil {
+		x.xxx_hidden_Response = &streamResponse_ExecInputAck{b.ExecInputAck}
+	}
+	if b.ExecReject != nil {
+		x.xxx_hidden_Response = &streamResponse_ExecReject{b.ExecReject}
+	}
+	x.xxx_hidden_ExecId = b.ExecId
 	return m0
 }
 
@@ -188,10 +279,22 @@ type streamResponse_ExecOutput struct {
 	ExecOutput *StreamResponse_Output `protobuf:"bytes,2,opt,name=execOutput,oneof"`
 }
 
+type streamResponse_ExecInputAck struct {
+	ExecInputAck *StreamResponse_InputAck `protobuf:"bytes,4,opt,name=execInputAck,oneof"`
+}
+
+type streamResponse_ExecReject struct {
+	ExecReject *StreamResponse_Reject `protobuf:"bytes,5,opt,name=execReject,oneof"`
+}
+
 func (*streamResponse_ExecResponse) isStreamResponse_Response() {}
 
 func (*streamResponse_ExecOutput) isStreamResponse_Response() {}
 
+func (*streamResponse_ExecInputAck) isStreamResponse_Response() {}
+
+func (*streamResponse_ExecReject) isStreamResponse_Response() {}
+
 type StreamResponse_Output struct {
 	state              protoimpl.MessageState `protogen:"opaque.v1"`
 	xxx_hidden_Index   uint32                 `protobuf:"varint,1,opt,name=index"`
@@ -280,37 +383,197 @@ func (b0 StreamResponse_Output_builder) Build() *StreamResponse_Output {
 	return m0
 }
 
+// InputAck acknowledges the input consumed when flow control is enabled
+type StreamResponse_InputAck struct {
+	state            protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Index uint32                 `protobuf:"varint,1,opt,name=index"`
+	xxx_hidden_Fd    uint32                 `protobuf:"varint,2,opt,name=fd"`
+	xxx_hidden_Bytes uint32                 `protobuf:"varint,3,opt,name=bytes"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StreamResponse_InputAck) Reset() {
+	*x = StreamResponse_InputAck{}
+	mi := &file_stream_response_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamResponse_InputAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamResponse_InputAck) ProtoMessage() {}
+
+func (x *StreamResponse_InputAck) ProtoReflect() protoreflect.Message {
+	mi := &file_stream_response_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StreamResponse_InputAck) GetIndex() uint32 {
+	if x != nil {
+		return x.xxx_hidden_Index
+	}
+	return 0
+}
+
+func (x *StreamResponse_InputAck) GetFd() uint32 {
+	if x != nil {
+		return x.xxx_hidden_Fd
+	}
+	return 0
+}
+
+func (x *StreamResponse_InputAck) GetBytes() uint32 {
+	if x != nil {
+		return x.xxx_hidden_Bytes
+	}
+	return 0
+}
+
+func (x *StreamResponse_InputAck) SetIndex(v uint32) {
+	x.xxx_hidden_Index = v
+}
+
+func (x *StreamResponse_InputAck) SetFd(v uint32) {
+	x.xxx_hidden_Fd = v
+}
+
+func (x *StreamResponse_InputAck) SetBytes(v uint32) {
+	x.xxx_hidden_Bytes = v
+}
+
+type StreamResponse_InputAck_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Index uint32
+	Fd    uint32
+	Bytes uint32
+}
+
+func (b0 StreamResponse_InputAck_builder) Build() *StreamResponse_InputAck {
+	m0 := &StreamResponse_InputAck{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Index = b.Index
+	x.xxx_hidden_Fd = b.Fd
+	x.xxx_hidden_Bytes = b.Bytes
+	return m0
+}
+
+// Reject reports a request rejected without affecting the execution of the
+// same execId, e.g. exec request of a running execId
+type StreamResponse_Reject struct {
+	state            protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Error string                 `protobuf:"bytes,1,opt,name=error"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StreamResponse_Reject) Reset() {
+	*x = StreamResponse_Reject{}
+	mi := &file_stream_response_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamResponse_Reject) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamResponse_Reject) ProtoMessage() {}
+
+func (x *StreamResponse_Reject) ProtoReflect() protoreflect.Message {
+	mi := &file_stream_response_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StreamResponse_Reject) GetError() string {
+	if x != nil {
+		return x.xxx_hidden_Error
+	}
+	return ""
+}
+
+func (x *StreamResponse_Reject) SetError(v string) {
+	x.xxx_hidden_Error = v
+}
+
+type StreamResponse_Reject_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Error string
+}
+
+func (b0 StreamResponse_Reject_builder) Build() *StreamResponse_Reject {
+	m0 := &StreamResponse_Reject{}
+	b, x := &b0, m0
+	_, _ = b, x
+	x.xxx_hidden_Error = b.Error
+	return m0
+}
+
 var File_stream_response_proto protoreflect.FileDescriptor
 
 const file_stream_response_proto_rawDesc = "" +
 	"\n" +
-	"\x15stream_response.proto\x12\x02pb\x1a\x0eresponse.proto\x1a!google/protobuf/go_features.proto\"\xd7\x01\n" +
+	"\x15stream_response.proto\x12\x02pb\x1a\x0eresponse.proto\x1a!google/protobuf/go_features.proto\"\xd7\x03\n" +
 	"\x0eStreamResponse\x122\n" +
 	"\fexecResponse\x18\x01 \x01(\v2\f.pb.ResponseH\x00R\fexecResponse\x12;\n" +
 	"\n" +
 	"execOutput\x18\x02 \x01(\v2\x19.pb.StreamResponse.OutputH\x00R\n" +
-	"execOutput\x1aH\n" +
+	"execOutput\x12A\n" +
+	"\fexecInputAck\x18\x04 \x01(\v2\x1b.pb.StreamResponse.InputAckH\x00R\fexecInputAck\x12;\n" +
+	"\n" +
+	"execReject\x18\x05 \x01(\v2\x19.pb.StreamResponse.RejectH\x00R\n" +
+	"execReject\x12\x16\n" +
+	"\x06execId\x18\x03 \x01(\rR\x06execId\x1aH\n" +
 	"\x06Output\x12\x14\n" +
 	"\x05index\x18\x01 \x01(\rR\x05index\x12\x0e\n" +
 	"\x02fd\x18\x03 \x01(\rR\x02fd\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\fR\acontentB\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x1aF\n" +
+	"\bInputAck\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x0e\n" +
+	"\x02fd\x18\x02 \x01(\rR\x02fd\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\rR\x05bytes\x1a\x1e\n" +
+	"\x06Reject\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05errorB\n" +
 	"\n" +
 	"\bresponseB)Z\x1dgithub.com/criyle/go-judge/pb\x92\x03\a\xd2>\x02\x10\x03\b\x02b\beditionsp\xe8\a"
 
-var file_stream_response_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_stream_response_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_stream_response_proto_goTypes = []any{
-	(*StreamResponse)(nil),        // 0: pb.StreamResponse
-	(*StreamResponse_Output)(nil), // 1: pb.StreamResponse.Output
-	(*Response)(nil),              // 2: pb.Response
+	(*StreamResponse)(nil),          // 0: pb.StreamResponse
+	(*StreamResponse_Output)(nil),   // 1: pb.StreamResponse.Output
+	(*StreamResponse_InputAck)(nil), // 2: pb.StreamResponse.InputAck
+	(*StreamResponse_Reject)(nil),   // 3: pb.StreamResponse.Reject
+	(*Response)(nil),                // 4: pb.Response
 }
 var file_stream_response_proto_depIdxs = []int32{
-	2, // 0: pb.StreamResponse.execResponse:type_name -> pb.Response
+	4, // 0: pb.StreamResponse.execResponse:type_name -> pb.Response
 	1, // 1: pb.StreamResponse.execOutput:type_name -> pb.StreamResponse.Output
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	2, // 2: pb.StreamResponse.execInputAck:type_name -> pb.StreamResponse.InputAck
+	3, // 3: pb.StreamResponse.execReject:type_name -> pb.StreamResponse.Reject
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_stream_response_proto_init() }
@@ -322,6 +585,8 @@ func file_stream_response_proto_init() {
 	file_stream_response_proto_msgTypes[0].OneofWrappers = []any{
 		(*streamResponse_ExecResponse)(nil),
 		(*streamResponse_ExecOutput)(nil),
+		(*streamResponse_ExecInputAck)(nil),
+		(*streamResponse_ExecReject)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -329,7 +594,7 @@ func file_stream_response_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_stream_response_proto_rawDesc), len(file_stream_response_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -3,6 +3,8 @@ package worker
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/filestore"
@@ -20,6 +22,11 @@ var (
 	_ CmdFile = &LocalFile{}
 	_ CmdFile = &MemoryFile{}
 	_ CmdFile = &CachedFile{}
+	_ CmdFile = &CachedDir{}
+	_ CmdFile = &DatasetFile{}
+	_ CmdFile = &ArchiveFile{}
+	_ CmdFile = &AttrFile{}
+	_ CmdFile = &BindFile{}
 	_ CmdFile = &Collector{}
 )
 
@@ -69,18 +76,133 @@ func (f *CachedFile) String() string {
 	return fmt.Sprintf("cached:(fileId:%s)", f.FileID)
 }
 
+// CachedDir defines directory tree cached in the file store as tar archive
+type CachedDir struct {
+	DirID string
+}
+
+// EnvFile prepares file for envexec file
+func (f *CachedDir) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	_, fd := fs.Get(f.DirID)
+	if fd == nil {
+		return nil, fmt.Errorf("dir does not exists with id: %q", f.DirID)
+	}
+	return envexec.NewFileDir(fd), nil
+}
+
+func (f *CachedDir) String() string {
+	return fmt.Sprintf("cachedDir:(dirId:%s)", f.DirID)
+}
+
+// DatasetPrefix is the prefix of source to address file in read-only dataset
+const DatasetPrefix = "dataset:"
+
+// DatasetFile defines file inside the read-only dataset registered on the host
+type DatasetFile struct {
+	Name string // Name of the dataset
+	Path string // Path relative to the dataset root
+}
+
+// ParseDatasetFile parses the source in form of dataset:<name>/<path>
+func ParseDatasetFile(src string) (*DatasetFile, bool) {
+	s, ok := strings.CutPrefix(src, DatasetPrefix)
+	if !ok {
+		return nil, false
+	}
+	name, p, _ := strings.Cut(s, "/")
+	return &DatasetFile{Name: name, Path: p}, true
+}
+
+// EnvFile prepares file for envexec file
+func (f *DatasetFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	return filestore.OpenDataset(fs, f.Name, f.Path)
+}
+
+func (f *DatasetFile) String() string {
+	return fmt.Sprintf("%s%s/%s", DatasetPrefix, f.Name, f.Path)
+}
+
+// ArchiveFile defines archive in tar, tar.gz or zip format to be extracted
+// into a directory
+type ArchiveFile struct {
+	File CmdFile
+}
+
+// EnvFile prepares file for envexec file
+func (f *ArchiveFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	fd, err := f.File.EnvFile(fs)
+	if err != nil {
+		return nil, err
+	}
+	return envexec.NewFileDir(fd), nil
+}
+
+func (f *ArchiveFile) String() string {
+	return fmt.Sprintf("archive:(%s)", f.File)
+}
+
+// AttrFile defines copyIn file with mode, owner and read-only flag
+type AttrFile struct {
+	File     CmdFile
+	Mode     os.FileMode
+	Owner    envexec.FileOwner
+	ReadOnly bool
+}
+
+// EnvFile prepares file for envexec file
+func (f *AttrFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	fd, err := f.File.EnvFile(fs)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fd.(*envexec.FileDir); ok {
+		return nil, fmt.Errorf("file attributes cannot be applied to directory: %s", f.File)
+	}
+	return envexec.NewFileAttr(fd, f.Mode, f.Owner, f.ReadOnly), nil
+}
+
+func (f *AttrFile) String() string {
+	return fmt.Sprintf("attr:(%s,mode:%o,owner:%d,readOnly:%v)", f.File, f.Mode, f.Owner, f.ReadOnly)
+}
+
+// BindFile defines read-only copyIn file exposed to the program without
+// copying, it is copied when the environment does not support it
+type BindFile struct {
+	File CmdFile
+}
+
+// EnvFile prepares file for envexec file
+func (f *BindFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	fd, err := f.File.EnvFile(fs)
+	if err != nil {
+		return nil, err
+	}
+	switch fd.(type) {
+	case *envexec.FileInput, *envexec.FileReader:
+		return envexec.NewFileBind(fd), nil
+	default:
+		return nil, fmt.Errorf("zero-copy is not supported for file: %s", f.File)
+	}
+}
+
+func (f *BindFile) String() string {
+	return fmt.Sprintf("bind:(%s)", f.File)
+}
+
 // Collector defines on the output (stdout / stderr) to be collected over pipe
 type Collector struct {
 	Name string       // pseudo name generated into copyOut
 	Max  envexec.Size // max size to be collected
 	Pipe bool
+	Tail envexec.Size // bytes kept from the end out of Max when exceeded
+	Live bool         // output is reported as EventOutput while collected
 }
 
 // EnvFile prepares file for envexec file
 func (f *Collector) EnvFile(fs filestore.FileStore) (envexec.File, error) {
-	return envexec.NewFileCollector(f.Name, f.Max, f.Pipe), nil
+	return &envexec.FileCollector{Name: f.Name, Limit: f.Max, Pipe: f.Pipe, Tail: f.Tail}, nil
 }
 
 func (f *Collector) String() string {
-	return fmt.Sprintf("collector:(name:%s,max:%d,pipe:%v)", f.Name, f.Max, f.Pipe)
+	return fmt.Sprintf("collector:(name:%s,max:%d,pipe:%v,tail:%d,live:%v)", f.Name, f.Max, f.Pipe, f.Tail, f.Live)
 }
@@ -0,0 +1,253 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrCancelled is the cause of the context of requests cancelled by Cancel
+var ErrCancelled = errors.New("request cancelled")
+
+// RequestStat stores the state of a request queued or running in the worker
+type RequestStat struct {
+	RequestID string
+	Namespace string
+	Tags      []string
+	Phase     EventType // latest progress event of the request
+	StartTime time.Time // time the request is submitted
+	Elapsed   time.Duration
+}
+
+// Cancelled is a request cancelled by Cancel
+type Cancelled struct {
+	RequestStat
+	f *inflight
+}
+
+// Wait waits for the response of the cancelled request. The response contains
+// the partial results without files, which are owned by the submitter.
+func (c Cancelled) Wait(ctx context.Context) (Response, error) {
+	select {
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	case <-c.f.done:
+		return c.f.result, nil
+	}
+}
+
+// inflight tracks the request from submitted until its response is sent
+type inflight struct {
+	*Request
+	w        *worker
+	start    time.Time
+	phase    atomic.Int32
+	cancel   context.CancelCauseFunc
+	started  chan<- struct{} // closed when picked up by worker or finished
+	resultCh chan<- Response
+	done     chan struct{} // closed when finished
+
+	mu       sync.Mutex
+	begun    bool
+	finished bool
+	killed   bool
+	envs     []envexec.Environment
+	result   Response // response without files
+}
+
+// report records the phase of the request and forwards the event to its
+// Progress
+func (f *inflight) report(e Event) {
+	if e.Type != EventOutput {
+		f.phase.Store(int32(e.Type))
+	}
+	f.progress(e)
+}
+
+// begin marks the request is picked up by the worker, it returns false if the
+// request is already finished
+func (f *inflight) begin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.finished {
+		return false
+	}
+	f.begun = true
+	if f.started != nil {
+		close(f.started)
+	}
+	return true
+}
+
+// finish sends the response of the request once
+func (f *inflight) finish(rt Response) {
+	f.mu.Lock()
+	if f.finished {
+		f.mu.Unlock()
+		return
+	}
+	f.finished = true
+	if !f.begun && f.started != nil {
+		close(f.started)
+	}
+	f.result = rt
+	f.result.Results = make([]Result, len(rt.Results))
+	for i, r := range rt.Results {
+		r.Files = nil
+		f.result.Results[i] = r
+	}
+	f.mu.Unlock()
+
+	f.w.untrack(f)
+	f.cancel(nil)
+	f.resultCh <- rt
+	close(f.done)
+}
+
+// cancelRequest finishes the request if it is queued, otherwise cancels it and
+// destroys its environments if kill
+func (f *inflight) cancelRequest(kill bool) {
+	f.cancel(ErrCancelled)
+
+	f.mu.Lock()
+	begun := f.begun
+	if kill && begun && !f.killed {
+		// discarded under lock so that they are not put back to the pool
+		// concurrently
+		f.killed = true
+		for _, e := range f.envs {
+			DiscardEnvironment(f.w.envPool, e)
+		}
+		f.envs = nil
+	}
+	f.mu.Unlock()
+
+	if !begun {
+		f.finish(Response{
+			RequestID: f.RequestID,
+			Error:     fmt.Errorf("cancelled before execute: %w", ErrCancelled),
+		})
+	}
+}
+
+func (f *inflight) stat(now time.Time) RequestStat {
+	return RequestStat{
+		RequestID: f.RequestID,
+		Namespace: f.Namespace,
+		Tags:      f.Tags,
+		Phase:     EventType(f.phase.Load()),
+		StartTime: f.start,
+		Elapsed:   now.Sub(f.start),
+	}
+}
+
+func (w *worker) track(ctx context.Context, req *Request, started chan<- struct{}, resultCh chan<- Response) (context.Context, *inflight) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	f := &inflight{
+		Request:  req,
+		w:        w,
+		start:    time.Now(),
+		cancel:   cancel,
+		started:  started,
+		resultCh: resultCh,
+		done:     make(chan struct{}),
+	}
+	f.phase.Store(int32(EventQueued))
+	w.inflightMu.Lock()
+	w.inflight[f] = struct{}{}
+	w.inflightMu.Unlock()
+	return ctx, f
+}
+
+func (w *worker) untrack(f *inflight) {
+	w.inflightMu.Lock()
+	delete(w.inflight, f)
+	w.inflightMu.Unlock()
+}
+
+// getEnv gets an environment for the request, the environment is discarded
+// if the request is killed
+func (w *worker) getEnv(f *inflight) (envexec.Environment, error) {
+	env, err := w.envPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.killed {
+		DiscardEnvironment(w.envPool, env)
+		return nil, ErrCancelled
+	}
+	f.envs = append(f.envs, env)
+	return env, nil
+}
+
+// putEnv puts the environment back to the pool unless it is discarded since
+// the request is killed
+func (w *worker) putEnv(f *inflight, env envexec.Environment) {
+	f.mu.Lock()
+	i := slices.Index(f.envs, env)
+	if i >= 0 {
+		f.envs = slices.Delete(f.envs, i, i+1)
+	}
+	f.mu.Unlock()
+	if i >= 0 {
+		w.envPool.Put(env)
+	}
+}
+
+func (w *worker) inflights() []*inflight {
+	w.inflightMu.Lock()
+	defer w.inflightMu.Unlock()
+
+	rt := make([]*inflight, 0, len(w.inflight))
+	for f := range w.inflight {
+		rt = append(rt, f)
+	}
+	slices.SortFunc(rt, func(a, b *inflight) int {
+		if c := a.start.Compare(b.start); c != 0 {
+			return c
+		}
+		return strings.Compare(a.RequestID, b.RequestID)
+	})
+	return rt
+}
+
+// Requests returns the requests queued or running ordered by submission
+func (w *worker) Requests() []RequestStat {
+	now := time.Now()
+	fs := w.inflights()
+	rt := make([]RequestStat, 0, len(fs))
+	for _, f := range fs {
+		rt = append(rt, f.stat(now))
+	}
+	return rt
+}
+
+// Cancel cancels the requests queued or running selected by match. Queued
+// requests are finished immediately, and running requests are cancelled with
+// ErrCancelled as the cause of their context. If kill, the environments of the
+// running requests are also discarded to stop them without waiting for the
+// processes to exit.
+func (w *worker) Cancel(match func(RequestStat) bool, kill bool) []Cancelled {
+	now := time.Now()
+	var rt []Cancelled
+	for _, f := range w.inflights() {
+		s := f.stat(now)
+		if !match(s) {
+			continue
+		}
+		f.cancelRequest(kill)
+		rt = append(rt, Cancelled{RequestStat: s, f: f})
+	}
+	return rt
+}
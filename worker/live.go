@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// liveWriter forwards the collected output as EventOutput. Output is coalesced
+// and sent at most once per interval, and bytes beyond the limit are dropped.
+// It never blocks nor fails the collector.
+type liveWriter struct {
+	send     func([]byte)
+	interval time.Duration
+
+	mu     sync.Mutex
+	remain int64
+	buf    []byte
+	last   time.Time
+	timer  *time.Timer
+	closed bool
+}
+
+func newLiveWriter(send func([]byte), interval time.Duration, limit envexec.Size) *liveWriter {
+	return &liveWriter{
+		send:     send,
+		interval: interval,
+		remain:   int64(limit),
+	}
+}
+
+func (w *liveWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed || w.remain <= 0 {
+		return n, nil
+	}
+	if int64(len(p)) > w.remain {
+		p = p[:w.remain]
+	}
+	w.remain -= int64(len(p))
+	w.buf = append(w.buf, p...)
+
+	wait := w.interval - time.Since(w.last)
+	if wait <= 0 || w.remain <= 0 {
+		w.flushLocked()
+	} else if w.timer == nil {
+		w.timer = time.AfterFunc(wait, w.flush)
+	}
+	return n, nil
+}
+
+func (w *liveWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+}
+
+func (w *liveWriter) flushLocked() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if len(w.buf) == 0 || w.closed {
+		return
+	}
+	w.send(w.buf)
+	w.buf = nil
+	w.last = time.Now()
+}
+
+// Close sends the pending output and stops further events
+func (w *liveWriter) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+	w.closed = true
+}
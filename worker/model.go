@@ -14,6 +14,7 @@ type CmdCopyOutFile = envexec.CmdCopyOutFile
 type PipeMap = envexec.Pipe
 type PipeIndex = envexec.PipeIndex
 type FileError = envexec.FileError
+type FileDigest = envexec.FileDigest
 
 // Cmd defines command and limits to start a program using in envexec
 type Cmd struct {
@@ -37,7 +38,17 @@ type Cmd struct {
 	CopyOut         []CmdCopyOutFile
 	CopyOutCached   []CmdCopyOutFile
 	CopyOutMax      uint64
+	CopyOutMaxFiles uint64
 	CopyOutTruncate bool
+	// CopyOutTruncateTail keeps the last bytes out of CopyOutMax when truncated
+	CopyOutTruncateTail uint64
+
+	// CopyOutDigest computes size and SHA-256 of copy out files and
+	// CopyOutDigestOnly returns the digests without the content of CopyOut
+	CopyOutDigest     bool
+	CopyOutDigestOnly bool
+
+	CopyOutDirFormat envexec.ArchiveFormat
 
 	TTY               bool
 	DataSegmentLimit  bool
@@ -47,22 +58,87 @@ type Cmd struct {
 // Request defines single worker request
 type Request struct {
 	RequestID   string
+	Namespace   string
 	Cmd         []Cmd
 	PipeMapping []PipeMap
+
+	// Tags labels the request to be selected by Worker.Cancel (optional)
+	Tags []string
+
+	// Progress is called when the request moves to the next stage (optional).
+	// It could be called concurrently by cmd in a group.
+	Progress func(Event)
+}
+
+// EventType defines the stage of the request reported by Request.Progress
+type EventType int
+
+// EventType enums
+const (
+	EventQueued          EventType = iota + 1 // request is queued with position
+	EventStarted                              // request is picked up by worker
+	EventCopyInDone                           // files of cmd are copied in
+	EventProcessStarted                       // process of cmd is started
+	EventProcessFinished                      // process of cmd exits with result
+	EventCopyOutDone                          // files of cmd are copied out
+	EventOutput                               // output chunk of live collector
+)
+
+// Event defines the progress event of a request
+type Event struct {
+	Type     EventType
+	Index    int     // index of the cmd for cmd events
+	Position int     // position in the queue for EventQueued
+	Result   *Result // result without files for EventProcessFinished
+	Name     string  // collector name for EventOutput
+	Content  []byte  // output chunk for EventOutput
+}
+
+// MaxEvents returns the max number of progress events of the request, not
+// counting EventOutput which is rate limited by the worker instead
+func (r *Request) MaxEvents() int {
+	return 2 + 4*len(r.Cmd)
+}
+
+// maxBufferedOutput is the number of EventOutput buffered by BufferEvents
+const maxBufferedOutput = 64
+
+// BufferEvents sets Progress to send events into the returned channel without
+// blocking the worker. EventOutput is dropped when the buffered events reach
+// maxBufferedOutput so that the other events are always buffered.
+func (r *Request) BufferEvents() <-chan Event {
+	ch := make(chan Event, r.MaxEvents()+maxBufferedOutput)
+	r.Progress = func(e Event) {
+		if e.Type == EventOutput && len(ch) >= maxBufferedOutput {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return ch
+}
+
+func (r *Request) progress(e Event) {
+	if r.Progress != nil {
+		r.Progress(e)
+	}
 }
 
 // Result defines single command response
 type Result struct {
-	Status     envexec.Status
-	ExitStatus int
-	Error      string
-	Time       time.Duration
-	RunTime    time.Duration
-	Memory     Size
-	ProcPeak   uint64
-	Files      map[string]*os.File
-	FileIDs    map[string]string
-	FileError  []FileError
+	Status      envexec.Status
+	ExitStatus  int
+	Error       string
+	Time        time.Duration
+	RunTime     time.Duration
+	Memory      Size
+	ProcPeak    uint64
+	Files       map[string]*os.File
+	FileIDs     map[string]string
+	FileDigests map[string]FileDigest
+	FileError   []FileError
 }
 
 // Response defines worker response for single request
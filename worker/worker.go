@@ -2,8 +2,10 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,6 +16,15 @@ import (
 
 const maxWaiting = 512
 
+// ErrDraining is the error of the response of requests submitted when the
+// worker is draining, the request could be retried later or on other workers
+var ErrDraining = errors.New("worker is draining")
+
+// ErrFileAttrUnsupported is the error of copyIn files that are read-only or
+// owned by the user when programs run as the container root, thus neither of
+// them is enforced
+var ErrFileAttrUnsupported = errors.New("readOnly and owner user copyIn require a separate container credential")
+
 // EnvironmentPool defines pools for environment to be used to execute commands
 type EnvironmentPool interface {
 	Get() (envexec.Environment, error)
@@ -24,6 +35,7 @@ type EnvironmentPool interface {
 // Config defines worker configuration
 type Config struct {
 	FileStore             filestore.FileStore
+	Datasets              *filestore.Datasets
 	EnvironmentPool       EnvironmentPool
 	Parallelism           int
 	WorkDir               string
@@ -31,9 +43,21 @@ type Config struct {
 	ExtraMemoryLimit      envexec.Size
 	OutputLimit           envexec.Size
 	CopyOutLimit          envexec.Size
+	CopyOutFileLimit      uint64
+	ExtractLimit          envexec.ArchiveLimit
 	OpenFileLimit         uint64
 	ExecObserver          func(Response)
 	CPUSets               []string
+
+	// LiveOutputInterval and LiveOutputLimit defines the min interval between
+	// EventOutput and the max bytes reported for each live collector (zero
+	// limit disables live output)
+	LiveOutputInterval time.Duration
+	LiveOutputLimit    envexec.Size
+
+	// SeparateCred reports whether programs run with a credential other than
+	// the container root, read-only and owner user copyIn are rejected if not
+	SeparateCred bool
 }
 
 // Worker defines interface for executor
@@ -47,13 +71,102 @@ type Worker interface {
 
 // Stat stores the statistic of the Worker
 type Stat struct {
-	Queue   int
-	Running int
+	Queue       int
+	Running     int
+	Parallelism int
+	Pool        PoolStat
+	Draining    bool
+}
+
+// PoolStat stores the statistic of the EnvironmentPool
+type PoolStat struct {
+	Idle  int // environments ready to be used
+	Total int // environments created and not destroyed
+}
+
+// PoolStater is implemented by the EnvironmentPool that reports its statistic
+type PoolStater interface {
+	Stat() PoolStat
+}
+
+// StatPool returns the statistic of the pool, or zero if the pool does not
+// implement PoolStater
+func StatPool(p EnvironmentPool) PoolStat {
+	if s, ok := p.(PoolStater); ok {
+		return s.Stat()
+	}
+	return PoolStat{}
+}
+
+// EnvironmentDiscarder is implemented by the EnvironmentPool that destroys the
+// environment taken by Get rather than putting it back
+type EnvironmentDiscarder interface {
+	Discard(envexec.Environment)
+}
+
+// DiscardEnvironment destroys the environment taken from the pool so that the
+// processes inside are killed, the pool is told if it implements
+// EnvironmentDiscarder and the environment must not be put back
+func DiscardEnvironment(p EnvironmentPool, env envexec.Environment) {
+	if d, ok := p.(EnvironmentDiscarder); ok {
+		d.Discard(env)
+		return
+	}
+	if d, ok := env.(interface{ Destroy() error }); ok {
+		d.Destroy()
+	}
+}
+
+// RequestStater is implemented by the Worker that reports its requests queued
+// or running
+type RequestStater interface {
+	Requests() []RequestStat
+}
+
+// StatRequests returns the requests queued or running in the worker, or nil if
+// the worker does not implement RequestStater
+func StatRequests(w Worker) []RequestStat {
+	if s, ok := w.(RequestStater); ok {
+		return s.Requests()
+	}
+	return nil
+}
+
+// Canceller is implemented by the Worker that cancels its requests queued or
+// running
+type Canceller interface {
+	Cancel(match func(RequestStat) bool, kill bool) []Cancelled
+}
+
+// CancelRequests cancels the requests selected by match, or returns nil if the
+// worker does not implement Canceller
+func CancelRequests(w Worker, match func(RequestStat) bool, kill bool) []Cancelled {
+	if c, ok := w.(Canceller); ok {
+		return c.Cancel(match, kill)
+	}
+	return nil
+}
+
+// Drainer is implemented by the Worker that rejects new requests with
+// ErrDraining while the requests in flight are kept to be finished
+type Drainer interface {
+	Drain(draining bool)
+}
+
+// DrainRequests drains or resumes the worker, it returns false if the worker
+// does not implement Drainer
+func DrainRequests(w Worker, draining bool) bool {
+	if d, ok := w.(Drainer); ok {
+		d.Drain(draining)
+		return true
+	}
+	return false
 }
 
 // worker defines executor worker
 type worker struct {
 	fs          filestore.FileStore
+	datasets    *filestore.Datasets
 	envPool     EnvironmentPool
 	parallelism int
 	workDir     string
@@ -62,8 +175,13 @@ type worker struct {
 	extraMemoryLimit      envexec.Size
 	outputLimit           envexec.Size
 	copyOutLimit          envexec.Size
+	copyOutFileLimit      uint64
+	extractLimit          envexec.ArchiveLimit
 	openFileLimit         uint64
 	cpuSets               []string
+	liveOutputInterval    time.Duration
+	liveOutputLimit       envexec.Size
+	separateCred          bool
 
 	execObserver func(Response)
 
@@ -74,19 +192,22 @@ type worker struct {
 	workCh    chan workRequest
 	done      chan struct{}
 	running   atomic.Int32
+	draining  atomic.Bool
+
+	inflightMu sync.Mutex
+	inflight   map[*inflight]struct{}
 }
 
 type workRequest struct {
-	*Request
+	*inflight
 	context.Context
-	started  chan<- struct{}
-	resultCh chan<- Response
 }
 
 // New creates new worker
 func New(conf Config) Worker {
 	return &worker{
 		fs:                    conf.FileStore,
+		datasets:              conf.Datasets,
 		envPool:               conf.EnvironmentPool,
 		parallelism:           conf.Parallelism,
 		workDir:               conf.WorkDir,
@@ -94,9 +215,15 @@ func New(conf Config) Worker {
 		extraMemoryLimit:      conf.ExtraMemoryLimit,
 		outputLimit:           conf.OutputLimit,
 		copyOutLimit:          conf.CopyOutLimit,
+		copyOutFileLimit:      conf.CopyOutFileLimit,
+		extractLimit:          conf.ExtractLimit,
 		openFileLimit:         conf.OpenFileLimit,
 		cpuSets:               conf.CPUSets,
+		liveOutputInterval:    conf.LiveOutputInterval,
+		liveOutputLimit:       conf.LiveOutputLimit,
+		separateCred:          conf.SeparateCred,
 		execObserver:          conf.ExecObserver,
+		inflight:              make(map[*inflight]struct{}),
 	}
 }
 
@@ -132,26 +259,33 @@ func (w *worker) Submit(ctx context.Context, req *Request) (<-chan Response, <-c
 		}
 		return ch, started
 	}
+	if w.draining.Load() {
+		close(started)
+		ch <- Response{
+			RequestID: req.RequestID,
+			Error:     ErrDraining,
+		}
+		return ch, started
+	}
 
+	// reported before enqueue so that it always precedes the started event
+	ctx, f := w.track(ctx, req, started, ch)
+	f.report(Event{Type: EventQueued, Position: len(w.workCh) + 1})
 	select {
 	case <-w.done:
-		close(started)
-		ch <- Response{
+		f.finish(Response{
 			RequestID: req.RequestID,
 			Error:     fmt.Errorf("worker is shutting down"),
-		}
+		})
 	case w.workCh <- workRequest{
-		Request:  req,
+		inflight: f,
 		Context:  ctx,
-		started:  started,
-		resultCh: ch,
 	}:
 	default:
-		close(started)
-		ch <- Response{
+		f.finish(Response{
 			RequestID: req.RequestID,
 			Error:     fmt.Errorf("worker queue is full"),
-		}
+		})
 	}
 	return ch, started
 }
@@ -159,19 +293,44 @@ func (w *worker) Submit(ctx context.Context, req *Request) (<-chan Response, <-c
 // Execute will execute the request in new goroutine (bypass the parallelism limit)
 func (w *worker) Execute(ctx context.Context, req *Request) <-chan Response {
 	ch := make(chan Response, 1)
+	if w.draining.Load() {
+		ch <- Response{
+			RequestID: req.RequestID,
+			Error:     ErrDraining,
+		}
+		return ch
+	}
+	ctx, f := w.track(ctx, req, nil, ch)
 	w.wg.Go(func() {
-		ch <- w.workDoCmd(ctx, req, "")
+		if !f.begin() {
+			return
+		}
+		f.report(Event{Type: EventStarted})
+		f.finish(w.workDoCmd(ctx, f, ""))
 	})
 	return ch
 }
 
 func (w *worker) Stat() Stat {
+	parallelism := w.parallelism
+	if len(w.cpuSets) > 0 {
+		parallelism = len(w.cpuSets)
+	}
 	return Stat{
-		Queue:   len(w.workCh),
-		Running: int(w.running.Load()),
+		Queue:       len(w.workCh),
+		Running:     int(w.running.Load()),
+		Parallelism: parallelism,
+		Pool:        StatPool(w.envPool),
+		Draining:    w.draining.Load(),
 	}
 }
 
+// Drain rejects new requests with ErrDraining while the requests queued and
+// running are kept to be finished, false resumes accepting requests
+func (w *worker) Drain(draining bool) {
+	w.draining.Store(draining)
+}
+
 // Shutdown waits all worker to finish
 func (w *worker) Shutdown() {
 	w.stopOnce.Do(func() {
@@ -194,16 +353,20 @@ func (w *worker) loop(cpuset string) {
 			if !ok {
 				return
 			}
-			close(req.started)
+			// cancelled while queued
+			if !req.begin() {
+				continue
+			}
+			req.report(Event{Type: EventStarted})
 
 			select {
 			case <-req.Context.Done():
-				req.resultCh <- Response{
+				req.finish(Response{
 					RequestID: req.RequestID,
 					Error:     fmt.Errorf("cancelled before execute"),
-				}
+				})
 			default:
-				req.resultCh <- w.workDoCmd(req.Context, req.Request, cpuset)
+				req.finish(w.workDoCmd(req.Context, req.inflight, cpuset))
 			}
 
 		case <-w.done:
@@ -212,15 +375,19 @@ func (w *worker) loop(cpuset string) {
 	}
 }
 
-func (w *worker) workDoCmd(ctx context.Context, req *Request, cpuset string) Response {
+func (w *worker) workDoCmd(ctx context.Context, req *inflight, cpuset string) Response {
 	w.running.Add(1)
 	defer w.running.Add(-1)
 
+	// files are resolved and cached within the namespace of the request while
+	// datasets are shared by all namespaces
+	fs := filestore.WithDatasets(filestore.ForNamespace(w.fs, req.Namespace), w.datasets)
+
 	var rt Response
 	if len(req.Cmd) == 1 {
-		rt = w.workDoSingle(ctx, req.Cmd[0], cpuset)
+		rt = w.workDoSingle(ctx, fs, req.Cmd[0], cpuset, req)
 	} else {
-		rt = w.workDoGroup(ctx, req.Cmd, req.PipeMapping, cpuset)
+		rt = w.workDoGroup(ctx, fs, req.Cmd, req.PipeMapping, cpuset, req)
 	}
 	rt.RequestID = req.RequestID
 	if w.execObserver != nil {
@@ -229,38 +396,41 @@ func (w *worker) workDoCmd(ctx context.Context, req *Request, cpuset string) Res
 	return rt
 }
 
-func (w *worker) workDoSingle(ctx context.Context, rc Cmd, cpuset string) (rt Response) {
-	c, err := w.prepareCmd(rc, make(map[string]bool), cpuset)
+func (w *worker) workDoSingle(ctx context.Context, fs filestore.FileStore, rc Cmd, cpuset string, req *inflight) (rt Response) {
+	c, err := w.prepareCmd(fs, rc, make(map[string]bool), cpuset)
 	if err != nil {
 		rt.Error = err
 		return
 	}
+	live := w.prepareLive(c, rc, 0, req.Progress)
+	defer closeLive(live)
+	c.Progress = cmdProgress(req.report, 0, rc, live)
 	// prepare environment
-	env, err := w.envPool.Get()
+	env, err := w.getEnv(req)
 	if err != nil {
 		return Response{Results: []Result{{
 			Status: envexec.StatusInternalError,
 			Error:  fmt.Sprintf("failed to get environment %v", err),
 		}}}
 	}
-	defer w.envPool.Put(env)
+	defer w.putEnv(req, env)
 	c.Environment = env
 
 	s := &envexec.Single{
 		Cmd:          c,
-		NewStoreFile: w.fs.New,
+		NewStoreFile: fs.New,
 	}
 	result, err := s.Run(ctx)
 	if err != nil {
 		rt.Error = err
 		return
 	}
-	res := w.convertResult(result, rc)
+	res := w.convertResult(fs, result, rc)
 	rt.Results = []Result{res}
 	return
 }
 
-func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap, cpuset string) (rt Response) {
+func (w *worker) workDoGroup(ctx context.Context, fs filestore.FileStore, rc []Cmd, pm []PipeMap, cpuset string, req *inflight) (rt Response) {
 	var rts []Result
 	cs := make([]*envexec.Cmd, 0, len(rc))
 	pipes := make([]PipeMap, 0, len(pm))
@@ -270,15 +440,18 @@ func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap, cpuset
 	}
 	pipeFileNames := preparePipeNames(pm, len(rc))
 	for i, cc := range rc {
-		c, err := w.prepareCmd(cc, pipeFileNames[i], cpuset)
+		c, err := w.prepareCmd(fs, cc, pipeFileNames[i], cpuset)
 		if err != nil {
 			rt.Error = err
 			return
 		}
+		live := w.prepareLive(c, cc, i, req.Progress)
+		defer closeLive(live)
+		c.Progress = cmdProgress(req.report, i, cc, live)
 		cs = append(cs, c)
 	}
 	for i := range cs {
-		env, err := w.envPool.Get()
+		env, err := w.getEnv(req)
 		if err != nil {
 			res := make([]Result, 0, len(cs))
 			for range cs {
@@ -289,13 +462,13 @@ func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap, cpuset
 			}
 			return Response{Results: res}
 		}
-		defer w.envPool.Put(env)
+		defer w.putEnv(req, env)
 		cs[i].Environment = env
 	}
 	g := envexec.Group{
 		Cmd:          cs,
 		Pipes:        pipes,
-		NewStoreFile: w.fs.New,
+		NewStoreFile: fs.New,
 	}
 	results, err := g.Run(ctx)
 	if err != nil {
@@ -304,14 +477,87 @@ func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap, cpuset
 	}
 	rts = make([]Result, 0, len(results))
 	for i, result := range results {
-		res := w.convertResult(result, rc[i])
+		res := w.convertResult(fs, result, rc[i])
 		rts = append(rts, res)
 	}
 	rt.Results = rts
 	return
 }
 
-func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
+// prepareLive attaches live writers to the live collectors of the cmd
+func (w *worker) prepareLive(c *envexec.Cmd, rc Cmd, index int, progress func(Event)) []*liveWriter {
+	if progress == nil {
+		return nil
+	}
+	var rt []*liveWriter
+	for i, f := range rc.Files {
+		lc, ok := f.(*Collector)
+		if !ok || !lc.Live || i >= len(c.Files) {
+			continue
+		}
+		fc, ok := c.Files[i].(*envexec.FileCollector)
+		if !ok {
+			continue
+		}
+		lw := newLiveWriter(func(b []byte) {
+			progress(Event{Type: EventOutput, Index: index, Name: lc.Name, Content: b})
+		}, w.liveOutputInterval, w.liveOutputLimit)
+		fc.Live = lw
+		rt = append(rt, lw)
+	}
+	return rt
+}
+
+func closeLive(live []*liveWriter) {
+	for _, lw := range live {
+		lw.Close()
+	}
+}
+
+// cmdProgress converts the stage of the cmd into event of the request, pending
+// live output is sent before EventCopyOutDone
+func cmdProgress(progress func(Event), index int, cmd Cmd, live []*liveWriter) func(envexec.Stage, *envexec.Result) {
+	if progress == nil {
+		return nil
+	}
+	return func(s envexec.Stage, r *envexec.Result) {
+		e := Event{Index: index}
+		switch s {
+		case envexec.StageCopyInDone:
+			e.Type = EventCopyInDone
+		case envexec.StageProcessStarted:
+			e.Type = EventProcessStarted
+		case envexec.StageProcessFinished:
+			e.Type = EventProcessFinished
+			e.Result = &Result{
+				Status:     r.Status,
+				ExitStatus: r.ExitStatus,
+				Error:      r.Error,
+				Time:       r.Time,
+				RunTime:    r.RunTime,
+				Memory:     r.Memory,
+				ProcPeak:   r.ProcPeak,
+			}
+			fixCancelledTLE(e.Result, cmd)
+		case envexec.StageCopyOutDone:
+			closeLive(live)
+			e.Type = EventCopyOutDone
+		default:
+			return
+		}
+		progress(e)
+	}
+}
+
+// fixCancelledTLE fixes TLE due to context cancel
+func fixCancelledTLE(res *Result, cmd Cmd) {
+	if res.Status == envexec.StatusTimeLimitExceeded && res.ExitStatus != 0 &&
+		res.Time < cmd.CPULimit && res.RunTime < cmd.ClockLimit {
+		res.Status = envexec.StatusSignalled
+	}
+}
+
+func (w *worker) convertResult(fs filestore.FileStore, result envexec.Result, cmd Cmd) (res Result) {
 	res.Status = result.Status
 	res.ExitStatus = result.ExitStatus
 	res.Error = result.Error
@@ -320,20 +566,36 @@ func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
 	res.Memory = result.Memory
 	res.ProcPeak = result.ProcPeak
 	res.FileError = result.FileError
+	res.FileDigests = result.FileDigests
 	res.Files = make(map[string]*os.File)
 	res.FileIDs = make(map[string]string)
 
-	// Fix TLE due to context cancel
-	if res.Status == envexec.StatusTimeLimitExceeded && res.ExitStatus != 0 &&
-		res.Time < cmd.CPULimit && res.RunTime < cmd.ClockLimit {
-		res.Status = envexec.StatusSignalled
-	}
+	fixCancelledTLE(&res, cmd)
 
+	copyOutSet := make(map[string]bool, len(cmd.CopyOut))
+	for _, f := range cmd.CopyOut {
+		if !f.Glob {
+			copyOutSet[f.Name] = true
+		}
+	}
 	copyOutCachedSet := make(map[string]bool, len(cmd.CopyOutCached))
+	var copyOutCachedGlobs []string
 	for _, f := range cmd.CopyOutCached {
+		if f.Glob {
+			copyOutCachedGlobs = append(copyOutCachedGlobs, path.Clean(f.Name))
+			continue
+		}
 		copyOutCachedSet[f.Name] = true
 	}
+	// exact names take priority over glob patterns
+	cached := func(name string) bool {
+		if copyOutCachedSet[name] {
+			return true
+		}
+		return !copyOutSet[name] && matchAnyGlob(copyOutCachedGlobs, name)
+	}
 	cachedAdded := make(map[string]bool, len(cmd.CopyOutCached))
+	discarded := make(map[string]bool)
 
 	defer func() {
 		if res.Status != envexec.StatusFileError {
@@ -343,7 +605,7 @@ func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
 			if _, ok := res.Files[name]; ok {
 				continue
 			}
-			if cachedAdded[name] {
+			if cachedAdded[name] || discarded[name] {
 				continue
 			}
 			f.Close()
@@ -351,11 +613,18 @@ func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
 	}()
 
 	for name, b := range result.Files {
-		if !copyOutCachedSet[name] {
+		if !cached(name) {
+			// only the digest is returned thus the content is dropped
+			if cmd.CopyOutDigestOnly {
+				discarded[name] = true
+				b.Close()
+				os.Remove(b.Name())
+				continue
+			}
 			res.Files[name] = b
 			continue
 		}
-		id, err := w.fs.Add(name, b.Name())
+		id, err := fs.Add(name, b.Name())
 		if err != nil {
 			res.Status = envexec.StatusFileError
 			res.Error = err.Error()
@@ -368,12 +637,21 @@ func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
 	return res
 }
 
-func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool, cpuset string) (*envexec.Cmd, error) {
-	files, err := w.prepareCmdFiles(rc.Files, pipeFileName)
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := envexec.MatchGlob(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *worker) prepareCmd(fs filestore.FileStore, rc Cmd, pipeFileName map[string]bool, cpuset string) (*envexec.Cmd, error) {
+	files, err := w.prepareCmdFiles(fs, rc.Files, pipeFileName)
 	if err != nil {
 		return nil, err
 	}
-	copyIn, err := w.prepareCopyIn(rc.CopyIn)
+	copyIn, err := w.prepareCopyIn(fs, rc.CopyIn)
 	if err != nil {
 		return nil, err
 	}
@@ -402,6 +680,11 @@ func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool, cpuset string)
 		copyOutMax = envexec.Size(rc.CopyOutMax)
 	}
 
+	copyOutMaxFiles := rc.CopyOutMaxFiles
+	if copyOutMaxFiles == 0 {
+		copyOutMaxFiles = w.copyOutFileLimit
+	}
+
 	outputLimit := rc.OutputLimit
 	if outputLimit == 0 {
 		outputLimit = w.outputLimit
@@ -418,56 +701,79 @@ func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool, cpuset string)
 	}
 
 	return &envexec.Cmd{
-		Args:              rc.Args,
-		Env:               rc.Env,
-		Files:             files,
-		TTY:               rc.TTY,
-		TimeLimit:         timeLimit,
-		MemoryLimit:       envexec.Size(rc.MemoryLimit),
-		StackLimit:        envexec.Size(rc.StackLimit),
-		ExtraMemoryLimit:  w.extraMemoryLimit,
-		OutputLimit:       outputLimit,
-		ProcLimit:         rc.ProcLimit,
-		OpenFileLimit:     openFileLimit,
-		CPURateLimit:      rc.CPURateLimit,
-		CPUSetLimit:       cpusetLimit,
-		DataSegmentLimit:  rc.DataSegmentLimit,
-		AddressSpaceLimit: rc.AddressSpaceLimit,
-		CopyIn:            copyIn,
-		SymLinks:          rc.Symlinks,
-		CopyOut:           copyOut,
-		CopyOutMax:        copyOutMax,
-		CopyOutTruncate:   rc.CopyOutTruncate,
-		Waiter:            wait.Wait,
+		Args:                rc.Args,
+		Env:                 rc.Env,
+		Files:               files,
+		TTY:                 rc.TTY,
+		TimeLimit:           timeLimit,
+		MemoryLimit:         envexec.Size(rc.MemoryLimit),
+		StackLimit:          envexec.Size(rc.StackLimit),
+		ExtraMemoryLimit:    w.extraMemoryLimit,
+		OutputLimit:         outputLimit,
+		ProcLimit:           rc.ProcLimit,
+		OpenFileLimit:       openFileLimit,
+		CPURateLimit:        rc.CPURateLimit,
+		CPUSetLimit:         cpusetLimit,
+		DataSegmentLimit:    rc.DataSegmentLimit,
+		AddressSpaceLimit:   rc.AddressSpaceLimit,
+		CopyIn:              copyIn,
+		SymLinks:            rc.Symlinks,
+		CopyOut:             copyOut,
+		CopyOutMax:          copyOutMax,
+		CopyOutMaxFiles:     copyOutMaxFiles,
+		CopyOutTruncate:     rc.CopyOutTruncate,
+		CopyOutTruncateTail: envexec.Size(rc.CopyOutTruncateTail),
+		CopyOutDigest:       rc.CopyOutDigest || rc.CopyOutDigestOnly,
+		CopyOutDirFormat:    rc.CopyOutDirFormat,
+		Waiter:              wait.Wait,
 	}, nil
 }
 
-func (w *worker) prepareCopyIn(cf map[string]CmdFile) (map[string]envexec.File, error) {
+func (w *worker) prepareCopyIn(fs filestore.FileStore, cf map[string]CmdFile) (map[string]envexec.File, error) {
 	rt := make(map[string]envexec.File)
 	for name, f := range cf {
 		if f == nil {
 			return nil, fmt.Errorf("nil type cannot be used for copyIn %s", name)
 		}
-		pcf, err := f.EnvFile(w.fs)
+		pcf, err := f.EnvFile(fs)
 		if err != nil {
 			return nil, err
 		}
+		if d, ok := pcf.(*envexec.FileDir); ok && d.Limit == (envexec.ArchiveLimit{}) {
+			d.Limit = w.extractLimit
+		}
+		if !w.separateCred && unenforcedAttr(pcf) {
+			return nil, fmt.Errorf("copyIn %s: %w", name, ErrFileAttrUnsupported)
+		}
 		rt[name] = pcf
 	}
 	return rt, nil
 }
 
-func (w *worker) prepareCmdFiles(files []CmdFile, pipeFileName map[string]bool) ([]envexec.File, error) {
+// unenforcedAttr reports whether the file is read-only or owned by the user,
+// which is not enforced when programs run as the container root
+func unenforcedAttr(f envexec.File) bool {
+	a, ok := f.(*envexec.FileAttr)
+	return ok && (a.ReadOnly || a.Owner == envexec.OwnerUser)
+}
+
+func (w *worker) prepareCmdFiles(fs filestore.FileStore, files []CmdFile, pipeFileName map[string]bool) ([]envexec.File, error) {
 	rt := make([]envexec.File, 0, len(files))
 	for _, f := range files {
 		if f == nil {
 			rt = append(rt, nil)
 			continue
 		}
-		cf, err := f.EnvFile(w.fs)
+		cf, err := f.EnvFile(fs)
 		if err != nil {
 			return nil, err
 		}
+		switch cf.(type) {
+		case *envexec.FileDir:
+			return nil, fmt.Errorf("directory cannot be used as cmd file %s", f)
+		case *envexec.FileBind:
+			return nil, fmt.Errorf("zero-copy file cannot be used as cmd file %s", f)
+		}
 		rt = append(rt, cf)
 		if t, ok := cf.(*envexec.FileCollector); ok {
 			pipeFileName[t.Name] = true
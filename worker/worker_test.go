@@ -1,11 +1,15 @@
 package worker
 
 import (
+	"context"
 	"errors"
 	"os"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
 )
 
 type failingFileStore struct{}
@@ -24,7 +28,7 @@ func TestConvertResultClosesCachedFileOnAddFailure(t *testing.T) {
 	}
 
 	w := &worker{fs: failingFileStore{}}
-	res := w.convertResult(envexec.Result{
+	res := w.convertResult(w.fs, envexec.Result{
 		Files: map[string]*os.File{"out": tmp},
 	}, Cmd{
 		CopyOutCached: []CmdCopyOutFile{{Name: "out"}},
@@ -40,3 +44,364 @@ func TestConvertResultClosesCachedFileOnAddFailure(t *testing.T) {
 		t.Fatal("expected cached file to be closed on add failure")
 	}
 }
+
+func TestConvertResultCachesGlobMatches(t *testing.T) {
+	dir := t.TempDir()
+	fs := filestore.NewFileLocalStore(dir)
+	files := make(map[string]*os.File)
+	for _, n := range []string{"out/a.class", "out/b.class", "out/log.txt"} {
+		f, err := fs.New()
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		files[n] = f
+	}
+
+	w := &worker{fs: fs}
+	res := w.convertResult(fs, envexec.Result{Files: files}, Cmd{
+		CopyOut:       []CmdCopyOutFile{{Name: "out/*.txt", Glob: true}},
+		CopyOutCached: []CmdCopyOutFile{{Name: "./out/*.class", Glob: true}},
+	})
+
+	if len(res.FileIDs) != 2 || res.FileIDs["out/a.class"] == "" || res.FileIDs["out/b.class"] == "" {
+		t.Fatalf("expected class files cached, got %v", res.FileIDs)
+	}
+	if _, ok := res.Files["out/log.txt"]; !ok || len(res.Files) != 1 {
+		t.Fatalf("expected log file returned, got %v", res.Files)
+	}
+	res.Files["out/log.txt"].Close()
+}
+
+func TestConvertResultExactNameOverGlob(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	files := make(map[string]*os.File)
+	for _, n := range []string{"out/a.class", "out/main.class"} {
+		f, err := fs.New()
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		files[n] = f
+	}
+
+	w := &worker{fs: fs}
+	res := w.convertResult(fs, envexec.Result{Files: files}, Cmd{
+		CopyOut:       []CmdCopyOutFile{{Name: "out/main.class"}},
+		CopyOutCached: []CmdCopyOutFile{{Name: "out/*.class", Glob: true}},
+	})
+
+	if len(res.FileIDs) != 1 || res.FileIDs["out/a.class"] == "" {
+		t.Fatalf("expected only glob match cached, got %v", res.FileIDs)
+	}
+	if _, ok := res.Files["out/main.class"]; !ok || len(res.Files) != 1 {
+		t.Fatalf("expected exact copyOut returned, got %v", res.Files)
+	}
+	res.Files["out/main.class"].Close()
+}
+
+func TestConvertResultDigestOnly(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	files := make(map[string]*os.File)
+	for _, n := range []string{"stdout", "out.bin"} {
+		f, err := fs.New()
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		files[n] = f
+	}
+	digests := map[string]FileDigest{
+		"stdout":  {Size: 1, SHA256: "a"},
+		"out.bin": {Size: 2, SHA256: "b"},
+	}
+	stdout := files["stdout"].Name()
+
+	w := &worker{fs: fs}
+	res := w.convertResult(fs, envexec.Result{Files: files, FileDigests: digests}, Cmd{
+		CopyOut:           []CmdCopyOutFile{{Name: "stdout"}},
+		CopyOutCached:     []CmdCopyOutFile{{Name: "out.bin"}},
+		CopyOutDigestOnly: true,
+	})
+
+	if len(res.Files) != 0 {
+		t.Fatalf("expected no content returned, got %v", res.Files)
+	}
+	if _, err := os.Stat(stdout); !os.IsNotExist(err) {
+		t.Fatalf("expected dropped file removed, got %v", err)
+	}
+	if res.FileIDs["out.bin"] == "" {
+		t.Fatalf("expected cached file kept, got %v", res.FileIDs)
+	}
+	if len(res.FileDigests) != 2 {
+		t.Fatalf("expected digests returned, got %v", res.FileDigests)
+	}
+}
+
+func TestPrepareCopyInWithoutSeparateCred(t *testing.T) {
+	content := &MemoryFile{Content: []byte("ans")}
+	for name, f := range map[string]CmdFile{
+		"readOnly": &AttrFile{File: content, ReadOnly: true},
+		"owner":    &AttrFile{File: content, Owner: envexec.OwnerUser},
+	} {
+		w := &worker{}
+		if _, err := w.prepareCopyIn(nil, map[string]CmdFile{"ans.txt": f}); !errors.Is(err, ErrFileAttrUnsupported) {
+			t.Fatalf("%s: expected ErrFileAttrUnsupported, got %v", name, err)
+		}
+		w = &worker{separateCred: true}
+		if _, err := w.prepareCopyIn(nil, map[string]CmdFile{"ans.txt": f}); err != nil {
+			t.Fatalf("%s: expected accepted with separate credential, got %v", name, err)
+		}
+	}
+
+	w := &worker{}
+	if _, err := w.prepareCopyIn(nil, map[string]CmdFile{"ans.txt": &AttrFile{File: content, Mode: 0600}}); err != nil {
+		t.Fatalf("expected mode accepted, got %v", err)
+	}
+}
+
+type failingEnvPool struct{}
+
+func (failingEnvPool) Get() (envexec.Environment, error) { return nil, errors.New("no environment") }
+func (failingEnvPool) Put(envexec.Environment)           {}
+func (failingEnvPool) Destroy()                          {}
+
+func TestSubmitProgress(t *testing.T) {
+	w := New(Config{
+		FileStore:       filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool: failingEnvPool{},
+		Parallelism:     1,
+	})
+	w.Start()
+	defer w.Shutdown()
+
+	var events []Event
+	req := &Request{
+		Cmd:      []Cmd{{Args: []string{"true"}}},
+		Progress: func(e Event) { events = append(events, e) },
+	}
+	rtCh, _ := w.Submit(context.Background(), req)
+	rt := <-rtCh
+	if len(rt.Results) != 1 || rt.Results[0].Status != envexec.StatusInternalError {
+		t.Fatalf("expected internal error, got %+v", rt)
+	}
+	if len(events) != 2 || events[0].Type != EventQueued || events[0].Position != 1 || events[1].Type != EventStarted {
+		t.Fatalf("expected queued and started events, got %+v", events)
+	}
+}
+
+func TestCmdProgress(t *testing.T) {
+	if cmdProgress(nil, 0, Cmd{}, nil) != nil {
+		t.Fatal("expected nil progress without callback")
+	}
+	var events []Event
+	p := cmdProgress(func(e Event) { events = append(events, e) }, 1, Cmd{CPULimit: time.Second, ClockLimit: time.Second}, nil)
+	p(envexec.StageCopyInDone, nil)
+	p(envexec.StageProcessFinished, &envexec.Result{Status: envexec.StatusTimeLimitExceeded, ExitStatus: 9})
+	if len(events) != 2 || events[0].Type != EventCopyInDone || events[1].Index != 1 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if r := events[1].Result; r == nil || r.Status != envexec.StatusSignalled {
+		t.Fatalf("expected cancelled TLE fixed as signalled, got %+v", r)
+	}
+}
+
+func TestLiveOutput(t *testing.T) {
+	var events []Event
+	progress := func(e Event) { events = append(events, e) }
+	w := New(Config{LiveOutputInterval: time.Hour, LiveOutputLimit: 8}).(*worker)
+	c := &envexec.Cmd{Files: []envexec.File{nil, &envexec.FileCollector{Name: "stdout"}}}
+	rc := Cmd{Files: []CmdFile{nil, &Collector{Name: "stdout", Live: true}}}
+	live := w.prepareLive(c, rc, 1, progress)
+	lw := c.Files[1].(*envexec.FileCollector).Live
+	if len(live) != 1 || lw == nil {
+		t.Fatalf("expected live writer attached, got %+v", c.Files[1])
+	}
+	lw.Write([]byte("hello"))
+	lw.Write([]byte(" "))
+	p := cmdProgress(progress, 1, rc, live)
+	p(envexec.StageCopyOutDone, nil)
+	lw.Write([]byte("world"))
+	if len(events) != 3 || string(events[0].Content) != "hello" || string(events[1].Content) != " " ||
+		events[1].Name != "stdout" || events[1].Index != 1 || events[2].Type != EventCopyOutDone {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	// limited bytes are sent without waiting the interval
+	events = nil
+	lw = newLiveWriter(func(b []byte) { progress(Event{Content: b}) }, time.Hour, 8)
+	lw.Write([]byte("hello"))
+	lw.Write([]byte(" world"))
+	if len(events) != 2 || string(events[1].Content) != " wo" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+type blockingEnvPool struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p blockingEnvPool) Get() (envexec.Environment, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return nil, errors.New("no environment")
+}
+func (blockingEnvPool) Put(envexec.Environment) {}
+func (blockingEnvPool) Destroy()                {}
+func (blockingEnvPool) Stat() PoolStat          { return PoolStat{Idle: 1, Total: 2} }
+
+func TestRequests(t *testing.T) {
+	pool := blockingEnvPool{started: make(chan struct{}), release: make(chan struct{})}
+	w := New(Config{
+		FileStore:       filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool: pool,
+		Parallelism:     1,
+	})
+	w.Start()
+	defer w.Shutdown()
+
+	cmd := []Cmd{{Args: []string{"true"}}}
+	rt1, _ := w.Submit(context.Background(), &Request{RequestID: "1", Namespace: "a", Cmd: cmd})
+	<-pool.started
+	rt2, _ := w.Submit(context.Background(), &Request{RequestID: "2", Cmd: cmd})
+
+	if s := w.Stat(); s != (Stat{Queue: 1, Running: 1, Parallelism: 1, Pool: PoolStat{Idle: 1, Total: 2}}) {
+		t.Fatalf("unexpected stat %+v", s)
+	}
+	reqs := StatRequests(w)
+	if len(reqs) != 2 || reqs[0].RequestID != "1" || reqs[0].Namespace != "a" || reqs[0].Phase != EventStarted ||
+		reqs[1].RequestID != "2" || reqs[1].Phase != EventQueued || reqs[0].Elapsed < reqs[1].Elapsed {
+		t.Fatalf("unexpected requests %+v", reqs)
+	}
+
+	close(pool.release)
+	go func() {
+		for range pool.started {
+		}
+	}()
+	<-rt1
+	<-rt2
+	if reqs := StatRequests(w); len(reqs) != 0 {
+		t.Fatalf("expected no requests after finished, got %+v", reqs)
+	}
+}
+
+type killableEnv struct {
+	envexec.Environment
+	destroyed int
+}
+
+func (e *killableEnv) Destroy() error {
+	e.destroyed++
+	return nil
+}
+
+type destroyEnvPool struct {
+	env       *killableEnv
+	put       int
+	discarded int
+}
+
+func (p *destroyEnvPool) Get() (envexec.Environment, error) { return p.env, nil }
+func (p *destroyEnvPool) Put(envexec.Environment)           { p.put++ }
+func (p *destroyEnvPool) Destroy()                          {}
+
+func (p *destroyEnvPool) Discard(env envexec.Environment) {
+	p.discarded++
+	env.(*killableEnv).Destroy()
+}
+
+func TestCancel(t *testing.T) {
+	pool := blockingEnvPool{started: make(chan struct{}), release: make(chan struct{})}
+	w := New(Config{
+		FileStore:       filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool: pool,
+		Parallelism:     1,
+	})
+	w.Start()
+	defer w.Shutdown()
+
+	cmd := []Cmd{{Args: []string{"true"}}}
+	rt1, _ := w.Submit(context.Background(), &Request{RequestID: "1", Cmd: cmd})
+	<-pool.started
+	rt2, started := w.Submit(context.Background(), &Request{RequestID: "2", Cmd: cmd, Tags: []string{"t"}})
+
+	cancelled := CancelRequests(w, func(s RequestStat) bool { return slices.Contains(s.Tags, "t") }, false)
+	if len(cancelled) != 1 || cancelled[0].RequestID != "2" || cancelled[0].Phase != EventQueued {
+		t.Fatalf("unexpected cancelled %+v", cancelled)
+	}
+	<-started
+	if rt := <-rt2; !errors.Is(rt.Error, ErrCancelled) {
+		t.Fatalf("expected cancelled response, got %+v", rt)
+	}
+	if rt, err := cancelled[0].Wait(context.Background()); err != nil || !errors.Is(rt.Error, ErrCancelled) {
+		t.Fatalf("expected cancelled partial response, got %+v %v", rt, err)
+	}
+	if reqs := StatRequests(w); len(reqs) != 1 || reqs[0].RequestID != "1" {
+		t.Fatalf("expected the running request left, got %+v", reqs)
+	}
+
+	close(pool.release)
+	<-rt1
+}
+
+func TestCancelKill(t *testing.T) {
+	env := &killableEnv{}
+	pool := &destroyEnvPool{env: env}
+	w := New(Config{EnvironmentPool: pool}).(*worker)
+	ctx, f := w.track(context.Background(), &Request{RequestID: "1"}, nil, make(chan Response, 1))
+	if !f.begin() {
+		t.Fatal("expected request to begin")
+	}
+	if _, err := w.getEnv(f); err != nil {
+		t.Fatalf("getEnv: %v", err)
+	}
+
+	cancelled := w.Cancel(func(s RequestStat) bool { return s.RequestID == "1" }, true)
+	if len(cancelled) != 1 || env.destroyed != 1 || !errors.Is(context.Cause(ctx), ErrCancelled) {
+		t.Fatalf("expected request killed, got %+v destroyed=%v cause=%v", cancelled, env.destroyed, context.Cause(ctx))
+	}
+	w.putEnv(f, env)
+	if pool.put != 0 || pool.discarded != 1 || env.destroyed != 1 {
+		t.Fatalf("expected killed env discarded once, got put=%d discarded=%d destroyed=%d", pool.put, pool.discarded, env.destroyed)
+	}
+	if _, err := w.getEnv(f); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected getEnv of killed request cancelled, got %v", err)
+	}
+	if pool.put != 0 || pool.discarded != 2 || len(f.envs) != 0 {
+		t.Fatalf("expected env after kill discarded, got put=%d discarded=%d envs=%d", pool.put, pool.discarded, len(f.envs))
+	}
+	f.finish(Response{RequestID: "1", Results: []Result{{Status: envexec.StatusSignalled, Files: map[string]*os.File{"stdout": nil}}}})
+	rt, err := cancelled[0].Wait(context.Background())
+	if err != nil || len(rt.Results) != 1 || rt.Results[0].Status != envexec.StatusSignalled || rt.Results[0].Files != nil {
+		t.Fatalf("expected partial result without files, got %+v %v", rt, err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	w := New(Config{
+		FileStore:       filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool: failingEnvPool{},
+		Parallelism:     1,
+	})
+	w.Start()
+	defer w.Shutdown()
+
+	DrainRequests(w, true)
+	if !w.Stat().Draining {
+		t.Fatal("expected draining stat")
+	}
+	req := &Request{Cmd: []Cmd{{Args: []string{"true"}}}}
+	rtCh, started := w.Submit(context.Background(), req)
+	<-started
+	if rt := <-rtCh; !errors.Is(rt.Error, ErrDraining) {
+		t.Fatalf("expected draining error from submit, got %+v", rt)
+	}
+	if rt := <-w.Execute(context.Background(), req); !errors.Is(rt.Error, ErrDraining) {
+		t.Fatalf("expected draining error from execute, got %+v", rt)
+	}
+
+	DrainRequests(w, false)
+	rtCh, _ = w.Submit(context.Background(), req)
+	if rt := <-rtCh; rt.Error != nil {
+		t.Fatalf("expected request accepted after resume, got %+v", rt)
+	}
+}